@@ -37,7 +37,7 @@ export default function SurveyPage({ params }: { params: { corpusId: string } })
 
   const downloadCurrentText = () => {
     if (!report.trim()) return;
-    const ext = outputFormat === "latex" ? "tex" : "txt";
+    const ext = outputFormat === "latex" ? "tex" : outputFormat === "markdown" ? "md" : outputFormat === "html" ? "html" : "txt";
     const blob = new Blob([report], { type: outputFormat === "latex" ? "text/x-tex;charset=utf-8" : "text/plain;charset=utf-8" });
     const href = URL.createObjectURL(blob);
     const a = document.createElement("a");
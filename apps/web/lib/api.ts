@@ -33,6 +33,135 @@ async function req<T>(path: string, init?: RequestInit): Promise<T> {
   return res.json() as Promise<T>;
 }
 
+export type SurveyOutlineSection = {
+  section_id?: string;
+  kind: "topic" | "questions";
+  title: string;
+  query: string;
+  focus?: string;
+  questions?: string[];
+};
+
+export type SurveyOutlineState = {
+  survey_run_id: string;
+  status: "planning" | "pending_review" | "approved" | "edited" | "timed_out" | "not_required";
+  outline: SurveyOutlineSection[];
+  review_deadline?: string;
+  last_error?: string;
+};
+
+export type SurveyRun = {
+  survey_run_id: string;
+  corpus_id: string;
+  parent_run_id?: string;
+  topics: string[];
+  questions: string[];
+  status: string;
+  output_format: string;
+  template_id?: string;
+  citation_style?: string;
+  models: string[];
+  out_path?: string;
+  created_at: string;
+  updated_at: string;
+};
+
+export type SurveyTemplate = { id: string; name: string; format: "latex" | "markdown" | "html"; citation_style: "ieee" | "apa" | "acm"; description: string };
+
+export type SurveyReference = { key: string; corpus_id?: string; paper_id: string; title: string; authors?: string; year?: number; venue?: string; doi?: string };
+
+export type Draft = { draft_id: string; corpus_id: string; filename: string; format: "pdf" | "latex"; file_path: string; created_at: string };
+
+export type PositioningRun = { positioning_run_id: string; draft_id: string; corpus_id: string; status: string; config: Record<string, unknown>; out_path?: string; last_error?: string; created_at: string; updated_at: string };
+
+export type CiteSuggestion = { rank: number; corpus_id: string; paper_id: string; title: string; filename: string; chunk_id: string; section?: string; snippet: string; vector_score: number; lexical_score: number; support_score: number; bib_key: string };
+
+export type AnswerVerification = {
+  method: string;
+  grounding_score: number;
+  claims: number;
+  supported: number;
+  uncited: number;
+  invalid_citations: number;
+  sentences: Array<{ index: number; text: string; citations: string[]; invalid_citations?: string[]; support: number; supported_by?: string[]; supported: boolean; flags?: string[] }>;
+};
+
+export type PaperSummary = {
+  paper_id: string;
+  corpus_id: string;
+  tldr: string;
+  contributions: string[];
+  method: string;
+  results: string;
+  limitations: string;
+  prompt_version: string;
+  llm_provider?: string;
+  llm_model?: string;
+  chunk_count: number;
+  updated_at: string;
+};
+
+export type CitationSummaryStats = { mode: "batch" | "batch_partial" | "per_citation"; llm_calls: number; extractive: number; latency_ms: number };
+
+export type AskRecord = {
+  ask_id: string;
+  corpus_id: string;
+  corpus_ids: string[];
+  session_id?: string;
+  replay_of?: string;
+  question: string;
+  standalone_query?: string;
+  filters: Record<string, unknown>;
+  rewritten_queries: Array<{ kind: string; text: string }>;
+  embed_provider?: string;
+  embed_model?: string;
+  embed_version: string;
+  citations?: Array<Record<string, unknown>>;
+  prompt_version: string;
+  llm_provider?: string;
+  llm_model?: string;
+  answer?: string;
+  grounding_score?: number;
+  created_at: string;
+};
+
+export type CitationChange = { chunk_id: string; paper_id: string; title?: string; rank_before?: number; rank_after?: number; score_before?: number; score_after?: number };
+
+export type AskStreamEvent = { event: string; data: Record<string, unknown> };
+
+async function streamSSE(path: string, body: unknown, onEvent: (ev: AskStreamEvent) => void): Promise<void> {
+  const res = await fetch(`${API_BASE}${path}`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json", Accept: "text/event-stream" },
+    body: JSON.stringify(body),
+    cache: "no-store"
+  });
+  if (!res.ok || !res.body) {
+    throw new Error(await parseApiError(res));
+  }
+  const reader = res.body.getReader();
+  const decoder = new TextDecoder();
+  let buf = "";
+  for (;;) {
+    const { done, value } = await reader.read();
+    if (done) break;
+    buf += decoder.decode(value, { stream: true });
+    let sep = buf.indexOf("\n\n");
+    while (sep >= 0) {
+      const frame = buf.slice(0, sep);
+      buf = buf.slice(sep + 2);
+      let event = "message";
+      let data = "";
+      for (const line of frame.split("\n")) {
+        if (line.startsWith("event:")) event = line.slice(6).trim();
+        if (line.startsWith("data:")) data += line.slice(5).trim();
+      }
+      if (data) onEvent({ event, data: JSON.parse(data) as Record<string, unknown> });
+      sep = buf.indexOf("\n\n");
+    }
+  }
+}
+
 export const api = {
   getEmbeddingProviders: () => req<{ options: Array<{ id: string; label: string; model: string }>; default_embed_version: string }>("/providers/embeddings"),
   getCorpora: () => req<{ corpora: Array<{ corpus_id: string; name: string; created_at: string }> }>("/corpora"),
@@ -47,21 +176,174 @@ export const api = {
   startIngest: (corpusId: string) => req<{ workflow_id: string; run_id: string }>(`/corpora/${corpusId}/ingest`, { method: "POST" }),
   getProgress: (corpusId: string) => req<{ total: number; done: number; failed: number; per_paper_status: Record<string, string> }>(`/corpora/${corpusId}/progress`),
   getPapers: (corpusId: string) => req<{ papers: Array<{ paper_id: string; filename: string; title?: string; status: string; fail_reason?: string }> }>(`/corpora/${corpusId}/papers`),
-  ask: (payload: { corpus_id: string; question: string; top_k?: number; embed_provider?: string; embed_version?: string }) => req<{ answer: string; citations: Array<{ ref_id: string; paper_id: string; title: string; filename?: string; paper_url?: string; chunk_id: string; snippet: string; summary?: string; score: number }>; embed_provider?: string; embed_model?: string; embed_version?: string }>("/ask", { method: "POST", body: JSON.stringify(payload) }),
+  getPaper: (corpusId: string, paperId: string) => req<{ paper: { paper_id: string; filename: string; title?: string; authors?: string; year?: number; venue?: string; doi?: string; status: string; fail_reason?: string }; summary: PaperSummary | null }>(`/corpora/${corpusId}/papers/${paperId}`),
+  summarizePaper: (corpusId: string, paperId: string) => req<{ paper_id: string; workflow_id: string; run_id: string; prompt_version: string }>(`/corpora/${corpusId}/papers/${paperId}/summarize`, { method: "POST" }),
+  listCorpusGroups: () => req<{ groups: Array<{ group_id: string; name: string; corpus_ids: string[] }> }>("/corpus-groups"),
+  createCorpusGroup: (name: string, corpusIds: string[]) => req<{ group_id: string; name: string; corpus_ids: string[] }>("/corpus-groups", { method: "POST", body: JSON.stringify({ name, corpus_ids: corpusIds }) }),
+  search: (payload: { corpus_id?: string; corpus_ids?: string[]; corpus_group?: string; query: string; top_k?: number; paper_ids?: string[]; embed_provider?: string; embed_version?: string }) => req<{ results: Array<{ corpus_id: string; paper_id: string; title: string; filename: string; chunk_id: string; snippet: string; score: number }>; corpus_ids: string[] }>("/search", { method: "POST", body: JSON.stringify(payload) }),
+  ask: (payload: { corpus_id?: string; corpus_ids?: string[]; corpus_group?: string; question: string; top_k?: number; embed_provider?: string; embed_version?: string; query_rewrite?: "none" | "multi_query" | "hyde" | "multi_query_hyde"; rewrite_count?: number; context_window?: number; context_token_budget?: number; session_id?: string; new_session?: boolean; verify?: "lexical" | "llm" | "none" }) => req<{ answer: string; session_id?: string; turn_position?: number; standalone_query?: string; grounding_score?: number; verification?: AnswerVerification; citation_summaries?: CitationSummaryStats; ask_id?: string; prompt_version?: string; citations: Array<{ ref_id: string; corpus_id: string; paper_id: string; title: string; filename?: string; paper_url?: string; chunk_id: string; snippet: string; summary?: string; score: number }>; corpus_ids?: string[]; embed_provider?: string; embed_model?: string; embed_version?: string; query_rewrite?: string; rewritten_queries?: Array<{ kind: string; text: string }>; context_window?: number; context_token_budget?: number }>("/ask", { method: "POST", body: JSON.stringify(payload) }),
+  listAskSessions: (corpusId: string) => req<{ sessions: Array<{ session_id: string; corpus_id: string; title: string; turn_count: number; created_at: string; updated_at: string }> }>(`/corpora/${corpusId}/sessions`),
+  getAskSession: (corpusId: string, sessionId: string) => req<{ session_id: string; corpus_id: string; title: string; turns: Array<{ turn_id: string; position: number; question: string; standalone_query: string; answer: string; citations: Array<Record<string, unknown>>; llm_provider: string; llm_model: string; created_at: string }> }>(`/corpora/${corpusId}/sessions/${sessionId}`),
+  askSessionExportUrl: (corpusId: string, sessionId: string) => `${API_BASE}/corpora/${corpusId}/sessions/${sessionId}/export`,
+  listAsks: (corpusId: string, limit = 50) => req<{ asks: AskRecord[] }>(`/corpora/${corpusId}/asks?limit=${limit}`),
+  getAsk: (corpusId: string, askId: string) => req<AskRecord>(`/corpora/${corpusId}/asks/${askId}`),
+  replayAsk: (corpusId: string, askId: string) =>
+    req<{
+      original_ask_id: string;
+      replay: { ask_id: string; answer: string; citations: Array<{ ref_id: string; paper_id: string; title: string; chunk_id: string; score: number }> };
+      citation_diff: { changed: boolean; added: CitationChange[]; removed: CitationChange[]; kept: CitationChange[]; chunk_overlap: number; paper_overlap: number };
+      answer_changed: boolean;
+      prompt_changed: boolean;
+      embed_changed: boolean;
+    }>(`/corpora/${corpusId}/asks/${askId}/replay`, { method: "POST" }),
+  comparePapers: (corpusId: string, payload: { paper_ids: string[]; dimensions?: string[]; top_k?: number; embed_version?: string }) =>
+    req<{
+      table: {
+        papers: Array<{ paper_id: string; title: string }>;
+        dimensions: string[];
+        cells: Array<Array<{ paper_id: string; dimension: string; text: string; citations: string[]; source: "llm" | "extractive" | "none" }>>;
+        evidence: Array<{ ref_id: string; paper_id: string; dimension: string; chunk_id: string; snippet: string; score: number }>;
+      };
+      llm_calls: number;
+      llm_provider?: string;
+      llm_model?: string;
+      embed_version: string;
+    }>(`/corpora/${corpusId}/compare`, { method: "POST", body: JSON.stringify(payload) }),
+  citeSuggest: (corpusId: string, payload: { text: string; corpus_ids?: string[]; corpus_group?: string; top_k?: number; min_score?: number; paper_ids?: string[]; embed_provider?: string; embed_version?: string }) =>
+    req<{
+      sentences: Array<{ index: number; text: string; suggestions: CiteSuggestion[]; cite?: string }>;
+      bibtex: string;
+      corpus_ids: string[];
+      embed_provider: string;
+      embed_model: string;
+      embed_version: string;
+    }>(`/corpora/${corpusId}/cite-suggest`, { method: "POST", body: JSON.stringify(payload) }),
+  exportComparison: async (corpusId: string, format: "markdown" | "csv" | "latex", payload: { paper_ids: string[]; dimensions?: string[]; top_k?: number; embed_version?: string }) => {
+    const res = await fetch(`${API_BASE}/corpora/${corpusId}/compare?format=${format}`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(payload),
+      cache: "no-store"
+    });
+    if (!res.ok) {
+      throw new Error(await parseApiError(res));
+    }
+    return res.text();
+  },
+  startDeepResearch: (corpusId: string, payload: { question: string; corpus_ids?: string[]; corpus_group?: string; max_steps?: number; token_budget?: number; top_k?: number; embed_version?: string }) =>
+    req<{ research_run_id: string; workflow_id: string; run_id: string }>(`/corpora/${corpusId}/research`, { method: "POST", body: JSON.stringify(payload) }),
+  listDeepResearch: (corpusId: string) => req<{ research_runs: Array<{ research_run_id: string; question: string; status: string; created_at: string; updated_at: string }> }>(`/corpora/${corpusId}/research`),
+  getDeepResearch: (corpusId: string, researchRunId: string) =>
+    req<{
+      run: { research_run_id: string; question: string; status: string; last_error?: string };
+      report?: string;
+      progress?: {
+        status: string;
+        phase: string;
+        step: number;
+        max_steps: number;
+        tokens_used: number;
+        token_budget: number;
+        plan: Array<{ question: string; source: "chunks" | "graph"; terms?: string[]; status: string }>;
+        findings: Array<{ step: number; sub_question: string; source: string; note: string; evidence_ids: string[] }>;
+        stop_reason?: string;
+      };
+      evidence?: Array<{ evidence_id: string; kind: "chunk" | "edge"; paper_id?: string; chunk_id?: string; edge_id?: string; title?: string; text: string; score?: number }>;
+    }>(`/corpora/${corpusId}/research/${researchRunId}`),
+  uploadDraft: async (corpusId: string, file: File) => {
+    const fd = new FormData();
+    fd.append("file", file);
+    const res = await fetch(`${API_BASE}/corpora/${corpusId}/drafts`, { method: "POST", body: fd });
+    if (!res.ok) throw new Error(await parseApiError(res));
+    return res.json() as Promise<{ draft: Draft }>;
+  },
+  listDrafts: (corpusId: string) => req<{ drafts: Draft[] }>(`/corpora/${corpusId}/drafts`),
+  getDraft: (corpusId: string, draftId: string) => req<{ draft: Draft; positioning_runs: PositioningRun[] }>(`/corpora/${corpusId}/drafts/${draftId}`),
+  startPositioning: (corpusId: string, draftId: string, payload: { corpus_ids?: string[]; corpus_group?: string; max_claims?: number; top_k?: number; embed_version?: string } = {}) =>
+    req<{ positioning_run_id: string; workflow_id: string; run_id: string }>(`/corpora/${corpusId}/drafts/${draftId}/positioning`, { method: "POST", body: JSON.stringify(payload) }),
+  getPositioning: (corpusId: string, draftId: string, runId: string) =>
+    req<{
+      run: PositioningRun;
+      report?: string;
+      claims?: Array<{ claim_id: string; kind: "contribution" | "claim"; text: string; ref_keys: string[]; positioning: string; source: "llm" | "extractive" | "none" }>;
+      prior_work?: Array<{ key: string; corpus_id?: string; paper_id: string; title: string; claim_ids: string[]; best_score: number; cited_in_draft: boolean }>;
+      references?: SurveyReference[];
+    }>(`/corpora/${corpusId}/drafts/${draftId}/positioning/${runId}`),
+  listEvalSets: (corpusId: string) => req<{ eval_sets: Array<{ eval_set_id: string; name: string; created_at: string }> }>(`/corpora/${corpusId}/eval-sets`),
+  createEvalSet: (corpusId: string, payload: { name: string; questions: Array<{ question: string; relevant_paper_ids?: string[]; relevant_chunk_ids?: string[] }> }) => req<{ eval_set_id: string }>(`/corpora/${corpusId}/eval-sets`, { method: "POST", body: JSON.stringify(payload) }),
+  startEvalRun: (corpusId: string, payload: { eval_set_id: string; top_k?: number; ks?: number[]; query_rewrite?: string; rewrite_count?: number; embed_version?: string; chunk_version?: string; embed_provider?: string; hybrid?: boolean }) => req<{ eval_run_id: string }>(`/corpora/${corpusId}/eval-runs`, { method: "POST", body: JSON.stringify(payload) }),
+  listEvalRuns: (corpusId: string) => req<{ eval_runs: Array<{ eval_run_id: string; eval_set_id: string; status: string; config: Record<string, unknown>; metrics?: { recall_at_k: Record<string, number>; mrr: number; ndcg_at_k: Record<string, number> }; created_at: string }> }>(`/corpora/${corpusId}/eval-runs`),
+  compareEvalRuns: (corpusId: string, a: string, b: string) => req<{ delta: { recall_at_k: Record<string, number>; mrr: number; ndcg_at_k: Record<string, number> }; per_question: Array<{ question_id: string; question: string; mrr_a: number; mrr_b: number; delta_mrr: number }>; same_set: boolean }>(`/corpora/${corpusId}/eval-runs/compare?a=${encodeURIComponent(a)}&b=${encodeURIComponent(b)}`),
+  askStream: (payload: { corpus_id?: string; corpus_ids?: string[]; corpus_group?: string; question: string; top_k?: number; embed_provider?: string; embed_version?: string; query_rewrite?: string; rewrite_count?: number; context_window?: number; context_token_budget?: number; session_id?: string; new_session?: boolean; verify?: "lexical" | "llm" | "none" }, onEvent: (ev: AskStreamEvent) => void) =>
+    streamSSE("/ask/stream", payload, onEvent),
   createSurvey: (payload: {
-    corpus_id: string;
+    corpus_id?: string;
+    corpus_ids?: string[];
+    corpus_group?: string;
     prompt: string;
     topics?: string[];
     questions?: string[];
-    output_format?: "latex" | "markdown";
+    output_format?: "latex" | "markdown" | "html";
+    template_id?: string;
+    citation_style?: "ieee" | "apa" | "acm";
     retrieval_top_k?: number;
+    use_paper_summaries?: boolean;
+    max_concurrent_sections?: number;
+    repair_rounds?: number;
+    review_outline?: boolean;
+    outline_review_timeout_seconds?: number;
+    critique_rounds?: number;
+    kg_tables?: boolean;
   }) => req<{ survey_run_id: string }>("/survey", { method: "POST", body: JSON.stringify(payload) }),
-  surveyProgress: (id: string) => req<{ total_topics: number; done_topics: number; topic_status: Record<string, string> }>(`/survey/${id}/progress`),
-  surveyReport: (id: string) =>
-    req<{ status: string; report_text: string; report_markdown?: string; output_format?: string; path?: string }>(`/survey/${id}/report`),
+  surveyTemplates: () => req<{ templates: SurveyTemplate[]; citation_styles: string[] }>("/survey/templates"),
+  listSurveys: (corpusId: string) => req<{ surveys: SurveyRun[] }>(`/corpora/${corpusId}/surveys`),
+  diffSurveys: (corpusId: string, b: string, a?: string) =>
+    req<{
+      a: SurveyRun;
+      b: SurveyRun;
+      diff: {
+        added_references: SurveyReference[];
+        removed_references: SurveyReference[];
+        shared_references: number;
+        sections: Array<{ title: string; status: "added" | "removed" | "changed"; added_paragraphs?: string[]; removed_paragraphs?: string[] }>;
+        unchanged_sections: number;
+      };
+    }>(`/corpora/${corpusId}/surveys/diff?b=${encodeURIComponent(b)}${a ? `&a=${encodeURIComponent(a)}` : ""}`),
+  surveyProgress: (id: string) => req<{
+      total_topics: number;
+      done_topics: number;
+      topic_status: Record<string, string>;
+      phase?: "outline" | "review" | "sections" | "assembling" | "done";
+      outline?: SurveyOutlineSection[];
+      section_workflows?: Record<string, string>;
+    }>(`/survey/${id}/progress`),
+  surveyReport: (id: string, format?: "latex" | "markdown" | "html") =>
+    req<{ status: string; report_text: string; report_markdown?: string; output_format?: string; path?: string; formats?: string[]; bibtex?: string }>(
+      `/survey/${id}/report${format ? `?format=${format}` : ""}`
+    ),
+  surveyOutline: (id: string) => req<SurveyOutlineState>(`/survey/${id}/outline`),
+  reviewSurveyOutline: (id: string, payload: { action: "approve" } | { action: "edit"; sections: SurveyOutlineSection[] }) =>
+    req<{ survey_run_id: string; action: string }>(`/survey/${id}/outline`, { method: "POST", body: JSON.stringify(payload) }),
+  surveyRevisions: (id: string) => req<{
+      survey_run_id: string;
+      sections: Array<{
+        section_id: string;
+        drafts: Array<{ round: number; text: string }>;
+        critiques: Array<{ round: number; coverage: number; grounding: number; redundancy: number; structure: number; comments: string[]; revised: boolean }>;
+      }>;
+    }>(`/survey/${id}/revisions`),
+  surveyValidation: (id: string) => req<{
+      survey_run_id: string;
+      status: string;
+      validation: null | {
+        constraints: Array<{ kind: string; label: string; satisfied: boolean; detail?: string }>;
+        sections: Array<{ section_id: string; title: string; initial_issues: number; repair_rounds: number; issues: Array<{ kind: string; detail: string }> }>;
+      };
+    }>(`/survey/${id}/validation`),
+  surveyDownloadUrl: (id: string, format?: "latex" | "markdown" | "html") => `${API_BASE}/survey/${id}/download${format ? `?format=${format}` : ""}`,
   graph: (corpusId: string) => req<{ nodes: Array<{ node_id: string; node_type: string; label: string }>; edges: Array<{ source_node_id: string; target_node_id: string; weight: number; edge_type: string }> }>(`/corpora/${corpusId}/graph`),
   workflowStatus: (workflowId: string, runId?: string) => req<{ workflow_id: string; run_id?: string; type: string; status: string; task_queue?: string; history_length?: number; start_time?: string; close_time?: string }>(`/workflows/status?workflow_id=${encodeURIComponent(workflowId)}${runId ? `&run_id=${encodeURIComponent(runId)}` : ""}`),
-  backfill: (payload: { corpus_id: string; mode: "RETRY_FAILED_PAPERS" | "REEMBED_ALL_PAPERS" | "REGENERATE_SURVEY"; embed_provider?: string; embed_version?: string; chunk_version?: string; topics?: string[]; questions?: string[] }) =>
+  backfill: (payload: { corpus_id: string; mode: "RETRY_FAILED_PAPERS" | "REEMBED_ALL_PAPERS" | "REGENERATE_SURVEY" | "UPDATE_SURVEY" | "SUMMARIZE_PAPERS"; survey_run_id?: string; parent_run_id?: string; prompt_version?: string; force?: boolean; embed_provider?: string; embed_version?: string; chunk_version?: string; topics?: string[]; questions?: string[] }) =>
     req<{ workflow_id: string; run_id: string; mode: string; corpus_id: string; embed_version: string }>(
       "/backfill",
       { method: "POST", body: JSON.stringify(payload) }
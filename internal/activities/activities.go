@@ -5,17 +5,22 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"litflow/internal/config"
 	"litflow/internal/models"
 	"litflow/internal/providers"
+	"litflow/internal/retrieval"
 	"litflow/internal/storage"
+	"litflow/internal/survey"
 	"litflow/internal/util"
 	"litflow/internal/vector"
 
@@ -29,6 +34,9 @@ type Activities struct {
 	surveyRepo   *storage.SurveyRepo
 	llmAuditRepo *storage.LLMAuditRepo
 	graphRepo    *storage.GraphRepo
+	evalRepo     *storage.EvalRepo
+	researchRepo *storage.ResearchRepo
+	draftRepo    *storage.DraftRepo
 	searcher     *vector.Searcher
 	providers    *providers.Manager
 }
@@ -45,6 +53,9 @@ func New(cfg config.Config, db *storage.DB) (*Activities, error) {
 		surveyRepo:   storage.NewSurveyRepo(db),
 		llmAuditRepo: storage.NewLLMAuditRepo(db),
 		graphRepo:    storage.NewGraphRepo(db),
+		evalRepo:     storage.NewEvalRepo(db),
+		researchRepo: storage.NewResearchRepo(db),
+		draftRepo:    storage.NewDraftRepo(db),
 		searcher:     vector.NewSearcher(db.Pool),
 		providers:    pm,
 	}, nil
@@ -107,6 +118,7 @@ func (a *Activities) ListCorpusPapersActivity(ctx context.Context, in ListCorpus
 			Authors:    p.Authors,
 			Year:       year,
 			FailReason: p.FailReason,
+			CreatedAt:  p.CreatedAt,
 		})
 	}
 	return out, nil
@@ -121,6 +133,107 @@ func (a *Activities) WriteRunManifestActivity(ctx context.Context, in WriteRunMa
 	return WriteRunManifestOutput{Path: path}, nil
 }
 
+func (a *Activities) LoadEvalSetActivity(ctx context.Context, in LoadEvalSetInput) (LoadEvalSetOutput, error) {
+	set, err := a.evalRepo.GetSet(ctx, in.CorpusID, in.EvalSetID)
+	if err != nil {
+		return LoadEvalSetOutput{}, err
+	}
+	out := LoadEvalSetOutput{Name: set.Name, Questions: make([]EvalQuestionItem, 0, len(set.Questions))}
+	for _, q := range set.Questions {
+		out.Questions = append(out.Questions, EvalQuestionItem{
+			QuestionID: q.QuestionID,
+			Question:   q.Question,
+			Judgment:   retrieval.EvalJudgment{ChunkIDs: q.RelevantChunkIDs, PaperIDs: q.RelevantPaperIDs},
+		})
+	}
+	return out, nil
+}
+
+func (a *Activities) UpdateEvalRunActivity(ctx context.Context, in UpdateEvalRunInput) error {
+	var metrics any
+	if in.Metrics != nil {
+		metrics = in.Metrics
+	}
+	return a.evalRepo.UpdateRun(ctx, in.EvalRunID, in.Status, metrics, in.OutPath, in.Error)
+}
+
+// WriteEvalReportActivity stores an eval run beside the backfill manifests so retrieval
+// changes and their measured effect share one versioned runs directory.
+func (a *Activities) WriteEvalReportActivity(ctx context.Context, in WriteEvalReportInput) (WriteEvalReportOutput, error) {
+	_ = ctx
+	path := filepath.Join(a.cfg.DataOutRoot, in.CorpusID, "runs", in.EvalRunID, "retrieval_eval.json")
+	if err := util.WriteJSONAtomic(path, in.Report); err != nil {
+		return WriteEvalReportOutput{}, err
+	}
+	return WriteEvalReportOutput{Path: path}, nil
+}
+
+// SearchGraphEdgesActivity looks up extracted KG relations mentioning any of the terms so
+// multi-hop research can follow method lineage and evaluation links across papers.
+func (a *Activities) SearchGraphEdgesActivity(ctx context.Context, in SearchGraphEdgesInput) (SearchGraphEdgesOutput, error) {
+	hits, err := a.graphRepo.SearchKGEdges(ctx, corpusScope(in.CorpusID, in.CorpusIDs), in.Terms, in.Limit)
+	if err != nil {
+		return SearchGraphEdgesOutput{}, err
+	}
+	out := SearchGraphEdgesOutput{Edges: make([]GraphEdgeHit, 0, len(hits))}
+	for _, h := range hits {
+		e := GraphEdgeHit{
+			EdgeID:       h.EdgeID,
+			CorpusID:     h.CorpusID,
+			EdgeType:     h.EdgeType,
+			SourceName:   h.SourceName,
+			TargetName:   h.TargetName,
+			SupportCount: h.SupportCount,
+			Weight:       h.Weight,
+		}
+		if len(h.Provenance) > 0 {
+			e.PaperID, _ = h.Provenance[0]["paper_id"].(string)
+			e.ChunkID, _ = h.Provenance[0]["chunk_id"].(string)
+			e.Evidence, _ = h.Provenance[0]["evidence"].(string)
+		}
+		out.Edges = append(out.Edges, e)
+	}
+	return out, nil
+}
+
+func (a *Activities) UpdateResearchRunActivity(ctx context.Context, in UpdateResearchRunInput) error {
+	return a.researchRepo.UpdateRun(ctx, in.ResearchRunID, in.Status, in.OutPath, in.Error)
+}
+
+// WriteResearchReportActivity writes the cited Markdown report and the full plan/notes trace.
+func (a *Activities) WriteResearchReportActivity(ctx context.Context, in WriteResearchReportInput) (WriteResearchReportOutput, error) {
+	_ = ctx
+	dir := filepath.Join(a.cfg.DataOutRoot, in.CorpusID, "research", in.ResearchRunID)
+	reportPath := filepath.Join(dir, "report.md")
+	if err := util.WriteTextAtomic(reportPath, in.Markdown); err != nil {
+		return WriteResearchReportOutput{}, err
+	}
+	tracePath := filepath.Join(dir, "research.json")
+	if err := util.WriteJSONAtomic(tracePath, in.Trace); err != nil {
+		return WriteResearchReportOutput{}, err
+	}
+	return WriteResearchReportOutput{ReportPath: reportPath, TracePath: tracePath}, nil
+}
+
+func (a *Activities) UpdatePositioningRunActivity(ctx context.Context, in UpdatePositioningRunInput) error {
+	return a.draftRepo.UpdateRun(ctx, in.PositioningRunID, in.Status, in.OutPath, in.Error)
+}
+
+// WritePositioningReportActivity writes the positioning report and its trace next to the draft.
+func (a *Activities) WritePositioningReportActivity(ctx context.Context, in WritePositioningReportInput) (WritePositioningReportOutput, error) {
+	_ = ctx
+	dir := filepath.Join(a.cfg.DataOutRoot, in.CorpusID, "drafts", in.DraftID, "positioning", in.PositioningRunID)
+	reportPath := filepath.Join(dir, "report.md")
+	if err := util.WriteTextAtomic(reportPath, in.Markdown); err != nil {
+		return WritePositioningReportOutput{}, err
+	}
+	tracePath := filepath.Join(dir, "positioning.json")
+	if err := util.WriteJSONAtomic(tracePath, in.Trace); err != nil {
+		return WritePositioningReportOutput{}, err
+	}
+	return WritePositioningReportOutput{ReportPath: reportPath, TracePath: tracePath}, nil
+}
+
 func (a *Activities) ComputePaperIDActivity(ctx context.Context, in ComputePaperIDInput) (ComputePaperIDOutput, error) {
 	_ = ctx
 	f, err := os.Open(in.PaperPath)
@@ -137,32 +250,65 @@ func (a *Activities) ComputePaperIDActivity(ctx context.Context, in ComputePaper
 
 func (a *Activities) ExtractTextActivity(ctx context.Context, in ExtractTextInput) (ExtractTextOutput, error) {
 	_ = ctx
-	f, r, err := pdf.Open(in.PaperPath)
+	text, err := extractPDFText(in.PaperPath)
 	if err != nil {
-		return ExtractTextOutput{}, fmt.Errorf("open pdf: %w", err)
+		return ExtractTextOutput{}, err
+	}
+	return ExtractTextOutput{Text: text}, nil
+}
+
+func extractPDFText(path string) (string, error) {
+	f, r, err := pdf.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open pdf: %w", err)
 	}
 	defer f.Close()
 
 	reader, err := r.GetPlainText()
 	if err != nil {
-		return ExtractTextOutput{}, fmt.Errorf("extract pdf text: %w", err)
+		return "", fmt.Errorf("extract pdf text: %w", err)
 	}
 	buf := new(strings.Builder)
 	if _, err := io.Copy(buf, reader); err != nil {
-		return ExtractTextOutput{}, fmt.Errorf("read extracted text: %w", err)
+		return "", fmt.Errorf("read extracted text: %w", err)
 	}
 	text := strings.TrimSpace(buf.String())
 	text = util.SanitizeText(text)
 	if text == "" {
-		return ExtractTextOutput{}, util.ErrNoExtractableText
+		return "", util.ErrNoExtractableText
 	}
-	return ExtractTextOutput{Text: text}, nil
+	return text, nil
+}
+
+// ExtractDraftTextActivity reads an uploaded draft. LaTeX sources are reduced to their prose
+// and titled from \title; PDFs go through the same extraction as corpus papers.
+func (a *Activities) ExtractDraftTextActivity(ctx context.Context, in ExtractDraftTextInput) (ExtractDraftTextOutput, error) {
+	_ = ctx
+	if in.Format == "latex" {
+		b, err := os.ReadFile(in.Path)
+		if err != nil {
+			return ExtractDraftTextOutput{}, fmt.Errorf("read draft: %w", err)
+		}
+		src := string(b)
+		text := util.SanitizeText(util.LaTeXPlainText(src))
+		if text == "" {
+			return ExtractDraftTextOutput{}, util.ErrNoExtractableText
+		}
+		return ExtractDraftTextOutput{Title: util.LaTeXTitle(src), Text: text}, nil
+	}
+	text, err := extractPDFText(in.Path)
+	if err != nil {
+		return ExtractDraftTextOutput{}, err
+	}
+	title, _ := heuristicTitleAndAuthors(text)
+	return ExtractDraftTextOutput{Title: title, Text: text}, nil
 }
 
 func (a *Activities) ExtractMetadataActivity(ctx context.Context, in ExtractMetadataInput) (ExtractMetadataOutput, error) {
 	_ = ctx
 	title, authors := heuristicTitleAndAuthors(in.Text)
-	return ExtractMetadataOutput{Title: title, Authors: authors}, nil
+	year, venue, doi := heuristicBibMetadata(in.Text)
+	return ExtractMetadataOutput{Title: title, Authors: authors, Year: year, Venue: venue, DOI: doi}, nil
 }
 
 func (a *Activities) ChunkTextActivity(ctx context.Context, in ChunkTextInput) (ChunkTextOutput, error) {
@@ -184,11 +330,12 @@ func (a *Activities) ChunkTextActivity(ctx context.Context, in ChunkTextInput) (
 		chunkHash := util.SHA256Hex([]byte(part))
 		chunkID := util.SHA256Hex([]byte(fmt.Sprintf("%s:%d:%s:%s", in.PaperID, idx, chunkHash, in.Version)))
 		chunks = append(chunks, ChunkItem{
-			ChunkID:    chunkID,
-			PaperID:    in.PaperID,
-			CorpusID:   in.CorpusID,
-			ChunkIndex: idx,
-			Text:       part,
+			ChunkID:      chunkID,
+			PaperID:      in.PaperID,
+			CorpusID:     in.CorpusID,
+			ChunkIndex:   idx,
+			Text:         part,
+			ChunkVersion: in.Version,
 		})
 	}
 	return ChunkTextOutput{Chunks: chunks}, nil
@@ -208,6 +355,7 @@ func (a *Activities) UpsertChunksActivity(ctx context.Context, in UpsertChunksIn
 			CorpusID:         c.CorpusID,
 			ChunkIndex:       c.ChunkIndex,
 			Text:             util.SanitizeText(c.Text),
+			ChunkVersion:     c.ChunkVersion,
 			EmbeddingVersion: in.EmbeddingVersion,
 			EmbeddingVector:  embedding,
 		})
@@ -238,12 +386,19 @@ func (a *Activities) WritePaperArtifactsActivity(ctx context.Context, in WritePa
 }
 
 func (a *Activities) UpdatePaperStatusActivity(ctx context.Context, in UpdatePaperStatusInput) error {
+	var year *int
+	if in.Year > 0 {
+		year = &in.Year
+	}
 	return a.paperRepo.UpsertPaper(ctx, models.Paper{
 		PaperID:    in.PaperID,
 		CorpusID:   in.CorpusID,
 		Filename:   in.Filename,
 		Title:      in.Title,
 		Authors:    in.Authors,
+		Year:       year,
+		Venue:      in.Venue,
+		DOI:        in.DOI,
 		Status:     in.Status,
 		FailReason: in.FailReason,
 	})
@@ -311,41 +466,142 @@ func (a *Activities) EmbedQueryActivity(ctx context.Context, in EmbedQueryInput)
 }
 
 func (a *Activities) SearchChunksActivity(ctx context.Context, in SearchChunksInput) (SearchChunksOutput, error) {
-	results, err := a.searcher.SearchChunks(ctx, in.CorpusID, in.QueryVec, in.TopK, vector.SearchFilters{
+	filters := vector.SearchFilters{
 		EmbeddingVersion: in.EmbeddingVersion,
-	})
+		ChunkVersion:     in.ChunkVersion,
+		PaperIDs:         in.PaperIDs,
+	}
+	var results []models.ChunkResult
+	var err error
+	if strings.TrimSpace(in.TextQuery) != "" {
+		results, err = a.searcher.SearchChunksText(ctx, corpusScope(in.CorpusID, in.CorpusIDs), in.TextQuery, in.QueryVec, in.TopK, filters)
+	} else {
+		results, err = a.searcher.SearchChunks(ctx, corpusScope(in.CorpusID, in.CorpusIDs), in.QueryVec, in.TopK, filters)
+	}
 	if err != nil {
 		return SearchChunksOutput{}, err
 	}
 	out := make([]SearchChunk, 0, len(results))
 	for _, r := range results {
 		out = append(out, SearchChunk{
-			PaperID: r.PaperID,
-			Title:   r.Title,
-			ChunkID: r.ChunkID,
-			Snippet: r.Snippet,
-			Score:   r.Score,
-			Text:    r.ChunkText,
+			CorpusID:   r.CorpusID,
+			PaperID:    r.PaperID,
+			Title:      r.Title,
+			ChunkID:    r.ChunkID,
+			ChunkIndex: r.ChunkIndex,
+			Section:    r.Section,
+			Snippet:    r.Snippet,
+			Score:      r.Score,
+			Text:       r.ChunkText,
 		})
 	}
 	return SearchChunksOutput{Results: out}, nil
 }
 
+// corpusScope returns the corpora a retrieval activity should read: the explicit list
+// for cross-corpus runs, otherwise the single primary corpus.
+func corpusScope(corpusID string, corpusIDs []string) []string {
+	if len(corpusIDs) > 0 {
+		return corpusIDs
+	}
+	return []string{corpusID}
+}
+
+func (a *Activities) ExpandChunkContextActivity(ctx context.Context, in ExpandChunkContextInput) (ExpandChunkContextOutput, error) {
+	refs := make([]retrieval.ChunkRef, 0, len(in.Hits))
+	for _, h := range in.Hits {
+		refs = append(refs, retrieval.ChunkRef{ChunkID: h.ChunkID, PaperID: h.PaperID, ChunkIndex: h.ChunkIndex, Section: h.Section, Text: h.Text})
+	}
+	byID, err := a.chunkRepo.ExpandHitContext(ctx, corpusScope(in.CorpusID, in.CorpusIDs), refs, in.Window, in.TokenBudget)
+	if err != nil {
+		return ExpandChunkContextOutput{}, err
+	}
+	out := ExpandChunkContextOutput{Results: make([]SearchChunk, 0, len(byID))}
+	for _, h := range in.Hits {
+		e, ok := byID[h.ChunkID]
+		if !ok {
+			continue
+		}
+		h.Text = e.Text
+		h.ContextChunkIDs = e.ChunkIDs
+		out.Results = append(out.Results, h)
+	}
+	return out, nil
+}
+
+// WriteSurveyReportActivity renders the survey in the requested format and stores the
+// document model beside it as survey.json so other formats can be rendered later. LaTeX
+// reports get their refs.bib alongside.
 func (a *Activities) WriteSurveyReportActivity(ctx context.Context, in WriteSurveyReportInput) (WriteSurveyReportOutput, error) {
 	_ = ctx
-	ext := "md"
-	if strings.EqualFold(strings.TrimSpace(in.OutputFormat), "latex") {
-		ext = "tex"
+	format, ok := survey.NormalizeFormat(in.OutputFormat)
+	if !ok {
+		return WriteSurveyReportOutput{}, fmt.Errorf("unsupported survey format %q", in.OutputFormat)
 	}
-	outPath := filepath.Join(a.cfg.DataOutRoot, in.CorpusID, "surveys", in.SurveyRunID, "report."+ext)
-	if err := util.WriteTextAtomic(outPath, in.Report); err != nil {
+	dir := filepath.Join(a.cfg.DataOutRoot, in.CorpusID, "surveys", in.SurveyRunID)
+	if err := util.WriteJSONAtomic(filepath.Join(dir, "survey.json"), in.Document); err != nil {
 		return WriteSurveyReportOutput{}, err
 	}
+	outPath := filepath.Join(dir, "report."+survey.FileExtension(format))
+	report, err := survey.Render(in.Document, format)
+	if err != nil {
+		return WriteSurveyReportOutput{}, err
+	}
+	if err := util.WriteTextAtomic(outPath, report); err != nil {
+		return WriteSurveyReportOutput{}, err
+	}
+	if format == survey.FormatLaTeX {
+		if err := util.WriteTextAtomic(filepath.Join(dir, survey.BibFilename), survey.RenderBibTeX(in.Document.References)); err != nil {
+			return WriteSurveyReportOutput{}, err
+		}
+	}
 	return WriteSurveyReportOutput{OutPath: outPath}, nil
 }
 
+// ReadSurveyDocumentActivity loads the survey.json stored next to a run's report.
+func (a *Activities) ReadSurveyDocumentActivity(ctx context.Context, in ReadSurveyDocumentInput) (ReadSurveyDocumentOutput, error) {
+	_ = ctx
+	b, err := os.ReadFile(filepath.Join(filepath.Dir(in.OutPath), "survey.json"))
+	if err != nil {
+		return ReadSurveyDocumentOutput{}, fmt.Errorf("read survey document: %w", err)
+	}
+	var out ReadSurveyDocumentOutput
+	if err := json.Unmarshal(b, &out.Document); err != nil {
+		return ReadSurveyDocumentOutput{}, fmt.Errorf("decode survey document: %w", err)
+	}
+	return out, nil
+}
+
+// WriteSurveyArtifactActivity writes an intermediate artifact of a survey run, such as a
+// section draft or critique, next to its report.
+func (a *Activities) WriteSurveyArtifactActivity(ctx context.Context, in WriteSurveyArtifactInput) error {
+	_ = ctx
+	name := filepath.Clean(filepath.FromSlash(in.Name))
+	if name == "." || filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid survey artifact name %q", in.Name)
+	}
+	return util.WriteTextAtomic(filepath.Join(a.cfg.DataOutRoot, in.CorpusID, "surveys", in.SurveyRunID, name), in.Content)
+}
+
 func (a *Activities) UpdateSurveyRunActivity(ctx context.Context, in UpdateSurveyRunInput) error {
-	return a.surveyRepo.UpdateRunStatus(ctx, in.SurveyRunID, in.Status, in.OutPath)
+	return a.surveyRepo.UpdateRunStatus(ctx, in.SurveyRunID, in.Status, in.OutPath, in.Models)
+}
+
+func (a *Activities) CreateSurveyRunActivity(ctx context.Context, in CreateSurveyRunInput) error {
+	return a.surveyRepo.CreateRun(ctx, in.SurveyRunID, in.CorpusID, in.ParentRunID, in.OutputFormat, in.TemplateID, in.CitationStyle, in.Topics, in.Questions)
+}
+
+func (a *Activities) GetSurveyRunActivity(ctx context.Context, in GetSurveyRunInput) (GetSurveyRunOutput, error) {
+	run, err := a.surveyRepo.GetRun(ctx, in.SurveyRunID)
+	if err != nil {
+		return GetSurveyRunOutput{}, err
+	}
+	return GetSurveyRunOutput{Run: run}, nil
+}
+
+// SaveSurveyValidationActivity stores the citation checks of a survey run.
+func (a *Activities) SaveSurveyValidationActivity(ctx context.Context, in SaveSurveyValidationInput) error {
+	return a.surveyRepo.SaveValidation(ctx, in.SurveyRunID, in.Validation)
 }
 
 func (a *Activities) LogLLMCallActivity(ctx context.Context, in LogLLMCallInput) error {
@@ -367,7 +623,7 @@ func (a *Activities) UpsertTopicGraphActivity(ctx context.Context, in UpsertTopi
 }
 
 func (a *Activities) GetSurveyPaperMetaActivity(ctx context.Context, in GetSurveyPaperMetaInput) (GetSurveyPaperMetaOutput, error) {
-	papers, err := a.paperRepo.ListPapersByIDs(ctx, in.CorpusID, in.PaperIDs)
+	papers, err := a.paperRepo.ListPapersByIDs(ctx, corpusScope(in.CorpusID, in.CorpusIDs), in.PaperIDs)
 	if err != nil {
 		return GetSurveyPaperMetaOutput{}, err
 	}
@@ -382,12 +638,45 @@ func (a *Activities) GetSurveyPaperMetaActivity(ctx context.Context, in GetSurve
 			Title:    p.Title,
 			Authors:  p.Authors,
 			Year:     year,
+			Venue:    p.Venue,
+			DOI:      p.DOI,
 			Filename: p.Filename,
 		})
 	}
 	return out, nil
 }
 
+// GetSurveyKGFactsActivity loads the KG comparison and lineage facts of the methods the
+// survey's papers mention, with every provenance entry so each fact can be cited.
+func (a *Activities) GetSurveyKGFactsActivity(ctx context.Context, in GetSurveyKGFactsInput) (GetSurveyKGFactsOutput, error) {
+	hits, err := a.graphRepo.ListMethodFacts(ctx, corpusScope(in.CorpusID, in.CorpusIDs), in.PaperIDs, in.Limit)
+	if err != nil {
+		return GetSurveyKGFactsOutput{}, err
+	}
+	out := GetSurveyKGFactsOutput{Facts: make([]SurveyKGFact, 0, len(hits))}
+	for _, h := range hits {
+		f := SurveyKGFact{
+			CorpusID:     h.CorpusID,
+			EdgeType:     h.EdgeType,
+			SourceType:   h.SourceType,
+			SourceName:   h.SourceName,
+			TargetType:   h.TargetType,
+			TargetName:   h.TargetName,
+			SupportCount: h.SupportCount,
+		}
+		for _, p := range h.Provenance {
+			paperID, _ := p["paper_id"].(string)
+			if paperID == "" {
+				continue
+			}
+			chunkID, _ := p["chunk_id"].(string)
+			f.Provenance = append(f.Provenance, SurveyKGProvenance{PaperID: paperID, ChunkID: chunkID})
+		}
+		out.Facts = append(out.Facts, f)
+	}
+	return out, nil
+}
+
 func (a *Activities) ListPaperChunksActivity(ctx context.Context, in KGPaperInput) (ListPaperChunksOutput, error) {
 	paper, err := a.paperRepo.GetPaperByID(ctx, in.CorpusID, in.PaperID)
 	if err != nil {
@@ -407,6 +696,44 @@ func (a *Activities) ListPaperChunksActivity(ctx context.Context, in KGPaperInpu
 	return out, nil
 }
 
+func (a *Activities) UpsertPaperSummaryActivity(ctx context.Context, in UpsertPaperSummaryInput) error {
+	return a.paperRepo.UpsertPaperSummary(ctx, models.PaperSummary{
+		PaperID:       in.Summary.PaperID,
+		CorpusID:      in.CorpusID,
+		TLDR:          in.Summary.TLDR,
+		Contributions: in.Summary.Contributions,
+		Method:        in.Summary.Method,
+		Results:       in.Summary.Results,
+		Limitations:   in.Summary.Limitations,
+		PromptVersion: in.PromptVersion,
+		LLMProvider:   in.LLMProvider,
+		LLMModel:      in.LLMModel,
+		ChunkCount:    in.ChunkCount,
+	})
+}
+
+// ListPaperSummariesActivity loads stored summaries so backfills can skip current ones and
+// surveys can use them as context.
+func (a *Activities) ListPaperSummariesActivity(ctx context.Context, in ListPaperSummariesInput) (ListPaperSummariesOutput, error) {
+	summaries, err := a.paperRepo.ListPaperSummaries(ctx, corpusScope(in.CorpusID, in.CorpusIDs), in.PaperIDs)
+	if err != nil {
+		return ListPaperSummariesOutput{}, err
+	}
+	out := ListPaperSummariesOutput{Summaries: make([]PaperSummaryRecord, 0, len(summaries))}
+	for _, s := range summaries {
+		out.Summaries = append(out.Summaries, PaperSummaryRecord{
+			PaperID:       s.PaperID,
+			TLDR:          s.TLDR,
+			Contributions: s.Contributions,
+			Method:        s.Method,
+			Results:       s.Results,
+			Limitations:   s.Limitations,
+			PromptVersion: s.PromptVersion,
+		})
+	}
+	return out, nil
+}
+
 func (a *Activities) UpsertKGTriplesActivity(ctx context.Context, in UpsertKGTriplesInput) error {
 	triples := make([]storage.KGTripleInput, 0, len(in.Triples))
 	for _, t := range in.Triples {
@@ -463,3 +790,52 @@ func heuristicTitleAndAuthors(text string) (string, string) {
 	}
 	return title, authors
 }
+
+var (
+	doiPattern      = regexp.MustCompile(`\b10\.\d{4,9}/[^\s"<>]+`)
+	yearPattern     = regexp.MustCompile(`\b(19[5-9]\d|20\d{2})\b`)
+	arxivIDPattern  = regexp.MustCompile(`arXiv:(\d{2})(\d{2})\.\d{4,5}`)
+	venueLineMarker = regexp.MustCompile(`(?i)\b(proceedings of|conference|symposium|workshop|journal|transactions|arxiv:)`)
+)
+
+// heuristicBibMetadata looks for a DOI, a venue line and a publication year in the first
+// page. The year comes from the venue line, then a copyright line, then an arXiv identifier.
+func heuristicBibMetadata(text string) (int, string, string) {
+	head := text
+	if len(head) > 4000 {
+		head = head[:4000]
+	}
+	doi := strings.TrimRight(doiPattern.FindString(head), ".,;)")
+	venue := ""
+	year := 0
+	copyrightYear := 0
+	for _, line := range strings.Split(head, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" || len(line) > 200 {
+			continue
+		}
+		if venue == "" && venueLineMarker.MatchString(line) {
+			venue = line
+			if m := yearPattern.FindString(line); m != "" {
+				year, _ = strconv.Atoi(m)
+			}
+		}
+		lower := strings.ToLower(line)
+		if copyrightYear == 0 && (strings.Contains(line, "©") || strings.Contains(lower, "copyright")) {
+			if m := yearPattern.FindString(line); m != "" {
+				copyrightYear, _ = strconv.Atoi(m)
+			}
+		}
+	}
+	if year == 0 {
+		year = copyrightYear
+	}
+	if m := arxivIDPattern.FindStringSubmatch(head); m != nil && year == 0 {
+		yy, _ := strconv.Atoi(m[1])
+		year = 2000 + yy
+	}
+	if m := arxivIDPattern.FindString(venue); m != "" {
+		venue = "arXiv preprint " + strings.TrimPrefix(m, "arXiv:")
+	}
+	return year, venue, doi
+}
@@ -0,0 +1,32 @@
+package activities
+
+// ExtractDraftTextInput names an uploaded draft; Format is "pdf" or "latex".
+type ExtractDraftTextInput struct {
+	Path   string `json:"path"`
+	Format string `json:"format"`
+}
+
+type ExtractDraftTextOutput struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+type UpdatePositioningRunInput struct {
+	PositioningRunID string `json:"positioning_run_id"`
+	Status           string `json:"status"`
+	OutPath          string `json:"out_path,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+type WritePositioningReportInput struct {
+	CorpusID         string `json:"corpus_id"`
+	DraftID          string `json:"draft_id"`
+	PositioningRunID string `json:"positioning_run_id"`
+	Markdown         string `json:"markdown"`
+	Trace            any    `json:"trace"`
+}
+
+type WritePositioningReportOutput struct {
+	ReportPath string `json:"report_path"`
+	TracePath  string `json:"trace_path"`
+}
@@ -0,0 +1,37 @@
+package activities
+
+import "litflow/internal/retrieval"
+
+type LoadEvalSetInput struct {
+	CorpusID  string `json:"corpus_id"`
+	EvalSetID string `json:"eval_set_id"`
+}
+
+type EvalQuestionItem struct {
+	QuestionID string                 `json:"question_id"`
+	Question   string                 `json:"question"`
+	Judgment   retrieval.EvalJudgment `json:"judgment"`
+}
+
+type LoadEvalSetOutput struct {
+	Name      string             `json:"name"`
+	Questions []EvalQuestionItem `json:"questions"`
+}
+
+type UpdateEvalRunInput struct {
+	EvalRunID string                 `json:"eval_run_id"`
+	Status    string                 `json:"status"`
+	Metrics   *retrieval.EvalMetrics `json:"metrics,omitempty"`
+	OutPath   string                 `json:"out_path,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+type WriteEvalReportInput struct {
+	CorpusID  string `json:"corpus_id"`
+	EvalRunID string `json:"eval_run_id"`
+	Report    any    `json:"report"`
+}
+
+type WriteEvalReportOutput struct {
+	Path string `json:"path"`
+}
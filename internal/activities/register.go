@@ -8,8 +8,17 @@ func Register(w worker.Worker, a *Activities) {
 	w.RegisterActivity(a.ListFailedPapersActivity)
 	w.RegisterActivity(a.ListCorpusPapersActivity)
 	w.RegisterActivity(a.WriteRunManifestActivity)
+	w.RegisterActivity(a.LoadEvalSetActivity)
+	w.RegisterActivity(a.UpdateEvalRunActivity)
+	w.RegisterActivity(a.WriteEvalReportActivity)
+	w.RegisterActivity(a.SearchGraphEdgesActivity)
+	w.RegisterActivity(a.UpdateResearchRunActivity)
+	w.RegisterActivity(a.WriteResearchReportActivity)
+	w.RegisterActivity(a.UpdatePositioningRunActivity)
+	w.RegisterActivity(a.WritePositioningReportActivity)
 	w.RegisterActivity(a.ComputePaperIDActivity)
 	w.RegisterActivity(a.ExtractTextActivity)
+	w.RegisterActivity(a.ExtractDraftTextActivity)
 	w.RegisterActivity(a.ExtractMetadataActivity)
 	w.RegisterActivity(a.ChunkTextActivity)
 	w.RegisterActivity(a.UpsertChunksActivity)
@@ -19,12 +28,21 @@ func Register(w worker.Worker, a *Activities) {
 	w.RegisterActivity(a.LLMGenerateActivity)
 	w.RegisterActivity(a.EmbedQueryActivity)
 	w.RegisterActivity(a.SearchChunksActivity)
+	w.RegisterActivity(a.ExpandChunkContextActivity)
 	w.RegisterActivity(a.WriteSurveyReportActivity)
 	w.RegisterActivity(a.UpdateSurveyRunActivity)
+	w.RegisterActivity(a.CreateSurveyRunActivity)
+	w.RegisterActivity(a.GetSurveyRunActivity)
+	w.RegisterActivity(a.ReadSurveyDocumentActivity)
+	w.RegisterActivity(a.SaveSurveyValidationActivity)
+	w.RegisterActivity(a.WriteSurveyArtifactActivity)
 	w.RegisterActivity(a.LogLLMCallActivity)
 	w.RegisterActivity(a.UpsertTopicGraphActivity)
 	w.RegisterActivity(a.GetSurveyPaperMetaActivity)
+	w.RegisterActivity(a.GetSurveyKGFactsActivity)
 	w.RegisterActivity(a.ListPaperChunksActivity)
+	w.RegisterActivity(a.UpsertPaperSummaryActivity)
+	w.RegisterActivity(a.ListPaperSummariesActivity)
 	w.RegisterActivity(a.UpsertKGTriplesActivity)
 	w.RegisterActivity(a.MarkKGPaperRunActivity)
 }
@@ -0,0 +1,46 @@
+package activities
+
+type SearchGraphEdgesInput struct {
+	CorpusID  string   `json:"corpus_id"`
+	CorpusIDs []string `json:"corpus_ids,omitempty"`
+	Terms     []string `json:"terms"`
+	Limit     int      `json:"limit"`
+}
+
+// GraphEdgeHit is a KG relation with the first provenance entry flattened so it can be cited
+// like a chunk.
+type GraphEdgeHit struct {
+	EdgeID       string  `json:"edge_id"`
+	CorpusID     string  `json:"corpus_id"`
+	EdgeType     string  `json:"edge_type"`
+	SourceName   string  `json:"source_name"`
+	TargetName   string  `json:"target_name"`
+	SupportCount int     `json:"support_count"`
+	Weight       float64 `json:"weight"`
+	PaperID      string  `json:"paper_id,omitempty"`
+	ChunkID      string  `json:"chunk_id,omitempty"`
+	Evidence     string  `json:"evidence,omitempty"`
+}
+
+type SearchGraphEdgesOutput struct {
+	Edges []GraphEdgeHit `json:"edges"`
+}
+
+type UpdateResearchRunInput struct {
+	ResearchRunID string `json:"research_run_id"`
+	Status        string `json:"status"`
+	OutPath       string `json:"out_path,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+type WriteResearchReportInput struct {
+	CorpusID      string `json:"corpus_id"`
+	ResearchRunID string `json:"research_run_id"`
+	Markdown      string `json:"markdown"`
+	Trace         any    `json:"trace"`
+}
+
+type WriteResearchReportOutput struct {
+	ReportPath string `json:"report_path"`
+	TracePath  string `json:"trace_path"`
+}
@@ -0,0 +1,31 @@
+package activities
+
+// PaperSummaryRecord is the structured content of a paper summary, independent of storage.
+type PaperSummaryRecord struct {
+	PaperID       string   `json:"paper_id"`
+	TLDR          string   `json:"tldr"`
+	Contributions []string `json:"contributions"`
+	Method        string   `json:"method"`
+	Results       string   `json:"results"`
+	Limitations   string   `json:"limitations"`
+	PromptVersion string   `json:"prompt_version,omitempty"`
+}
+
+type UpsertPaperSummaryInput struct {
+	CorpusID      string             `json:"corpus_id"`
+	Summary       PaperSummaryRecord `json:"summary"`
+	PromptVersion string             `json:"prompt_version"`
+	LLMProvider   string             `json:"llm_provider,omitempty"`
+	LLMModel      string             `json:"llm_model,omitempty"`
+	ChunkCount    int                `json:"chunk_count"`
+}
+
+type ListPaperSummariesInput struct {
+	CorpusID  string   `json:"corpus_id"`
+	CorpusIDs []string `json:"corpus_ids,omitempty"`
+	PaperIDs  []string `json:"paper_ids,omitempty"`
+}
+
+type ListPaperSummariesOutput struct {
+	Summaries []PaperSummaryRecord `json:"summaries"`
+}
@@ -1,5 +1,10 @@
 package activities
 
+import (
+	"litflow/internal/models"
+	"litflow/internal/survey"
+)
+
 type EmbedQueryInput struct {
 	Operation     string `json:"operation"`
 	Text          string `json:"text"`
@@ -14,54 +19,156 @@ type EmbedQueryOutput struct {
 
 type SearchChunksInput struct {
 	CorpusID         string    `json:"corpus_id"`
+	CorpusIDs        []string  `json:"corpus_ids,omitempty"`
 	QueryVec         []float32 `json:"query_vec"`
 	TopK             int       `json:"top_k"`
 	EmbeddingVersion string    `json:"embedding_version,omitempty"`
+	// PaperIDs restricts the search to these papers when set.
+	PaperIDs     []string `json:"paper_ids,omitempty"`
+	ChunkVersion string   `json:"chunk_version,omitempty"`
+	// TextQuery switches to full-text ranking of the query's words; QueryVec still scores hits.
+	TextQuery string `json:"text_query,omitempty"`
 }
 
 type SearchChunk struct {
-	PaperID string  `json:"paper_id"`
-	Title   string  `json:"title"`
-	ChunkID string  `json:"chunk_id"`
-	Snippet string  `json:"snippet"`
-	Score   float64 `json:"score"`
-	Text    string  `json:"text"`
+	CorpusID   string  `json:"corpus_id,omitempty"`
+	PaperID    string  `json:"paper_id"`
+	Title      string  `json:"title"`
+	ChunkID    string  `json:"chunk_id"`
+	ChunkIndex int     `json:"chunk_index"`
+	Section    string  `json:"section,omitempty"`
+	Snippet    string  `json:"snippet"`
+	Score      float64 `json:"score"`
+	Text       string  `json:"text"`
+
+	ContextChunkIDs []string `json:"context_chunk_ids,omitempty"`
 }
 
 type SearchChunksOutput struct {
 	Results []SearchChunk `json:"results"`
 }
 
+type ExpandChunkContextInput struct {
+	CorpusID    string        `json:"corpus_id"`
+	CorpusIDs   []string      `json:"corpus_ids,omitempty"`
+	Hits        []SearchChunk `json:"hits"`
+	Window      int           `json:"window"`
+	TokenBudget int           `json:"token_budget"`
+}
+
+// ExpandChunkContextOutput holds the hits that fit the budget, in rank order, with Text
+// replaced by the expanded window.
+type ExpandChunkContextOutput struct {
+	Results []SearchChunk `json:"results"`
+}
+
 type SurveyPaperMeta struct {
 	PaperID  string `json:"paper_id"`
 	Title    string `json:"title,omitempty"`
 	Authors  string `json:"authors,omitempty"`
 	Year     int    `json:"year,omitempty"`
+	Venue    string `json:"venue,omitempty"`
+	DOI      string `json:"doi,omitempty"`
 	Filename string `json:"filename,omitempty"`
 }
 
 type GetSurveyPaperMetaInput struct {
-	CorpusID string   `json:"corpus_id"`
-	PaperIDs []string `json:"paper_ids"`
+	CorpusID  string   `json:"corpus_id"`
+	CorpusIDs []string `json:"corpus_ids,omitempty"`
+	PaperIDs  []string `json:"paper_ids"`
 }
 
 type GetSurveyPaperMetaOutput struct {
 	Papers []SurveyPaperMeta `json:"papers"`
 }
 
+type GetSurveyKGFactsInput struct {
+	CorpusID  string   `json:"corpus_id"`
+	CorpusIDs []string `json:"corpus_ids,omitempty"`
+	PaperIDs  []string `json:"paper_ids"`
+	Limit     int      `json:"limit,omitempty"`
+}
+
+// SurveyKGFact is one extracted relation between named entities; Provenance lists the papers
+// (and chunks) it was extracted from.
+type SurveyKGFact struct {
+	CorpusID     string               `json:"corpus_id"`
+	EdgeType     string               `json:"edge_type"`
+	SourceType   string               `json:"source_type"`
+	SourceName   string               `json:"source_name"`
+	TargetType   string               `json:"target_type"`
+	TargetName   string               `json:"target_name"`
+	SupportCount int                  `json:"support_count"`
+	Provenance   []SurveyKGProvenance `json:"provenance"`
+}
+
+type SurveyKGProvenance struct {
+	PaperID string `json:"paper_id"`
+	ChunkID string `json:"chunk_id,omitempty"`
+}
+
+type GetSurveyKGFactsOutput struct {
+	Facts []SurveyKGFact `json:"facts"`
+}
+
 type WriteSurveyReportInput struct {
-	CorpusID     string `json:"corpus_id"`
-	SurveyRunID  string `json:"survey_run_id"`
-	Report       string `json:"report"`
-	OutputFormat string `json:"output_format,omitempty"`
+	CorpusID     string          `json:"corpus_id"`
+	SurveyRunID  string          `json:"survey_run_id"`
+	Document     survey.Document `json:"document"`
+	OutputFormat string          `json:"output_format,omitempty"`
+}
+
+// WriteSurveyArtifactInput names an artifact by its slash-separated path inside the run's
+// output directory.
+type WriteSurveyArtifactInput struct {
+	CorpusID    string `json:"corpus_id"`
+	SurveyRunID string `json:"survey_run_id"`
+	Name        string `json:"name"`
+	Content     string `json:"content"`
 }
 
 type WriteSurveyReportOutput struct {
 	OutPath string `json:"out_path"`
 }
 
+type SaveSurveyValidationInput struct {
+	SurveyRunID string            `json:"survey_run_id"`
+	Validation  survey.Validation `json:"validation"`
+}
+
 type UpdateSurveyRunInput struct {
 	SurveyRunID string `json:"survey_run_id"`
 	Status      string `json:"status"`
 	OutPath     string `json:"out_path"`
+	// Models, when set, records the "provider/model" pairs that generated the run.
+	Models []string `json:"models,omitempty"`
+}
+
+// CreateSurveyRunInput registers a run before its workflow starts; ParentRunID links a
+// regeneration to the run it regenerates.
+type CreateSurveyRunInput struct {
+	SurveyRunID   string   `json:"survey_run_id"`
+	CorpusID      string   `json:"corpus_id"`
+	ParentRunID   string   `json:"parent_run_id,omitempty"`
+	OutputFormat  string   `json:"output_format"`
+	TemplateID    string   `json:"template_id,omitempty"`
+	CitationStyle string   `json:"citation_style,omitempty"`
+	Topics        []string `json:"topics"`
+	Questions     []string `json:"questions"`
+}
+
+type ReadSurveyDocumentInput struct {
+	OutPath string `json:"out_path"`
+}
+
+type ReadSurveyDocumentOutput struct {
+	Document survey.Document `json:"document"`
+}
+
+type GetSurveyRunInput struct {
+	SurveyRunID string `json:"survey_run_id"`
+}
+
+type GetSurveyRunOutput struct {
+	Run models.SurveyRun `json:"run"`
 }
@@ -1,5 +1,7 @@
 package activities
 
+import "time"
+
 type ComputePaperIDInput struct {
 	PaperPath string `json:"paper_path"`
 }
@@ -39,13 +41,14 @@ type ListCorpusPapersInput struct {
 }
 
 type CorpusPaper struct {
-	PaperID    string `json:"paper_id"`
-	Filename   string `json:"filename"`
-	Status     string `json:"status"`
-	Title      string `json:"title,omitempty"`
-	Authors    string `json:"authors,omitempty"`
-	Year       int    `json:"year,omitempty"`
-	FailReason string `json:"fail_reason,omitempty"`
+	PaperID    string    `json:"paper_id"`
+	Filename   string    `json:"filename"`
+	Status     string    `json:"status"`
+	Title      string    `json:"title,omitempty"`
+	Authors    string    `json:"authors,omitempty"`
+	Year       int       `json:"year,omitempty"`
+	FailReason string    `json:"fail_reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type ListCorpusPapersOutput struct {
@@ -77,6 +80,9 @@ type ExtractMetadataInput struct {
 type ExtractMetadataOutput struct {
 	Title   string `json:"title"`
 	Authors string `json:"authors"`
+	Year    int    `json:"year,omitempty"`
+	Venue   string `json:"venue,omitempty"`
+	DOI     string `json:"doi,omitempty"`
 }
 
 type ChunkTextInput struct {
@@ -94,6 +100,8 @@ type ChunkItem struct {
 	CorpusID   string `json:"corpus_id"`
 	ChunkIndex int    `json:"chunk_index"`
 	Text       string `json:"text"`
+	// ChunkVersion is the chunker version the chunk was produced with.
+	ChunkVersion string `json:"chunk_version,omitempty"`
 }
 
 type ChunkTextOutput struct {
@@ -121,6 +129,9 @@ type UpdatePaperStatusInput struct {
 	Filename   string `json:"filename"`
 	Title      string `json:"title"`
 	Authors    string `json:"authors"`
+	Year       int    `json:"year,omitempty"`
+	Venue      string `json:"venue,omitempty"`
+	DOI        string `json:"doi,omitempty"`
 	Status     string `json:"status"`
 	FailReason string `json:"fail_reason"`
 }
@@ -1,15 +1,21 @@
 package api
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,7 +23,9 @@ import (
 	"litflow/internal/config"
 	"litflow/internal/models"
 	"litflow/internal/providers"
+	"litflow/internal/retrieval"
 	"litflow/internal/storage"
+	"litflow/internal/survey"
 	"litflow/internal/util"
 	"litflow/internal/vector"
 	"litflow/internal/workflows"
@@ -32,8 +40,14 @@ type Server struct {
 	db         *storage.DB
 	corpusRepo *storage.CorpusRepo
 	paperRepo  *storage.PaperRepo
+	chunkRepo  *storage.ChunkRepo
 	surveyRepo *storage.SurveyRepo
 	graphRepo  *storage.GraphRepo
+	evalRepo   *storage.EvalRepo
+	sessions   *storage.SessionRepo
+	askLog     *storage.AskLogRepo
+	research   *storage.ResearchRepo
+	drafts     *storage.DraftRepo
 	searcher   *vector.Searcher
 	providers  *providers.Manager
 	temporal   tclient.Client
@@ -41,6 +55,7 @@ type Server struct {
 
 type askCitation struct {
 	RefID    string  `json:"ref_id"`
+	CorpusID string  `json:"corpus_id"`
 	PaperID  string  `json:"paper_id"`
 	Title    string  `json:"title"`
 	Filename string  `json:"filename,omitempty"`
@@ -49,6 +64,8 @@ type askCitation struct {
 	Snippet  string  `json:"snippet"`
 	Summary  string  `json:"summary,omitempty"`
 	Score    float64 `json:"score"`
+
+	ContextChunkIDs []string `json:"context_chunk_ids,omitempty"`
 }
 
 func NewServer(cfg config.Config) *Server {
@@ -71,8 +88,14 @@ func NewServer(cfg config.Config) *Server {
 		db:         db,
 		corpusRepo: storage.NewCorpusRepo(db),
 		paperRepo:  storage.NewPaperRepo(db),
+		chunkRepo:  storage.NewChunkRepo(db),
 		surveyRepo: storage.NewSurveyRepo(db),
 		graphRepo:  storage.NewGraphRepo(db),
+		evalRepo:   storage.NewEvalRepo(db),
+		sessions:   storage.NewSessionRepo(db),
+		askLog:     storage.NewAskLogRepo(db),
+		research:   storage.NewResearchRepo(db),
+		drafts:     storage.NewDraftRepo(db),
 		searcher:   vector.NewSearcher(db.Pool),
 		providers:  pm,
 		temporal:   tc,
@@ -84,9 +107,13 @@ func (s *Server) Routes() http.Handler {
 	mux.HandleFunc("/healthz", s.handleHealthz)
 	mux.HandleFunc("/corpora", s.handleCorpora)
 	mux.HandleFunc("/corpora/", s.handleCorporaScoped)
+	mux.HandleFunc("/corpus-groups", s.handleCorpusGroups)
 	mux.HandleFunc("/ask", s.handleAsk)
+	mux.HandleFunc("/ask/stream", s.handleAskStream)
+	mux.HandleFunc("/search", s.handleSearch)
 	mux.HandleFunc("/survey", s.handleSurvey)
 	mux.HandleFunc("/survey/", s.handleSurveyScoped)
+	mux.HandleFunc("/survey/templates", s.handleSurveyTemplates)
 	mux.HandleFunc("/backfill", s.handleBackfill)
 	mux.HandleFunc("/providers/embeddings", s.handleEmbeddingProviders)
 	mux.HandleFunc("/workflows/status", s.handleWorkflowStatus)
@@ -181,6 +208,55 @@ func (s *Server) handleCorporaScoped(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]any{"papers": papers})
 		return
 	}
+	if len(parts) == 3 && parts[1] == "papers" {
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+		p, err := s.paperRepo.GetPaperByID(r.Context(), corpusID, parts[2])
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		summaries, err := s.paperRepo.ListPaperSummaries(r.Context(), []string{corpusID}, []string{p.PaperID})
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		resp := map[string]any{"paper": p, "summary": nil}
+		if len(summaries) > 0 {
+			resp["summary"] = summaries[0]
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	if len(parts) == 4 && parts[1] == "papers" && parts[3] == "summarize" {
+		if r.Method != http.MethodPost {
+			writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+		p, err := s.paperRepo.GetPaperByID(r.Context(), corpusID, parts[2])
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		we, err := s.temporal.ExecuteWorkflow(r.Context(), tclient.StartWorkflowOptions{
+			ID:        fmt.Sprintf("paper-summary-%s-%s-%d", corpusID, p.PaperID, time.Now().Unix()),
+			TaskQueue: s.cfg.TemporalTaskQueue,
+		}, workflows.PaperSummarizeWorkflow, workflows.PaperSummarizeInput{
+			CorpusID:        corpusID,
+			PaperID:         p.PaperID,
+			LLMProviders:    s.providers.LLMCount(),
+			LLMProviderRefs: providerRawRefs(s.providers.LLMProviderRefs()),
+			CooldownSeconds: s.cfg.ProviderCooldownSecs,
+		})
+		if err != nil {
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]any{"paper_id": p.PaperID, "workflow_id": we.GetID(), "run_id": we.GetRunID(), "prompt_version": workflows.PaperSummaryPromptVersion})
+		return
+	}
 	if len(parts) == 4 && parts[1] == "papers" && parts[3] == "file" {
 		if r.Method != http.MethodGet {
 			writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
@@ -278,188 +354,1376 @@ func (s *Server) handleCorporaScoped(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]any{"nodes": nodes, "edges": edges})
 		return
 	}
+	if len(parts) == 2 && parts[1] == "compare" {
+		s.handleCompare(w, r, corpusID)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "cite-suggest" {
+		s.handleCiteSuggest(w, r, corpusID)
+		return
+	}
+	if len(parts) >= 2 && parts[1] == "research" {
+		s.handleDeepResearch(w, r, corpusID, parts[1:])
+		return
+	}
+	if len(parts) >= 2 && parts[1] == "drafts" {
+		s.handleDrafts(w, r, corpusID, parts[1:])
+		return
+	}
+	if len(parts) >= 2 && parts[1] == "asks" {
+		s.handleAskLog(w, r, corpusID, parts[1:])
+		return
+	}
+	if len(parts) >= 2 && parts[1] == "sessions" {
+		s.handleAskSessions(w, r, corpusID, parts[1:])
+		return
+	}
+	if len(parts) >= 2 && parts[1] == "surveys" {
+		s.handleSurveyRuns(w, r, corpusID, parts[1:])
+		return
+	}
+	if len(parts) >= 2 && (parts[1] == "eval-sets" || parts[1] == "eval-runs") {
+		s.handleRetrievalEval(w, r, corpusID, parts[1:])
+		return
+	}
 
 	writeErr(w, http.StatusNotFound, fmt.Errorf("not found"))
 }
 
-func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request, corpusID string) {
-	if err := r.ParseMultipartForm(128 << 20); err != nil {
-		writeErr(w, http.StatusBadRequest, fmt.Errorf("parse multipart: %w", err))
-		return
+// handleDeepResearch serves /corpora/{id}/research[/{research_run_id}]. Runs are started as
+// DeepResearchWorkflow executions; a run in progress reports the workflow's plan and findings.
+func (s *Server) handleDeepResearch(w http.ResponseWriter, r *http.Request, corpusID string, parts []string) {
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		runs, err := s.research.ListRuns(r.Context(), corpusID)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"research_runs": runs})
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		var req struct {
+			Question     string   `json:"question"`
+			CorpusIDs    []string `json:"corpus_ids,omitempty"`
+			CorpusGroup  string   `json:"corpus_group,omitempty"`
+			MaxSteps     int      `json:"max_steps,omitempty"`
+			TokenBudget  int      `json:"token_budget,omitempty"`
+			TopK         int      `json:"top_k,omitempty"`
+			EmbedVersion string   `json:"embed_version,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+			return
+		}
+		req.Question = strings.TrimSpace(req.Question)
+		if req.Question == "" {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("question is required"))
+			return
+		}
+		corpusIDs, err := s.resolveCorpusScope(r.Context(), corpusID, req.CorpusIDs, req.CorpusGroup, req.EmbedVersion)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		if strings.TrimSpace(req.EmbedVersion) == "" {
+			req.EmbedVersion = s.cfg.EmbedVersion
+		}
+		input := workflows.DeepResearchInput{
+			ResearchRunID:   uuid.NewString(),
+			CorpusID:        corpusID,
+			Question:        req.Question,
+			MaxSteps:        req.MaxSteps,
+			TokenBudget:     req.TokenBudget,
+			TopK:            req.TopK,
+			EmbedVersion:    req.EmbedVersion,
+			EmbedProviders:  s.providers.EmbedCount(),
+			LLMProviders:    s.providers.LLMCount(),
+			LLMProviderRefs: providerRawRefs(s.providers.LLMProviderRefs()),
+			CooldownSeconds: s.cfg.ProviderCooldownSecs,
+		}
+		if len(corpusIDs) > 1 {
+			input.CorpusIDs = corpusIDs
+		}
+		cfg := map[string]any{"corpus_ids": corpusIDs, "max_steps": req.MaxSteps, "token_budget": req.TokenBudget, "top_k": req.TopK, "embed_version": req.EmbedVersion}
+		if err := s.research.CreateRun(r.Context(), input.ResearchRunID, corpusID, req.Question, cfg); err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		we, err := s.temporal.ExecuteWorkflow(r.Context(), tclient.StartWorkflowOptions{
+			ID:        "deep-research-" + input.ResearchRunID,
+			TaskQueue: s.cfg.TemporalTaskQueue,
+		}, workflows.DeepResearchWorkflow, input)
+		if err != nil {
+			_ = s.research.UpdateRun(r.Context(), input.ResearchRunID, "failed", "", err.Error())
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]any{"research_run_id": input.ResearchRunID, "workflow_id": we.GetID(), "run_id": we.GetRunID()})
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		run, err := s.research.GetRun(r.Context(), corpusID, parts[1])
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		resp := map[string]any{"run": run}
+		if run.Status == "completed" && run.OutPath != "" {
+			if b, err := os.ReadFile(run.OutPath); err == nil {
+				resp["report"] = string(b)
+			}
+			if b, err := os.ReadFile(filepath.Join(filepath.Dir(run.OutPath), "research.json")); err == nil {
+				var trace workflows.DeepResearchTrace
+				if err := json.Unmarshal(b, &trace); err == nil {
+					resp["progress"] = trace.Progress
+					resp["evidence"] = trace.Evidence
+				}
+			}
+		} else if q, err := s.temporal.QueryWorkflow(r.Context(), "deep-research-"+run.ResearchRunID, "", workflows.QueryGetDeepResearchProgress); err == nil {
+			var prog workflows.DeepResearchProgress
+			if err := q.Get(&prog); err == nil {
+				resp["progress"] = prog
+			}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case len(parts) <= 2:
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	default:
+		writeErr(w, http.StatusNotFound, fmt.Errorf("not found"))
 	}
+}
 
-	files := r.MultipartForm.File["files"]
-	if len(files) == 0 {
-		if single, ok := firstSingleFile(r.MultipartForm.File); ok {
-			files = append(files, single)
+// handleDrafts serves /corpora/{id}/drafts[/{draft_id}[/positioning[/{positioning_run_id}]]].
+// Drafts are stored under the corpus output directory rather than its input directory, so
+// ingestion never picks them up; positioning runs are DraftPositioningWorkflow executions.
+func (s *Server) handleDrafts(w http.ResponseWriter, r *http.Request, corpusID string, parts []string) {
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			drafts, err := s.drafts.ListDrafts(r.Context(), corpusID)
+			if err != nil {
+				writeErr(w, http.StatusInternalServerError, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"drafts": drafts})
+		case http.MethodPost:
+			s.handleDraftUpload(w, r, corpusID)
+		default:
+			writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		}
+		return
 	}
-	if len(files) == 0 {
-		writeErr(w, http.StatusBadRequest, fmt.Errorf("no files provided"))
+	if len(parts) > 4 || (len(parts) >= 3 && parts[2] != "positioning") {
+		writeErr(w, http.StatusNotFound, fmt.Errorf("not found"))
 		return
 	}
-
-	inDir := filepath.Join(s.cfg.DataInRoot, corpusID)
-	if err := util.EnsureDir(inDir); err != nil {
-		writeErr(w, http.StatusInternalServerError, err)
+	if _, err := uuid.Parse(parts[1]); err != nil {
+		writeErr(w, http.StatusNotFound, fmt.Errorf("draft not found"))
 		return
 	}
-
-	type uploadResult struct {
-		Filename string `json:"filename"`
-		PaperID  string `json:"paper_id"`
+	draft, err := s.drafts.GetDraft(r.Context(), corpusID, parts[1])
+	if err != nil {
+		writeErr(w, http.StatusNotFound, fmt.Errorf("draft not found"))
+		return
 	}
-	out := make([]uploadResult, 0, len(files))
-
-	for _, fh := range files {
-		if !strings.HasSuffix(strings.ToLower(fh.Filename), ".pdf") {
-			continue
-		}
-		paperID, savedPath, err := saveUploadedFile(inDir, fh)
+	switch {
+	case len(parts) <= 3 && r.Method == http.MethodGet:
+		runs, err := s.drafts.ListRuns(r.Context(), draft.DraftID)
 		if err != nil {
 			writeErr(w, http.StatusInternalServerError, err)
 			return
 		}
-		if err := s.paperRepo.UpsertPaper(r.Context(), models.Paper{
-			PaperID:  paperID,
-			CorpusID: corpusID,
-			Filename: filepath.Base(savedPath),
-			Status:   "pending",
-		}); err != nil {
+		writeJSON(w, http.StatusOK, map[string]any{"draft": draft, "positioning_runs": runs})
+	case len(parts) == 3 && r.Method == http.MethodPost:
+		var req struct {
+			CorpusIDs    []string `json:"corpus_ids,omitempty"`
+			CorpusGroup  string   `json:"corpus_group,omitempty"`
+			MaxClaims    int      `json:"max_claims,omitempty"`
+			TopK         int      `json:"top_k,omitempty"`
+			EmbedVersion string   `json:"embed_version,omitempty"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+				return
+			}
+		}
+		corpusIDs, err := s.resolveCorpusScope(r.Context(), corpusID, req.CorpusIDs, req.CorpusGroup, req.EmbedVersion)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		if strings.TrimSpace(req.EmbedVersion) == "" {
+			req.EmbedVersion = s.cfg.EmbedVersion
+		}
+		input := workflows.DraftPositioningInput{
+			PositioningRunID: uuid.NewString(),
+			DraftID:          draft.DraftID,
+			CorpusID:         corpusID,
+			DraftPath:        draft.FilePath,
+			DraftFormat:      draft.Format,
+			DraftFilename:    draft.Filename,
+			MaxClaims:        req.MaxClaims,
+			TopK:             req.TopK,
+			EmbedVersion:     req.EmbedVersion,
+			EmbedProviders:   s.providers.EmbedCount(),
+			LLMProviders:     s.providers.LLMCount(),
+			LLMProviderRefs:  providerRawRefs(s.providers.LLMProviderRefs()),
+			CooldownSeconds:  s.cfg.ProviderCooldownSecs,
+		}
+		if len(corpusIDs) > 1 {
+			input.CorpusIDs = corpusIDs
+		}
+		cfg := map[string]any{"corpus_ids": corpusIDs, "max_claims": req.MaxClaims, "top_k": req.TopK, "embed_version": req.EmbedVersion}
+		if err := s.drafts.CreateRun(r.Context(), input.PositioningRunID, draft.DraftID, corpusID, cfg); err != nil {
 			writeErr(w, http.StatusInternalServerError, err)
 			return
 		}
-		out = append(out, uploadResult{Filename: filepath.Base(savedPath), PaperID: paperID})
+		we, err := s.temporal.ExecuteWorkflow(r.Context(), tclient.StartWorkflowOptions{
+			ID:        "draft-positioning-" + input.PositioningRunID,
+			TaskQueue: s.cfg.TemporalTaskQueue,
+		}, workflows.DraftPositioningWorkflow, input)
+		if err != nil {
+			_ = s.drafts.UpdateRun(r.Context(), input.PositioningRunID, "failed", "", err.Error())
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]any{"positioning_run_id": input.PositioningRunID, "workflow_id": we.GetID(), "run_id": we.GetRunID()})
+	case len(parts) == 4 && r.Method == http.MethodGet:
+		if _, err := uuid.Parse(parts[3]); err != nil {
+			writeErr(w, http.StatusNotFound, fmt.Errorf("positioning run not found"))
+			return
+		}
+		run, err := s.drafts.GetRun(r.Context(), draft.DraftID, parts[3])
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		resp := map[string]any{"run": run}
+		if run.Status == "completed" && run.OutPath != "" {
+			if b, err := os.ReadFile(run.OutPath); err == nil {
+				resp["report"] = string(b)
+			}
+			if b, err := os.ReadFile(filepath.Join(filepath.Dir(run.OutPath), "positioning.json")); err == nil {
+				var trace workflows.DraftPositioningTrace
+				if err := json.Unmarshal(b, &trace); err == nil {
+					resp["claims"] = trace.Claims
+					resp["prior_work"] = trace.PriorWork
+					resp["references"] = trace.References
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 	}
-
-	writeJSON(w, http.StatusOK, map[string]any{"uploaded": out})
 }
 
-func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+// handleDraftUpload stores one .pdf or .tex file as a new draft.
+func (s *Server) handleDraftUpload(w http.ResponseWriter, r *http.Request, corpusID string) {
+	if err := r.ParseMultipartForm(64 << 20); err != nil {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("parse multipart: %w", err))
 		return
 	}
-	var req struct {
-		CorpusID      string `json:"corpus_id"`
-		Question      string `json:"question"`
-		TopK          int    `json:"top_k"`
-		EmbedProvider string `json:"embed_provider,omitempty"`
-		EmbedVersion  string `json:"embed_version,omitempty"`
+	fh, ok := firstSingleFile(r.MultipartForm.File)
+	if files := r.MultipartForm.File["file"]; len(files) > 0 {
+		fh, ok = files[0], true
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+	if !ok {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("no file provided"))
 		return
 	}
-	req.CorpusID = strings.TrimSpace(req.CorpusID)
-	req.Question = strings.TrimSpace(req.Question)
-	if req.CorpusID == "" || req.Question == "" {
-		writeErr(w, http.StatusBadRequest, fmt.Errorf("corpus_id and question are required"))
+	var format string
+	switch strings.ToLower(filepath.Ext(fh.Filename)) {
+	case ".pdf":
+		format = "pdf"
+	case ".tex":
+		format = "latex"
+	default:
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("draft must be a .pdf or .tex file"))
 		return
 	}
-	if req.TopK <= 0 {
-		req.TopK = 8
+	if _, err := uuid.Parse(corpusID); err != nil {
+		writeErr(w, http.StatusNotFound, fmt.Errorf("corpus not found"))
+		return
 	}
-	if strings.TrimSpace(req.EmbedVersion) == "" {
-		req.EmbedVersion = s.cfg.EmbedVersion
+	if ids, err := s.corpusRepo.ExistingCorpusIDs(r.Context(), []string{corpusID}); err != nil || len(ids) == 0 {
+		writeErr(w, http.StatusNotFound, fmt.Errorf("corpus not found"))
+		return
 	}
+	draft := models.Draft{DraftID: uuid.NewString(), CorpusID: corpusID, Filename: filepath.Base(fh.Filename), Format: format}
+	dir := filepath.Join(s.cfg.DataOutRoot, corpusID, "drafts", draft.DraftID)
+	if err := util.EnsureDir(dir); err != nil {
+		writeErr(w, http.StatusInternalServerError, err)
+		return
+	}
+	draft.FilePath = filepath.Join(dir, draft.Filename)
+	if err := saveDraftFile(draft.FilePath, fh); err != nil {
+		writeErr(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := s.drafts.CreateDraft(r.Context(), draft); err != nil {
+		writeErr(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"draft": draft})
+}
 
-	var (
-		info providers.ProviderInfo
-		err  error
-	)
-	embedOrders := s.providers.PreferredEmbedOrder()
-	preferredIdx := s.providers.FindEmbedProviderIndex(req.EmbedProvider)
-	if strings.TrimSpace(req.EmbedProvider) != "" && preferredIdx < 0 {
-		writeErr(w, http.StatusBadRequest, fmt.Errorf("unknown embed_provider: %s", req.EmbedProvider))
+// handleAskLog serves /corpora/{id}/asks[/{ask_id}[/replay]]. A replay re-runs the logged
+// question with the same filters against the current corpus and diffs the citations.
+func (s *Server) handleAskLog(w http.ResponseWriter, r *http.Request, corpusID string, parts []string) {
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		asks, err := s.askLog.List(r.Context(), corpusID, limit)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"asks": asks})
+		return
+	}
+	if len(parts) > 3 || (len(parts) == 3 && parts[2] != "replay") {
+		writeErr(w, http.StatusNotFound, fmt.Errorf("not found"))
 		return
 	}
-	if preferredIdx >= 0 {
-		embedOrders = orderWithPreferredFirst(embedOrders, preferredIdx)
+	if _, err := uuid.Parse(parts[1]); err != nil {
+		writeErr(w, http.StatusNotFound, fmt.Errorf("ask not found"))
+		return
 	}
-	queryVectors := [][]float32(nil)
-	for _, idx := range embedOrders {
-		p, _ := s.providers.EmbedProviderByIndex(idx)
-		queryVectors, info, err = p.Embed(r.Context(), providers.EmbedRequest{
-			Operation: "ask_query_embed",
-			Inputs:    []string{req.Question},
-			Dimension: s.cfg.EmbedDim,
-		})
-		if err == nil && len(queryVectors) > 0 {
-			break
+	rec, err := s.askLog.Get(r.Context(), parts[1])
+	if err != nil || rec.CorpusID != corpusID {
+		writeErr(w, http.StatusNotFound, fmt.Errorf("ask not found"))
+		return
+	}
+	if len(parts) == 2 {
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
 		}
+		writeJSON(w, http.StatusOK, rec)
+		return
 	}
-	if err != nil || len(queryVectors) == 0 {
-		writeErr(w, http.StatusBadGateway, fmt.Errorf("embedding providers unavailable"))
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		return
 	}
-	results, err := s.searcher.SearchChunks(r.Context(), req.CorpusID, queryVectors[0], req.TopK, vector.SearchFilters{
-		EmbeddingVersion: req.EmbedVersion,
-	})
+	var req askRequest
+	filtersJSON, _ := json.Marshal(rec.Filters)
+	_ = json.Unmarshal(filtersJSON, &req)
+	// Replays ask the original question but retrieve with the logged condensed query, so they
+	// do not depend on the session history. They pin the resolved corpus list rather than a
+	// group that may have changed since, and the embedding provider, model and version.
+	req.Question = rec.Question
+	req.standaloneQuery = rec.StandaloneQuery
+	req.CorpusID = rec.CorpusID
+	req.CorpusIDs = rec.CorpusIDs
+	req.CorpusGroup = ""
+	req.EmbedProvider = rec.EmbedProvider
+	req.EmbedVersion = rec.EmbedVersion
+	req.pinnedEmbedModel = rec.EmbedModel
+	resp, ar, status, err := s.runAsk(r.Context(), req, rec.AskID)
 	if err != nil {
-		writeErr(w, http.StatusInternalServerError, err)
+		writeErr(w, status, err)
 		return
 	}
-	citations := make([]askCitation, 0, len(results))
-	contextSnippets := make([]string, 0, len(results))
-	citationContexts := make([]string, 0, len(results))
-	for i, r := range results {
-		refID := fmt.Sprintf("C%d", i+1)
-		displayTitle := util.DisplaySnippet(r.Title, 100)
-		if displayTitle == "" {
-			displayTitle = util.DisplaySnippet(r.Filename, 100)
-		}
-		snippet := util.DisplayEvidenceSnippet(r.ChunkText, req.Question, 420)
-		if snippet == "" {
-			snippet = util.DisplaySnippet(r.Snippet, 420)
-		}
-		contextText := util.DisplaySnippet(r.ChunkText, 1200)
-		citations = append(citations, askCitation{
-			RefID:    refID,
-			PaperID:  r.PaperID,
-			Title:    displayTitle,
-			Filename: r.Filename,
-			PaperURL: fmt.Sprintf("/corpora/%s/papers/%s/file", req.CorpusID, r.PaperID),
-			ChunkID:  r.ChunkID,
-			Snippet:  snippet,
-			Score:    r.Score,
-		})
-		fullContext := fmt.Sprintf("%s | %s [%s]: %s", refID, displayTitle, r.ChunkID, contextText)
-		contextSnippets = append(contextSnippets, fullContext)
-		citationContexts = append(citationContexts, fullContext)
+	before := make([]retrieval.CitationRef, 0, len(rec.Citations))
+	for _, c := range rec.Citations {
+		score, _ := c["score"].(float64)
+		before = append(before, retrieval.CitationRef{ChunkID: stringField(c, "chunk_id"), PaperID: stringField(c, "paper_id"), Title: stringField(c, "title"), Score: score})
 	}
+	after := make([]retrieval.CitationRef, 0, len(ar.citations))
+	for _, c := range ar.citations {
+		after = append(after, retrieval.CitationRef{ChunkID: c.ChunkID, PaperID: c.PaperID, Title: c.Title, Score: c.Score})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"original_ask_id": rec.AskID,
+		"replay":          resp,
+		"citation_diff":   retrieval.DiffCitations(before, after),
+		"answer_changed":  strings.TrimSpace(rec.Answer) != strings.TrimSpace(fmt.Sprint(resp["answer"])),
+		"prompt_changed":  rec.PromptVersion != askPromptVersion,
+		"embed_changed":   rec.EmbedProvider != ar.embedInfo.Name || rec.EmbedModel != ar.embedInfo.Model,
+	})
+}
 
-	var (
-		llmResp providers.GenerateResponse
-		llmInfo providers.ProviderInfo
-		llmErr  error
-	)
-	generate := func(op, prompt string, ctxSnippets []string) (providers.GenerateResponse, providers.ProviderInfo, error) {
-		var (
-			resp providers.GenerateResponse
-			info providers.ProviderInfo
-			err  error
-		)
-		for _, idx := range s.providers.PreferredLLMOrder() {
-			p, _ := s.providers.LLMProviderByIndex(idx)
-			resp, info, err = p.Generate(r.Context(), providers.GenerateRequest{
-				Operation: op,
-				Prompt:    prompt,
-				Context:   ctxSnippets,
-			})
-			if err == nil && strings.TrimSpace(resp.Text) != "" {
-				return resp, info, nil
-			}
+// handleAskSessions serves /corpora/{id}/sessions[/{session_id}[/export]].
+func (s *Server) handleAskSessions(w http.ResponseWriter, r *http.Request, corpusID string, parts []string) {
+	if len(parts) > 3 || (len(parts) == 3 && parts[2] != "export") {
+		writeErr(w, http.StatusNotFound, fmt.Errorf("not found"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+	if len(parts) == 1 {
+		sessions, err := s.sessions.ListSessions(r.Context(), corpusID)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
 		}
-		return resp, info, err
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
+		return
 	}
+	if _, err := uuid.Parse(parts[1]); err != nil {
+		writeErr(w, http.StatusNotFound, fmt.Errorf("session not found"))
+		return
+	}
+	sess, err := s.sessions.GetSession(r.Context(), parts[1])
+	if err != nil || sess.CorpusID != corpusID {
+		writeErr(w, http.StatusNotFound, fmt.Errorf("session not found"))
+		return
+	}
+	if len(parts) == 2 {
+		writeJSON(w, http.StatusOK, sess)
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "ask-session-"+sess.SessionID+".md"))
+	_, _ = io.WriteString(w, renderSessionMarkdown(sess))
+}
 
-	prompt := "" +
-		"Question: " + req.Question + "\n\n" +
-
-		"You must answer using ONLY the provided evidence snippets.\n" +
-		"Do NOT use outside knowledge.\n" +
-		"If the snippets do not contain enough information, explicitly state what is missing.\n\n" +
+// renderSessionMarkdown exports a session as one section per turn followed by that turn's
+// numbered citations. Citation IDs restart per turn, matching the stored answers.
+func renderSessionMarkdown(sess models.AskSession) string {
+	var b strings.Builder
+	title := strings.TrimSpace(sess.Title)
+	if title == "" {
+		title = "Ask session"
+	}
+	b.WriteString("# " + title + "\n\n")
+	b.WriteString(fmt.Sprintf("_Corpus `%s` · session `%s` · %d turns_\n\n", sess.CorpusID, sess.SessionID, len(sess.Turns)))
+	for i, t := range sess.Turns {
+		b.WriteString(fmt.Sprintf("## Q%d. %s\n\n", i+1, strings.TrimSpace(t.Question)))
+		if sq := strings.TrimSpace(t.StandaloneQuery); sq != "" && sq != strings.TrimSpace(t.Question) {
+			b.WriteString("> Searched as: " + sq + "\n\n")
+		}
+		b.WriteString(strings.TrimSpace(t.Answer) + "\n\n")
+		if len(t.Citations) == 0 {
+			continue
+		}
+		b.WriteString("**Citations**\n\n")
+		for _, c := range t.Citations {
+			line := fmt.Sprintf("- [%s] %s", stringField(c, "ref_id"), stringField(c, "title"))
+			if pid := stringField(c, "paper_id"); pid != "" {
+				line += fmt.Sprintf(" — paper `%s`", pid)
+			}
+			if cid := stringField(c, "chunk_id"); cid != "" {
+				line += fmt.Sprintf(", chunk `%s`", cid)
+			}
+			if summary := stringField(c, "summary"); summary != "" {
+				line += ". " + summary
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func stringField(m map[string]any, key string) string {
+	v, _ := m[key].(string)
+	return strings.TrimSpace(v)
+}
+
+// handleRetrievalEval serves /corpora/{id}/eval-sets[/{set_id}] and
+// /corpora/{id}/eval-runs[/compare|/{eval_run_id}].
+func (s *Server) handleRetrievalEval(w http.ResponseWriter, r *http.Request, corpusID string, parts []string) {
+	switch {
+	case parts[0] == "eval-sets" && len(parts) == 1 && r.Method == http.MethodGet:
+		sets, err := s.evalRepo.ListSets(r.Context(), corpusID)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"eval_sets": sets})
+	case parts[0] == "eval-sets" && len(parts) == 1 && r.Method == http.MethodPost:
+		var req struct {
+			Name      string `json:"name"`
+			Questions []struct {
+				Question         string   `json:"question"`
+				RelevantPaperIDs []string `json:"relevant_paper_ids"`
+				RelevantChunkIDs []string `json:"relevant_chunk_ids"`
+			} `json:"questions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || len(req.Questions) == 0 {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("name and questions are required"))
+			return
+		}
+		set := models.EvalSet{EvalSetID: uuid.NewString(), CorpusID: corpusID, Name: req.Name}
+		for i, q := range req.Questions {
+			text := strings.TrimSpace(q.Question)
+			if text == "" || (len(q.RelevantPaperIDs) == 0 && len(q.RelevantChunkIDs) == 0) {
+				writeErr(w, http.StatusBadRequest, fmt.Errorf("question %d needs text and at least one relevant paper or chunk", i+1))
+				return
+			}
+			set.Questions = append(set.Questions, models.EvalQuestion{
+				QuestionID:       uuid.NewString(),
+				Question:         text,
+				RelevantPaperIDs: q.RelevantPaperIDs,
+				RelevantChunkIDs: q.RelevantChunkIDs,
+			})
+		}
+		if err := s.evalRepo.CreateSet(r.Context(), set); err != nil {
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, set)
+	case parts[0] == "eval-sets" && len(parts) == 2 && r.Method == http.MethodGet:
+		set, err := s.evalRepo.GetSet(r.Context(), corpusID, parts[1])
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, set)
+	case parts[0] == "eval-runs" && len(parts) == 1 && r.Method == http.MethodGet:
+		runs, err := s.evalRepo.ListRuns(r.Context(), corpusID)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"eval_runs": runs})
+	case parts[0] == "eval-runs" && len(parts) == 1 && r.Method == http.MethodPost:
+		var req struct {
+			EvalSetID     string `json:"eval_set_id"`
+			TopK          int    `json:"top_k"`
+			Ks            []int  `json:"ks"`
+			QueryRewrite  string `json:"query_rewrite,omitempty"`
+			RewriteCount  int    `json:"rewrite_count,omitempty"`
+			EmbedVersion  string `json:"embed_version,omitempty"`
+			ChunkVersion  string `json:"chunk_version,omitempty"`
+			EmbedProvider string `json:"embed_provider,omitempty"`
+			Hybrid        bool   `json:"hybrid,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+			return
+		}
+		if strings.TrimSpace(req.EvalSetID) == "" {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("eval_set_id is required"))
+			return
+		}
+		if _, err := s.evalRepo.GetSet(r.Context(), corpusID, req.EvalSetID); err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		rewriteMode, err := retrieval.NormalizeRewriteMode(req.QueryRewrite)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		if strings.TrimSpace(req.EmbedVersion) == "" {
+			req.EmbedVersion = s.cfg.EmbedVersion
+		}
+		embedIndex := 0
+		if req.EmbedProvider = strings.TrimSpace(req.EmbedProvider); req.EmbedProvider != "" {
+			if embedIndex = s.providers.FindEmbedProviderIndex(req.EmbedProvider); embedIndex < 0 {
+				writeErr(w, http.StatusBadRequest, fmt.Errorf("unknown embed_provider: %s", req.EmbedProvider))
+				return
+			}
+		}
+		cfg := workflows.RetrievalEvalConfig{
+			TopK:          req.TopK,
+			Ks:            req.Ks,
+			QueryRewrite:  rewriteMode,
+			RewriteCount:  req.RewriteCount,
+			EmbedVersion:  req.EmbedVersion,
+			ChunkVersion:  req.ChunkVersion,
+			EmbedProvider: req.EmbedProvider,
+			Hybrid:        req.Hybrid,
+		}
+		evalRunID := uuid.NewString()
+		if err := s.evalRepo.CreateRun(r.Context(), evalRunID, corpusID, req.EvalSetID, cfg); err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		we, err := s.temporal.ExecuteWorkflow(r.Context(), tclient.StartWorkflowOptions{
+			ID:        "retrieval-eval-" + evalRunID,
+			TaskQueue: s.cfg.TemporalTaskQueue,
+		}, workflows.RetrievalEvalWorkflow, workflows.RetrievalEvalInput{
+			EvalRunID:          evalRunID,
+			CorpusID:           corpusID,
+			EvalSetID:          req.EvalSetID,
+			Config:             cfg,
+			EmbedProviderIndex: embedIndex,
+			EmbedProviders:     s.providers.EmbedCount(),
+			LLMProviders:       s.providers.LLMCount(),
+			LLMProviderRefs:    providerRawRefs(s.providers.LLMProviderRefs()),
+			CooldownSeconds:    s.cfg.ProviderCooldownSecs,
+		})
+		if err != nil {
+			_ = s.evalRepo.UpdateRun(r.Context(), evalRunID, "failed", nil, "", err.Error())
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]any{"eval_run_id": evalRunID, "workflow_id": we.GetID(), "run_id": we.GetRunID()})
+	case parts[0] == "eval-runs" && len(parts) == 2 && parts[1] == "compare" && r.Method == http.MethodGet:
+		idA := strings.TrimSpace(r.URL.Query().Get("a"))
+		idB := strings.TrimSpace(r.URL.Query().Get("b"))
+		if idA == "" || idB == "" {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("query params a and b are required"))
+			return
+		}
+		reportA, err := s.loadEvalReport(r.Context(), corpusID, idA)
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		reportB, err := s.loadEvalReport(r.Context(), corpusID, idB)
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"a":            map[string]any{"eval_run_id": idA, "eval_set_id": reportA.EvalSetID, "config": reportA.Config, "metrics": reportA.Aggregate},
+			"b":            map[string]any{"eval_run_id": idB, "eval_set_id": reportB.EvalSetID, "config": reportB.Config, "metrics": reportB.Aggregate},
+			"same_set":     reportA.EvalSetID == reportB.EvalSetID,
+			"delta":        retrieval.CompareMetrics(reportA.Aggregate, reportB.Aggregate),
+			"per_question": retrieval.CompareQuestions(reportA.Questions, reportB.Questions),
+		})
+	case parts[0] == "eval-runs" && len(parts) == 2 && r.Method == http.MethodGet:
+		run, err := s.evalRepo.GetRun(r.Context(), corpusID, parts[1])
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		resp := map[string]any{"run": run}
+		if run.Status == "completed" {
+			if report, err := s.loadEvalReport(r.Context(), corpusID, run.EvalRunID); err == nil {
+				resp["report"] = report
+			}
+		} else if q, err := s.temporal.QueryWorkflow(r.Context(), "retrieval-eval-"+run.EvalRunID, "", workflows.QueryGetRetrievalEvalProgress); err == nil {
+			var prog workflows.RetrievalEvalProgress
+			if err := q.Get(&prog); err == nil {
+				resp["progress"] = prog
+			}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case len(parts) <= 2:
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	default:
+		writeErr(w, http.StatusNotFound, fmt.Errorf("not found"))
+	}
+}
+
+func (s *Server) loadEvalReport(ctx context.Context, corpusID, evalRunID string) (workflows.RetrievalEvalReport, error) {
+	run, err := s.evalRepo.GetRun(ctx, corpusID, evalRunID)
+	if err != nil {
+		return workflows.RetrievalEvalReport{}, err
+	}
+	if run.Status != "completed" || run.OutPath == "" {
+		return workflows.RetrievalEvalReport{}, fmt.Errorf("eval run %s is %s", evalRunID, run.Status)
+	}
+	b, err := os.ReadFile(run.OutPath)
+	if err != nil {
+		return workflows.RetrievalEvalReport{}, fmt.Errorf("read eval report: %w", err)
+	}
+	var report workflows.RetrievalEvalReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		return workflows.RetrievalEvalReport{}, fmt.Errorf("decode eval report: %w", err)
+	}
+	return report, nil
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request, corpusID string) {
+	if err := r.ParseMultipartForm(128 << 20); err != nil {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("parse multipart: %w", err))
+		return
+	}
+
+	files := r.MultipartForm.File["files"]
+	if len(files) == 0 {
+		if single, ok := firstSingleFile(r.MultipartForm.File); ok {
+			files = append(files, single)
+		}
+	}
+	if len(files) == 0 {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("no files provided"))
+		return
+	}
+
+	inDir := filepath.Join(s.cfg.DataInRoot, corpusID)
+	if err := util.EnsureDir(inDir); err != nil {
+		writeErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	type uploadResult struct {
+		Filename string `json:"filename"`
+		PaperID  string `json:"paper_id"`
+	}
+	out := make([]uploadResult, 0, len(files))
+
+	for _, fh := range files {
+		if !strings.HasSuffix(strings.ToLower(fh.Filename), ".pdf") {
+			continue
+		}
+		paperID, savedPath, err := saveUploadedFile(inDir, fh)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		if err := s.paperRepo.UpsertPaper(r.Context(), models.Paper{
+			PaperID:  paperID,
+			CorpusID: corpusID,
+			Filename: filepath.Base(savedPath),
+			Status:   "pending",
+		}); err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		out = append(out, uploadResult{Filename: filepath.Base(savedPath), PaperID: paperID})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"uploaded": out})
+}
+
+type askRequest struct {
+	CorpusID      string   `json:"corpus_id"`
+	CorpusIDs     []string `json:"corpus_ids,omitempty"`
+	CorpusGroup   string   `json:"corpus_group,omitempty"`
+	Question      string   `json:"question"`
+	TopK          int      `json:"top_k"`
+	EmbedProvider string   `json:"embed_provider,omitempty"`
+	EmbedVersion  string   `json:"embed_version,omitempty"`
+	QueryRewrite  string   `json:"query_rewrite,omitempty"`
+	RewriteCount  int      `json:"rewrite_count,omitempty"`
+	ContextWindow int      `json:"context_window,omitempty"`
+	ContextBudget int      `json:"context_token_budget,omitempty"`
+	SessionID     string   `json:"session_id,omitempty"`
+	NewSession    bool     `json:"new_session,omitempty"`
+	Verify        string   `json:"verify,omitempty"`
+
+	// Set by replays only: the logged condensed query, and the embedding model the replay
+	// must use without failing over.
+	standaloneQuery  string
+	pinnedEmbedModel string
+}
+
+// askRetrieval is everything /ask needs before generation: the resolved request, the
+// rewritten queries and the numbered citations with their LLM context lines.
+type askRetrieval struct {
+	req             askRequest
+	sessionID       string
+	newSession      bool
+	standaloneQuery string
+	promptQuestion  string
+	corpusIDs       []string
+	rewriteMode     string
+	verifyMode      string
+	queries         []retrieval.RewrittenQuery
+	embedInfo       providers.ProviderInfo
+	citations       []askCitation
+	contextSnippets []string
+}
+
+func (a *askRetrieval) meta() map[string]any {
+	return map[string]any{
+		"citations":            a.citations,
+		"corpus_ids":           a.corpusIDs,
+		"embed_provider":       a.embedInfo.Name,
+		"embed_model":          a.embedInfo.Model,
+		"embed_version":        a.req.EmbedVersion,
+		"retrieved_count":      len(a.citations),
+		"query_rewrite":        a.rewriteMode,
+		"rewritten_queries":    a.queries[1:],
+		"context_window":       a.req.ContextWindow,
+		"context_token_budget": a.req.ContextBudget,
+		"session_id":           a.sessionID,
+		"standalone_query":     a.standaloneQuery,
+		"verify":               a.verifyMode,
+		"prompt_version":       askPromptVersion,
+	}
+}
+
+func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+	var req askRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+		return
+	}
+	resp, _, status, err := s.runAsk(r.Context(), req, "")
+	if err != nil {
+		writeErr(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// runAsk answers one question end to end and logs it. replayOf links a replay to the
+// answer it reproduces. The int is the HTTP status for errors.
+func (s *Server) runAsk(ctx context.Context, req askRequest, replayOf string) (map[string]any, *askRetrieval, int, error) {
+	ar, status, err := s.retrieveForAsk(ctx, req)
+	if err != nil {
+		return nil, nil, status, err
+	}
+	citations := ar.citations
+
+	llmResp, llmInfo, llmErr := s.generateWithFailover(ctx, "rag_answer", buildAskPrompt(ar.promptQuestion), ar.contextSnippets)
+	if llmErr != nil {
+		return nil, nil, http.StatusBadGateway, fmt.Errorf("generation failed: %w", llmErr)
+	}
+
+	summaryStats, _ := s.summarizeCitations(ctx, ar.standaloneQuery, citations, ar.contextSnippets, nil)
+
+	answer := retrieval.StripConfidenceSection(llmResp.Text)
+	if answer == "" {
+		answer = fallbackExtractiveAnswer(citations)
+	}
+	resp := ar.meta()
+	resp["answer"] = answer
+	resp["llm_provider"] = llmInfo.Name
+	resp["llm_model"] = llmInfo.Model
+	resp["citation_summaries"] = summaryStats
+	v := s.verifyAnswer(ctx, ar, answer)
+	if v != nil {
+		resp["verification"] = v
+		resp["grounding_score"] = v.GroundingScore
+	}
+	if ar.sessionID != "" {
+		position, err := s.recordAskTurn(ctx, ar, answer, llmInfo)
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, err
+		}
+		resp["turn_position"] = position
+	}
+	// The answer stands even when the ask log is unavailable; it just cannot be replayed.
+	if askID, err := s.logAsk(ctx, ar, answer, llmInfo, v, replayOf); err != nil {
+		log.Printf("ask log insert failed: %v", err)
+	} else {
+		resp["ask_id"] = askID
+	}
+	return resp, ar, http.StatusOK, nil
+}
+
+// handleAskStream is the Server-Sent Events variant of /ask. It emits a retrieval event as
+// soon as citations are known, answer_delta events while the answer is generated, then one
+// verification event with per-sentence support flags, one citation_summary event per
+// citation as each summary completes, and finally done.
+func (s *Server) handleAskStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErr(w, http.StatusInternalServerError, fmt.Errorf("streaming unsupported"))
+		return
+	}
+	var req askRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+		return
+	}
+	ar, status, err := s.retrieveForAsk(r.Context(), req)
+	if err != nil {
+		writeErr(w, status, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	send := func(event string, payload any) error {
+		b, _ := json.Marshal(payload)
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	if err := send("retrieval", ar.meta()); err != nil {
+		return
+	}
+
+	var (
+		answerText string
+		llmInfo    providers.ProviderInfo
+		llmErr     error
+	)
+	for _, idx := range s.providers.PreferredLLMOrder() {
+		p, _ := s.providers.LLMProviderByIndex(idx)
+		emitted := false
+		resp, info, err := p.GenerateStream(r.Context(), providers.GenerateRequest{
+			Operation: "rag_answer",
+			Prompt:    buildAskPrompt(ar.promptQuestion),
+			Context:   ar.contextSnippets,
+		}, func(delta string) error {
+			emitted = true
+			return send("answer_delta", map[string]any{"text": delta})
+		})
+		if r.Context().Err() != nil {
+			return
+		}
+		if err == nil && strings.TrimSpace(resp.Text) != "" {
+			answerText, llmInfo, llmErr = resp.Text, info, nil
+			break
+		}
+		llmErr = err
+		if emitted {
+			// Partial output from a failed provider is discarded by the client.
+			_ = send("answer_reset", map[string]any{"llm_provider": info.Name})
+		}
+	}
+	answer := retrieval.StripConfidenceSection(answerText)
+	if answer == "" {
+		answer = fallbackExtractiveAnswer(ar.citations)
+		if llmErr != nil {
+			_ = send("warning", map[string]any{"message": "generation failed; returning extractive answer", "error": llmErr.Error()})
+		}
+		_ = send("answer_delta", map[string]any{"text": answer})
+	}
+	if err := send("answer_done", map[string]any{"answer": answer, "llm_provider": llmInfo.Name, "llm_model": llmInfo.Model}); err != nil {
+		return
+	}
+	v := s.verifyAnswer(r.Context(), ar, answer)
+	if v != nil {
+		if err := send("verification", v); err != nil {
+			return
+		}
+	}
+
+	summaryStats, err := s.summarizeCitations(r.Context(), ar.standaloneQuery, ar.citations, ar.contextSnippets, func(i int) error {
+		return send("citation_summary", map[string]any{"ref_id": ar.citations[i].RefID, "summary": ar.citations[i].Summary})
+	})
+	if err != nil {
+		return
+	}
+	done := map[string]any{"retrieved_count": len(ar.citations), "citation_summaries": summaryStats}
+	if ar.sessionID != "" {
+		position, err := s.recordAskTurn(r.Context(), ar, answer, llmInfo)
+		if err != nil {
+			_ = send("error", map[string]any{"error": err.Error()})
+			return
+		}
+		done["session_id"] = ar.sessionID
+		done["turn_position"] = position
+	}
+	if askID, err := s.logAsk(r.Context(), ar, answer, llmInfo, v, ""); err != nil {
+		log.Printf("ask log insert failed: %v", err)
+	} else {
+		done["ask_id"] = askID
+	}
+	_ = send("done", done)
+}
+
+// retrieveForAsk validates an ask request, rewrites and embeds the question, searches every
+// corpus in scope and builds numbered citations. The int is the HTTP status for errors.
+func (s *Server) retrieveForAsk(ctx context.Context, req askRequest) (*askRetrieval, int, error) {
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("question is required")
+	}
+	var history []retrieval.ConversationTurn
+	sessionCorpusID := ""
+	req.SessionID = strings.TrimSpace(req.SessionID)
+	if req.SessionID != "" {
+		if _, err := uuid.Parse(req.SessionID); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid session_id: %s", req.SessionID)
+		}
+		sess, err := s.sessions.GetSession(ctx, req.SessionID)
+		if err != nil {
+			return nil, http.StatusNotFound, err
+		}
+		if strings.TrimSpace(req.CorpusID) == "" && len(req.CorpusIDs) == 0 && strings.TrimSpace(req.CorpusGroup) == "" {
+			req.CorpusID = sess.CorpusID
+		}
+		sessionCorpusID = sess.CorpusID
+		for _, t := range sess.Turns {
+			history = append(history, retrieval.ConversationTurn{Question: t.Question, Answer: t.Answer})
+		}
+	}
+	corpusIDs, err := s.resolveCorpusScope(ctx, req.CorpusID, req.CorpusIDs, req.CorpusGroup, req.EmbedVersion)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if sessionCorpusID != "" && !slices.Contains(corpusIDs, sessionCorpusID) {
+		return nil, http.StatusBadRequest, fmt.Errorf("session %s belongs to corpus %s", req.SessionID, sessionCorpusID)
+	}
+	if req.TopK <= 0 {
+		req.TopK = 8
+	}
+	if strings.TrimSpace(req.EmbedVersion) == "" {
+		req.EmbedVersion = s.cfg.EmbedVersion
+	}
+	rewriteMode, err := retrieval.NormalizeRewriteMode(req.QueryRewrite)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	verifyMode, err := retrieval.NormalizeVerifyMode(req.Verify)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if req.ContextWindow < 0 || req.ContextWindow > 3 {
+		return nil, http.StatusBadRequest, fmt.Errorf("context_window must be between 0 and 3")
+	}
+	if req.ContextBudget <= 0 && req.ContextWindow > 0 {
+		req.ContextBudget = s.cfg.ContextTokenBudget
+	}
+	if strings.TrimSpace(req.EmbedProvider) != "" && s.providers.FindEmbedProviderIndex(req.EmbedProvider) < 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("unknown embed_provider: %s", req.EmbedProvider)
+	}
+
+	// Follow-ups are condensed into a standalone query so retrieval sees the topic the
+	// conversation is about rather than a bare "what about its limitations?".
+	standalone := req.Question
+	if req.standaloneQuery != "" {
+		standalone = req.standaloneQuery
+	} else if len(history) > 0 {
+		condensed, _, _ := s.generateWithFailover(ctx, "query_condense", retrieval.BuildCondensePrompt(history, req.Question), nil)
+		standalone = retrieval.ParseCondensedQuery(condensed.Text, history, req.Question)
+	}
+	queries := []retrieval.RewrittenQuery{{Kind: retrieval.QueryKindOriginal, Text: standalone}}
+	if retrieval.UsesMultiQuery(rewriteMode) {
+		rwResp, _, rwErr := s.generateWithFailover(ctx, "query_rewrite", retrieval.BuildMultiQueryPrompt(standalone, req.RewriteCount), nil)
+		if rwErr == nil {
+			for _, q := range retrieval.ParseRewrittenQueries(rwResp.Text, standalone, req.RewriteCount) {
+				queries = append(queries, retrieval.RewrittenQuery{Kind: retrieval.QueryKindParaphrase, Text: q})
+			}
+		}
+	}
+	if retrieval.UsesHyDE(rewriteMode) {
+		hydeResp, _, hydeErr := s.generateWithFailover(ctx, "query_hyde", retrieval.BuildHyDEPrompt(standalone), nil)
+		if passage := retrieval.CleanHyDEPassage(hydeResp.Text); hydeErr == nil && passage != "" {
+			queries = append(queries, retrieval.RewrittenQuery{Kind: retrieval.QueryKindHyDE, Text: passage})
+		}
+	}
+	queryTexts := make([]string, 0, len(queries))
+	for _, q := range queries {
+		queryTexts = append(queryTexts, q.Text)
+	}
+
+	var (
+		queryVectors [][]float32
+		info         providers.ProviderInfo
+	)
+	if req.pinnedEmbedModel != "" {
+		queryVectors, info, err = s.embedQueriesWith(ctx, "ask_query_embed", s.providers.FindEmbedProviderIndex(req.EmbedProvider), queryTexts)
+		if err == nil && info.Model != req.pinnedEmbedModel {
+			return nil, http.StatusConflict, fmt.Errorf("embed provider %s now uses model %s, not the logged %s", info.Name, info.Model, req.pinnedEmbedModel)
+		}
+	} else {
+		queryVectors, info, err = s.embedQueries(ctx, "ask_query_embed", req.EmbedProvider, queryTexts)
+	}
+	if err != nil {
+		return nil, http.StatusBadGateway, err
+	}
+	resultLists := make([][]models.ChunkResult, 0, len(queryVectors))
+	for _, vec := range queryVectors {
+		hits, err := s.searcher.SearchChunks(ctx, corpusIDs, vec, req.TopK, vector.SearchFilters{
+			EmbeddingVersion: req.EmbedVersion,
+		})
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		resultLists = append(resultLists, hits)
+	}
+	results := resultLists[0]
+	if len(resultLists) > 1 {
+		results = retrieval.FuseRanked(resultLists, func(c models.ChunkResult) string { return c.ChunkID }, req.TopK)
+	}
+	var expanded map[string]retrieval.ExpandedHit
+	contextCap := 1200
+	if req.ContextBudget > 0 {
+		results, expanded, err = s.expandAskContext(ctx, corpusIDs, results, req.ContextWindow, req.ContextBudget)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		contextCap = 4 * req.ContextBudget
+	}
+	// A new session is only stored with its first answered turn; see recordAskTurn.
+	newSession := req.NewSession && req.SessionID == ""
+	if newSession {
+		req.SessionID = uuid.NewString()
+	}
+	promptQuestion := req.Question
+	if standalone != req.Question {
+		promptQuestion = req.Question + "\n(Standalone question: " + standalone + ")"
+	}
+	ar := &askRetrieval{
+		req:             req,
+		sessionID:       req.SessionID,
+		newSession:      newSession,
+		standaloneQuery: standalone,
+		promptQuestion:  promptQuestion,
+		corpusIDs:       corpusIDs,
+		rewriteMode:     rewriteMode,
+		verifyMode:      verifyMode,
+		queries:         queries,
+		embedInfo:       info,
+		citations:       make([]askCitation, 0, len(results)),
+		contextSnippets: make([]string, 0, len(results)),
+	}
+	for i, r := range results {
+		refID := fmt.Sprintf("C%d", i+1)
+		displayTitle := util.DisplaySnippet(r.Title, 100)
+		if displayTitle == "" {
+			displayTitle = util.DisplaySnippet(r.Filename, 100)
+		}
+		snippet := util.DisplayEvidenceSnippet(r.ChunkText, standalone, 420)
+		if snippet == "" {
+			snippet = util.DisplaySnippet(r.Snippet, 420)
+		}
+		contextText := util.DisplaySnippet(r.ChunkText, contextCap)
+		var contextChunkIDs []string
+		if exp, ok := expanded[r.ChunkID]; ok {
+			contextText = util.DisplaySnippet(exp.Text, contextCap)
+			contextChunkIDs = exp.ChunkIDs
+		}
+		ar.citations = append(ar.citations, askCitation{
+			RefID:           refID,
+			CorpusID:        r.CorpusID,
+			PaperID:         r.PaperID,
+			Title:           displayTitle,
+			Filename:        r.Filename,
+			PaperURL:        fmt.Sprintf("/corpora/%s/papers/%s/file", r.CorpusID, r.PaperID),
+			ChunkID:         r.ChunkID,
+			Snippet:         snippet,
+			Score:           r.Score,
+			ContextChunkIDs: contextChunkIDs,
+		})
+		ar.contextSnippets = append(ar.contextSnippets, fmt.Sprintf("%s | %s [%s]: %s", refID, displayTitle, r.ChunkID, contextText))
+	}
+	return ar, 0, nil
+}
+
+// askPromptVersion identifies the answer prompt in the ask log; bump it when buildAskPrompt
+// changes so replays can tell prompt drift apart from retrieval drift.
+const askPromptVersion = "ask-v2"
+
+// logAsk persists an answered question with its normalized request, embedding space,
+// citations and answer, and returns the new ask ID.
+func (s *Server) logAsk(ctx context.Context, ar *askRetrieval, answer string, llmInfo providers.ProviderInfo, v *retrieval.AnswerVerification, replayOf string) (string, error) {
+	filters := ar.req
+	filters.Question = ""
+	filters.SessionID = ""
+	filters.NewSession = false
+	rec := models.AskRecord{
+		AskID:           uuid.NewString(),
+		CorpusID:        ar.corpusIDs[0],
+		CorpusIDs:       ar.corpusIDs,
+		SessionID:       ar.sessionID,
+		ReplayOf:        replayOf,
+		Question:        ar.req.Question,
+		StandaloneQuery: ar.standaloneQuery,
+		EmbedProvider:   ar.embedInfo.Name,
+		EmbedModel:      ar.embedInfo.Model,
+		EmbedVersion:    ar.req.EmbedVersion,
+		PromptVersion:   askPromptVersion,
+		LLMProvider:     llmInfo.Name,
+		LLMModel:        llmInfo.Model,
+		Answer:          answer,
+	}
+	if v != nil {
+		score := v.GroundingScore
+		rec.GroundingScore = &score
+	}
+	if err := s.askLog.Insert(ctx, rec, filters, ar.queries[1:], ar.citations); err != nil {
+		return "", err
+	}
+	return rec.AskID, nil
+}
+
+// recordAskTurn appends the answered turn to its session, creating the session with it when
+// the request asked for a new one.
+func (s *Server) recordAskTurn(ctx context.Context, ar *askRetrieval, answer string, llmInfo providers.ProviderInfo) (int, error) {
+	turn := models.AskTurn{
+		TurnID:          uuid.NewString(),
+		Question:        ar.req.Question,
+		StandaloneQuery: ar.standaloneQuery,
+		Answer:          answer,
+		LLMProvider:     llmInfo.Name,
+		LLMModel:        llmInfo.Model,
+	}
+	if ar.newSession {
+		return s.sessions.StartSession(ctx, ar.sessionID, ar.corpusIDs[0], util.DisplaySnippet(ar.req.Question, 80), turn, ar.citations)
+	}
+	return s.sessions.AppendTurn(ctx, ar.sessionID, turn, ar.citations)
+}
+
+// verifyAnswer checks the answer's [C#] citations against the evidence snippets. In llm mode a
+// single entailment call scores every cited sentence; unusable output falls back to lexical
+// overlap. It returns nil when verification is disabled.
+func (s *Server) verifyAnswer(ctx context.Context, ar *askRetrieval, answer string) *retrieval.AnswerVerification {
+	if ar.verifyMode == retrieval.VerifyNone {
+		return nil
+	}
+	claims := retrieval.ExtractClaims(answer)
+	method := retrieval.VerifyLexical
+	var judged map[int]float64
+	if ar.verifyMode == retrieval.VerifyLLM && len(claims) > 0 {
+		resp, _, err := s.generateWithFailover(ctx, "citation_verify", retrieval.BuildEntailmentPrompt(claims), ar.contextSnippets)
+		if err == nil {
+			if judged = retrieval.ParseEntailmentJudgments(resp.Text); judged != nil {
+				method = retrieval.VerifyLLM
+			}
+		}
+	}
+	v := retrieval.VerifyClaims(claims, ar.contextSnippets, retrieval.LexicalSupport, judged, retrieval.DefaultSupportThreshold, method)
+	return &v
+}
+
+// generateWithFailover tries LLM providers in preferred order until one returns text.
+func (s *Server) generateWithFailover(ctx context.Context, op, prompt string, ctxSnippets []string) (providers.GenerateResponse, providers.ProviderInfo, error) {
+	resp, info, _, err := s.generateCounted(ctx, op, prompt, ctxSnippets)
+	return resp, info, err
+}
+
+// generateCounted is generateWithFailover that also reports how many providers it called.
+func (s *Server) generateCounted(ctx context.Context, op, prompt string, ctxSnippets []string) (providers.GenerateResponse, providers.ProviderInfo, int, error) {
+	var (
+		resp     providers.GenerateResponse
+		info     providers.ProviderInfo
+		err      error
+		attempts int
+	)
+	for _, idx := range s.providers.PreferredLLMOrder() {
+		p, _ := s.providers.LLMProviderByIndex(idx)
+		attempts++
+		resp, info, err = p.Generate(ctx, providers.GenerateRequest{
+			Operation: op,
+			Prompt:    prompt,
+			Context:   ctxSnippets,
+		})
+		if err == nil && strings.TrimSpace(resp.Text) != "" {
+			return resp, info, attempts, nil
+		}
+	}
+	return resp, info, attempts, err
+}
+
+// citationSummaryConcurrency bounds per-citation summary calls when the batch call fails.
+const citationSummaryConcurrency = 4
+
+// citationSummaryStats reports how citation summaries were produced. Mode is batch when one
+// structured call covered every citation, batch_partial when some IDs were missing from it
+// (those use the extractive snippet), and per_citation when the batch call was unusable.
+// LLMCalls counts every provider attempt; Failovers counts the attempts that moved on to the
+// next provider, and Extractive the summaries that fell back to the snippet.
+type citationSummaryStats struct {
+	Mode       string `json:"mode"`
+	LLMCalls   int    `json:"llm_calls"`
+	Failovers  int    `json:"failovers"`
+	Extractive int    `json:"extractive"`
+	LatencyMS  int64  `json:"latency_ms"`
+}
+
+func (st *citationSummaryStats) addAttempts(attempts int) {
+	st.LLMCalls += attempts
+	if attempts > 1 {
+		st.Failovers += attempts - 1
+	}
+}
+
+// summarizeCitations fills every citation's Summary, preferring a single batch call keyed by
+// citation ID. onSummary, when set, is called once per citation as its summary is ready; an
+// error from it stops the remaining work.
+func (s *Server) summarizeCitations(ctx context.Context, question string, citations []askCitation, citationContexts []string, onSummary func(i int) error) (citationSummaryStats, error) {
+	start := time.Now()
+	stats := citationSummaryStats{Mode: "batch"}
+	if len(citations) == 0 {
+		return stats, nil
+	}
+	emit := func(i int) error {
+		if onSummary == nil {
+			return nil
+		}
+		return onSummary(i)
+	}
+	refIDs := make([]string, 0, len(citations))
+	for _, c := range citations {
+		refIDs = append(refIDs, c.RefID)
+	}
+	var parsed map[string]string
+	resp, _, attempts, err := s.generateCounted(ctx, "citation_summary_batch", retrieval.BuildCitationSummaryPrompt(question, refIDs), citationContexts)
+	stats.addAttempts(attempts)
+	if err == nil {
+		parsed = retrieval.ParseCitationSummaries(resp.Text, refIDs)
+	}
+	if parsed != nil {
+		for i := range citations {
+			if summary, ok := parsed[citations[i].RefID]; ok {
+				citations[i].Summary = util.DisplaySnippet(summary, 260)
+			} else {
+				citations[i].Summary = util.DisplayEvidenceSnippet(citations[i].Snippet, question, 240)
+				stats.Mode = "batch_partial"
+				stats.Extractive++
+			}
+			if err := emit(i); err != nil {
+				stats.LatencyMS = time.Since(start).Milliseconds()
+				return stats, err
+			}
+		}
+		stats.LatencyMS = time.Since(start).Milliseconds()
+		return stats, nil
+	}
+
+	stats.Mode = "per_citation"
+	type summaryResult struct {
+		index      int
+		summary    string
+		extractive bool
+		attempts   int
+	}
+	results := make(chan summaryResult, len(citations))
+	sem := make(chan struct{}, citationSummaryConcurrency)
+	for i := range citations {
+		go func(i int) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			summary, extractive, attempts := s.summarizeCitation(ctx, question, citations[i], citationContexts[i])
+			results <- summaryResult{index: i, summary: summary, extractive: extractive, attempts: attempts}
+		}(i)
+	}
+	for range citations {
+		res := <-results
+		stats.addAttempts(res.attempts)
+		citations[res.index].Summary = res.summary
+		if res.extractive {
+			stats.Extractive++
+		}
+		if err := emit(res.index); err != nil {
+			stats.LatencyMS = time.Since(start).Milliseconds()
+			return stats, err
+		}
+	}
+	stats.LatencyMS = time.Since(start).Milliseconds()
+	return stats, nil
+}
+
+// summarizeCitation summarizes one citation; the bool reports an extractive fallback and the
+// int the number of provider attempts.
+func (s *Server) summarizeCitation(ctx context.Context, question string, c askCitation, citationContext string) (string, bool, int) {
+	summaryPrompt := "Question: " + question + "\n\n" +
+		"Write exactly two short sentences:\n" +
+		"1) what this citation supports for the question\n" +
+		"2) one caveat or limitation.\n" +
+		"Use plain language and do not include citation ids."
+	sumResp, _, attempts, sumErr := s.generateCounted(ctx, "citation_summary", summaryPrompt, []string{citationContext})
+	if sumErr != nil || strings.TrimSpace(sumResp.Text) == "" {
+		return util.DisplayEvidenceSnippet(c.Snippet, question, 240), true, attempts
+	}
+	return util.DisplaySnippet(sumResp.Text, 260), false, attempts
+}
+
+func buildAskPrompt(question string) string {
+	return "" +
+		"Question: " + question + "\n\n" +
+
+		"You must answer using ONLY the provided evidence snippets.\n" +
+		"Do NOT use outside knowledge.\n" +
+		"If the snippets do not contain enough information, explicitly state what is missing.\n\n" +
 
 		"Citation rules:\n" +
 		"- Use citations like [C1], [C2], etc. whenever making a factual claim.\n" +
@@ -476,45 +1740,653 @@ func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
 		"Return markdown with this structure:\n" +
 		"## Direct Answer\n" +
 		"(Write a clear explanation. Bullets are optional.)\n\n" +
-		"## Confidence\n" +
-		"(State High/Medium/Low and briefly explain why, including evidence gaps.)\n\n" +
 
 		"Evidence snippets (cite as [C#]):\n"
-	llmResp, llmInfo, llmErr = generate("rag_answer", prompt, contextSnippets)
-	if llmErr != nil {
-		writeErr(w, http.StatusBadGateway, fmt.Errorf("generation failed: %w", llmErr))
+}
+
+// expandAskContext grows ask hits with neighboring chunks and applies the token budget.
+// Hits that no longer fit are dropped; the map is keyed by the original hit chunk ID.
+func (s *Server) expandAskContext(ctx context.Context, corpusIDs []string, hits []models.ChunkResult, window, budget int) ([]models.ChunkResult, map[string]retrieval.ExpandedHit, error) {
+	refs := make([]retrieval.ChunkRef, 0, len(hits))
+	for _, h := range hits {
+		refs = append(refs, retrieval.ChunkRef{ChunkID: h.ChunkID, PaperID: h.PaperID, ChunkIndex: h.ChunkIndex, Section: h.Section, Text: h.ChunkText})
+	}
+	byID, err := s.chunkRepo.ExpandHitContext(ctx, corpusIDs, refs, window, budget)
+	if err != nil {
+		return nil, nil, err
+	}
+	kept := make([]models.ChunkResult, 0, len(byID))
+	for _, h := range hits {
+		if _, ok := byID[h.ChunkID]; ok {
+			kept = append(kept, h)
+		}
+	}
+	return kept, byID, nil
+}
+
+// embedQueries embeds texts in one call, trying the preferred provider first and failing
+// over through the remaining embedding providers.
+func (s *Server) embedQueries(ctx context.Context, op, preferred string, texts []string) ([][]float32, providers.ProviderInfo, error) {
+	embedOrders := s.providers.PreferredEmbedOrder()
+	if idx := s.providers.FindEmbedProviderIndex(preferred); idx >= 0 {
+		embedOrders = orderWithPreferredFirst(embedOrders, idx)
+	}
+	for _, idx := range embedOrders {
+		if vectors, info, err := s.embedQueriesWith(ctx, op, idx, texts); err == nil {
+			return vectors, info, nil
+		}
+	}
+	return nil, providers.ProviderInfo{}, fmt.Errorf("embedding providers unavailable")
+}
+
+// embedQueriesWith embeds texts with one provider, without failover.
+func (s *Server) embedQueriesWith(ctx context.Context, op string, idx int, texts []string) ([][]float32, providers.ProviderInfo, error) {
+	if idx < 0 {
+		return nil, providers.ProviderInfo{}, fmt.Errorf("embed provider not configured")
+	}
+	p, _ := s.providers.EmbedProviderByIndex(idx)
+	vectors, info, err := p.Embed(ctx, providers.EmbedRequest{
+		Operation: op,
+		Inputs:    texts,
+		Dimension: s.cfg.EmbedDim,
+	})
+	if err != nil {
+		return nil, info, err
+	}
+	if len(vectors) != len(texts) {
+		return nil, info, fmt.Errorf("embed provider %s returned %d vectors for %d queries", info.Name, len(vectors), len(texts))
+	}
+	return vectors, info, nil
+}
+
+// resolveCorpusScope resolves the corpora a retrieval request reads (see resolveCorpusIDs).
+// Scores are only comparable within one embedding space, so unless the caller pins
+// embedVersion, corpora whose chunks were embedded under different versions are rejected.
+func (s *Server) resolveCorpusScope(ctx context.Context, corpusID string, corpusIDs []string, group, embedVersion string) ([]string, error) {
+	scope, err := s.resolveCorpusIDs(ctx, corpusID, corpusIDs, group)
+	if err != nil || len(scope) < 2 || strings.TrimSpace(embedVersion) != "" {
+		return scope, err
+	}
+	versions, err := s.corpusRepo.EmbeddingVersions(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	var spaces []string
+	distinct := map[string]bool{}
+	for _, id := range scope {
+		for _, v := range versions[id] {
+			spaces = append(spaces, id+"="+v)
+			distinct[v] = true
+		}
+	}
+	if len(distinct) > 1 {
+		return nil, fmt.Errorf("corpora use different embedding versions (%s); pin embed_version to search them together", strings.Join(spaces, ", "))
+	}
+	return scope, nil
+}
+
+// resolveCorpusIDs turns corpus_id, corpus_ids and corpus_group into a de-duplicated list
+// of existing corpora. The explicit corpus_id, when present, stays first.
+func (s *Server) resolveCorpusIDs(ctx context.Context, corpusID string, corpusIDs []string, group string) ([]string, error) {
+	candidates := make([]string, 0, len(corpusIDs)+1)
+	candidates = append(candidates, corpusID)
+	candidates = append(candidates, corpusIDs...)
+	if group = strings.TrimSpace(group); group != "" {
+		g, err := s.corpusRepo.GetGroup(ctx, group)
+		if err != nil {
+			return nil, fmt.Errorf("unknown corpus_group: %s", group)
+		}
+		candidates = append(candidates, g.CorpusIDs...)
+	}
+	scope := make([]string, 0, len(candidates))
+	seen := map[string]bool{}
+	for _, id := range candidates {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, fmt.Errorf("invalid corpus id: %s", id)
+		}
+		seen[id] = true
+		scope = append(scope, id)
+	}
+	if len(scope) == 0 {
+		return nil, fmt.Errorf("corpus_id, corpus_ids or corpus_group is required")
+	}
+	existing, err := s.corpusRepo.ExistingCorpusIDs(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) != len(scope) {
+		found := make(map[string]bool, len(existing))
+		for _, id := range existing {
+			found[id] = true
+		}
+		for _, id := range scope {
+			if !found[id] {
+				return nil, fmt.Errorf("unknown corpus: %s", id)
+			}
+		}
+	}
+	return scope, nil
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+	var req struct {
+		CorpusID      string   `json:"corpus_id"`
+		CorpusIDs     []string `json:"corpus_ids,omitempty"`
+		CorpusGroup   string   `json:"corpus_group,omitempty"`
+		Query         string   `json:"query"`
+		TopK          int      `json:"top_k"`
+		PaperIDs      []string `json:"paper_ids,omitempty"`
+		EmbedProvider string   `json:"embed_provider,omitempty"`
+		EmbedVersion  string   `json:"embed_version,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+		return
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("query is required"))
+		return
+	}
+	corpusIDs, err := s.resolveCorpusScope(r.Context(), req.CorpusID, req.CorpusIDs, req.CorpusGroup, req.EmbedVersion)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.TopK <= 0 {
+		req.TopK = 8
+	}
+	if strings.TrimSpace(req.EmbedVersion) == "" {
+		req.EmbedVersion = s.cfg.EmbedVersion
+	}
+	if strings.TrimSpace(req.EmbedProvider) != "" && s.providers.FindEmbedProviderIndex(req.EmbedProvider) < 0 {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("unknown embed_provider: %s", req.EmbedProvider))
+		return
+	}
+	vectors, info, err := s.embedQueries(r.Context(), "search_query_embed", req.EmbedProvider, []string{req.Query})
+	if err != nil {
+		writeErr(w, http.StatusBadGateway, err)
+		return
+	}
+	results, err := s.searcher.SearchChunks(r.Context(), corpusIDs, vectors[0], req.TopK, vector.SearchFilters{
+		PaperIDs:         req.PaperIDs,
+		EmbeddingVersion: req.EmbedVersion,
+	})
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err)
 		return
 	}
+	for i := range results {
+		results[i].ChunkText = ""
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"results":        results,
+		"corpus_ids":     corpusIDs,
+		"embed_provider": info.Name,
+		"embed_model":    info.Model,
+		"embed_version":  req.EmbedVersion,
+	})
+}
 
-	for i := range citations {
-		c := citations[i]
-		summaryPrompt := "Question: " + req.Question + "\n\n" +
-			"Write exactly two short sentences:\n" +
-			"1) what this citation supports for the question\n" +
-			"2) one caveat or limitation.\n" +
-			"Use plain language and do not include citation ids."
-		sumResp, _, sumErr := generate("citation_summary", summaryPrompt, []string{citationContexts[i]})
-		if sumErr != nil || strings.TrimSpace(sumResp.Text) == "" {
-			citations[i].Summary = util.DisplayEvidenceSnippet(c.Snippet, req.Question, 240)
+// Cite-as-you-write looks up at most maxCiteSuggestSentences sentences per request and
+// suggests up to maxCiteSuggestTopK papers for each.
+const (
+	maxCiteSuggestSentences = 50
+	maxCiteSuggestTopK      = 10
+)
+
+type citeSuggestion struct {
+	Rank         int     `json:"rank"`
+	CorpusID     string  `json:"corpus_id"`
+	PaperID      string  `json:"paper_id"`
+	Title        string  `json:"title"`
+	Filename     string  `json:"filename"`
+	ChunkID      string  `json:"chunk_id"`
+	Section      string  `json:"section,omitempty"`
+	Snippet      string  `json:"snippet"`
+	VectorScore  float64 `json:"vector_score"`
+	LexicalScore float64 `json:"lexical_score"`
+	SupportScore float64 `json:"support_score"`
+	BibKey       string  `json:"bib_key"`
+}
+
+type citeSentence struct {
+	Index       int              `json:"index"`
+	Text        string           `json:"text"`
+	Suggestions []citeSuggestion `json:"suggestions"`
+	Cite        string           `json:"cite,omitempty"`
+}
+
+// handleCiteSuggest serves POST /corpora/{id}/cite-suggest. The pasted text is split into
+// sentences; each one long enough to cite is embedded (with provider failover) and looked up
+// by both vector and full-text search, and the fused rankings yield candidate papers with the
+// chunk that supports them. BibTeX keys match the ones survey exports assign. No LLM is called.
+func (s *Server) handleCiteSuggest(w http.ResponseWriter, r *http.Request, corpusID string) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+	var req struct {
+		Text          string   `json:"text"`
+		CorpusIDs     []string `json:"corpus_ids,omitempty"`
+		CorpusGroup   string   `json:"corpus_group,omitempty"`
+		TopK          int      `json:"top_k,omitempty"`
+		MinScore      float64  `json:"min_score,omitempty"`
+		PaperIDs      []string `json:"paper_ids,omitempty"`
+		EmbedProvider string   `json:"embed_provider,omitempty"`
+		EmbedVersion  string   `json:"embed_version,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+		return
+	}
+	sentences := retrieval.PassageSentences(req.Text)
+	if len(sentences) == 0 {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("text is required"))
+		return
+	}
+	if len(sentences) > maxCiteSuggestSentences {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("text is limited to %d sentences", maxCiteSuggestSentences))
+		return
+	}
+	corpusIDs, err := s.resolveCorpusScope(r.Context(), corpusID, req.CorpusIDs, req.CorpusGroup, req.EmbedVersion)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.TopK <= 0 {
+		req.TopK = 3
+	}
+	if req.TopK > maxCiteSuggestTopK {
+		req.TopK = maxCiteSuggestTopK
+	}
+	if strings.TrimSpace(req.EmbedVersion) == "" {
+		req.EmbedVersion = s.cfg.EmbedVersion
+	}
+	if strings.TrimSpace(req.EmbedProvider) != "" && s.providers.FindEmbedProviderIndex(req.EmbedProvider) < 0 {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("unknown embed_provider: %s", req.EmbedProvider))
+		return
+	}
+
+	var queryIdx []int
+	var queries []string
+	for i, sentence := range sentences {
+		if retrieval.Suggestible(sentence) {
+			queryIdx = append(queryIdx, i)
+			queries = append(queries, sentence)
+		}
+	}
+	var info providers.ProviderInfo
+	var vectors [][]float32
+	if len(queries) > 0 {
+		if vectors, info, err = s.embedQueries(r.Context(), "cite_suggest_embed", req.EmbedProvider, queries); err != nil {
+			writeErr(w, http.StatusBadGateway, err)
+			return
+		}
+	}
+
+	filters := vector.SearchFilters{PaperIDs: req.PaperIDs, EmbeddingVersion: req.EmbedVersion}
+	pool := max(req.TopK*4, 12)
+	out := make([]citeSentence, len(sentences))
+	for i, sentence := range sentences {
+		out[i] = citeSentence{Index: i, Text: sentence, Suggestions: []citeSuggestion{}}
+	}
+	chunks := map[string]models.ChunkResult{}
+	var refs []survey.Reference
+	refKeys := map[string]string{}
+	for q, i := range queryIdx {
+		vecHits, err := s.searcher.SearchChunks(r.Context(), corpusIDs, vectors[q], pool, filters)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		textHits, err := s.searcher.SearchChunksText(r.Context(), corpusIDs, queries[q], vectors[q], pool, filters)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		toHits := func(results []models.ChunkResult) []retrieval.SuggestionHit {
+			hits := make([]retrieval.SuggestionHit, 0, len(results))
+			for _, c := range results {
+				chunks[c.ChunkID] = c
+				hits = append(hits, retrieval.SuggestionHit{PaperID: c.PaperID, ChunkID: c.ChunkID, Text: c.ChunkText, VectorScore: c.Score})
+			}
+			return hits
+		}
+		for rank, sug := range retrieval.SuggestCitations(queries[q], toHits(vecHits), toHits(textHits), req.TopK, req.MinScore) {
+			c := chunks[sug.ChunkID]
+			if _, ok := refKeys[c.PaperID]; !ok {
+				refKeys[c.PaperID] = fmt.Sprintf("ref%d", len(refs)+1)
+				refs = append(refs, survey.Reference{Key: refKeys[c.PaperID], CorpusID: c.CorpusID, PaperID: c.PaperID, Title: c.Title, Filename: c.Filename})
+			}
+			out[i].Suggestions = append(out[i].Suggestions, citeSuggestion{
+				Rank:         rank + 1,
+				CorpusID:     c.CorpusID,
+				PaperID:      c.PaperID,
+				Title:        c.Title,
+				Filename:     c.Filename,
+				ChunkID:      c.ChunkID,
+				Section:      c.Section,
+				Snippet:      c.Snippet,
+				VectorScore:  sug.VectorScore,
+				LexicalScore: sug.LexicalScore,
+				SupportScore: sug.SupportScore,
+			})
+		}
+	}
+
+	if len(refs) > 0 {
+		paperIDs := make([]string, len(refs))
+		for i, ref := range refs {
+			paperIDs[i] = ref.PaperID
+		}
+		papers, err := s.paperRepo.ListPapersByIDs(r.Context(), corpusIDs, paperIDs)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		byID := make(map[string]models.Paper, len(papers))
+		for _, p := range papers {
+			byID[p.PaperID] = p
+		}
+		for i := range refs {
+			p, ok := byID[refs[i].PaperID]
+			if !ok {
+				continue
+			}
+			if strings.TrimSpace(p.Title) != "" {
+				refs[i].Title = p.Title
+			}
+			refs[i].Authors, refs[i].Venue, refs[i].DOI = p.Authors, p.Venue, p.DOI
+			if p.Year != nil {
+				refs[i].Year = *p.Year
+			}
+		}
+	}
+	bibKeys := survey.AssignBibKeys(refs)
+	for i := range out {
+		keys := make([]string, 0, len(out[i].Suggestions))
+		for j := range out[i].Suggestions {
+			key := bibKeys[refKeys[out[i].Suggestions[j].PaperID]]
+			out[i].Suggestions[j].BibKey = key
+			keys = append(keys, key)
+		}
+		if len(keys) > 0 {
+			out[i].Cite = `\cite{` + strings.Join(keys, ",") + `}`
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sentences":      out,
+		"bibtex":         survey.RenderBibTeX(refs),
+		"corpus_ids":     corpusIDs,
+		"embed_provider": info.Name,
+		"embed_model":    info.Model,
+		"embed_version":  req.EmbedVersion,
+	})
+}
+
+// Comparison tables take two to five papers; each paper × dimension cell draws on at most
+// maxCompareTopK retrieved chunks.
+const (
+	minComparePapers     = 2
+	maxComparePapers     = 5
+	maxCompareDimensions = 10
+	maxCompareTopK       = 8
+)
+
+// handleCompare serves POST /corpora/{id}/compare. Evidence for every paper and dimension is
+// retrieved with a single-paper filter, then one structured call per paper fills its cells;
+// cells the model leaves out fall back to the best evidence snippet. ?format= (or the format
+// field) selects markdown, csv or latex output instead of JSON.
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request, corpusID string) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+	var req struct {
+		PaperIDs     []string `json:"paper_ids"`
+		Dimensions   []string `json:"dimensions,omitempty"`
+		TopK         int      `json:"top_k,omitempty"`
+		EmbedVersion string   `json:"embed_version,omitempty"`
+		Format       string   `json:"format,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+		return
+	}
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	if format == "" {
+		format = strings.ToLower(strings.TrimSpace(req.Format))
+	}
+	switch format {
+	case "":
+		format = "json"
+	case "json", "markdown", "csv", "latex":
+	default:
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("format must be json, markdown, csv or latex"))
+		return
+	}
+	paperIDs := make([]string, 0, len(req.PaperIDs))
+	seen := map[string]bool{}
+	for _, id := range req.PaperIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		paperIDs = append(paperIDs, id)
+	}
+	if len(paperIDs) < minComparePapers || len(paperIDs) > maxComparePapers {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("paper_ids must name %d to %d distinct papers", minComparePapers, maxComparePapers))
+		return
+	}
+	dimensions := make([]string, 0, len(req.Dimensions))
+	seen = map[string]bool{}
+	for _, d := range req.Dimensions {
+		d = strings.TrimSpace(d)
+		if d == "" || seen[strings.ToLower(d)] {
 			continue
 		}
-		citations[i].Summary = util.DisplaySnippet(sumResp.Text, 260)
+		seen[strings.ToLower(d)] = true
+		dimensions = append(dimensions, d)
+	}
+	if len(dimensions) == 0 {
+		dimensions = append(dimensions, retrieval.DefaultCompareDimensions...)
+	}
+	if len(dimensions) > maxCompareDimensions {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("at most %d dimensions are supported", maxCompareDimensions))
+		return
+	}
+	if req.TopK <= 0 {
+		req.TopK = 3
+	}
+	if req.TopK > maxCompareTopK {
+		req.TopK = maxCompareTopK
+	}
+	if strings.TrimSpace(req.EmbedVersion) == "" {
+		req.EmbedVersion = s.cfg.EmbedVersion
+	}
+
+	papers, err := s.paperRepo.ListPapersByIDs(r.Context(), []string{corpusID}, paperIDs)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err)
+		return
+	}
+	byID := make(map[string]models.Paper, len(papers))
+	for _, p := range papers {
+		byID[p.PaperID] = p
+	}
+	table := retrieval.ComparisonTable{
+		Papers:     make([]retrieval.ComparePaper, 0, len(paperIDs)),
+		Dimensions: dimensions,
+		Cells:      make([][]retrieval.CompareCell, len(dimensions)),
+		Evidence:   []retrieval.CompareEvidence{},
+	}
+	for _, id := range paperIDs {
+		p, ok := byID[id]
+		if !ok {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("unknown paper: %s", id))
+			return
+		}
+		title := util.DisplaySnippet(p.Title, 100)
+		if title == "" {
+			title = util.DisplaySnippet(p.Filename, 100)
+		}
+		table.Papers = append(table.Papers, retrieval.ComparePaper{PaperID: id, Title: title})
+	}
+
+	queries := make([]string, len(dimensions))
+	for i, d := range dimensions {
+		queries[i] = retrieval.CompareDimensionQuery(d)
+	}
+	vectors, embedInfo, err := s.embedQueries(r.Context(), "compare_query_embed", "", queries)
+	if err != nil {
+		writeErr(w, http.StatusBadGateway, err)
+		return
+	}
+
+	var llmInfo providers.ProviderInfo
+	llmCalls := 0
+	for _, paper := range table.Papers {
+		evidenceByChunk := map[string]int{}
+		dimEvidence := make([][]int, len(dimensions))
+		var refIDs, contexts []string
+		for d := range dimensions {
+			hits, err := s.searcher.SearchChunks(r.Context(), []string{corpusID}, vectors[d], req.TopK, vector.SearchFilters{
+				PaperIDs:         []string{paper.PaperID},
+				EmbeddingVersion: req.EmbedVersion,
+			})
+			if err != nil {
+				writeErr(w, http.StatusInternalServerError, err)
+				return
+			}
+			for _, h := range hits {
+				idx, ok := evidenceByChunk[h.ChunkID]
+				if !ok {
+					idx = len(table.Evidence)
+					evidenceByChunk[h.ChunkID] = idx
+					refID := fmt.Sprintf("C%d", idx+1)
+					snippet := util.DisplayEvidenceSnippet(h.ChunkText, queries[d], 420)
+					if snippet == "" {
+						snippet = util.DisplaySnippet(h.Snippet, 420)
+					}
+					table.Evidence = append(table.Evidence, retrieval.CompareEvidence{
+						RefID:     refID,
+						PaperID:   paper.PaperID,
+						Dimension: dimensions[d],
+						ChunkID:   h.ChunkID,
+						Snippet:   snippet,
+						Score:     h.Score,
+					})
+					refIDs = append(refIDs, refID)
+					contexts = append(contexts, fmt.Sprintf("%s | %s [%s]: %s", refID, paper.Title, h.ChunkID, util.DisplaySnippet(h.ChunkText, 1200)))
+				}
+				dimEvidence[d] = append(dimEvidence[d], idx)
+			}
+		}
+
+		var parsed map[string]retrieval.CompareCell
+		if len(contexts) > 0 {
+			resp, info, err := s.generateWithFailover(r.Context(), "paper_compare", retrieval.BuildComparePrompt(paper.Title, dimensions, refIDs), contexts)
+			llmCalls++
+			if err == nil {
+				llmInfo = info
+				parsed = retrieval.ParseCompareCells(resp.Text, dimensions, refIDs)
+			}
+		}
+		for d, dim := range dimensions {
+			cell, ok := parsed[dim]
+			switch {
+			case ok:
+			case len(dimEvidence[d]) > 0:
+				ev := table.Evidence[dimEvidence[d][0]]
+				cell = retrieval.CompareCell{Text: util.DisplaySnippet(ev.Snippet, 240), Citations: []string{ev.RefID}, Source: "extractive"}
+			default:
+				cell = retrieval.CompareCell{Text: "Not reported.", Citations: []string{}, Source: "none"}
+			}
+			cell.PaperID = paper.PaperID
+			cell.Dimension = dim
+			table.Cells[d] = append(table.Cells[d], cell)
+		}
+	}
+
+	switch format {
+	case "markdown":
+		writeCompareExport(w, "text/markdown; charset=utf-8", "comparison.md", retrieval.RenderComparisonMarkdown(table))
+	case "csv":
+		out, err := retrieval.RenderComparisonCSV(table)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeCompareExport(w, "text/csv; charset=utf-8", "comparison.csv", out)
+	case "latex":
+		writeCompareExport(w, "text/x-tex; charset=utf-8", "comparison.tex", retrieval.RenderComparisonLaTeX(table))
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"table":          table,
+			"llm_calls":      llmCalls,
+			"llm_provider":   llmInfo.Name,
+			"llm_model":      llmInfo.Model,
+			"embed_provider": embedInfo.Name,
+			"embed_model":    embedInfo.Model,
+			"embed_version":  req.EmbedVersion,
+		})
 	}
+}
 
-	answer := strings.TrimSpace(llmResp.Text)
-	if answer == "" {
-		answer = fallbackExtractiveAnswer(citations)
+func writeCompareExport(w http.ResponseWriter, contentType, filename, body string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(body))
+}
+
+func (s *Server) handleCorpusGroups(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		groups, err := s.corpusRepo.ListGroups(r.Context())
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"groups": groups})
+	case http.MethodPost:
+		var req struct {
+			Name      string   `json:"name"`
+			CorpusIDs []string `json:"corpus_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || len(req.CorpusIDs) == 0 {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("name and corpus_ids are required"))
+			return
+		}
+		corpusIDs, err := s.resolveCorpusIDs(r.Context(), "", req.CorpusIDs, "")
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		group := models.CorpusGroup{GroupID: uuid.NewString(), Name: req.Name, CorpusIDs: corpusIDs}
+		if err := s.corpusRepo.CreateGroup(r.Context(), group); err != nil {
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, group)
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 	}
-	writeJSON(w, http.StatusOK, map[string]any{
-		"answer":          answer,
-		"citations":       citations,
-		"embed_provider":  info.Name,
-		"embed_model":     info.Model,
-		"embed_version":   req.EmbedVersion,
-		"llm_provider":    llmInfo.Name,
-		"llm_model":       llmInfo.Model,
-		"retrieved_count": len(citations),
-	})
 }
 
 func fallbackExtractiveAnswer(citations []askCitation) string {
@@ -537,8 +2409,6 @@ func fallbackExtractiveAnswer(citations []askCitation) string {
 		}
 		lines = append(lines, fmt.Sprintf("- %s [%s]: %s [%s]", title, chunkID, snippet, citations[i].RefID))
 	}
-	lines = append(lines, "## Confidence")
-	lines = append(lines, "- Medium confidence based on retrieved chunks; verify with full paper text.")
 	return strings.Join(lines, "\n")
 }
 
@@ -548,24 +2418,106 @@ func (s *Server) handleSurvey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		CorpusID      string   `json:"corpus_id"`
-		Prompt        string   `json:"prompt"`
-		Topics        []string `json:"topics"`
-		Questions     []string `json:"questions"`
-		OutputFormat  string   `json:"output_format"`
-		RetrievalTopK int      `json:"retrieval_top_k"`
+		CorpusID          string   `json:"corpus_id"`
+		CorpusIDs         []string `json:"corpus_ids,omitempty"`
+		CorpusGroup       string   `json:"corpus_group,omitempty"`
+		Prompt            string   `json:"prompt"`
+		Topics            []string `json:"topics"`
+		Questions         []string `json:"questions"`
+		OutputFormat      string   `json:"output_format"`
+		TemplateID        string   `json:"template_id,omitempty"`
+		CitationStyle     string   `json:"citation_style,omitempty"`
+		Author            string   `json:"author,omitempty"`
+		RetrievalTopK     int      `json:"retrieval_top_k"`
+		QueryRewrite      string   `json:"query_rewrite,omitempty"`
+		RewriteCount      int      `json:"rewrite_count,omitempty"`
+		ContextWindow     int      `json:"context_window,omitempty"`
+		ContextBudget     int      `json:"context_token_budget,omitempty"`
+		UsePaperSummaries bool     `json:"use_paper_summaries,omitempty"`
+		MaxConcurrent     int      `json:"max_concurrent_sections,omitempty"`
+		RepairRounds      *int     `json:"repair_rounds,omitempty"`
+		ReviewOutline     bool     `json:"review_outline,omitempty"`
+		ReviewTimeoutSecs int      `json:"outline_review_timeout_seconds,omitempty"`
+		CritiqueRounds    int      `json:"critique_rounds,omitempty"`
+		KGTables          bool     `json:"kg_tables,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
 		return
 	}
-	req.CorpusID = strings.TrimSpace(req.CorpusID)
 	req.Prompt = strings.TrimSpace(req.Prompt)
-	if req.OutputFormat == "" {
-		req.OutputFormat = "latex"
+	outputFormat, ok := survey.NormalizeFormat(req.OutputFormat)
+	if !ok {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("output_format must be latex, markdown or html"))
+		return
+	}
+	// A template fixes the output format; output_format may repeat it but not contradict it.
+	templateID := ""
+	if strings.TrimSpace(req.TemplateID) != "" {
+		tmpl, ok := survey.LookupTemplate(req.TemplateID)
+		if !ok {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("unknown template_id: %s", req.TemplateID))
+			return
+		}
+		if strings.TrimSpace(req.OutputFormat) != "" && outputFormat != tmpl.Format {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("template %s renders %s, not %s", tmpl.ID, tmpl.Format, outputFormat))
+			return
+		}
+		templateID, outputFormat = tmpl.ID, tmpl.Format
+	}
+	citationStyle, ok := survey.NormalizeCitationStyle(req.CitationStyle)
+	if !ok {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("citation_style must be ieee, apa or acm"))
+		return
+	}
+	if req.Prompt == "" && len(req.Topics) == 0 {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("prompt (or at least one topic) is required"))
+		return
+	}
+	corpusIDs, err := s.resolveCorpusScope(r.Context(), req.CorpusID, req.CorpusIDs, req.CorpusGroup, "")
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, err)
+		return
+	}
+	// The run is owned by the first corpus in scope; retrieval spans all of them.
+	req.CorpusID = corpusIDs[0]
+	if len(corpusIDs) == 1 {
+		corpusIDs = nil
+	}
+	rewriteMode, err := retrieval.NormalizeRewriteMode(req.QueryRewrite)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.ContextWindow < 0 || req.ContextWindow > 3 {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("context_window must be between 0 and 3"))
+		return
+	}
+	if req.ContextBudget <= 0 && req.ContextWindow > 0 {
+		req.ContextBudget = s.cfg.ContextTokenBudget
+	}
+	if req.MaxConcurrent < 0 || req.MaxConcurrent > 8 {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("max_concurrent_sections must be between 0 and 8"))
+		return
+	}
+	// Omitted repair_rounds uses the workflow default; an explicit 0 only validates.
+	repairRounds := 0
+	if req.RepairRounds != nil {
+		if *req.RepairRounds < 0 || *req.RepairRounds > 3 {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("repair_rounds must be between 0 and 3"))
+			return
+		}
+		repairRounds = *req.RepairRounds
+		if repairRounds == 0 {
+			repairRounds = -1
+		}
+	}
+	if req.CritiqueRounds < 0 || req.CritiqueRounds > 3 {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("critique_rounds must be between 0 and 3"))
+		return
 	}
-	if req.CorpusID == "" || (req.Prompt == "" && len(req.Topics) == 0) {
-		writeErr(w, http.StatusBadRequest, fmt.Errorf("corpus_id and prompt (or at least one topic) are required"))
+	if req.ReviewTimeoutSecs < 0 || req.ReviewTimeoutSecs > 7*24*60*60 {
+		writeErr(w, http.StatusBadRequest, fmt.Errorf("outline_review_timeout_seconds must be between 0 and 604800"))
 		return
 	}
 	topics := req.Topics
@@ -573,7 +2525,7 @@ func (s *Server) handleSurvey(w http.ResponseWriter, r *http.Request) {
 		topics = []string{req.Prompt}
 	}
 	runID := uuid.NewString()
-	if err := s.surveyRepo.CreateRun(r.Context(), runID, req.CorpusID, topics, req.Questions); err != nil {
+	if err := s.surveyRepo.CreateRun(r.Context(), runID, req.CorpusID, "", outputFormat, templateID, citationStyle, topics, req.Questions); err != nil {
 		writeErr(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -581,26 +2533,54 @@ func (s *Server) handleSurvey(w http.ResponseWriter, r *http.Request) {
 		ID:        "survey-" + runID,
 		TaskQueue: s.cfg.TemporalTaskQueue,
 	}, workflows.SurveyBuildWorkflow, workflows.SurveyBuildInput{
-		SurveyRunID:     runID,
-		CorpusID:        req.CorpusID,
-		Prompt:          req.Prompt,
-		Topics:          topics,
-		Questions:       req.Questions,
-		OutputFormat:    req.OutputFormat,
-		RetrievalTopK:   req.RetrievalTopK,
-		EmbedProviders:  s.providers.EmbedCount(),
-		LLMProviders:    s.providers.LLMCount(),
-		LLMProviderRefs: providerRawRefs(s.providers.LLMProviderRefs()),
-		CooldownSeconds: s.cfg.ProviderCooldownSecs,
-		EmbedVersion:    s.cfg.EmbedVersion,
+		SurveyRunID:                 runID,
+		CorpusID:                    req.CorpusID,
+		Prompt:                      req.Prompt,
+		Topics:                      topics,
+		Questions:                   req.Questions,
+		OutputFormat:                outputFormat,
+		TemplateID:                  templateID,
+		CitationStyle:               citationStyle,
+		Author:                      strings.TrimSpace(req.Author),
+		RetrievalTopK:               req.RetrievalTopK,
+		EmbedProviders:              s.providers.EmbedCount(),
+		LLMProviders:                s.providers.LLMCount(),
+		LLMProviderRefs:             providerRawRefs(s.providers.LLMProviderRefs()),
+		CooldownSeconds:             s.cfg.ProviderCooldownSecs,
+		EmbedVersion:                s.cfg.EmbedVersion,
+		QueryRewrite:                rewriteMode,
+		RewriteCount:                req.RewriteCount,
+		ContextWindow:               req.ContextWindow,
+		ContextBudget:               req.ContextBudget,
+		CorpusIDs:                   corpusIDs,
+		UsePaperSummaries:           req.UsePaperSummaries,
+		MaxConcurrentSections:       req.MaxConcurrent,
+		RepairRounds:                repairRounds,
+		ReviewOutline:               req.ReviewOutline,
+		OutlineReviewTimeoutSeconds: req.ReviewTimeoutSecs,
+		CritiqueRounds:              req.CritiqueRounds,
+		KGTables:                    req.KGTables,
 	})
 	if err != nil {
+		_ = s.surveyRepo.UpdateRunStatus(r.Context(), runID, "failed", "", nil)
 		writeErr(w, http.StatusConflict, err)
 		return
 	}
 	writeJSON(w, http.StatusAccepted, map[string]any{"survey_run_id": runID, "workflow_id": we.GetID(), "run_id": we.GetRunID()})
 }
 
+// handleSurveyTemplates lists the report templates a survey request can name in template_id.
+func (s *Server) handleSurveyTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"templates":       survey.Templates(),
+		"citation_styles": []string{survey.StyleIEEE, survey.StyleAPA, survey.StyleACM},
+	})
+}
+
 func (s *Server) handleSurveyScoped(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/survey/"), "/"), "/")
 	if len(parts) < 2 {
@@ -625,6 +2605,40 @@ func (s *Server) handleSurveyScoped(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		writeJSON(w, http.StatusOK, prog)
+	case "outline":
+		s.handleSurveyOutline(w, r, runID)
+	case "revisions":
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+		run, err := s.surveyRepo.GetRun(r.Context(), runID)
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		sections, err := readSurveyRevisions(filepath.Join(s.cfg.DataOutRoot, run.CorpusID, "surveys", runID, survey.RevisionsDir))
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"survey_run_id": runID, "sections": sections})
+	case "validation":
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+		_, status, err := s.surveyRepo.GetRunPath(r.Context(), runID)
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		validation, err := s.surveyRepo.GetValidation(r.Context(), runID)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"survey_run_id": runID, "status": status, "validation": validation})
 	case "report":
 		if r.Method != http.MethodGet {
 			writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
@@ -639,13 +2653,22 @@ func (s *Server) handleSurveyScoped(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusOK, map[string]any{"status": status, "report_text": "", "report_markdown": "", "output_format": "unknown"})
 			return
 		}
-		b, err := os.ReadFile(outPath)
+		report, format, err := readSurveyReport(outPath, r.URL.Query().Get("format"))
 		if err != nil {
-			writeErr(w, http.StatusInternalServerError, err)
+			writeErr(w, surveyReportStatus(err), err)
 			return
 		}
-		format := strings.TrimPrefix(strings.ToLower(filepath.Ext(outPath)), ".")
-		writeJSON(w, http.StatusOK, map[string]any{"status": status, "report_text": string(b), "report_markdown": string(b), "path": outPath, "output_format": format})
+		resp := map[string]any{"status": status, "report_text": report, "report_markdown": report, "path": outPath, "output_format": format}
+		if doc, err := readSurveyDocument(outPath); err == nil {
+			if md, err := survey.Render(doc, survey.FormatMarkdown); err == nil {
+				resp["report_markdown"] = md
+			}
+			resp["formats"] = []string{survey.FormatLaTeX, survey.FormatMarkdown, survey.FormatHTML}
+			if format == survey.FormatLaTeX {
+				resp["bibtex"] = survey.RenderBibTeX(doc.References)
+			}
+		}
+		writeJSON(w, http.StatusOK, resp)
 	case "download":
 		if r.Method != http.MethodGet {
 			writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
@@ -660,28 +2683,284 @@ func (s *Server) handleSurveyScoped(w http.ResponseWriter, r *http.Request) {
 			writeErr(w, http.StatusNotFound, fmt.Errorf("survey output not ready"))
 			return
 		}
-		b, err := os.ReadFile(outPath)
+		report, format, err := readSurveyReport(outPath, r.URL.Query().Get("format"))
 		if err != nil {
-			writeErr(w, http.StatusInternalServerError, err)
+			writeErr(w, surveyReportStatus(err), err)
+			return
+		}
+		if format == survey.FormatLaTeX {
+			writeSurveyLaTeXZip(w, runID, outPath, report)
 			return
 		}
-		ext := strings.ToLower(filepath.Ext(outPath))
 		contentType := "text/plain; charset=utf-8"
-		if ext == ".tex" {
-			contentType = "text/x-tex; charset=utf-8"
-		} else if ext == ".md" {
+		switch format {
+		case survey.FormatMarkdown:
 			contentType = "text/markdown; charset=utf-8"
+		case survey.FormatHTML:
+			contentType = "text/html; charset=utf-8"
 		}
-		filename := fmt.Sprintf("survey-%s%s", runID, ext)
+		filename := fmt.Sprintf("survey-%s.%s", runID, survey.FileExtension(format))
 		w.Header().Set("Content-Type", contentType)
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
 		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write(b)
+		_, _ = w.Write([]byte(report))
+	default:
+		writeErr(w, http.StatusNotFound, fmt.Errorf("not found"))
+	}
+}
+
+// writeSurveyLaTeXZip serves report.tex with its refs.bib. The bibliography is rendered from
+// the stored document; reports from before BibTeX output are zipped without one.
+func writeSurveyLaTeXZip(w http.ResponseWriter, runID, outPath, report string) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct{ name, body string }{{"report.tex", report}}
+	if doc, err := readSurveyDocument(outPath); err == nil {
+		files = append(files, struct{ name, body string }{survey.BibFilename, survey.RenderBibTeX(doc.References)})
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err == nil {
+			_, err = fw.Write([]byte(f.body))
+		}
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		writeErr(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "survey-"+runID+".zip"))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// handleSurveyRuns serves /corpora/{id}/surveys (runs newest first) and
+// /corpora/{id}/surveys/diff?a=&b=, which compares two runs' documents; a defaults to b's
+// parent run.
+func (s *Server) handleSurveyRuns(w http.ResponseWriter, r *http.Request, corpusID string, parts []string) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+	switch {
+	case len(parts) == 1:
+		runs, err := s.surveyRepo.ListRuns(r.Context(), corpusID)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"surveys": runs})
+	case len(parts) == 2 && parts[1] == "diff":
+		idA := strings.TrimSpace(r.URL.Query().Get("a"))
+		idB := strings.TrimSpace(r.URL.Query().Get("b"))
+		if idB == "" {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("query param b is required"))
+			return
+		}
+		runB, docB, err := s.loadSurveyRun(r.Context(), corpusID, idB)
+		if err != nil {
+			writeErr(w, surveyReportStatus(err), err)
+			return
+		}
+		if idA == "" {
+			idA = runB.ParentRunID
+		}
+		if idA == "" {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("query param a is required when b has no parent run"))
+			return
+		}
+		runA, docA, err := s.loadSurveyRun(r.Context(), corpusID, idA)
+		if err != nil {
+			writeErr(w, surveyReportStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"a": runA, "b": runB, "diff": survey.Compare(docA, docB)})
 	default:
 		writeErr(w, http.StatusNotFound, fmt.Errorf("not found"))
 	}
 }
 
+// loadSurveyRun returns a completed run of the corpus with its stored document.
+func (s *Server) loadSurveyRun(ctx context.Context, corpusID, surveyRunID string) (models.SurveyRun, survey.Document, error) {
+	run, err := s.surveyRepo.GetRun(ctx, surveyRunID)
+	if err != nil || run.CorpusID != corpusID {
+		return models.SurveyRun{}, survey.Document{}, fmt.Errorf("survey run %s not found in corpus: %w", surveyRunID, os.ErrNotExist)
+	}
+	if run.OutPath == "" {
+		return models.SurveyRun{}, survey.Document{}, fmt.Errorf("survey run %s has no output yet: %w", surveyRunID, os.ErrNotExist)
+	}
+	doc, err := readSurveyDocument(run.OutPath)
+	if err != nil {
+		return models.SurveyRun{}, survey.Document{}, err
+	}
+	return run, doc, nil
+}
+
+// handleSurveyOutline reads the planned outline of a run (GET) or ends its review (POST) with
+// {"action": "approve"} or {"action": "edit", "sections": [...]}.
+func (s *Server) handleSurveyOutline(w http.ResponseWriter, r *http.Request, runID string) {
+	var state workflows.SurveyOutlineState
+	resp, err := s.temporal.QueryWorkflow(r.Context(), "survey-"+runID, "", workflows.QueryGetSurveyOutline)
+	if err != nil {
+		writeErr(w, http.StatusNotFound, err)
+		return
+	}
+	if err := resp.Get(&state); err != nil {
+		writeErr(w, http.StatusInternalServerError, err)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, state)
+	case http.MethodPost:
+		var review workflows.SurveyOutlineReview
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
+			return
+		}
+		review.Action = strings.ToLower(strings.TrimSpace(review.Action))
+		switch review.Action {
+		case workflows.SurveyOutlineApprove:
+			review.Sections = nil
+		case workflows.SurveyOutlineEdit:
+			if _, err := workflows.NormalizeSurveyOutline(review.Sections); err != nil {
+				writeErr(w, http.StatusBadRequest, err)
+				return
+			}
+		default:
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("action must be approve or edit"))
+			return
+		}
+		if state.Status != "pending_review" {
+			writeErr(w, http.StatusConflict, fmt.Errorf("survey outline is not awaiting review (status %s)", state.Status))
+			return
+		}
+		if err := s.temporal.SignalWorkflow(r.Context(), "survey-"+runID, "", workflows.SignalSurveyOutlineReview, review); err != nil {
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]any{"survey_run_id": runID, "action": review.Action})
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	}
+}
+
+var errSurveyFormat = errors.New("format must be latex, markdown or html")
+
+// readSurveyReport returns the stored report, or renders the stored survey document when
+// another format is requested. Reports written before the document model existed can only
+// be served in their original format.
+func readSurveyReport(outPath, requested string) (string, string, error) {
+	stored, _ := survey.NormalizeFormat(strings.TrimPrefix(strings.ToLower(filepath.Ext(outPath)), "."))
+	format := stored
+	if strings.TrimSpace(requested) != "" {
+		var ok bool
+		if format, ok = survey.NormalizeFormat(requested); !ok {
+			return "", "", errSurveyFormat
+		}
+	}
+	if format == stored {
+		b, err := os.ReadFile(outPath)
+		if err != nil {
+			return "", "", err
+		}
+		return string(b), format, nil
+	}
+	doc, err := readSurveyDocument(outPath)
+	if err != nil {
+		return "", "", err
+	}
+	report, err := survey.Render(doc, format)
+	if err != nil {
+		return "", "", err
+	}
+	return report, format, nil
+}
+
+func readSurveyDocument(outPath string) (survey.Document, error) {
+	var doc survey.Document
+	b, err := os.ReadFile(filepath.Join(filepath.Dir(outPath), "survey.json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return doc, fmt.Errorf("survey has no stored document to re-render: %w", err)
+		}
+		return doc, err
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return doc, fmt.Errorf("decode survey document: %w", err)
+	}
+	return doc, nil
+}
+
+type surveyDraft struct {
+	Round int    `json:"round"`
+	Text  string `json:"text"`
+}
+
+type surveySectionRevisions struct {
+	SectionID string            `json:"section_id"`
+	Drafts    []surveyDraft     `json:"drafts"`
+	Critiques []json.RawMessage `json:"critiques"`
+}
+
+// readSurveyRevisions lists the drafts and critiques each section wrote under dir, in round
+// order. A run without critique rounds has none.
+func readSurveyRevisions(dir string) ([]surveySectionRevisions, error) {
+	out := []surveySectionRevisions{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return out, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		sec := surveySectionRevisions{SectionID: entry.Name(), Drafts: []surveyDraft{}, Critiques: []json.RawMessage{}}
+		for round := 0; ; round++ {
+			draft, draftErr := os.ReadFile(filepath.Join(dir, entry.Name(), fmt.Sprintf("draft-%d.txt", round)))
+			if draftErr == nil {
+				sec.Drafts = append(sec.Drafts, surveyDraft{Round: round, Text: string(draft)})
+			}
+			critique, critiqueErr := os.ReadFile(filepath.Join(dir, entry.Name(), fmt.Sprintf("critique-%d.json", round)))
+			if critiqueErr == nil && json.Valid(critique) {
+				sec.Critiques = append(sec.Critiques, json.RawMessage(critique))
+			}
+			if draftErr != nil && critiqueErr != nil && round > 0 {
+				break
+			}
+		}
+		out = append(out, sec)
+	}
+	sort.Slice(out, func(i, j int) bool { return sectionOrder(out[i].SectionID) < sectionOrder(out[j].SectionID) })
+	return out, nil
+}
+
+// sectionOrder sorts s2 before s10.
+func sectionOrder(sectionID string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(sectionID, "s"))
+	if err != nil {
+		return int(^uint(0) >> 1)
+	}
+	return n
+}
+
+func surveyReportStatus(err error) int {
+	switch {
+	case errors.Is(err, errSurveyFormat):
+		return http.StatusBadRequest
+	case errors.Is(err, os.ErrNotExist):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
 func (s *Server) handleBackfill(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
@@ -691,11 +2970,13 @@ func (s *Server) handleBackfill(w http.ResponseWriter, r *http.Request) {
 		CorpusID      string   `json:"corpus_id"`
 		Mode          string   `json:"mode"`
 		SurveyRunID   string   `json:"survey_run_id,omitempty"`
+		ParentRunID   string   `json:"parent_run_id,omitempty"`
 		Topics        []string `json:"topics,omitempty"`
 		Questions     []string `json:"questions,omitempty"`
 		ChunkVersion  string   `json:"chunk_version,omitempty"`
 		EmbedVersion  string   `json:"embed_version,omitempty"`
 		EmbedProvider string   `json:"embed_provider,omitempty"`
+		Force         bool     `json:"force,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeErr(w, http.StatusBadRequest, fmt.Errorf("invalid json: %w", err))
@@ -707,6 +2988,43 @@ func (s *Server) handleBackfill(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, http.StatusBadRequest, fmt.Errorf("corpus_id and mode are required"))
 		return
 	}
+	if req.Mode == "UPDATE_SURVEY" {
+		req.SurveyRunID = strings.TrimSpace(req.SurveyRunID)
+		if req.SurveyRunID == "" {
+			writeErr(w, http.StatusBadRequest, fmt.Errorf("survey_run_id is required for UPDATE_SURVEY"))
+			return
+		}
+		run, _, err := s.loadSurveyRun(r.Context(), req.CorpusID, req.SurveyRunID)
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		if run.Status != "completed" {
+			writeErr(w, http.StatusConflict, fmt.Errorf("survey run %s is %s; only completed runs can be updated", run.SurveyRunID, run.Status))
+			return
+		}
+	}
+	if req.Mode == "REGENERATE_SURVEY" {
+		// survey_run_id is the ID of the new run; the run to regenerate is parent_run_id.
+		req.SurveyRunID = strings.TrimSpace(req.SurveyRunID)
+		req.ParentRunID = strings.TrimSpace(req.ParentRunID)
+		if req.SurveyRunID != "" {
+			if _, err := uuid.Parse(req.SurveyRunID); err != nil {
+				writeErr(w, http.StatusBadRequest, fmt.Errorf("survey_run_id must be a UUID for the new run; pass the run to regenerate as parent_run_id"))
+				return
+			}
+			if _, err := s.surveyRepo.GetRun(r.Context(), req.SurveyRunID); err == nil {
+				writeErr(w, http.StatusConflict, fmt.Errorf("survey run %s already exists; pass it as parent_run_id to regenerate it", req.SurveyRunID))
+				return
+			}
+		}
+		if req.ParentRunID != "" {
+			if parent, err := s.surveyRepo.GetRun(r.Context(), req.ParentRunID); err != nil || parent.CorpusID != req.CorpusID {
+				writeErr(w, http.StatusNotFound, fmt.Errorf("survey run %s not found in corpus", req.ParentRunID))
+				return
+			}
+		}
+	}
 	if req.EmbedVersion == "" {
 		req.EmbedVersion = s.cfg.EmbedVersion
 	}
@@ -727,6 +3045,7 @@ func (s *Server) handleBackfill(w http.ResponseWriter, r *http.Request) {
 		CorpusID:                    req.CorpusID,
 		Mode:                        req.Mode,
 		SurveyRunID:                 req.SurveyRunID,
+		ParentRunID:                 req.ParentRunID,
 		Topics:                      req.Topics,
 		Questions:                   req.Questions,
 		DataInRoot:                  s.cfg.DataInRoot,
@@ -738,6 +3057,7 @@ func (s *Server) handleBackfill(w http.ResponseWriter, r *http.Request) {
 		LLMProviders:                s.providers.LLMCount(),
 		LLMProviderRefs:             providerRawRefs(s.providers.LLMProviderRefs()),
 		CooldownSeconds:             s.cfg.ProviderCooldownSecs,
+		Force:                       req.Force,
 	})
 	if err != nil {
 		writeErr(w, http.StatusConflict, err)
@@ -782,6 +3102,8 @@ func (s *Server) handleEmbeddingProviders(w http.ResponseWriter, r *http.Request
 			model = "text-embedding-3-small"
 		case "ollama":
 			model = providers.ResolveOllamaEmbedModel(ref.KeyAlias)
+		case "openai-compatible":
+			model = providers.ResolveOpenAICompatibleConfig(ref.KeyAlias).EmbedModel
 		default:
 			model = "unknown"
 		}
@@ -1164,6 +3486,23 @@ func saveUploadedFile(dstDir string, fh *multipart.FileHeader) (paperID, path st
 	return paperID, finalPath, nil
 }
 
+func saveDraftFile(path string, fh *multipart.FileHeader) error {
+	src, err := fh.Open()
+	if err != nil {
+		return fmt.Errorf("open upload: %w", err)
+	}
+	defer src.Close()
+	dst, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create draft file: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return fmt.Errorf("write draft: %w", err)
+	}
+	return dst.Close()
+}
+
 func firstSingleFile(m map[string][]*multipart.FileHeader) (*multipart.FileHeader, bool) {
 	for _, v := range m {
 		if len(v) > 0 {
@@ -21,6 +21,7 @@ type Config struct {
 	LLMProviders         string
 	EmbedProviders       string
 	IngestMaxChildren    int
+	ContextTokenBudget   int
 }
 
 func Load() Config {
@@ -40,6 +41,7 @@ func Load() Config {
 		LLMProviders:         getenv("LITFLOW_LLM_PROVIDERS", "mock"),
 		EmbedProviders:       getenv("LITFLOW_EMBED_PROVIDERS", "mock"),
 		IngestMaxChildren:    getenvInt("LITFLOW_INGEST_MAX_CHILDREN", 3),
+		ContextTokenBudget:   getenvInt("LITFLOW_CONTEXT_TOKEN_BUDGET", 3000),
 	}
 }
 
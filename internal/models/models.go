@@ -8,6 +8,13 @@ type Corpus struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type CorpusGroup struct {
+	GroupID   string    `json:"group_id"`
+	Name      string    `json:"name"`
+	CorpusIDs []string  `json:"corpus_ids"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Paper struct {
 	PaperID    string    `json:"paper_id"`
 	CorpusID   string    `json:"corpus_id"`
@@ -15,6 +22,8 @@ type Paper struct {
 	Title      string    `json:"title,omitempty"`
 	Authors    string    `json:"authors,omitempty"`
 	Year       *int      `json:"year,omitempty"`
+	Venue      string    `json:"venue,omitempty"`
+	DOI        string    `json:"doi,omitempty"`
 	Abstract   string    `json:"abstract,omitempty"`
 	Status     string    `json:"status"`
 	FailReason string    `json:"fail_reason,omitempty"`
@@ -36,11 +45,159 @@ type Chunk struct {
 }
 
 type ChunkResult struct {
-	PaperID   string  `json:"paper_id"`
-	Title     string  `json:"title"`
-	Filename  string  `json:"filename"`
-	ChunkID   string  `json:"chunk_id"`
-	Snippet   string  `json:"snippet"`
-	Score     float64 `json:"score"`
-	ChunkText string  `json:"chunk_text,omitempty"`
+	CorpusID   string  `json:"corpus_id"`
+	PaperID    string  `json:"paper_id"`
+	Title      string  `json:"title"`
+	Filename   string  `json:"filename"`
+	ChunkID    string  `json:"chunk_id"`
+	ChunkIndex int     `json:"chunk_index"`
+	Section    string  `json:"section,omitempty"`
+	Snippet    string  `json:"snippet"`
+	Score      float64 `json:"score"`
+	ChunkText  string  `json:"chunk_text,omitempty"`
+}
+
+type EvalQuestion struct {
+	QuestionID       string   `json:"question_id"`
+	Question         string   `json:"question"`
+	RelevantPaperIDs []string `json:"relevant_paper_ids"`
+	RelevantChunkIDs []string `json:"relevant_chunk_ids"`
+}
+
+type EvalSet struct {
+	EvalSetID string         `json:"eval_set_id"`
+	CorpusID  string         `json:"corpus_id"`
+	Name      string         `json:"name"`
+	Questions []EvalQuestion `json:"questions,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type EvalRun struct {
+	EvalRunID string         `json:"eval_run_id"`
+	CorpusID  string         `json:"corpus_id"`
+	EvalSetID string         `json:"eval_set_id"`
+	Status    string         `json:"status"`
+	Config    map[string]any `json:"config"`
+	Metrics   map[string]any `json:"metrics,omitempty"`
+	OutPath   string         `json:"out_path,omitempty"`
+	LastError string         `json:"last_error,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// SurveyRun is a survey generation run. ParentRunID links a regeneration to the run it
+// regenerated; Models lists the "provider/model" pairs that wrote it.
+type SurveyRun struct {
+	SurveyRunID  string   `json:"survey_run_id"`
+	CorpusID     string   `json:"corpus_id"`
+	ParentRunID  string   `json:"parent_run_id,omitempty"`
+	Topics       []string `json:"topics"`
+	Questions    []string `json:"questions"`
+	Status       string   `json:"status"`
+	OutputFormat string   `json:"output_format"`
+	// TemplateID and CitationStyle are empty for runs that use the format's default layout.
+	TemplateID    string    `json:"template_id,omitempty"`
+	CitationStyle string    `json:"citation_style,omitempty"`
+	Models        []string  `json:"models"`
+	OutPath       string    `json:"out_path,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type AskTurn struct {
+	TurnID          string           `json:"turn_id"`
+	Position        int              `json:"position"`
+	Question        string           `json:"question"`
+	StandaloneQuery string           `json:"standalone_query,omitempty"`
+	Answer          string           `json:"answer"`
+	Citations       []map[string]any `json:"citations"`
+	LLMProvider     string           `json:"llm_provider,omitempty"`
+	LLMModel        string           `json:"llm_model,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+}
+
+type AskSession struct {
+	SessionID string    `json:"session_id"`
+	CorpusID  string    `json:"corpus_id"`
+	Title     string    `json:"title"`
+	TurnCount int       `json:"turn_count"`
+	Turns     []AskTurn `json:"turns,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AskRecord is one persisted /ask answer with everything needed to reproduce it.
+type AskRecord struct {
+	AskID            string           `json:"ask_id"`
+	CorpusID         string           `json:"corpus_id"`
+	CorpusIDs        []string         `json:"corpus_ids"`
+	SessionID        string           `json:"session_id,omitempty"`
+	ReplayOf         string           `json:"replay_of,omitempty"`
+	Question         string           `json:"question"`
+	StandaloneQuery  string           `json:"standalone_query,omitempty"`
+	Filters          map[string]any   `json:"filters"`
+	RewrittenQueries []map[string]any `json:"rewritten_queries"`
+	EmbedProvider    string           `json:"embed_provider,omitempty"`
+	EmbedModel       string           `json:"embed_model,omitempty"`
+	EmbedVersion     string           `json:"embed_version"`
+	Citations        []map[string]any `json:"citations,omitempty"`
+	PromptVersion    string           `json:"prompt_version"`
+	LLMProvider      string           `json:"llm_provider,omitempty"`
+	LLMModel         string           `json:"llm_model,omitempty"`
+	Answer           string           `json:"answer,omitempty"`
+	GroundingScore   *float64         `json:"grounding_score,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+}
+
+type ResearchRun struct {
+	ResearchRunID string         `json:"research_run_id"`
+	CorpusID      string         `json:"corpus_id"`
+	Question      string         `json:"question"`
+	Status        string         `json:"status"`
+	Config        map[string]any `json:"config"`
+	OutPath       string         `json:"out_path,omitempty"`
+	LastError     string         `json:"last_error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// Draft is an uploaded paper draft. Drafts are kept next to the corpus, not in it: they are
+// never chunked or embedded and only serve as input to positioning runs.
+type Draft struct {
+	DraftID   string    `json:"draft_id"`
+	CorpusID  string    `json:"corpus_id"`
+	Filename  string    `json:"filename"`
+	Format    string    `json:"format"`
+	FilePath  string    `json:"file_path"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PositioningRun struct {
+	PositioningRunID string         `json:"positioning_run_id"`
+	DraftID          string         `json:"draft_id"`
+	CorpusID         string         `json:"corpus_id"`
+	Status           string         `json:"status"`
+	Config           map[string]any `json:"config"`
+	OutPath          string         `json:"out_path,omitempty"`
+	LastError        string         `json:"last_error,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
+
+// PaperSummary is the structured summary PaperSummarizeWorkflow stores for one paper, with
+// the prompt version and model that produced it.
+type PaperSummary struct {
+	PaperID       string    `json:"paper_id"`
+	CorpusID      string    `json:"corpus_id"`
+	TLDR          string    `json:"tldr"`
+	Contributions []string  `json:"contributions"`
+	Method        string    `json:"method"`
+	Results       string    `json:"results"`
+	Limitations   string    `json:"limitations"`
+	PromptVersion string    `json:"prompt_version"`
+	LLMProvider   string    `json:"llm_provider,omitempty"`
+	LLMModel      string    `json:"llm_model,omitempty"`
+	ChunkCount    int       `json:"chunk_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
@@ -37,17 +37,7 @@ func (g *GroqProvider) Generate(ctx context.Context, req GenerateRequest) (Gener
 	if g.apiKey == "" {
 		return GenerateResponse{}, ProviderInfo{Name: "groq", Key: g.keyName, Model: g.model}, fmt.Errorf("groq key missing for alias %q", g.keyName)
 	}
-	prompt := req.Prompt
-	if len(req.Context) > 0 {
-		prompt += "\n\nContext:\n" + strings.Join(req.Context, "\n\n")
-	}
-	payload, _ := json.Marshal(map[string]any{
-		"model": g.model,
-		"messages": []map[string]string{
-			{"role": "system", "content": "You are a literature survey assistant. Keep responses concise and grounded in provided context."},
-			{"role": "user", "content": prompt},
-		},
-	})
+	payload, _ := json.Marshal(g.chatPayload(req))
 	httpReq, _ := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.groq.com/openai/v1/chat/completions", bytes.NewReader(payload))
 	httpReq.Header.Set("Authorization", "Bearer "+g.apiKey)
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -76,6 +66,32 @@ func (g *GroqProvider) Generate(ctx context.Context, req GenerateRequest) (Gener
 	return GenerateResponse{Text: parsed.Choices[0].Message.Content}, ProviderInfo{Name: "groq", Key: g.keyName, Model: g.model}, nil
 }
 
+func (g *GroqProvider) GenerateStream(ctx context.Context, req GenerateRequest, onDelta func(string) error) (GenerateResponse, ProviderInfo, error) {
+	info := ProviderInfo{Name: "groq", Key: g.keyName, Model: g.model}
+	if g.apiKey == "" {
+		return GenerateResponse{}, info, fmt.Errorf("groq key missing for alias %q", g.keyName)
+	}
+	text, err := streamChatCompletion(ctx, g.client, "https://api.groq.com/openai/v1/chat/completions", g.apiKey, g.chatPayload(req), onDelta)
+	if err != nil {
+		return GenerateResponse{Text: text}, info, fmt.Errorf("groq generate %w", err)
+	}
+	return GenerateResponse{Text: text}, info, nil
+}
+
+func (g *GroqProvider) chatPayload(req GenerateRequest) map[string]any {
+	prompt := req.Prompt
+	if len(req.Context) > 0 {
+		prompt += "\n\nContext:\n" + strings.Join(req.Context, "\n\n")
+	}
+	return map[string]any{
+		"model": g.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": "You are a literature survey assistant. Keep responses concise and grounded in provided context."},
+			{"role": "user", "content": prompt},
+		},
+	}
+}
+
 func resolveGroqKey(alias string) string {
 	if alias != "" {
 		if v := os.Getenv("LITFLOW_GROQ_KEY_" + strings.ToUpper(alias)); v != "" {
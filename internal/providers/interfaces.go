@@ -24,8 +24,27 @@ type EmbedRequest struct {
 	Dimension int      `json:"dimension"`
 }
 
+// LLMProvider generates text. GenerateStream emits the answer incrementally: onDelta
+// receives each text fragment in order and the returned response carries the full text.
+// Providers without a streaming endpoint deliver the whole answer as a single fragment.
 type LLMProvider interface {
 	Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, ProviderInfo, error)
+	GenerateStream(ctx context.Context, req GenerateRequest, onDelta func(string) error) (GenerateResponse, ProviderInfo, error)
+}
+
+// generateWhole is the non-streaming GenerateStream fallback: one Generate call whose text
+// is delivered as a single fragment.
+func generateWhole(ctx context.Context, generate func(context.Context, GenerateRequest) (GenerateResponse, ProviderInfo, error), req GenerateRequest, onDelta func(string) error) (GenerateResponse, ProviderInfo, error) {
+	resp, info, err := generate(ctx, req)
+	if err != nil {
+		return resp, info, err
+	}
+	if resp.Text != "" {
+		if err := onDelta(resp.Text); err != nil {
+			return resp, info, err
+		}
+	}
+	return resp, info, nil
 }
 
 type EmbeddingProvider interface {
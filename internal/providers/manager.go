@@ -212,6 +212,8 @@ func buildProvider(ref ProviderRef, dim int) (any, error) {
 		return NewOllamaEmbeddingProvider(ref.KeyAlias), nil
 	case "groq":
 		return NewGroqProvider(ref.KeyAlias), nil
+	case "openai-compatible":
+		return NewOpenAICompatibleProvider(ref.KeyAlias, ResolveOpenAICompatibleConfig(ref.KeyAlias)), nil
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", ref.Name)
 	}
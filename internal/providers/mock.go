@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -36,8 +37,10 @@ func (m *MockProvider) Embed(ctx context.Context, req EmbedRequest) ([][]float32
 func (m *MockProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, ProviderInfo, error) {
 	_ = ctx
 	text := "Mock response."
-	if strings.Contains(strings.ToLower(req.Operation), "survey") {
-		text = "# Mock Survey\n\n## Findings\nDeterministic section output with citations [mock-paper:chunk-0]."
+	if strings.Contains(strings.ToLower(req.Operation), "survey_critique") {
+		text = `{"coverage": 4, "grounding": 4, "redundancy": 5, "structure": 4, "comments": ["Deterministic review without a model."]}`
+	} else if strings.Contains(strings.ToLower(req.Operation), "survey") {
+		text = "Deterministic section output with citations [ref1].\n\n### Findings\nThe retrieved evidence is summarized without a model [ref1]."
 	} else if strings.Contains(strings.ToLower(req.Operation), "rag") || strings.Contains(strings.ToLower(req.Operation), "ask") {
 		builder := strings.Builder{}
 		builder.WriteString("## Direct Answer\n")
@@ -47,14 +50,56 @@ func (m *MockProvider) Generate(ctx context.Context, req GenerateRequest) (Gener
 			builder.WriteString(strconv.Itoa(i + 1))
 			builder.WriteString("]")
 		}
-		builder.WriteString("\n## Confidence\n- Mock confidence only; replace with real provider for semantic quality.")
 		text = builder.String()
+	} else if strings.Contains(strings.ToLower(req.Operation), "citation_verify") {
+		text = `{"judgments": []}`
+	} else if strings.Contains(strings.ToLower(req.Operation), "citation_summary_batch") {
+		summaries := make(map[string]string, len(req.Context))
+		for i, snippet := range req.Context {
+			refID := "C" + strconv.Itoa(i+1)
+			if cut := strings.Index(snippet, " | "); cut > 0 {
+				refID = snippet[:cut]
+			}
+			summaries[refID] = "This citation is relevant to the question and provides supporting context. Interpret with caution because this is deterministic mock output."
+		}
+		b, _ := json.Marshal(map[string]any{"summaries": summaries})
+		text = string(b)
 	} else if strings.Contains(strings.ToLower(req.Operation), "citation_summary") {
 		text = "This citation is relevant to the question and provides supporting context. Interpret with caution because this is deterministic mock output."
+	} else if strings.Contains(strings.ToLower(req.Operation), "paper_summary_reduce") {
+		text = `{"tldr": "Deterministic mock summary of the paper.", "contributions": ["Mock contribution."], "method": "Mock method description.", "results": "Mock results.", "limitations": "Not reported."}`
+	} else if strings.Contains(strings.ToLower(req.Operation), "query_rewrite") {
+		text = `{"queries": []}`
+	} else if strings.Contains(strings.ToLower(req.Operation), "query_condense") {
+		text = `{"standalone_query": ""}`
+	} else if strings.Contains(strings.ToLower(req.Operation), "query_hyde") {
+		text = "Deterministic hypothetical passage describing the method, datasets and evaluation results relevant to the question."
 	}
 	return GenerateResponse{Text: text}, ProviderInfo{Name: "mock", Model: "mock-llm-v1", Key: "mock"}, nil
 }
 
+// GenerateStream emits the deterministic mock text word by word so streaming clients can be
+// exercised without a real provider.
+func (m *MockProvider) GenerateStream(ctx context.Context, req GenerateRequest, onDelta func(string) error) (GenerateResponse, ProviderInfo, error) {
+	resp, info, err := m.Generate(ctx, req)
+	if err != nil {
+		return resp, info, err
+	}
+	rest := resp.Text
+	for rest != "" {
+		cut := strings.IndexAny(rest[1:], " \n")
+		piece := rest
+		if cut >= 0 {
+			piece = rest[:cut+1]
+		}
+		rest = rest[len(piece):]
+		if err := onDelta(piece); err != nil {
+			return resp, info, err
+		}
+	}
+	return resp, info, nil
+}
+
 func deterministicVector(input string, dim int) []float32 {
 	vec := make([]float32, dim)
 	seed := []byte(input)
@@ -66,17 +66,7 @@ func (o *OpenAIProvider) Generate(ctx context.Context, req GenerateRequest) (Gen
 		return GenerateResponse{}, ProviderInfo{Name: "openai", Key: o.keyName}, fmt.Errorf("openai key missing for alias %q", o.keyName)
 	}
 	model := "gpt-4o-mini"
-	prompt := req.Prompt
-	if len(req.Context) > 0 {
-		prompt = prompt + "\n\nContext:\n" + strings.Join(req.Context, "\n\n")
-	}
-	payload, _ := json.Marshal(map[string]any{
-		"model": model,
-		"messages": []map[string]string{
-			{"role": "system", "content": "You are a literature survey assistant. Use concise, citation-grounded responses."},
-			{"role": "user", "content": prompt},
-		},
-	})
+	payload, _ := json.Marshal(o.chatPayload(model, req))
 	httpReq, _ := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/chat/completions", bytes.NewReader(payload))
 	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -105,6 +95,33 @@ func (o *OpenAIProvider) Generate(ctx context.Context, req GenerateRequest) (Gen
 	return GenerateResponse{Text: parsed.Choices[0].Message.Content}, ProviderInfo{Name: "openai", Model: model, Key: o.keyName}, nil
 }
 
+func (o *OpenAIProvider) GenerateStream(ctx context.Context, req GenerateRequest, onDelta func(string) error) (GenerateResponse, ProviderInfo, error) {
+	model := "gpt-4o-mini"
+	info := ProviderInfo{Name: "openai", Model: model, Key: o.keyName}
+	if o.apiKey == "" {
+		return GenerateResponse{}, info, fmt.Errorf("openai key missing for alias %q", o.keyName)
+	}
+	text, err := streamChatCompletion(ctx, o.client, "https://api.openai.com/v1/chat/completions", o.apiKey, o.chatPayload(model, req), onDelta)
+	if err != nil {
+		return GenerateResponse{Text: text}, info, fmt.Errorf("openai generate %w", err)
+	}
+	return GenerateResponse{Text: text}, info, nil
+}
+
+func (o *OpenAIProvider) chatPayload(model string, req GenerateRequest) map[string]any {
+	prompt := req.Prompt
+	if len(req.Context) > 0 {
+		prompt = prompt + "\n\nContext:\n" + strings.Join(req.Context, "\n\n")
+	}
+	return map[string]any{
+		"model": model,
+		"messages": []map[string]string{
+			{"role": "system", "content": "You are a literature survey assistant. Use concise, citation-grounded responses."},
+			{"role": "user", "content": prompt},
+		},
+	}
+}
+
 func resolveOpenAIKey(alias string) string {
 	if alias != "" {
 		k := os.Getenv("LITFLOW_OPENAI_KEY_" + strings.ToUpper(alias))
@@ -0,0 +1,228 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAICompatibleConfig describes one server that speaks the OpenAI chat completions and
+// embeddings API, such as vLLM or llama.cpp. BaseURL includes the API prefix, for example
+// http://localhost:8000/v1.
+type OpenAICompatibleConfig struct {
+	BaseURL    string
+	APIKey     string
+	ChatModel  string
+	EmbedModel string
+	Headers    map[string]string
+	Timeout    time.Duration
+}
+
+// OpenAICompatibleProvider supports LLM generation and embeddings against a self-hosted
+// OpenAI-compatible server configured per alias.
+type OpenAICompatibleProvider struct {
+	alias  string
+	cfg    OpenAICompatibleConfig
+	client *http.Client
+}
+
+func NewOpenAICompatibleProvider(alias string, cfg OpenAICompatibleConfig) *OpenAICompatibleProvider {
+	cfg.BaseURL = strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	return &OpenAICompatibleProvider{
+		alias: alias,
+		cfg:   cfg,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: headerTransport{headers: cfg.Headers, base: http.DefaultTransport},
+		},
+	}
+}
+
+func (o *OpenAICompatibleProvider) Embed(ctx context.Context, req EmbedRequest) ([][]float32, ProviderInfo, error) {
+	info := ProviderInfo{Name: "openai-compatible", Model: o.cfg.EmbedModel, Key: o.alias}
+	if err := o.check(o.cfg.EmbedModel, "embed"); err != nil {
+		return nil, info, err
+	}
+	if len(req.Inputs) == 0 {
+		return nil, info, fmt.Errorf("no embedding inputs")
+	}
+	body, err := o.post(ctx, "/embeddings", map[string]any{"model": o.cfg.EmbedModel, "input": req.Inputs}, "embedding")
+	if err != nil {
+		return nil, info, err
+	}
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, info, fmt.Errorf("decode openai-compatible embedding response: %w", err)
+	}
+	if len(parsed.Data) != len(req.Inputs) {
+		return nil, info, fmt.Errorf("openai-compatible returned %d embeddings for %d inputs", len(parsed.Data), len(req.Inputs))
+	}
+	out := make([][]float32, len(req.Inputs))
+	for i, d := range parsed.Data {
+		// Servers report each vector's input index; fall back to response order without it.
+		idx := d.Index
+		if idx < 0 || idx >= len(out) || out[idx] != nil {
+			idx = i
+		}
+		if len(d.Embedding) == 0 {
+			return nil, info, fmt.Errorf("openai-compatible returned empty embedding")
+		}
+		out[idx] = matchDimension(d.Embedding, req.Dimension)
+	}
+	return out, info, nil
+}
+
+func (o *OpenAICompatibleProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, ProviderInfo, error) {
+	info := ProviderInfo{Name: "openai-compatible", Model: o.cfg.ChatModel, Key: o.alias}
+	if err := o.check(o.cfg.ChatModel, "chat"); err != nil {
+		return GenerateResponse{}, info, err
+	}
+	body, err := o.post(ctx, "/chat/completions", o.chatPayload(req), "generate")
+	if err != nil {
+		return GenerateResponse{}, info, err
+	}
+	var parsed struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return GenerateResponse{}, info, fmt.Errorf("decode openai-compatible generate response: %w", err)
+	}
+	if len(parsed.Choices) == 0 {
+		return GenerateResponse{}, info, fmt.Errorf("openai-compatible returned empty choices")
+	}
+	return GenerateResponse{Text: parsed.Choices[0].Message.Content}, info, nil
+}
+
+func (o *OpenAICompatibleProvider) GenerateStream(ctx context.Context, req GenerateRequest, onDelta func(string) error) (GenerateResponse, ProviderInfo, error) {
+	info := ProviderInfo{Name: "openai-compatible", Model: o.cfg.ChatModel, Key: o.alias}
+	if err := o.check(o.cfg.ChatModel, "chat"); err != nil {
+		return GenerateResponse{}, info, err
+	}
+	text, err := streamChatCompletion(ctx, o.client, o.cfg.BaseURL+"/chat/completions", o.cfg.APIKey, o.chatPayload(req), onDelta)
+	if err != nil {
+		return GenerateResponse{Text: text}, info, fmt.Errorf("openai-compatible generate %w", err)
+	}
+	return GenerateResponse{Text: text}, info, nil
+}
+
+func (o *OpenAICompatibleProvider) check(model, kind string) error {
+	if o.cfg.BaseURL == "" {
+		return fmt.Errorf("openai-compatible base url missing for alias %q", o.alias)
+	}
+	if model == "" {
+		return fmt.Errorf("openai-compatible %s model missing for alias %q", kind, o.alias)
+	}
+	return nil
+}
+
+func (o *OpenAICompatibleProvider) post(ctx context.Context, path string, payload map[string]any, op string) ([]byte, error) {
+	b, _ := json.Marshal(payload)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.cfg.BaseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("build openai-compatible %s request: %w", op, err)
+	}
+	if o.cfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+o.cfg.APIKey)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := o.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("openai-compatible %s request failed: %w", op, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("openai-compatible %s error %d: %s", op, resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+func (o *OpenAICompatibleProvider) chatPayload(req GenerateRequest) map[string]any {
+	prompt := req.Prompt
+	if len(req.Context) > 0 {
+		prompt += "\n\nContext:\n" + strings.Join(req.Context, "\n\n")
+	}
+	return map[string]any{
+		"model": o.cfg.ChatModel,
+		"messages": []map[string]string{
+			{"role": "system", "content": "You are a literature survey assistant. Keep responses concise and grounded in provided context."},
+			{"role": "user", "content": prompt},
+		},
+	}
+}
+
+// headerTransport adds the configured headers to every request, including streamed ones.
+type headerTransport struct {
+	headers map[string]string
+	base    http.RoundTripper
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.headers) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// ResolveOpenAICompatibleConfig reads LITFLOW_OPENAI_COMPAT_<SETTING>_<ALIAS>, falling back
+// to the unsuffixed LITFLOW_OPENAI_COMPAT_<SETTING> for settings the alias does not set.
+func ResolveOpenAICompatibleConfig(alias string) OpenAICompatibleConfig {
+	alias = strings.TrimSpace(alias)
+	get := func(setting string) string {
+		key := "LITFLOW_OPENAI_COMPAT_" + setting
+		if alias != "" {
+			if v := strings.TrimSpace(os.Getenv(key + "_" + sanitizeEnvToken(alias))); v != "" {
+				return v
+			}
+		}
+		return strings.TrimSpace(os.Getenv(key))
+	}
+	cfg := OpenAICompatibleConfig{
+		BaseURL:    get("BASE_URL"),
+		APIKey:     get("API_KEY"),
+		ChatModel:  get("CHAT_MODEL"),
+		EmbedModel: get("EMBED_MODEL"),
+		Headers:    parseHeaderList(get("HEADERS")),
+	}
+	if secs, err := strconv.Atoi(get("TIMEOUT_SECONDS")); err == nil && secs > 0 {
+		cfg.Timeout = time.Duration(secs) * time.Second
+	}
+	return cfg
+}
+
+// parseHeaderList parses "Name: value; Other: value" into a header map, skipping entries
+// without a name.
+func parseHeaderList(raw string) map[string]string {
+	out := map[string]string{}
+	for _, entry := range strings.Split(raw, ";") {
+		name, value, ok := strings.Cut(entry, ":")
+		if name = strings.TrimSpace(name); !ok || name == "" {
+			continue
+		}
+		out[http.CanonicalHeaderKey(name)] = strings.TrimSpace(value)
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"litflow/internal/config"
+)
+
+func newCompatServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer local-key" || r.Header.Get("X-Tenant") != "lab" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Model  string   `json:"model"`
+			Input  []string `json:"input"`
+			Stream bool     `json:"stream"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/v1/embeddings":
+			if body.Model != "bge-m3" {
+				http.Error(w, "unknown model", http.StatusNotFound)
+				return
+			}
+			// Report vectors out of order; the provider must place them by index.
+			fmt.Fprintf(w, `{"data":[{"index":1,"embedding":[0.3,0.4,0.5]},{"index":0,"embedding":[0.1,0.2,0.3]}]}`)
+		case "/v1/chat/completions":
+			if body.Model != "qwen2.5-7b" {
+				http.Error(w, "unknown model", http.StatusNotFound)
+				return
+			}
+			if body.Stream {
+				fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Local\"}}]}\n\n")
+				fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\" answer\"}}]}\n\n")
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				return
+			}
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Local answer"}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestOpenAICompatibleProviderAgainstLocalServer(t *testing.T) {
+	srv := newCompatServer(t)
+	defer srv.Close()
+
+	t.Setenv("LITFLOW_OPENAI_COMPAT_BASE_URL_VLLM_A", srv.URL+"/v1/")
+	t.Setenv("LITFLOW_OPENAI_COMPAT_API_KEY_VLLM_A", "local-key")
+	t.Setenv("LITFLOW_OPENAI_COMPAT_CHAT_MODEL_VLLM_A", "qwen2.5-7b")
+	t.Setenv("LITFLOW_OPENAI_COMPAT_EMBED_MODEL_VLLM_A", "bge-m3")
+	t.Setenv("LITFLOW_OPENAI_COMPAT_HEADERS", "x-tenant: lab")
+	m, err := NewManager(config.Config{LLMProviders: "openai-compatible:vllm-a", EmbedProviders: "openai-compatible:vllm-a", EmbedDim: 4})
+	if err != nil {
+		t.Fatalf("unexpected manager error: %v", err)
+	}
+
+	vectors, info, err := m.FirstEmbedProvider().Embed(context.Background(), EmbedRequest{Inputs: []string{"a", "b"}, Dimension: 4})
+	if err != nil {
+		t.Fatalf("unexpected embed error: %v", err)
+	}
+	if info.Name != "openai-compatible" || info.Model != "bge-m3" || info.Key != "vllm-a" {
+		t.Fatalf("unexpected embed info: %#v", info)
+	}
+	if len(vectors) != 2 || len(vectors[0]) != 4 || vectors[0][0] != 0.1 || vectors[1][0] != 0.3 || vectors[1][3] != 0 {
+		t.Fatalf("unexpected vectors: %#v", vectors)
+	}
+
+	llm := m.FirstLLMProvider()
+	resp, info, err := llm.Generate(context.Background(), GenerateRequest{Prompt: "q", Context: []string{"c"}})
+	if err != nil || resp.Text != "Local answer" || info.Model != "qwen2.5-7b" {
+		t.Fatalf("unexpected generate result %q %#v %v", resp.Text, info, err)
+	}
+	var deltas []string
+	streamed, _, err := llm.GenerateStream(context.Background(), GenerateRequest{Prompt: "q"}, func(s string) error {
+		deltas = append(deltas, s)
+		return nil
+	})
+	if err != nil || streamed.Text != "Local answer" || len(deltas) != 2 {
+		t.Fatalf("unexpected stream result %q %#v %v", streamed.Text, deltas, err)
+	}
+}
+
+func TestOpenAICompatibleProviderErrors(t *testing.T) {
+	srv := newCompatServer(t)
+	defer srv.Close()
+
+	p := NewOpenAICompatibleProvider("local", OpenAICompatibleConfig{BaseURL: srv.URL + "/v1", ChatModel: "qwen2.5-7b"})
+	if _, _, err := p.Generate(context.Background(), GenerateRequest{Prompt: "q"}); err == nil {
+		t.Fatalf("expected missing headers to be rejected")
+	}
+	if _, _, err := p.Embed(context.Background(), EmbedRequest{Inputs: []string{"a"}}); err == nil {
+		t.Fatalf("expected missing embed model error")
+	}
+	if _, _, err := NewOpenAICompatibleProvider("none", OpenAICompatibleConfig{ChatModel: "m"}).Generate(context.Background(), GenerateRequest{}); err == nil {
+		t.Fatalf("expected missing base url error")
+	}
+}
+
+func TestResolveOpenAICompatibleConfig(t *testing.T) {
+	t.Setenv("LITFLOW_OPENAI_COMPAT_BASE_URL", "http://shared:8000/v1")
+	t.Setenv("LITFLOW_OPENAI_COMPAT_CHAT_MODEL_LLAMACPP", "llama-3.1-8b")
+	t.Setenv("LITFLOW_OPENAI_COMPAT_HEADERS_LLAMACPP", "x-team: nlp; : skipped; X-Trace:on")
+	t.Setenv("LITFLOW_OPENAI_COMPAT_TIMEOUT_SECONDS_LLAMACPP", "300")
+
+	cfg := ResolveOpenAICompatibleConfig("llamacpp")
+	if cfg.BaseURL != "http://shared:8000/v1" || cfg.ChatModel != "llama-3.1-8b" || cfg.EmbedModel != "" || cfg.Timeout != 300*time.Second {
+		t.Fatalf("unexpected config: %#v", cfg)
+	}
+	if len(cfg.Headers) != 2 || cfg.Headers["X-Team"] != "nlp" || cfg.Headers["X-Trace"] != "on" {
+		t.Fatalf("unexpected headers: %#v", cfg.Headers)
+	}
+}
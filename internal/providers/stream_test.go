@@ -0,0 +1,80 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamChatCompletionForwardsDeltas(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("missing bearer token")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\" world\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	var deltas []string
+	text, err := streamChatCompletion(context.Background(), srv.Client(), srv.URL, "k", map[string]any{"model": "m"}, func(s string) error {
+		deltas = append(deltas, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "Hello world" || len(deltas) != 2 {
+		t.Fatalf("unexpected stream result %q %#v", text, deltas)
+	}
+}
+
+func TestStreamChatCompletionSurfacesStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := streamChatCompletion(context.Background(), srv.Client(), srv.URL, "", map[string]any{}, func(string) error { return nil })
+	if ClassifyError(err) != ErrorRate {
+		t.Fatalf("expected rate error, got %v", err)
+	}
+}
+
+// wholeAnswerProvider is an LLMProvider without a streaming endpoint.
+type wholeAnswerProvider struct{}
+
+func (wholeAnswerProvider) Generate(context.Context, GenerateRequest) (GenerateResponse, ProviderInfo, error) {
+	return GenerateResponse{Text: "whole answer"}, ProviderInfo{Name: "plain"}, nil
+}
+
+func (p wholeAnswerProvider) GenerateStream(ctx context.Context, req GenerateRequest, onDelta func(string) error) (GenerateResponse, ProviderInfo, error) {
+	return generateWhole(ctx, p.Generate, req, onDelta)
+}
+
+func TestGenerateStreamFallsBackToSingleChunk(t *testing.T) {
+	var llm LLMProvider = wholeAnswerProvider{}
+	var deltas []string
+	resp, info, err := llm.GenerateStream(context.Background(), GenerateRequest{Prompt: "q"}, func(s string) error {
+		deltas = append(deltas, s)
+		return nil
+	})
+	if err != nil || resp.Text != "whole answer" || len(deltas) != 1 || deltas[0] != "whole answer" || info.Name != "plain" {
+		t.Fatalf("unexpected fallback result %q %#v %v", resp.Text, deltas, err)
+	}
+
+	var mockDeltas []string
+	mockResp, _, err := NewMockProvider(8).GenerateStream(context.Background(), GenerateRequest{Operation: "rag_answer", Context: []string{"a"}}, func(s string) error {
+		mockDeltas = append(mockDeltas, s)
+		return nil
+	})
+	if err != nil || len(mockDeltas) < 2 || strings.Join(mockDeltas, "") != mockResp.Text {
+		t.Fatalf("mock stream should reassemble to full text: %#v", mockDeltas)
+	}
+}
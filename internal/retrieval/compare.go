@@ -0,0 +1,250 @@
+package retrieval
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"litflow/internal/util"
+)
+
+// DefaultCompareDimensions are used when a comparison request names no dimensions.
+var DefaultCompareDimensions = []string{"problem", "method", "datasets", "metrics", "results", "limitations"}
+
+var inlineRefPattern = regexp.MustCompile(`\s*\[C\d+\]`)
+
+var compareDimensionQueries = map[string]string{
+	"problem":     "research problem and motivation addressed by the paper",
+	"method":      "proposed method, model architecture and approach",
+	"datasets":    "datasets and benchmarks used for training and evaluation",
+	"metrics":     "evaluation metrics used to measure performance",
+	"results":     "main experimental results and reported improvements",
+	"limitations": "limitations, failure cases and future work",
+}
+
+// CompareDimensionQuery is the retrieval query for one comparison dimension. Known
+// dimensions get a descriptive query; anything else is searched as written.
+func CompareDimensionQuery(dimension string) string {
+	key := strings.ToLower(strings.TrimSpace(dimension))
+	if q, ok := compareDimensionQueries[key]; ok {
+		return q
+	}
+	return strings.TrimSpace(dimension) + " of the paper"
+}
+
+// ComparePaper is one column of a comparison table.
+type ComparePaper struct {
+	PaperID string `json:"paper_id"`
+	Title   string `json:"title"`
+}
+
+// CompareEvidence is a retrieved chunk that table cells may cite by RefID.
+type CompareEvidence struct {
+	RefID     string  `json:"ref_id"`
+	PaperID   string  `json:"paper_id"`
+	Dimension string  `json:"dimension"`
+	ChunkID   string  `json:"chunk_id"`
+	Snippet   string  `json:"snippet"`
+	Score     float64 `json:"score"`
+}
+
+// CompareCell is the entry for one paper and dimension. Source is llm when the cell came
+// from the model, extractive when it is the best evidence snippet, and none when nothing
+// was retrieved.
+type CompareCell struct {
+	PaperID   string   `json:"paper_id"`
+	Dimension string   `json:"dimension"`
+	Text      string   `json:"text"`
+	Citations []string `json:"citations"`
+	Source    string   `json:"source"`
+}
+
+// ComparisonTable holds one row per dimension and one column per paper; Cells[d][p] is the
+// cell for Dimensions[d] and Papers[p].
+type ComparisonTable struct {
+	Papers     []ComparePaper    `json:"papers"`
+	Dimensions []string          `json:"dimensions"`
+	Cells      [][]CompareCell   `json:"cells"`
+	Evidence   []CompareEvidence `json:"evidence"`
+}
+
+// notReported is the cell text for a dimension the evidence does not cover.
+const notReported = "Not reported."
+
+// BuildComparePrompt asks for every dimension of one paper in a single structured
+// completion. The paper's evidence snippets are passed as context, labelled with refIDs.
+func BuildComparePrompt(title string, dimensions, refIDs []string) string {
+	return strings.Join([]string{
+		"Paper: " + strings.TrimSpace(title),
+		"",
+		"Using only the evidence snippets below (labelled " + strings.Join(refIDs, ", ") + "), describe the paper along each dimension: " + strings.Join(dimensions, ", ") + ".",
+		"Write one or two concise sentences per dimension and list the IDs of the snippets that support it.",
+		`If the evidence does not cover a dimension, use the text "` + notReported + `" with no citations.`,
+		"",
+		`Output STRICT JSON keyed by dimension: {"cells": {"method": {"text": "...", "citations": ["C1"]}}}`,
+	}, "\n")
+}
+
+// ParseCompareCells reads a comparison completion for one paper. Citations must be among
+// refIDs; inline [C#] markers in the text are moved into the citation list. Cells with a
+// claim but no valid citation are dropped so the caller falls back to extractive evidence;
+// only "Not reported." may stand uncited. A nil map means the output was unusable.
+func ParseCompareCells(raw string, dimensions, refIDs []string) map[string]CompareCell {
+	raw = stripCodeFence(strings.TrimSpace(raw))
+	type rawCell struct {
+		Text      string   `json:"text"`
+		Citations []string `json:"citations"`
+	}
+	var wrapped struct {
+		Cells map[string]rawCell `json:"cells"`
+	}
+	if err := json.Unmarshal([]byte(raw), &wrapped); err != nil || len(wrapped.Cells) == 0 {
+		return nil
+	}
+	known := make(map[string]string, len(refIDs))
+	for _, id := range refIDs {
+		known[strings.ToUpper(id)] = id
+	}
+	dims := make(map[string]string, len(dimensions))
+	for _, d := range dimensions {
+		dims[strings.ToLower(d)] = d
+	}
+	out := make(map[string]CompareCell, len(wrapped.Cells))
+	for k, c := range wrapped.Cells {
+		dim, ok := dims[strings.ToLower(strings.TrimSpace(k))]
+		if !ok {
+			continue
+		}
+		cites := append([]string(nil), c.Citations...)
+		for _, m := range citationRefPattern.FindAllStringSubmatch(c.Text, -1) {
+			cites = append(cites, "C"+m[1])
+		}
+		text := strings.Join(strings.Fields(inlineRefPattern.ReplaceAllString(c.Text, "")), " ")
+		if text == "" {
+			continue
+		}
+		cell := CompareCell{Dimension: dim, Text: text, Citations: []string{}, Source: "llm"}
+		seen := map[string]bool{}
+		for _, id := range cites {
+			id, ok := known[strings.ToUpper(strings.Trim(strings.TrimSpace(id), "[]"))]
+			if !ok || seen[id] {
+				continue
+			}
+			seen[id] = true
+			cell.Citations = append(cell.Citations, id)
+		}
+		if len(cell.Citations) == 0 && !strings.EqualFold(text, notReported) {
+			continue
+		}
+		out[dim] = cell
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// cellText renders a cell with its citation markers appended, e.g. "Uses LoRA. [C1][C3]".
+func (c CompareCell) cellText() string {
+	if len(c.Citations) == 0 {
+		return c.Text
+	}
+	return c.Text + " [" + strings.Join(c.Citations, "][") + "]"
+}
+
+func (t ComparisonTable) paperLabel(i int) string {
+	if title := strings.TrimSpace(t.Papers[i].Title); title != "" {
+		return title
+	}
+	return t.Papers[i].PaperID
+}
+
+// RenderComparisonMarkdown renders the table followed by the cited evidence.
+func RenderComparisonMarkdown(t ComparisonTable) string {
+	esc := strings.NewReplacer("|", `\|`, "\n", " ")
+	b := &strings.Builder{}
+	b.WriteString("| Dimension |")
+	for i := range t.Papers {
+		fmt.Fprintf(b, " %s |", esc.Replace(t.paperLabel(i)))
+	}
+	b.WriteString("\n|---|")
+	b.WriteString(strings.Repeat("---|", len(t.Papers)))
+	b.WriteString("\n")
+	for d, dim := range t.Dimensions {
+		fmt.Fprintf(b, "| %s |", esc.Replace(dim))
+		for _, c := range t.Cells[d] {
+			fmt.Fprintf(b, " %s |", esc.Replace(c.cellText()))
+		}
+		b.WriteString("\n")
+	}
+	if len(t.Evidence) > 0 {
+		b.WriteString("\n## Evidence\n")
+		for _, e := range t.Evidence {
+			fmt.Fprintf(b, "- [%s] paper `%s`, chunk `%s`: %s\n", e.RefID, e.PaperID, e.ChunkID, e.Snippet)
+		}
+	}
+	return b.String()
+}
+
+// RenderComparisonCSV renders the table only; citation IDs stay inline in each cell.
+func RenderComparisonCSV(t ComparisonTable) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := []string{"dimension"}
+	for i := range t.Papers {
+		header = append(header, t.paperLabel(i))
+	}
+	if err := w.Write(header); err != nil {
+		return "", fmt.Errorf("write csv header: %w", err)
+	}
+	for d, dim := range t.Dimensions {
+		row := []string{dim}
+		for _, c := range t.Cells[d] {
+			row = append(row, c.cellText())
+		}
+		if err := w.Write(row); err != nil {
+			return "", fmt.Errorf("write csv row: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("flush csv: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// RenderComparisonLaTeX renders a tabular with wrapped paper columns and the cited evidence
+// as a description list.
+func RenderComparisonLaTeX(t ComparisonTable) string {
+	width := 0.8
+	if len(t.Papers) > 0 {
+		width = 0.8 / float64(len(t.Papers))
+	}
+	b := &strings.Builder{}
+	b.WriteString("\\begin{table}[ht]\n\\centering\n\\small\n")
+	fmt.Fprintf(b, "\\begin{tabular}{l%s}\n\\hline\n", strings.Repeat(fmt.Sprintf("p{%.2f\\linewidth}", width), len(t.Papers)))
+	b.WriteString("\\textbf{Dimension}")
+	for i := range t.Papers {
+		fmt.Fprintf(b, " & \\textbf{%s}", util.EscapeLaTeX(t.paperLabel(i)))
+	}
+	b.WriteString(" \\\\\n\\hline\n")
+	for d, dim := range t.Dimensions {
+		b.WriteString(util.EscapeLaTeX(dim))
+		for _, c := range t.Cells[d] {
+			b.WriteString(" & " + util.EscapeLaTeX(c.cellText()))
+		}
+		b.WriteString(" \\\\\n")
+	}
+	b.WriteString("\\hline\n\\end{tabular}\n\\caption{Paper comparison}\n\\end{table}\n")
+	if len(t.Evidence) > 0 {
+		b.WriteString("\n\\begin{description}\n")
+		for _, e := range t.Evidence {
+			fmt.Fprintf(b, "\\item[{[%s]}] %s\n", e.RefID, util.EscapeLaTeX(e.Snippet))
+		}
+		b.WriteString("\\end{description}\n")
+	}
+	return b.String()
+}
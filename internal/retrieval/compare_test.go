@@ -0,0 +1,62 @@
+package retrieval
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCompareCells(t *testing.T) {
+	raw := "```json\n{\"cells\": {\"Method\": {\"text\": \"Low-rank adapters on attention [C2].\", \"citations\": [\"C1\", \"C9\"]}, \"venue\": {\"text\": \"ignored\"}, \"results\": {\"text\": \"  \"}}}\n```"
+	got := ParseCompareCells(raw, []string{"method", "results"}, []string{"C1", "C2"})
+	if len(got) != 1 {
+		t.Fatalf("expected one usable cell, got %#v", got)
+	}
+	cell := got["method"]
+	if cell.Text != "Low-rank adapters on attention." {
+		t.Fatalf("unexpected text %q", cell.Text)
+	}
+	if len(cell.Citations) != 2 || cell.Citations[0] != "C1" || cell.Citations[1] != "C2" {
+		t.Fatalf("unexpected citations %#v", cell.Citations)
+	}
+	uncited := ParseCompareCells(`{"cells": {"method": {"text": "Uses adapters."}, "results": {"text": "Not reported."}}}`, []string{"method", "results"}, []string{"C1"})
+	if _, ok := uncited["method"]; ok || uncited["results"].Text != "Not reported." {
+		t.Fatalf("expected uncited claims to be dropped: %#v", uncited)
+	}
+	if ParseCompareCells("Mock response.", []string{"method"}, []string{"C1"}) != nil {
+		t.Fatalf("expected nil for unusable output")
+	}
+}
+
+func TestRenderComparison(t *testing.T) {
+	table := ComparisonTable{
+		Papers:     []ComparePaper{{PaperID: "p1", Title: "LoRA"}, {PaperID: "p2"}},
+		Dimensions: []string{"method", "metrics"},
+		Cells: [][]CompareCell{
+			{{Text: "Low-rank | adapters", Citations: []string{"C1"}}, {Text: "Prefix tuning", Citations: []string{"C2"}}},
+			{{Text: "Accuracy & F1 on 100% of GLUE"}, {Text: "Not reported."}},
+		},
+		Evidence: []CompareEvidence{{RefID: "C1", PaperID: "p1", ChunkID: "c1", Snippet: "We use low_rank updates."}},
+	}
+
+	md := RenderComparisonMarkdown(table)
+	if !strings.Contains(md, "| Dimension | LoRA | p2 |") || !strings.Contains(md, `Low-rank \| adapters [C1]`) {
+		t.Fatalf("unexpected markdown:\n%s", md)
+	}
+
+	csvOut, err := RenderComparisonCSV(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csvOut), "\n")
+	if len(lines) != 3 || lines[0] != "dimension,LoRA,p2" || lines[1] != "method,Low-rank | adapters [C1],Prefix tuning [C2]" {
+		t.Fatalf("unexpected csv:\n%s", csvOut)
+	}
+
+	tex := RenderComparisonLaTeX(table)
+	if !strings.Contains(tex, `Accuracy \& F1 on 100\% of GLUE`) || !strings.Contains(tex, `low\_rank`) {
+		t.Fatalf("expected escaped latex:\n%s", tex)
+	}
+	if !strings.Contains(tex, `\begin{tabular}{lp{0.40\linewidth}p{0.40\linewidth}}`) {
+		t.Fatalf("unexpected column spec:\n%s", tex)
+	}
+}
@@ -0,0 +1,72 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// maxCondenseTurns bounds how much history is sent when condensing a follow-up question.
+const maxCondenseTurns = 4
+
+// maxCondenseAnswerRunes caps each earlier answer quoted in the condense prompt.
+const maxCondenseAnswerRunes = 600
+
+// ConversationTurn is one earlier question/answer pair in an ask session.
+type ConversationTurn struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// BuildCondensePrompt asks the model to rewrite a follow-up into a standalone search query
+// that resolves pronouns and implicit references against the recent turns.
+func BuildCondensePrompt(history []ConversationTurn, followUp string) string {
+	if len(history) > maxCondenseTurns {
+		history = history[len(history)-maxCondenseTurns:]
+	}
+	lines := []string{
+		"Rewrite the follow-up question into a single standalone question for searching a scientific paper index.",
+		"Resolve pronouns and references such as \"it\", \"they\" or \"that method\" using the conversation.",
+		"Keep method, dataset and paper names exactly as written. Do not answer the question.",
+		"",
+		`Output STRICT JSON: {"standalone_query": "..."}`,
+		"",
+		"Conversation:",
+	}
+	for _, t := range history {
+		lines = append(lines, "User: "+strings.TrimSpace(t.Question))
+		answer := strings.Join(strings.Fields(t.Answer), " ")
+		if r := []rune(answer); len(r) > maxCondenseAnswerRunes {
+			answer = string(r[:maxCondenseAnswerRunes]) + "..."
+		}
+		if answer != "" {
+			lines = append(lines, "Assistant: "+answer)
+		}
+	}
+	lines = append(lines, "", "Follow-up: "+strings.TrimSpace(followUp))
+	return strings.Join(lines, "\n")
+}
+
+// ParseCondensedQuery reads the standalone query from a condense completion. When the model
+// output is unusable it falls back to the previous question joined with the follow-up, which
+// keeps the earlier topic terms in the retrieval query.
+func ParseCondensedQuery(raw string, history []ConversationTurn, followUp string) string {
+	followUp = strings.TrimSpace(followUp)
+	raw = stripCodeFence(strings.TrimSpace(raw))
+	var payload struct {
+		StandaloneQuery string `json:"standalone_query"`
+	}
+	candidate := ""
+	if err := json.Unmarshal([]byte(raw), &payload); err == nil {
+		candidate = payload.StandaloneQuery
+	} else if !strings.HasPrefix(raw, "{") && !strings.Contains(raw, "\n") {
+		candidate = trimListMarker(raw)
+	}
+	candidate = strings.Join(strings.Fields(candidate), " ")
+	if candidate != "" && len(candidate) <= 400 {
+		return candidate
+	}
+	if len(history) == 0 {
+		return followUp
+	}
+	return strings.TrimSpace(strings.TrimSpace(history[len(history)-1].Question) + " " + followUp)
+}
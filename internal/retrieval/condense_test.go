@@ -0,0 +1,33 @@
+package retrieval
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseCondensedQuery(t *testing.T) {
+	history := []ConversationTurn{{Question: "How does FlashAttention reduce memory?", Answer: "It tiles the attention computation."}}
+	got := ParseCondensedQuery(`{"standalone_query": "What are the limitations of FlashAttention?"}`, history, "what about its limitations?")
+	if got != "What are the limitations of FlashAttention?" {
+		t.Fatalf("unexpected condensed query: %q", got)
+	}
+	fallback := ParseCondensedQuery(`{"standalone_query": ""}`, history, "what about its limitations?")
+	if fallback != "How does FlashAttention reduce memory? what about its limitations?" {
+		t.Fatalf("unexpected fallback: %q", fallback)
+	}
+	if ParseCondensedQuery("", nil, " first question ") != "first question" {
+		t.Fatalf("expected follow-up unchanged without history")
+	}
+}
+
+func TestBuildCondensePromptTruncatesAnswersByRune(t *testing.T) {
+	history := []ConversationTurn{{Question: "Was ist Aufmerksamkeit?", Answer: strings.Repeat("ä", 700)}}
+	prompt := BuildCondensePrompt(history, "und danach?")
+	if !utf8.ValidString(prompt) {
+		t.Fatalf("condense prompt is not valid UTF-8")
+	}
+	if !strings.Contains(prompt, "Assistant: "+strings.Repeat("ä", maxCondenseAnswerRunes)+"...") {
+		t.Fatalf("answer not truncated to %d runes", maxCondenseAnswerRunes)
+	}
+}
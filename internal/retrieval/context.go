@@ -0,0 +1,147 @@
+package retrieval
+
+import "strings"
+
+// ChunkRef is the minimal chunk view needed to assemble expanded context windows.
+type ChunkRef struct {
+	ChunkID    string
+	PaperID    string
+	ChunkIndex int
+	Section    string
+	Text       string
+}
+
+// ExpandedHit is a retrieved chunk together with the neighboring text assembled around it.
+// HitChunkID always names the retrieved chunk so citations keep pointing at it.
+type ExpandedHit struct {
+	HitChunkID string   `json:"hit_chunk_id"`
+	ChunkIDs   []string `json:"chunk_ids"`
+	HitText    string   `json:"-"`
+	Text       string   `json:"text"`
+	Tokens     int      `json:"tokens"`
+}
+
+type chunkPos struct {
+	paperID string
+	index   int
+}
+
+// ExpandHits grows every hit into a contiguous window of up to window chunks on each
+// side, staying inside the hit's paper and section. Windows never overlap: every hit
+// keeps its own chunk, and a neighbor is assigned to the best-ranked hit that reaches it.
+func ExpandHits(hits []ChunkRef, neighbors []ChunkRef, window int) []ExpandedHit {
+	byPos := make(map[chunkPos]ChunkRef, len(neighbors)+len(hits))
+	for _, n := range neighbors {
+		byPos[chunkPos{n.PaperID, n.ChunkIndex}] = n
+	}
+	claimed := make(map[chunkPos]bool, len(hits))
+	for _, h := range hits {
+		pos := chunkPos{h.PaperID, h.ChunkIndex}
+		byPos[pos] = h
+		claimed[pos] = true
+	}
+	out := make([]ExpandedHit, 0, len(hits))
+	for _, h := range hits {
+		lo, hi := h.ChunkIndex, h.ChunkIndex
+		for d := 1; d <= window; d++ {
+			pos := chunkPos{h.PaperID, h.ChunkIndex - d}
+			n, ok := byPos[pos]
+			if !ok || claimed[pos] || n.Section != h.Section {
+				break
+			}
+			claimed[pos] = true
+			lo = pos.index
+		}
+		for d := 1; d <= window; d++ {
+			pos := chunkPos{h.PaperID, h.ChunkIndex + d}
+			n, ok := byPos[pos]
+			if !ok || claimed[pos] || n.Section != h.Section {
+				break
+			}
+			claimed[pos] = true
+			hi = pos.index
+		}
+		exp := ExpandedHit{HitChunkID: h.ChunkID, HitText: h.Text}
+		for i := lo; i <= hi; i++ {
+			c := byPos[chunkPos{h.PaperID, i}]
+			exp.ChunkIDs = append(exp.ChunkIDs, c.ChunkID)
+			exp.Text = joinOverlapping(exp.Text, c.Text)
+		}
+		exp.Tokens = EstimateTokens(exp.Text)
+		out = append(out, exp)
+	}
+	return out
+}
+
+// ApplyTokenBudget keeps expanded hits in rank order until budget tokens are used.
+// A window that does not fit falls back to its hit chunk alone; the first hit is
+// truncated rather than dropped so an answer always has some evidence.
+func ApplyTokenBudget(hits []ExpandedHit, budget int) []ExpandedHit {
+	if budget <= 0 {
+		return hits
+	}
+	out := make([]ExpandedHit, 0, len(hits))
+	used := 0
+	for _, h := range hits {
+		if used+h.Tokens <= budget {
+			used += h.Tokens
+			out = append(out, h)
+			continue
+		}
+		hitTokens := EstimateTokens(h.HitText)
+		if len(h.ChunkIDs) > 1 && used+hitTokens <= budget {
+			used += hitTokens
+			out = append(out, ExpandedHit{HitChunkID: h.HitChunkID, ChunkIDs: []string{h.HitChunkID}, HitText: h.HitText, Text: h.HitText, Tokens: hitTokens})
+			continue
+		}
+		if len(out) == 0 {
+			text := truncateToTokens(h.HitText, budget)
+			out = append(out, ExpandedHit{HitChunkID: h.HitChunkID, ChunkIDs: []string{h.HitChunkID}, HitText: h.HitText, Text: text, Tokens: EstimateTokens(text)})
+		}
+		break
+	}
+	return out
+}
+
+// EstimateTokens approximates a model token count at roughly four characters per token.
+func EstimateTokens(s string) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return (n + 3) / 4
+}
+
+func truncateToTokens(s string, tokens int) string {
+	r := []rune(s)
+	if limit := tokens * 4; len(r) > limit {
+		return strings.TrimSpace(string(r[:limit])) + "..."
+	}
+	return s
+}
+
+// joinOverlapping appends next to prev, dropping the prefix of next that repeats the end
+// of prev (the chunker emits overlapping windows).
+func joinOverlapping(prev, next string) string {
+	prev = strings.TrimSpace(prev)
+	next = strings.TrimSpace(next)
+	if prev == "" {
+		return next
+	}
+	if next == "" {
+		return prev
+	}
+	maxOverlap := len(prev)
+	if len(next) < maxOverlap {
+		maxOverlap = len(next)
+	}
+	if maxOverlap > 600 {
+		maxOverlap = 600
+	}
+	for k := maxOverlap; k >= 20; k-- {
+		if strings.HasSuffix(prev, next[:k]) {
+			return prev + next[k:]
+		}
+	}
+	return prev + " " + next
+}
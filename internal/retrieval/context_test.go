@@ -0,0 +1,59 @@
+package retrieval
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandHitsKeepsWindowsDisjoint(t *testing.T) {
+	hits := []ChunkRef{
+		{ChunkID: "p1-3", PaperID: "p1", ChunkIndex: 3, Section: "Method", Text: "three"},
+		{ChunkID: "p1-5", PaperID: "p1", ChunkIndex: 5, Section: "Method", Text: "five"},
+	}
+	neighbors := []ChunkRef{
+		{ChunkID: "p1-2", PaperID: "p1", ChunkIndex: 2, Section: "Intro", Text: "two"},
+		{ChunkID: "p1-4", PaperID: "p1", ChunkIndex: 4, Section: "Method", Text: "four"},
+		{ChunkID: "p1-6", PaperID: "p1", ChunkIndex: 6, Section: "Method", Text: "six"},
+	}
+	got := ExpandHits(hits, neighbors, 1)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 expanded hits, got %d", len(got))
+	}
+	if strings.Join(got[0].ChunkIDs, ",") != "p1-3,p1-4" || got[0].HitChunkID != "p1-3" {
+		t.Fatalf("unexpected first window: %#v", got[0])
+	}
+	if strings.Join(got[1].ChunkIDs, ",") != "p1-5,p1-6" {
+		t.Fatalf("unexpected second window: %#v", got[1])
+	}
+	if got[0].Text != "three four" {
+		t.Fatalf("unexpected joined text: %q", got[0].Text)
+	}
+}
+
+func TestJoinOverlappingDropsRepeatedPrefix(t *testing.T) {
+	prev := "The encoder stacks six identical layers with residual connections."
+	next := "six identical layers with residual connections. Each layer has two sub-layers."
+	got := joinOverlapping(prev, next)
+	want := "The encoder stacks six identical layers with residual connections. Each layer has two sub-layers."
+	if got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestApplyTokenBudget(t *testing.T) {
+	long := strings.Repeat("a", 400)
+	hits := []ExpandedHit{
+		{HitChunkID: "a", ChunkIDs: []string{"a"}, HitText: long, Text: long, Tokens: 100},
+		{HitChunkID: "b", ChunkIDs: []string{"b0", "b"}, HitText: "bbbb", Text: long, Tokens: 100},
+		{HitChunkID: "c", ChunkIDs: []string{"c"}, HitText: long, Text: long, Tokens: 100},
+	}
+	got := ApplyTokenBudget(hits, 120)
+	if len(got) != 2 || got[1].Text != "bbbb" || len(got[1].ChunkIDs) != 1 {
+		t.Fatalf("expected second hit to fall back to its own chunk: %#v", got)
+	}
+
+	first := ApplyTokenBudget(hits[:1], 10)
+	if len(first) != 1 || first[0].Tokens > 11 {
+		t.Fatalf("expected truncated first hit, got %#v", first)
+	}
+}
@@ -0,0 +1,100 @@
+package retrieval
+
+// CitationRef is the part of a citation that replays compare: which chunk was cited, at
+// what rank (its position in the list) and with what retrieval score.
+type CitationRef struct {
+	ChunkID string  `json:"chunk_id"`
+	PaperID string  `json:"paper_id"`
+	Title   string  `json:"title,omitempty"`
+	Score   float64 `json:"score"`
+}
+
+// CitationChange describes one chunk across two citation lists. Ranks are 1-based and zero
+// when the chunk is absent from that side.
+type CitationChange struct {
+	ChunkID     string  `json:"chunk_id"`
+	PaperID     string  `json:"paper_id"`
+	Title       string  `json:"title,omitempty"`
+	RankBefore  int     `json:"rank_before,omitempty"`
+	RankAfter   int     `json:"rank_after,omitempty"`
+	ScoreBefore float64 `json:"score_before,omitempty"`
+	ScoreAfter  float64 `json:"score_after,omitempty"`
+}
+
+// CitationDiff compares the citations of an original answer with those of its replay.
+// ChunkOverlap and PaperOverlap are Jaccard similarities of the cited chunk and paper sets.
+type CitationDiff struct {
+	Changed      bool             `json:"changed"`
+	Added        []CitationChange `json:"added"`
+	Removed      []CitationChange `json:"removed"`
+	Kept         []CitationChange `json:"kept"`
+	ChunkOverlap float64          `json:"chunk_overlap"`
+	PaperOverlap float64          `json:"paper_overlap"`
+}
+
+// DiffCitations pairs citations by chunk ID. Kept entries carry both ranks so reorderings
+// are visible; a diff is Changed when any chunk was added, removed or moved.
+func DiffCitations(before, after []CitationRef) CitationDiff {
+	d := CitationDiff{Added: []CitationChange{}, Removed: []CitationChange{}, Kept: []CitationChange{}}
+	afterRank := make(map[string]int, len(after))
+	for i, c := range after {
+		if _, ok := afterRank[c.ChunkID]; !ok {
+			afterRank[c.ChunkID] = i + 1
+		}
+	}
+	beforeRank := make(map[string]int, len(before))
+	for i, c := range before {
+		if _, ok := beforeRank[c.ChunkID]; ok {
+			continue
+		}
+		beforeRank[c.ChunkID] = i + 1
+		change := CitationChange{ChunkID: c.ChunkID, PaperID: c.PaperID, Title: c.Title, RankBefore: i + 1, ScoreBefore: c.Score}
+		if r, ok := afterRank[c.ChunkID]; ok {
+			change.RankAfter = r
+			change.ScoreAfter = after[r-1].Score
+			if r != i+1 {
+				d.Changed = true
+			}
+			d.Kept = append(d.Kept, change)
+			continue
+		}
+		d.Removed = append(d.Removed, change)
+	}
+	for i, c := range after {
+		if _, ok := beforeRank[c.ChunkID]; ok || afterRank[c.ChunkID] != i+1 {
+			continue
+		}
+		d.Added = append(d.Added, CitationChange{ChunkID: c.ChunkID, PaperID: c.PaperID, Title: c.Title, RankAfter: i + 1, ScoreAfter: c.Score})
+	}
+	if len(d.Added) > 0 || len(d.Removed) > 0 {
+		d.Changed = true
+	}
+	d.ChunkOverlap = jaccard(refIDs(before, func(c CitationRef) string { return c.ChunkID }), refIDs(after, func(c CitationRef) string { return c.ChunkID }))
+	d.PaperOverlap = jaccard(refIDs(before, func(c CitationRef) string { return c.PaperID }), refIDs(after, func(c CitationRef) string { return c.PaperID }))
+	return d
+}
+
+func refIDs(refs []CitationRef, key func(CitationRef) string) []string {
+	ids := make([]string, 0, len(refs))
+	for _, c := range refs {
+		ids = append(ids, key(c))
+	}
+	return dedupe(ids)
+}
+
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	set := make(map[string]bool, len(a))
+	for _, id := range a {
+		set[id] = true
+	}
+	inter := 0
+	for _, id := range b {
+		if set[id] {
+			inter++
+		}
+	}
+	return roundScore(float64(inter) / float64(len(a)+len(b)-inter))
+}
@@ -0,0 +1,21 @@
+package retrieval
+
+import "testing"
+
+func TestDiffCitations(t *testing.T) {
+	before := []CitationRef{{ChunkID: "a", PaperID: "p1", Score: 0.9}, {ChunkID: "b", PaperID: "p1", Score: 0.8}, {ChunkID: "c", PaperID: "p2", Score: 0.7}}
+	after := []CitationRef{{ChunkID: "b", PaperID: "p1", Score: 0.85}, {ChunkID: "a", PaperID: "p1", Score: 0.84}, {ChunkID: "d", PaperID: "p3", Score: 0.6}}
+	d := DiffCitations(before, after)
+	if !d.Changed || len(d.Added) != 1 || d.Added[0].ChunkID != "d" || len(d.Removed) != 1 || d.Removed[0].ChunkID != "c" {
+		t.Fatalf("unexpected diff: %+v", d)
+	}
+	if len(d.Kept) != 2 || d.Kept[0].RankBefore != 1 || d.Kept[0].RankAfter != 2 || d.Kept[0].ScoreAfter != 0.84 {
+		t.Fatalf("unexpected kept entries: %+v", d.Kept)
+	}
+	if d.ChunkOverlap != 0.5 || d.PaperOverlap != 0.333 {
+		t.Fatalf("unexpected overlap: chunk %v paper %v", d.ChunkOverlap, d.PaperOverlap)
+	}
+	if same := DiffCitations(before, before); same.Changed || same.ChunkOverlap != 1 {
+		t.Fatalf("expected identical lists to be unchanged: %+v", same)
+	}
+}
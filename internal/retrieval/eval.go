@@ -0,0 +1,188 @@
+package retrieval
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultEvalKs are the cutoffs reported when an eval run does not ask for specific ones.
+var DefaultEvalKs = []int{1, 5, 10}
+
+// EvalJudgment lists what counts as relevant for one golden question. When chunk judgments
+// are present they are used; otherwise relevance falls back to the paper level.
+type EvalJudgment struct {
+	ChunkIDs []string `json:"relevant_chunk_ids,omitempty"`
+	PaperIDs []string `json:"relevant_paper_ids,omitempty"`
+}
+
+// EvalHit is one ranked retrieval result.
+type EvalHit struct {
+	ChunkID string `json:"chunk_id"`
+	PaperID string `json:"paper_id"`
+}
+
+type EvalMetrics struct {
+	RecallAtK map[int]float64 `json:"recall_at_k"`
+	MRR       float64         `json:"mrr"`
+	NDCGAtK   map[int]float64 `json:"ndcg_at_k"`
+}
+
+type EvalQuestionResult struct {
+	QuestionID string      `json:"question_id"`
+	Question   string      `json:"question"`
+	Level      string      `json:"level"`
+	Relevant   []string    `json:"relevant"`
+	Retrieved  []string    `json:"retrieved"`
+	Metrics    EvalMetrics `json:"metrics"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// ScoreHits computes recall@k, reciprocal rank and binary nDCG@k for a ranked hit list.
+// Paper-level judgments collapse hits from the same paper to its first occurrence.
+func ScoreHits(hits []EvalHit, j EvalJudgment, ks []int) (EvalMetrics, string, []string, []string) {
+	level := "chunk"
+	relevant := dedupe(j.ChunkIDs)
+	unit := func(h EvalHit) string { return h.ChunkID }
+	if len(relevant) == 0 {
+		level = "paper"
+		relevant = dedupe(j.PaperIDs)
+		unit = func(h EvalHit) string { return h.PaperID }
+	}
+	ranked := make([]string, 0, len(hits))
+	for _, h := range hits {
+		ranked = append(ranked, unit(h))
+	}
+	ranked = dedupe(ranked)
+
+	isRel := make(map[string]bool, len(relevant))
+	for _, id := range relevant {
+		isRel[id] = true
+	}
+	m := EvalMetrics{RecallAtK: map[int]float64{}, NDCGAtK: map[int]float64{}}
+	for i, id := range ranked {
+		if isRel[id] {
+			m.MRR = 1 / float64(i+1)
+			break
+		}
+	}
+	for _, k := range ks {
+		if k <= 0 {
+			continue
+		}
+		found := 0
+		dcg := 0.0
+		for i := 0; i < k && i < len(ranked); i++ {
+			if isRel[ranked[i]] {
+				found++
+				dcg += 1 / math.Log2(float64(i+2))
+			}
+		}
+		idcg := 0.0
+		for i := 0; i < k && i < len(relevant); i++ {
+			idcg += 1 / math.Log2(float64(i+2))
+		}
+		if len(relevant) > 0 {
+			m.RecallAtK[k] = float64(found) / float64(len(relevant))
+		}
+		if idcg > 0 {
+			m.NDCGAtK[k] = dcg / idcg
+		}
+	}
+	return m, level, relevant, ranked
+}
+
+// AggregateMetrics averages per-question metrics. Questions that failed to run are skipped.
+func AggregateMetrics(results []EvalQuestionResult, ks []int) EvalMetrics {
+	agg := EvalMetrics{RecallAtK: map[int]float64{}, NDCGAtK: map[int]float64{}}
+	n := 0
+	for _, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		n++
+		agg.MRR += r.Metrics.MRR
+		for _, k := range ks {
+			agg.RecallAtK[k] += r.Metrics.RecallAtK[k]
+			agg.NDCGAtK[k] += r.Metrics.NDCGAtK[k]
+		}
+	}
+	if n == 0 {
+		return agg
+	}
+	agg.MRR /= float64(n)
+	for _, k := range ks {
+		agg.RecallAtK[k] /= float64(n)
+		agg.NDCGAtK[k] /= float64(n)
+	}
+	return agg
+}
+
+// MetricDelta is the difference B minus A for every aggregate metric two runs share.
+type MetricDelta struct {
+	RecallAtK map[int]float64 `json:"recall_at_k"`
+	MRR       float64         `json:"mrr"`
+	NDCGAtK   map[int]float64 `json:"ndcg_at_k"`
+}
+
+type EvalQuestionComparison struct {
+	QuestionID string  `json:"question_id"`
+	Question   string  `json:"question"`
+	MRRA       float64 `json:"mrr_a"`
+	MRRB       float64 `json:"mrr_b"`
+	DeltaMRR   float64 `json:"delta_mrr"`
+}
+
+// CompareMetrics reports B minus A on the cutoffs both runs computed.
+func CompareMetrics(a, b EvalMetrics) MetricDelta {
+	d := MetricDelta{RecallAtK: map[int]float64{}, NDCGAtK: map[int]float64{}, MRR: b.MRR - a.MRR}
+	for k, av := range a.RecallAtK {
+		if bv, ok := b.RecallAtK[k]; ok {
+			d.RecallAtK[k] = bv - av
+		}
+	}
+	for k, av := range a.NDCGAtK {
+		if bv, ok := b.NDCGAtK[k]; ok {
+			d.NDCGAtK[k] = bv - av
+		}
+	}
+	return d
+}
+
+// CompareQuestions pairs per-question results by question ID, largest MRR change first.
+func CompareQuestions(a, b []EvalQuestionResult) []EvalQuestionComparison {
+	byID := make(map[string]EvalQuestionResult, len(a))
+	for _, r := range a {
+		byID[r.QuestionID] = r
+	}
+	out := make([]EvalQuestionComparison, 0, len(b))
+	for _, rb := range b {
+		ra, ok := byID[rb.QuestionID]
+		if !ok {
+			continue
+		}
+		out = append(out, EvalQuestionComparison{
+			QuestionID: rb.QuestionID,
+			Question:   rb.Question,
+			MRRA:       ra.Metrics.MRR,
+			MRRB:       rb.Metrics.MRR,
+			DeltaMRR:   rb.Metrics.MRR - ra.Metrics.MRR,
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return math.Abs(out[i].DeltaMRR) > math.Abs(out[j].DeltaMRR)
+	})
+	return out
+}
+
+func dedupe(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+package retrieval
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScoreHitsChunkLevel(t *testing.T) {
+	hits := []EvalHit{{ChunkID: "x", PaperID: "p1"}, {ChunkID: "a", PaperID: "p1"}, {ChunkID: "y", PaperID: "p2"}, {ChunkID: "b", PaperID: "p3"}}
+	m, level, _, _ := ScoreHits(hits, EvalJudgment{ChunkIDs: []string{"a", "b"}}, []int{1, 2, 4})
+	if level != "chunk" {
+		t.Fatalf("expected chunk level, got %s", level)
+	}
+	if m.MRR != 0.5 {
+		t.Fatalf("expected MRR 0.5, got %v", m.MRR)
+	}
+	if m.RecallAtK[1] != 0 || m.RecallAtK[2] != 0.5 || m.RecallAtK[4] != 1 {
+		t.Fatalf("unexpected recall: %#v", m.RecallAtK)
+	}
+	wantNDCG := (1/math.Log2(3) + 1/math.Log2(5)) / (1 + 1/math.Log2(3))
+	if math.Abs(m.NDCGAtK[4]-wantNDCG) > 1e-9 {
+		t.Fatalf("ndcg@4 = %v want %v", m.NDCGAtK[4], wantNDCG)
+	}
+}
+
+func TestScoreHitsPaperLevelCollapsesDuplicates(t *testing.T) {
+	hits := []EvalHit{{ChunkID: "c1", PaperID: "p1"}, {ChunkID: "c2", PaperID: "p1"}, {ChunkID: "c3", PaperID: "p2"}}
+	m, level, _, ranked := ScoreHits(hits, EvalJudgment{PaperIDs: []string{"p2"}}, []int{2})
+	if level != "paper" || len(ranked) != 2 {
+		t.Fatalf("expected deduped paper ranking, got %s %#v", level, ranked)
+	}
+	if m.MRR != 0.5 || m.RecallAtK[2] != 1 {
+		t.Fatalf("unexpected metrics: %#v", m)
+	}
+}
+
+func TestCompareMetricsAndQuestions(t *testing.T) {
+	a := []EvalQuestionResult{{QuestionID: "q1", Metrics: EvalMetrics{MRR: 0.5}}, {QuestionID: "q2", Metrics: EvalMetrics{MRR: 1}}}
+	b := []EvalQuestionResult{{QuestionID: "q1", Metrics: EvalMetrics{MRR: 1}}, {QuestionID: "q2", Metrics: EvalMetrics{MRR: 0.2}}}
+	aggA := AggregateMetrics(a, nil)
+	aggB := AggregateMetrics(b, nil)
+	if d := CompareMetrics(aggA, aggB); math.Abs(d.MRR-(-0.15)) > 1e-9 {
+		t.Fatalf("unexpected MRR delta: %v", d.MRR)
+	}
+	per := CompareQuestions(a, b)
+	if len(per) != 2 || per[0].QuestionID != "q2" {
+		t.Fatalf("expected largest change first: %#v", per)
+	}
+}
@@ -0,0 +1,48 @@
+package retrieval
+
+import "sort"
+
+// rrfK is the standard reciprocal rank fusion damping constant.
+const rrfK = 60
+
+// FuseRanked merges several ranked result lists with reciprocal rank fusion. Items are
+// identified by key; the first occurrence of an item is kept. Ties keep first-seen order,
+// so the output is deterministic and safe to call from workflow code.
+func FuseRanked[T any](lists [][]T, key func(T) string, topK int) []T {
+	type entry struct {
+		item  T
+		score float64
+		order int
+	}
+	byKey := map[string]*entry{}
+	entries := make([]*entry, 0)
+	for _, list := range lists {
+		for rank, item := range list {
+			k := key(item)
+			if k == "" {
+				continue
+			}
+			e, ok := byKey[k]
+			if !ok {
+				e = &entry{item: item, order: len(entries)}
+				byKey[k] = e
+				entries = append(entries, e)
+			}
+			e.score += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].score != entries[j].score {
+			return entries[i].score > entries[j].score
+		}
+		return entries[i].order < entries[j].order
+	})
+	if topK > 0 && len(entries) > topK {
+		entries = entries[:topK]
+	}
+	out := make([]T, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, e.item)
+	}
+	return out
+}
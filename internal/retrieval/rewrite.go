@@ -0,0 +1,164 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	RewriteNone       = "none"
+	RewriteMultiQuery = "multi_query"
+	RewriteHyDE       = "hyde"
+	RewriteBoth       = "multi_query_hyde"
+)
+
+const (
+	QueryKindOriginal   = "original"
+	QueryKindParaphrase = "paraphrase"
+	QueryKindHyDE       = "hyde"
+)
+
+const defaultRewriteCount = 3
+
+// RewrittenQuery is one retrieval query produced by the query-understanding stage.
+type RewrittenQuery struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+}
+
+// NormalizeRewriteMode maps user input to a supported rewrite mode; empty means none.
+func NormalizeRewriteMode(mode string) (string, error) {
+	m := strings.ToLower(strings.TrimSpace(mode))
+	m = strings.ReplaceAll(m, "-", "_")
+	switch m {
+	case "", RewriteNone:
+		return RewriteNone, nil
+	case RewriteMultiQuery, "multiquery":
+		return RewriteMultiQuery, nil
+	case RewriteHyDE:
+		return RewriteHyDE, nil
+	case RewriteBoth, "all":
+		return RewriteBoth, nil
+	default:
+		return "", fmt.Errorf("unsupported query_rewrite mode: %s", mode)
+	}
+}
+
+func UsesMultiQuery(mode string) bool {
+	return mode == RewriteMultiQuery || mode == RewriteBoth
+}
+
+func UsesHyDE(mode string) bool {
+	return mode == RewriteHyDE || mode == RewriteBoth
+}
+
+func DefaultRewriteCount(n int) int {
+	if n <= 0 {
+		return defaultRewriteCount
+	}
+	if n > 8 {
+		return 8
+	}
+	return n
+}
+
+func BuildMultiQueryPrompt(question string, n int) string {
+	n = DefaultRewriteCount(n)
+	return strings.Join([]string{
+		"You rewrite research questions into search queries for a scientific paper index.",
+		fmt.Sprintf("Produce up to %d alternative queries for the question below.", n),
+		"Mix paraphrases that use the technical vocabulary of the field with narrower sub-queries for each distinct aspect of the question.",
+		"Do not answer the question. Do not repeat the original question verbatim.",
+		"",
+		`Output STRICT JSON: {"queries": ["...", "..."]}`,
+		"",
+		"Question: " + strings.TrimSpace(question),
+	}, "\n")
+}
+
+func BuildHyDEPrompt(question string) string {
+	return strings.Join([]string{
+		"Write a short passage (4-6 sentences) that could appear in a research paper and directly answers the question below.",
+		"Use the terminology, method names and evaluation vocabulary a paper on this topic would use.",
+		"It is fine to be uncertain about specifics; the passage is only used as a search query and is never shown as an answer.",
+		"Output only the passage, with no preamble and no citations.",
+		"",
+		"Question: " + strings.TrimSpace(question),
+	}, "\n")
+}
+
+// ParseRewrittenQueries extracts queries from a multi-query completion. It accepts the
+// requested JSON shape and falls back to one query per line for models that ignore it.
+func ParseRewrittenQueries(raw, original string, max int) []string {
+	max = DefaultRewriteCount(max)
+	raw = stripCodeFence(strings.TrimSpace(raw))
+	if raw == "" {
+		return nil
+	}
+	candidates := make([]string, 0, max)
+	var payload struct {
+		Queries []string `json:"queries"`
+	}
+	if err := json.Unmarshal([]byte(raw), &payload); err == nil {
+		candidates = payload.Queries
+	} else {
+		for _, line := range strings.Split(raw, "\n") {
+			candidates = append(candidates, trimListMarker(line))
+		}
+	}
+	seen := map[string]struct{}{normalizeQuery(original): {}}
+	out := make([]string, 0, max)
+	for _, q := range candidates {
+		q = strings.Join(strings.Fields(q), " ")
+		if q == "" || len(q) > 400 {
+			continue
+		}
+		key := normalizeQuery(q)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, q)
+		if len(out) == max {
+			break
+		}
+	}
+	return out
+}
+
+// maxHyDERunes caps the hypothetical passage that gets embedded.
+const maxHyDERunes = 2000
+
+// CleanHyDEPassage trims a hypothetical answer passage to something suitable for embedding.
+func CleanHyDEPassage(raw string) string {
+	s := strings.Join(strings.Fields(stripCodeFence(strings.TrimSpace(raw))), " ")
+	if r := []rune(s); len(r) > maxHyDERunes {
+		s = strings.TrimSpace(string(r[:maxHyDERunes]))
+	}
+	return s
+}
+
+func stripCodeFence(s string) string {
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```json")
+		s = strings.TrimPrefix(s, "```")
+		s = strings.TrimSuffix(s, "```")
+	}
+	return strings.TrimSpace(s)
+}
+
+func trimListMarker(line string) string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimLeft(line, "-*• ")
+	if i := strings.IndexAny(line, ".)"); i > 0 && i <= 3 {
+		if strings.Trim(line[:i], "0123456789") == "" {
+			line = line[i+1:]
+		}
+	}
+	return strings.Trim(strings.TrimSpace(line), `"`)
+}
+
+func normalizeQuery(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.Trim(s, " ?.!")), " "))
+}
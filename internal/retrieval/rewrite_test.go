@@ -0,0 +1,53 @@
+package retrieval
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNormalizeRewriteMode(t *testing.T) {
+	cases := map[string]string{
+		"":            RewriteNone,
+		"HyDE":        RewriteHyDE,
+		"multi-query": RewriteMultiQuery,
+		"all":         RewriteBoth,
+	}
+	for in, want := range cases {
+		got, err := NormalizeRewriteMode(in)
+		if err != nil || got != want {
+			t.Fatalf("mode %q: got %q err %v want %q", in, got, err, want)
+		}
+	}
+	if _, err := NormalizeRewriteMode("bogus"); err == nil {
+		t.Fatalf("expected error for unsupported mode")
+	}
+}
+
+func TestParseRewrittenQueries(t *testing.T) {
+	raw := "```json\n{\"queries\": [\"sparse attention long context\", \"What is sparse attention?\", \"sparse attention long context\", \"efficient transformers benchmarks\"]}\n```"
+	got := ParseRewrittenQueries(raw, "what is sparse attention", 3)
+	if len(got) != 2 || got[0] != "sparse attention long context" || got[1] != "efficient transformers benchmarks" {
+		t.Fatalf("unexpected queries: %#v", got)
+	}
+
+	lines := ParseRewrittenQueries("1. first query\n- second query\n\n", "q", 5)
+	if len(lines) != 2 || lines[0] != "first query" || lines[1] != "second query" {
+		t.Fatalf("unexpected line fallback: %#v", lines)
+	}
+}
+
+func TestCleanHyDEPassageTruncatesOnRunes(t *testing.T) {
+	got := CleanHyDEPassage("```\n" + strings.Repeat("é", maxHyDERunes+10) + "\n```")
+	if !utf8.ValidString(got) || utf8.RuneCountInString(got) != maxHyDERunes {
+		t.Fatalf("unexpected passage: %d runes, valid=%v", utf8.RuneCountInString(got), utf8.ValidString(got))
+	}
+}
+
+func TestFuseRanked(t *testing.T) {
+	id := func(s string) string { return s }
+	got := FuseRanked([][]string{{"a", "b", "c"}, {"b", "d"}}, id, 3)
+	if len(got) != 3 || got[0] != "b" || got[1] != "a" {
+		t.Fatalf("unexpected fusion order: %#v", got)
+	}
+}
@@ -0,0 +1,76 @@
+package retrieval
+
+import "strings"
+
+// minSuggestWords is the shortest sentence, in words, that gets citation suggestions;
+// shorter fragments such as headings match too loosely.
+const minSuggestWords = 4
+
+// PassageSentences splits pasted prose into sentences: paragraphs break at blank lines and
+// whitespace inside a paragraph, including hard line wraps, is collapsed first.
+func PassageSentences(text string) []string {
+	var out []string
+	for _, para := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if para = strings.Join(strings.Fields(para), " "); para != "" {
+			out = append(out, SplitSentences(para)...)
+		}
+	}
+	return out
+}
+
+// Suggestible reports whether a sentence is long enough to look up citations for.
+func Suggestible(sentence string) bool {
+	return len(strings.Fields(sentence)) >= minSuggestWords
+}
+
+// SuggestionHit is a retrieved chunk as seen by SuggestCitations. VectorScore is its cosine
+// similarity to the sentence, whichever ranking it came from.
+type SuggestionHit struct {
+	PaperID     string
+	ChunkID     string
+	Text        string
+	VectorScore float64
+}
+
+// CitationSuggestion is a candidate paper for one sentence with the chunk that ranked it.
+// SupportScore averages the vector score and the lexical support of the sentence by the
+// chunk, so a candidate must be both semantically close and share the sentence's terms.
+type CitationSuggestion struct {
+	PaperID      string  `json:"paper_id"`
+	ChunkID      string  `json:"chunk_id"`
+	VectorScore  float64 `json:"vector_score"`
+	LexicalScore float64 `json:"lexical_score"`
+	SupportScore float64 `json:"support_score"`
+}
+
+// SuggestCitations fuses the vector and full-text rankings for a sentence with reciprocal
+// rank fusion and returns up to topK papers in fused order, each represented by its best
+// ranked chunk. Papers whose support score falls below minSupport are left out.
+func SuggestCitations(sentence string, vectorHits, textHits []SuggestionHit, topK int, minSupport float64) []CitationSuggestion {
+	fused := FuseRanked([][]SuggestionHit{vectorHits, textHits}, func(h SuggestionHit) string { return h.ChunkID }, 0)
+	out := make([]CitationSuggestion, 0, topK)
+	seen := map[string]bool{}
+	for _, h := range fused {
+		if len(out) == topK {
+			break
+		}
+		if h.PaperID == "" || seen[h.PaperID] {
+			continue
+		}
+		lexical := LexicalSupport(sentence, h.Text)
+		support := (max(h.VectorScore, 0) + lexical) / 2
+		if support < minSupport {
+			// A later chunk of the same paper may still support the sentence.
+			continue
+		}
+		seen[h.PaperID] = true
+		out = append(out, CitationSuggestion{
+			PaperID:      h.PaperID,
+			ChunkID:      h.ChunkID,
+			VectorScore:  h.VectorScore,
+			LexicalScore: lexical,
+			SupportScore: support,
+		})
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package retrieval
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPassageSentences(t *testing.T) {
+	text := "Sparse attention reduces\nmemory on long inputs [1]. Does it scale?\r\n\r\nRelated Work\n\nRouting was learned before."
+	want := []string{
+		"Sparse attention reduces memory on long inputs [1].",
+		"Does it scale?",
+		"Related Work",
+		"Routing was learned before.",
+	}
+	if got := PassageSentences(text); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected sentences: %#v", got)
+	}
+	if Suggestible("Related Work") || !Suggestible("Routing was learned before.") {
+		t.Fatalf("unexpected suggestible verdicts")
+	}
+}
+
+func TestSuggestCitations(t *testing.T) {
+	sentence := "Sparse attention reduces memory on long sequences."
+	vectorHits := []SuggestionHit{
+		{PaperID: "p1", ChunkID: "p1-c1", Text: "Dense attention on images.", VectorScore: 0.7},
+		{PaperID: "p2", ChunkID: "p2-c4", Text: "Sparse attention reduces memory use on long sequences.", VectorScore: 0.6},
+		{PaperID: "p1", ChunkID: "p1-c2", Text: "Sparse attention for long sequences.", VectorScore: 0.5},
+	}
+	textHits := []SuggestionHit{
+		{PaperID: "p2", ChunkID: "p2-c4", Text: "Sparse attention reduces memory use on long sequences.", VectorScore: 0.6},
+		{PaperID: "p3", ChunkID: "p3-c1", Text: "Memory of long sequences.", VectorScore: 0.1},
+	}
+
+	got := SuggestCitations(sentence, vectorHits, textHits, 2, 0)
+	if len(got) != 2 {
+		t.Fatalf("expected two papers, got %#v", got)
+	}
+	// p2 ranks first in the full-text list and second by vector, so fusion puts it first.
+	if got[0].PaperID != "p2" || got[0].ChunkID != "p2-c4" || got[1].PaperID != "p1" || got[1].ChunkID != "p1-c1" {
+		t.Fatalf("unexpected ranking: %#v", got)
+	}
+	if got[0].LexicalScore != 1 || got[0].SupportScore != 0.8 {
+		t.Fatalf("unexpected scores for p2: %#v", got[0])
+	}
+
+	filtered := SuggestCitations(sentence, vectorHits, textHits, 3, 0.5)
+	for _, s := range filtered {
+		if s.SupportScore < 0.5 {
+			t.Fatalf("suggestion below min support: %#v", s)
+		}
+	}
+	// p1's best-fused chunk falls below the threshold, so its next chunk stands in for it.
+	if len(filtered) != 2 || filtered[0].PaperID != "p2" || filtered[1].PaperID != "p1" || filtered[1].ChunkID != "p1-c2" {
+		t.Fatalf("unexpected filtered suggestions: %#v", filtered)
+	}
+}
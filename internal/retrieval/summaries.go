@@ -0,0 +1,59 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// BuildCitationSummaryPrompt asks for every citation summary in one structured completion.
+// The evidence snippets are passed as context and are labelled with their citation IDs.
+func BuildCitationSummaryPrompt(question string, refIDs []string) string {
+	return strings.Join([]string{
+		"Question: " + strings.TrimSpace(question),
+		"",
+		"For each evidence snippet below (labelled " + strings.Join(refIDs, ", ") + "), write exactly two short sentences:",
+		"1) what this citation supports for the question",
+		"2) one caveat or limitation.",
+		"Use plain language and do not include citation ids inside the summaries.",
+		"",
+		`Output STRICT JSON keyed by citation ID: {"summaries": {"C1": "...", "C2": "..."}}`,
+	}, "\n")
+}
+
+// ParseCitationSummaries reads a batch summary completion. It accepts the requested
+// {"summaries": {...}} shape or a bare object keyed by citation ID, and keeps only known,
+// non-empty entries. A nil map means the output was unusable.
+func ParseCitationSummaries(raw string, refIDs []string) map[string]string {
+	raw = stripCodeFence(strings.TrimSpace(raw))
+	var wrapped struct {
+		Summaries map[string]string `json:"summaries"`
+	}
+	summaries := map[string]string(nil)
+	if err := json.Unmarshal([]byte(raw), &wrapped); err == nil && len(wrapped.Summaries) > 0 {
+		summaries = wrapped.Summaries
+	} else {
+		var bare map[string]string
+		if err := json.Unmarshal([]byte(raw), &bare); err != nil {
+			return nil
+		}
+		summaries = bare
+	}
+	known := make(map[string]string, len(refIDs))
+	for _, id := range refIDs {
+		known[strings.ToUpper(id)] = id
+	}
+	out := make(map[string]string, len(summaries))
+	for k, v := range summaries {
+		key := strings.ToUpper(strings.Trim(strings.TrimSpace(k), "[]"))
+		id, ok := known[key]
+		v = strings.Join(strings.Fields(v), " ")
+		if !ok || v == "" {
+			continue
+		}
+		out[id] = v
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
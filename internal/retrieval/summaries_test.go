@@ -0,0 +1,17 @@
+package retrieval
+
+import "testing"
+
+func TestParseCitationSummaries(t *testing.T) {
+	refs := []string{"C1", "C2", "C3"}
+	got := ParseCitationSummaries("```json\n{\"summaries\": {\"C1\": \"Supports the claim.  Small sample.\", \"[c2]\": \"Second.\", \"C7\": \"unknown\", \"C3\": \"  \"}}\n```", refs)
+	if len(got) != 2 || got["C1"] != "Supports the claim. Small sample." || got["C2"] != "Second." {
+		t.Fatalf("unexpected summaries: %#v", got)
+	}
+	if bare := ParseCitationSummaries(`{"C3": "Bare shape."}`, refs); bare["C3"] != "Bare shape." {
+		t.Fatalf("unexpected bare summaries: %#v", bare)
+	}
+	if ParseCitationSummaries("Mock response.", refs) != nil {
+		t.Fatalf("expected nil for unusable output")
+	}
+}
@@ -0,0 +1,358 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	VerifyLexical = "lexical"
+	VerifyLLM     = "llm"
+	VerifyNone    = "none"
+)
+
+const (
+	FlagInvalidCitation = "invalid_citation"
+	FlagUncitedClaim    = "uncited_claim"
+	FlagUnsupported     = "unsupported"
+)
+
+// DefaultSupportThreshold is the minimum support score for a cited sentence to count as grounded.
+const DefaultSupportThreshold = 0.5
+
+var (
+	citationRefPattern  = regexp.MustCompile(`\[C(\d+)\]`)
+	trailingRefsPattern = regexp.MustCompile(`^(\s*\[C\d+\])+`)
+)
+
+// NormalizeVerifyMode maps user input to a supported verification mode; empty means lexical.
+func NormalizeVerifyMode(mode string) (string, error) {
+	switch m := strings.ToLower(strings.TrimSpace(mode)); m {
+	case "", VerifyLexical:
+		return VerifyLexical, nil
+	case VerifyLLM, "entailment":
+		return VerifyLLM, nil
+	case VerifyNone, "off":
+		return VerifyNone, nil
+	default:
+		return "", fmt.Errorf("unsupported verify mode: %s", mode)
+	}
+}
+
+// Claim is one answer sentence together with the citation numbers it carries.
+type Claim struct {
+	Index     int
+	Text      string
+	Citations []int
+}
+
+// SentenceVerification is the verifier's verdict for one answer sentence. Support is the best
+// score across the sentence's valid citations; Flags is empty for a grounded sentence.
+type SentenceVerification struct {
+	Index            int      `json:"index"`
+	Text             string   `json:"text"`
+	Citations        []string `json:"citations"`
+	InvalidCitations []string `json:"invalid_citations,omitempty"`
+	Support          float64  `json:"support"`
+	SupportedBy      []string `json:"supported_by,omitempty"`
+	Supported        bool     `json:"supported"`
+	Flags            []string `json:"flags,omitempty"`
+}
+
+// AnswerVerification summarizes how well an answer is grounded in its evidence snippets.
+// GroundingScore is the mean support over every claim sentence, counting uncited claims as zero.
+type AnswerVerification struct {
+	Method           string                 `json:"method"`
+	GroundingScore   float64                `json:"grounding_score"`
+	Claims           int                    `json:"claims"`
+	Supported        int                    `json:"supported"`
+	Uncited          int                    `json:"uncited"`
+	InvalidCitations int                    `json:"invalid_citations"`
+	Sentences        []SentenceVerification `json:"sentences"`
+}
+
+// SupportFunc scores how well evidence supports claim, in [0,1].
+type SupportFunc func(claim, evidence string) float64
+
+// ExtractClaims splits a Markdown answer into sentences, skipping headings and the legacy
+// self-reported Confidence section. Citation markers stay attached to the sentence they end.
+func ExtractClaims(answer string) []Claim {
+	answer = StripConfidenceSection(answer)
+	var claims []Claim
+	for _, line := range strings.Split(answer, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = trimListMarker(line)
+		for _, sentence := range SplitSentences(line) {
+			refs := citationRefPattern.FindAllStringSubmatch(sentence, -1)
+			text := strings.TrimSpace(citationRefPattern.ReplaceAllString(sentence, ""))
+			if len(contentTokens(text)) < 3 && len(refs) == 0 {
+				continue
+			}
+			c := Claim{Index: len(claims) + 1, Text: strings.TrimSpace(sentence)}
+			for _, m := range refs {
+				if n, err := strconv.Atoi(m[1]); err == nil {
+					c.Citations = appendUniqueInt(c.Citations, n)
+				}
+			}
+			claims = append(claims, c)
+		}
+	}
+	return claims
+}
+
+// StripConfidenceSection removes a "## Confidence" section (up to the next heading). The
+// grounding score computed from evidence replaces the model's own confidence statement.
+func StripConfidenceSection(answer string) string {
+	lines := strings.Split(answer, "\n")
+	out := make([]string, 0, len(lines))
+	skipping := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			heading := strings.ToLower(strings.TrimSpace(strings.TrimLeft(trimmed, "#")))
+			skipping = heading == "confidence"
+			if skipping {
+				continue
+			}
+		}
+		if !skipping {
+			out = append(out, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// VerifyClaims checks every claim against the evidence snippets (evidence[i] is [C(i+1)]).
+// Out-of-range citation numbers are flagged and ignored for support. judged, when non-nil,
+// carries per-claim scores from an entailment pass and overrides support for those claims.
+func VerifyClaims(claims []Claim, evidence []string, support SupportFunc, judged map[int]float64, threshold float64, method string) AnswerVerification {
+	if support == nil {
+		support = LexicalSupport
+	}
+	if threshold <= 0 {
+		threshold = DefaultSupportThreshold
+	}
+	v := AnswerVerification{Method: method, Sentences: make([]SentenceVerification, 0, len(claims))}
+	total := 0.0
+	for _, c := range claims {
+		sv := SentenceVerification{Index: c.Index, Text: c.Text, Citations: []string{}}
+		valid := make([]int, 0, len(c.Citations))
+		for _, n := range c.Citations {
+			ref := fmt.Sprintf("C%d", n)
+			sv.Citations = append(sv.Citations, ref)
+			if n < 1 || n > len(evidence) {
+				sv.InvalidCitations = append(sv.InvalidCitations, ref)
+				continue
+			}
+			valid = append(valid, n)
+		}
+		if len(sv.InvalidCitations) > 0 {
+			sv.Flags = append(sv.Flags, FlagInvalidCitation)
+			v.InvalidCitations += len(sv.InvalidCitations)
+		}
+		switch {
+		case len(c.Citations) == 0:
+			sv.Flags = append(sv.Flags, FlagUncitedClaim)
+			v.Uncited++
+		case len(valid) > 0:
+			plain := strings.TrimSpace(citationRefPattern.ReplaceAllString(c.Text, ""))
+			for _, n := range valid {
+				score := support(plain, evidence[n-1])
+				if score >= threshold {
+					sv.SupportedBy = append(sv.SupportedBy, fmt.Sprintf("C%d", n))
+				}
+				if score > sv.Support {
+					sv.Support = score
+				}
+			}
+			if js, ok := judged[c.Index]; ok {
+				sv.Support = js
+				if js < threshold {
+					sv.SupportedBy = nil
+				}
+			}
+			sv.Supported = sv.Support >= threshold
+		}
+		if len(c.Citations) > 0 && !sv.Supported {
+			sv.Flags = append(sv.Flags, FlagUnsupported)
+		}
+		if sv.Supported {
+			v.Supported++
+		}
+		sv.Support = roundScore(sv.Support)
+		total += sv.Support
+		v.Sentences = append(v.Sentences, sv)
+	}
+	v.Claims = len(claims)
+	if v.Claims > 0 {
+		v.GroundingScore = roundScore(total / float64(v.Claims))
+	}
+	return v
+}
+
+// LexicalSupport is the fraction of the claim's content words that appear in the evidence,
+// with a light suffix match so "transformers" is supported by "transformer".
+func LexicalSupport(claim, evidence string) float64 {
+	claimTokens := dedupe(contentTokens(claim))
+	if len(claimTokens) == 0 {
+		return 0
+	}
+	have := make(map[string]bool)
+	for _, t := range contentTokens(evidence) {
+		have[t] = true
+		have[stemToken(t)] = true
+	}
+	hit := 0
+	for _, t := range claimTokens {
+		if have[t] || have[stemToken(t)] {
+			hit++
+		}
+	}
+	return float64(hit) / float64(len(claimTokens))
+}
+
+// BuildEntailmentPrompt asks the model to judge, for each cited claim, whether the cited
+// snippets entail it. Snippets are passed as context in citation order.
+func BuildEntailmentPrompt(claims []Claim) string {
+	lines := []string{
+		"You verify whether evidence snippets support claims from an answer.",
+		"For each numbered claim, decide whether the snippets it cites (by [C#], in the provided context order) entail the claim.",
+		"Score 1.0 when fully entailed, 0.5 when partially supported, 0.0 when unsupported or contradicted.",
+		"Judge only against the snippets; ignore outside knowledge.",
+		"",
+		`Output STRICT JSON: {"judgments": [{"claim": 1, "score": 0.0}]}`,
+		"",
+		"Claims:",
+	}
+	for _, c := range claims {
+		if len(c.Citations) == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s", c.Index, c.Text))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParseEntailmentJudgments reads per-claim scores from an entailment completion. It returns
+// nil when the output is unusable so callers fall back to lexical support.
+func ParseEntailmentJudgments(raw string) map[int]float64 {
+	raw = stripCodeFence(strings.TrimSpace(raw))
+	var payload struct {
+		Judgments []struct {
+			Claim int     `json:"claim"`
+			Score float64 `json:"score"`
+		} `json:"judgments"`
+	}
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil || len(payload.Judgments) == 0 {
+		return nil
+	}
+	out := make(map[int]float64, len(payload.Judgments))
+	for _, j := range payload.Judgments {
+		if j.Claim <= 0 {
+			continue
+		}
+		score := j.Score
+		if score < 0 {
+			score = 0
+		}
+		if score > 1 {
+			score = 1
+		}
+		out[j.Claim] = score
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// SplitSentences breaks a line after ., ! or ? followed by whitespace, keeping any citation
+// markers that directly follow the punctuation with the sentence they close.
+func SplitSentences(line string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(line); i++ {
+		if line[i] != '.' && line[i] != '!' && line[i] != '?' {
+			continue
+		}
+		end := i + 1
+		if loc := trailingRefsPattern.FindStringIndex(line[end:]); loc != nil {
+			end += loc[1]
+		}
+		if end < len(line) && line[end] != ' ' && line[end] != '\t' {
+			continue
+		}
+		if s := strings.TrimSpace(line[start:end]); s != "" {
+			out = append(out, s)
+		}
+		start = end
+		i = end - 1
+	}
+	if s := strings.TrimSpace(line[start:]); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+var verifyStopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "that": true, "this": true, "with": true, "are": true,
+	"was": true, "were": true, "from": true, "which": true, "has": true, "have": true, "its": true,
+	"can": true, "into": true, "than": true, "also": true, "such": true, "these": true, "those": true,
+	"their": true, "they": true, "but": true, "not": true, "been": true, "more": true, "most": true,
+	"use": true, "uses": true, "used": true, "using": true, "based": true, "while": true, "when": true,
+}
+
+func contentTokens(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if len(f) < 3 && !hasDigit(f) {
+			continue
+		}
+		if verifyStopwords[f] {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func stemToken(t string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if len(t) > len(suffix)+3 && strings.HasSuffix(t, suffix) {
+			return strings.TrimSuffix(t, suffix)
+		}
+	}
+	return t
+}
+
+func hasDigit(s string) bool {
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUniqueInt(xs []int, n int) []int {
+	for _, x := range xs {
+		if x == n {
+			return xs
+		}
+	}
+	return append(xs, n)
+}
+
+func roundScore(f float64) float64 {
+	return float64(int(f*1000+0.5)) / 1000
+}
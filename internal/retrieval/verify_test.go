@@ -0,0 +1,68 @@
+package retrieval
+
+import "testing"
+
+func TestExtractClaims(t *testing.T) {
+	answer := "## Direct Answer\n" +
+		"- Sparse attention reduces memory for long sequences. [C1][C2] It was evaluated on WikiText-103 [C3].\n" +
+		"Transformers replaced recurrence entirely.\n\n" +
+		"## Confidence\n- High confidence because the snippets agree."
+	claims := ExtractClaims(answer)
+	if len(claims) != 3 {
+		t.Fatalf("expected 3 claims, got %#v", claims)
+	}
+	if len(claims[0].Citations) != 2 || claims[0].Citations[0] != 1 || claims[0].Citations[1] != 2 {
+		t.Fatalf("unexpected citations on first claim: %#v", claims[0])
+	}
+	if len(claims[1].Citations) != 1 || claims[1].Citations[0] != 3 {
+		t.Fatalf("unexpected citations on second claim: %#v", claims[1])
+	}
+	if len(claims[2].Citations) != 0 {
+		t.Fatalf("expected uncited third claim: %#v", claims[2])
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	evidence := []string{
+		"We show that sparse attention reduces memory usage on long sequences.",
+		"Results on ImageNet classification with convolutional networks.",
+	}
+	claims := ExtractClaims("Sparse attention reduces memory on long sequences [C1]. " +
+		"Convolutional networks dominate speech recognition benchmarks [C2]. " +
+		"The method also improves translation quality [C9]. " +
+		"Nobody evaluated the approach on protein folding.")
+	v := VerifyClaims(claims, evidence, nil, nil, 0, VerifyLexical)
+	if v.Claims != 4 || v.Supported != 1 || v.Uncited != 1 || v.InvalidCitations != 1 {
+		t.Fatalf("unexpected summary: %+v", v)
+	}
+	if !v.Sentences[0].Supported || len(v.Sentences[0].Flags) != 0 {
+		t.Fatalf("expected first sentence supported: %+v", v.Sentences[0])
+	}
+	if v.Sentences[1].Supported || v.Sentences[1].Flags[0] != FlagUnsupported {
+		t.Fatalf("expected second sentence unsupported: %+v", v.Sentences[1])
+	}
+	if v.Sentences[2].Flags[0] != FlagInvalidCitation || v.Sentences[2].InvalidCitations[0] != "C9" {
+		t.Fatalf("expected out-of-range flag: %+v", v.Sentences[2])
+	}
+	if v.Sentences[3].Flags[0] != FlagUncitedClaim {
+		t.Fatalf("expected uncited flag: %+v", v.Sentences[3])
+	}
+	if v.GroundingScore <= 0 || v.GroundingScore >= 1 {
+		t.Fatalf("unexpected grounding score %v", v.GroundingScore)
+	}
+
+	judged := VerifyClaims(claims, evidence, nil, map[int]float64{2: 1}, 0, VerifyLLM)
+	if !judged.Sentences[1].Supported || judged.Supported != 2 {
+		t.Fatalf("expected entailment judgment to override lexical support: %+v", judged.Sentences[1])
+	}
+}
+
+func TestParseEntailmentJudgments(t *testing.T) {
+	got := ParseEntailmentJudgments("```json\n{\"judgments\": [{\"claim\": 1, \"score\": 0.9}, {\"claim\": 2, \"score\": 3}]}\n```")
+	if got[1] != 0.9 || got[2] != 1 {
+		t.Fatalf("unexpected judgments: %#v", got)
+	}
+	if ParseEntailmentJudgments("not json") != nil {
+		t.Fatalf("expected nil for unusable output")
+	}
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"litflow/internal/models"
+)
+
+type AskLogRepo struct {
+	db *DB
+}
+
+func NewAskLogRepo(db *DB) *AskLogRepo {
+	return &AskLogRepo{db: db}
+}
+
+// Insert stores one answered question. filters, queries and citations are marshaled as JSONB.
+func (r *AskLogRepo) Insert(ctx context.Context, rec models.AskRecord, filters, queries, citations any) error {
+	filtersJSON, _ := json.Marshal(filters)
+	queriesJSON, _ := json.Marshal(queries)
+	citationsJSON, _ := json.Marshal(citations)
+	_, err := r.db.Pool.Exec(ctx, `
+INSERT INTO ask_log (ask_id, corpus_id, corpus_ids, session_id, replay_of, question, standalone_query, filters, rewritten_queries,
+                     embed_provider, embed_model, embed_version, citations, prompt_version, llm_provider, llm_model, answer, grounding_score)
+VALUES ($1, $2, $3::uuid[], NULLIF($4, '')::uuid, NULLIF($5, '')::uuid, $6, $7, $8::jsonb, $9::jsonb,
+        NULLIF($10, ''), NULLIF($11, ''), $12, $13::jsonb, $14, NULLIF($15, ''), NULLIF($16, ''), $17, $18)`,
+		rec.AskID, rec.CorpusID, nonNilStrings(rec.CorpusIDs), rec.SessionID, rec.ReplayOf, rec.Question, rec.StandaloneQuery,
+		string(filtersJSON), string(queriesJSON), rec.EmbedProvider, rec.EmbedModel, rec.EmbedVersion, string(citationsJSON),
+		rec.PromptVersion, rec.LLMProvider, rec.LLMModel, rec.Answer, rec.GroundingScore)
+	if err != nil {
+		return fmt.Errorf("insert ask log: %w", err)
+	}
+	return nil
+}
+
+// List returns recent answers for a corpus, newest first, without citations or answer text.
+func (r *AskLogRepo) List(ctx context.Context, corpusID string, limit int) ([]models.AskRecord, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	rows, err := r.db.Pool.Query(ctx, `
+SELECT ask_id::text, corpus_id::text, corpus_ids::text[], COALESCE(session_id::text, ''), COALESCE(replay_of::text, ''),
+       question, standalone_query, filters, rewritten_queries, COALESCE(embed_provider, ''), COALESCE(embed_model, ''), embed_version,
+       prompt_version, COALESCE(llm_provider, ''), COALESCE(llm_model, ''), grounding_score, created_at
+FROM ask_log
+WHERE corpus_id = $1
+ORDER BY created_at DESC
+LIMIT $2`, corpusID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list ask log: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]models.AskRecord, 0)
+	for rows.Next() {
+		var rec models.AskRecord
+		if err := rows.Scan(&rec.AskID, &rec.CorpusID, &rec.CorpusIDs, &rec.SessionID, &rec.ReplayOf, &rec.Question, &rec.StandaloneQuery,
+			&rec.Filters, &rec.RewrittenQueries, &rec.EmbedProvider, &rec.EmbedModel, &rec.EmbedVersion,
+			&rec.PromptVersion, &rec.LLMProvider, &rec.LLMModel, &rec.GroundingScore, &rec.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan ask log: %w", err)
+		}
+		out = append(out, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate ask log: %w", err)
+	}
+	return out, nil
+}
+
+func (r *AskLogRepo) Get(ctx context.Context, askID string) (models.AskRecord, error) {
+	var rec models.AskRecord
+	err := r.db.Pool.QueryRow(ctx, `
+SELECT ask_id::text, corpus_id::text, corpus_ids::text[], COALESCE(session_id::text, ''), COALESCE(replay_of::text, ''),
+       question, standalone_query, filters, rewritten_queries, COALESCE(embed_provider, ''), COALESCE(embed_model, ''), embed_version,
+       citations, prompt_version, COALESCE(llm_provider, ''), COALESCE(llm_model, ''), answer, grounding_score, created_at
+FROM ask_log
+WHERE ask_id = $1`, askID).Scan(&rec.AskID, &rec.CorpusID, &rec.CorpusIDs, &rec.SessionID, &rec.ReplayOf, &rec.Question, &rec.StandaloneQuery,
+		&rec.Filters, &rec.RewrittenQueries, &rec.EmbedProvider, &rec.EmbedModel, &rec.EmbedVersion,
+		&rec.Citations, &rec.PromptVersion, &rec.LLMProvider, &rec.LLMModel, &rec.Answer, &rec.GroundingScore, &rec.CreatedAt)
+	if err != nil {
+		return models.AskRecord{}, fmt.Errorf("get ask log: %w", err)
+	}
+	return rec, nil
+}
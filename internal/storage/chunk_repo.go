@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"litflow/internal/models"
+	"litflow/internal/retrieval"
 )
 
 type ChunkRecord struct {
@@ -13,6 +14,7 @@ type ChunkRecord struct {
 	CorpusID         string
 	ChunkIndex       int
 	Text             string
+	ChunkVersion     string
 	EmbeddingVersion string
 	EmbeddingVector  *string
 }
@@ -39,14 +41,15 @@ func (r *ChunkRepo) UpsertChunks(ctx context.Context, chunks []ChunkRecord) erro
 
 	for _, c := range chunks {
 		_, err := tx.Exec(ctx, `
-INSERT INTO chunks (chunk_id, paper_id, corpus_id, chunk_index, text, embedding_version, embedding)
-VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $7::text IS NULL THEN NULL ELSE $7::vector END)
+INSERT INTO chunks (chunk_id, paper_id, corpus_id, chunk_index, text, chunk_version, embedding_version, embedding)
+VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6, ''), 'v1'), $7, CASE WHEN $8::text IS NULL THEN NULL ELSE $8::vector END)
 ON CONFLICT (chunk_id)
 DO UPDATE SET
   text = EXCLUDED.text,
+  chunk_version = EXCLUDED.chunk_version,
   embedding_version = EXCLUDED.embedding_version,
   embedding = COALESCE(EXCLUDED.embedding, chunks.embedding)`,
-			c.ChunkID, c.PaperID, c.CorpusID, c.ChunkIndex, c.Text, c.EmbeddingVersion, c.EmbeddingVector,
+			c.ChunkID, c.PaperID, c.CorpusID, c.ChunkIndex, c.Text, c.ChunkVersion, c.EmbeddingVersion, c.EmbeddingVector,
 		)
 		if err != nil {
 			return fmt.Errorf("upsert chunk %s: %w", c.ChunkID, err)
@@ -81,3 +84,75 @@ ORDER BY chunk_index ASC`, corpusID, paperID)
 	}
 	return out, nil
 }
+
+// ChunkAnchor identifies a retrieved chunk whose neighbors should be loaded.
+type ChunkAnchor struct {
+	PaperID    string
+	ChunkIndex int
+	Section    string
+}
+
+// ListNeighborChunks returns chunks within window positions of each anchor, restricted to
+// the anchor's paper and section. Rows are unique per chunk and ordered by paper and index.
+func (r *ChunkRepo) ListNeighborChunks(ctx context.Context, corpusIDs []string, anchors []ChunkAnchor, window int) ([]models.Chunk, error) {
+	if len(anchors) == 0 || window <= 0 {
+		return []models.Chunk{}, nil
+	}
+	paperIDs := make([]string, 0, len(anchors))
+	indexes := make([]int32, 0, len(anchors))
+	sections := make([]string, 0, len(anchors))
+	for _, a := range anchors {
+		paperIDs = append(paperIDs, a.PaperID)
+		indexes = append(indexes, int32(a.ChunkIndex))
+		sections = append(sections, a.Section)
+	}
+	rows, err := r.db.Pool.Query(ctx, `
+SELECT DISTINCT c.chunk_id, c.paper_id, c.corpus_id::text, c.chunk_index, c.text, COALESCE(c.section,''), c.embedding_version, c.created_at
+FROM chunks c
+JOIN unnest($2::text[], $3::int[], $4::text[]) AS a(paper_id, chunk_index, section)
+  ON c.paper_id = a.paper_id
+ AND c.chunk_index BETWEEN a.chunk_index - $5 AND a.chunk_index + $5
+ AND COALESCE(c.section,'') = a.section
+WHERE c.corpus_id = ANY($1::uuid[])
+ORDER BY c.paper_id, c.chunk_index`, corpusIDs, paperIDs, indexes, sections, window)
+	if err != nil {
+		return nil, fmt.Errorf("list neighbor chunks: %w", err)
+	}
+	defer rows.Close()
+	out := make([]models.Chunk, 0, len(anchors)*(2*window+1))
+	for rows.Next() {
+		var c models.Chunk
+		if err := rows.Scan(&c.ChunkID, &c.PaperID, &c.CorpusID, &c.ChunkIndex, &c.Text, &c.Section, &c.EmbeddingVersion, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan neighbor chunk: %w", err)
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate neighbor chunks: %w", err)
+	}
+	return out, nil
+}
+
+// ExpandHitContext loads the neighbors of each hit and grows the hits into context windows
+// of up to window chunks per side, trimmed to the token budget. The result is keyed by hit
+// chunk ID; hits dropped by the budget are absent.
+func (r *ChunkRepo) ExpandHitContext(ctx context.Context, corpusIDs []string, hits []retrieval.ChunkRef, window, budget int) (map[string]retrieval.ExpandedHit, error) {
+	anchors := make([]ChunkAnchor, 0, len(hits))
+	for _, h := range hits {
+		anchors = append(anchors, ChunkAnchor{PaperID: h.PaperID, ChunkIndex: h.ChunkIndex, Section: h.Section})
+	}
+	neighbors, err := r.ListNeighborChunks(ctx, corpusIDs, anchors, window)
+	if err != nil {
+		return nil, err
+	}
+	neighborRefs := make([]retrieval.ChunkRef, 0, len(neighbors))
+	for _, n := range neighbors {
+		neighborRefs = append(neighborRefs, retrieval.ChunkRef{ChunkID: n.ChunkID, PaperID: n.PaperID, ChunkIndex: n.ChunkIndex, Section: n.Section, Text: n.Text})
+	}
+	expanded := retrieval.ApplyTokenBudget(retrieval.ExpandHits(hits, neighborRefs, window), budget)
+	byID := make(map[string]retrieval.ExpandedHit, len(expanded))
+	for _, e := range expanded {
+		byID[e.HitChunkID] = e
+	}
+	return byID, nil
+}
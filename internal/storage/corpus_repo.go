@@ -43,3 +43,116 @@ func (r *CorpusRepo) ListCorpora(ctx context.Context) ([]models.Corpus, error) {
 	}
 	return out, nil
 }
+
+func (r *CorpusRepo) CreateGroup(ctx context.Context, group models.CorpusGroup) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin corpus group tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if _, err := tx.Exec(ctx, `INSERT INTO corpus_groups (group_id, name) VALUES ($1, $2)`, group.GroupID, group.Name); err != nil {
+		return fmt.Errorf("insert corpus group: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+INSERT INTO corpus_group_members (group_id, corpus_id)
+SELECT $1, unnest($2::uuid[])
+ON CONFLICT DO NOTHING`, group.GroupID, group.CorpusIDs); err != nil {
+		return fmt.Errorf("insert corpus group members: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit corpus group: %w", err)
+	}
+	return nil
+}
+
+func (r *CorpusRepo) ListGroups(ctx context.Context) ([]models.CorpusGroup, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+SELECT g.group_id::text, g.name, g.created_at,
+       COALESCE(array_agg(m.corpus_id::text ORDER BY m.corpus_id) FILTER (WHERE m.corpus_id IS NOT NULL), '{}')
+FROM corpus_groups g
+LEFT JOIN corpus_group_members m ON m.group_id = g.group_id
+GROUP BY g.group_id, g.name, g.created_at
+ORDER BY g.name`)
+	if err != nil {
+		return nil, fmt.Errorf("list corpus groups: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]models.CorpusGroup, 0)
+	for rows.Next() {
+		var g models.CorpusGroup
+		if err := rows.Scan(&g.GroupID, &g.Name, &g.CreatedAt, &g.CorpusIDs); err != nil {
+			return nil, fmt.Errorf("scan corpus group: %w", err)
+		}
+		out = append(out, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate corpus groups: %w", err)
+	}
+	return out, nil
+}
+
+// GetGroup resolves a corpus group by name or by group ID.
+func (r *CorpusRepo) GetGroup(ctx context.Context, nameOrID string) (models.CorpusGroup, error) {
+	var g models.CorpusGroup
+	err := r.db.Pool.QueryRow(ctx, `
+SELECT g.group_id::text, g.name, g.created_at,
+       COALESCE(array_agg(m.corpus_id::text ORDER BY m.corpus_id) FILTER (WHERE m.corpus_id IS NOT NULL), '{}')
+FROM corpus_groups g
+LEFT JOIN corpus_group_members m ON m.group_id = g.group_id
+WHERE g.name = $1 OR g.group_id::text = $1
+GROUP BY g.group_id, g.name, g.created_at`, nameOrID).Scan(&g.GroupID, &g.Name, &g.CreatedAt, &g.CorpusIDs)
+	if err != nil {
+		return models.CorpusGroup{}, fmt.Errorf("get corpus group %q: %w", nameOrID, err)
+	}
+	return g, nil
+}
+
+// ExistingCorpusIDs returns the subset of ids that name existing corpora.
+func (r *CorpusRepo) ExistingCorpusIDs(ctx context.Context, ids []string) ([]string, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT corpus_id::text FROM corpora WHERE corpus_id = ANY($1::uuid[])`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("lookup corpora: %w", err)
+	}
+	defer rows.Close()
+	out := make([]string, 0, len(ids))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan corpus id: %w", err)
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate corpus ids: %w", err)
+	}
+	return out, nil
+}
+
+// EmbeddingVersions returns the distinct embedding versions of the embedded chunks in each
+// of the given corpora. Corpora without embedded chunks are absent.
+func (r *CorpusRepo) EmbeddingVersions(ctx context.Context, ids []string) (map[string][]string, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+SELECT DISTINCT corpus_id::text, embedding_version
+FROM chunks
+WHERE corpus_id = ANY($1::uuid[]) AND embedding IS NOT NULL
+ORDER BY 1, 2`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list corpus embedding versions: %w", err)
+	}
+	defer rows.Close()
+	out := map[string][]string{}
+	for rows.Next() {
+		var id, version string
+		if err := rows.Scan(&id, &version); err != nil {
+			return nil, fmt.Errorf("scan corpus embedding version: %w", err)
+		}
+		out[id] = append(out[id], version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate corpus embedding versions: %w", err)
+	}
+	return out, nil
+}
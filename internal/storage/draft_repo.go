@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"litflow/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type DraftRepo struct {
+	db *DB
+}
+
+func NewDraftRepo(db *DB) *DraftRepo {
+	return &DraftRepo{db: db}
+}
+
+func (r *DraftRepo) CreateDraft(ctx context.Context, d models.Draft) error {
+	_, err := r.db.Pool.Exec(ctx, `
+INSERT INTO drafts (draft_id, corpus_id, filename, format, file_path)
+VALUES ($1, $2, $3, $4, $5)`, d.DraftID, d.CorpusID, d.Filename, d.Format, d.FilePath)
+	if err != nil {
+		return fmt.Errorf("create draft: %w", err)
+	}
+	return nil
+}
+
+func (r *DraftRepo) GetDraft(ctx context.Context, corpusID, draftID string) (models.Draft, error) {
+	var d models.Draft
+	if err := r.db.Pool.QueryRow(ctx, `
+SELECT draft_id::text, corpus_id::text, filename, format, file_path, created_at
+FROM drafts
+WHERE corpus_id = $1 AND draft_id = $2`, corpusID, draftID).Scan(&d.DraftID, &d.CorpusID, &d.Filename, &d.Format, &d.FilePath, &d.CreatedAt); err != nil {
+		return models.Draft{}, fmt.Errorf("get draft: %w", err)
+	}
+	return d, nil
+}
+
+func (r *DraftRepo) ListDrafts(ctx context.Context, corpusID string) ([]models.Draft, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+SELECT draft_id::text, corpus_id::text, filename, format, file_path, created_at
+FROM drafts
+WHERE corpus_id = $1
+ORDER BY created_at DESC`, corpusID)
+	if err != nil {
+		return nil, fmt.Errorf("list drafts: %w", err)
+	}
+	defer rows.Close()
+	out := make([]models.Draft, 0)
+	for rows.Next() {
+		var d models.Draft
+		if err := rows.Scan(&d.DraftID, &d.CorpusID, &d.Filename, &d.Format, &d.FilePath, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan draft: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate drafts: %w", err)
+	}
+	return out, nil
+}
+
+func (r *DraftRepo) CreateRun(ctx context.Context, runID, draftID, corpusID string, config any) error {
+	cfgJSON, _ := json.Marshal(config)
+	_, err := r.db.Pool.Exec(ctx, `
+INSERT INTO positioning_runs (positioning_run_id, draft_id, corpus_id, status, config)
+VALUES ($1, $2, $3, 'pending', $4::jsonb)`, runID, draftID, corpusID, string(cfgJSON))
+	if err != nil {
+		return fmt.Errorf("create positioning run: %w", err)
+	}
+	return nil
+}
+
+func (r *DraftRepo) UpdateRun(ctx context.Context, runID, status, outPath, lastError string) error {
+	_, err := r.db.Pool.Exec(ctx, `
+UPDATE positioning_runs
+SET status = $2,
+    out_path = COALESCE(NULLIF($3, ''), out_path),
+    last_error = NULLIF($4, ''),
+    updated_at = NOW()
+WHERE positioning_run_id = $1`, runID, status, outPath, lastError)
+	if err != nil {
+		return fmt.Errorf("update positioning run: %w", err)
+	}
+	return nil
+}
+
+func (r *DraftRepo) GetRun(ctx context.Context, draftID, runID string) (models.PositioningRun, error) {
+	rows, err := r.db.Pool.Query(ctx, positioningRunSelect+` WHERE draft_id = $1 AND positioning_run_id = $2`, draftID, runID)
+	if err != nil {
+		return models.PositioningRun{}, fmt.Errorf("get positioning run: %w", err)
+	}
+	runs, err := scanPositioningRuns(rows)
+	if err != nil {
+		return models.PositioningRun{}, err
+	}
+	if len(runs) == 0 {
+		return models.PositioningRun{}, fmt.Errorf("positioning run not found: %s", runID)
+	}
+	return runs[0], nil
+}
+
+func (r *DraftRepo) ListRuns(ctx context.Context, draftID string) ([]models.PositioningRun, error) {
+	rows, err := r.db.Pool.Query(ctx, positioningRunSelect+` WHERE draft_id = $1 ORDER BY created_at DESC`, draftID)
+	if err != nil {
+		return nil, fmt.Errorf("list positioning runs: %w", err)
+	}
+	return scanPositioningRuns(rows)
+}
+
+const positioningRunSelect = `
+SELECT positioning_run_id::text, draft_id::text, corpus_id::text, status, config,
+       COALESCE(out_path, ''), COALESCE(last_error, ''), created_at, updated_at
+FROM positioning_runs`
+
+func scanPositioningRuns(rows pgx.Rows) ([]models.PositioningRun, error) {
+	defer rows.Close()
+	out := make([]models.PositioningRun, 0)
+	for rows.Next() {
+		var run models.PositioningRun
+		if err := rows.Scan(&run.PositioningRunID, &run.DraftID, &run.CorpusID, &run.Status, &run.Config, &run.OutPath, &run.LastError, &run.CreatedAt, &run.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan positioning run: %w", err)
+		}
+		out = append(out, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate positioning runs: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"litflow/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type EvalRepo struct {
+	db *DB
+}
+
+func NewEvalRepo(db *DB) *EvalRepo {
+	return &EvalRepo{db: db}
+}
+
+func (r *EvalRepo) CreateSet(ctx context.Context, set models.EvalSet) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin eval set tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if _, err := tx.Exec(ctx, `INSERT INTO retrieval_eval_sets (eval_set_id, corpus_id, name) VALUES ($1, $2, $3)`, set.EvalSetID, set.CorpusID, set.Name); err != nil {
+		return fmt.Errorf("insert eval set: %w", err)
+	}
+	for i, q := range set.Questions {
+		if _, err := tx.Exec(ctx, `
+INSERT INTO retrieval_eval_questions (question_id, eval_set_id, position, question, relevant_paper_ids, relevant_chunk_ids)
+VALUES ($1, $2, $3, $4, $5, $6)`, q.QuestionID, set.EvalSetID, i, q.Question, nonNilStrings(q.RelevantPaperIDs), nonNilStrings(q.RelevantChunkIDs)); err != nil {
+			return fmt.Errorf("insert eval question: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit eval set: %w", err)
+	}
+	return nil
+}
+
+func (r *EvalRepo) ListSets(ctx context.Context, corpusID string) ([]models.EvalSet, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+SELECT eval_set_id::text, corpus_id::text, name, created_at
+FROM retrieval_eval_sets
+WHERE corpus_id = $1
+ORDER BY created_at DESC`, corpusID)
+	if err != nil {
+		return nil, fmt.Errorf("list eval sets: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]models.EvalSet, 0)
+	for rows.Next() {
+		var s models.EvalSet
+		if err := rows.Scan(&s.EvalSetID, &s.CorpusID, &s.Name, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan eval set: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate eval sets: %w", err)
+	}
+	return out, nil
+}
+
+// GetSet loads an eval set with its questions in insertion order.
+func (r *EvalRepo) GetSet(ctx context.Context, corpusID, evalSetID string) (models.EvalSet, error) {
+	var s models.EvalSet
+	if err := r.db.Pool.QueryRow(ctx, `
+SELECT eval_set_id::text, corpus_id::text, name, created_at
+FROM retrieval_eval_sets
+WHERE corpus_id = $1 AND eval_set_id = $2`, corpusID, evalSetID).Scan(&s.EvalSetID, &s.CorpusID, &s.Name, &s.CreatedAt); err != nil {
+		return models.EvalSet{}, fmt.Errorf("get eval set: %w", err)
+	}
+	rows, err := r.db.Pool.Query(ctx, `
+SELECT question_id::text, question, relevant_paper_ids, relevant_chunk_ids
+FROM retrieval_eval_questions
+WHERE eval_set_id = $1
+ORDER BY position`, evalSetID)
+	if err != nil {
+		return models.EvalSet{}, fmt.Errorf("list eval questions: %w", err)
+	}
+	defer rows.Close()
+	s.Questions = make([]models.EvalQuestion, 0)
+	for rows.Next() {
+		var q models.EvalQuestion
+		if err := rows.Scan(&q.QuestionID, &q.Question, &q.RelevantPaperIDs, &q.RelevantChunkIDs); err != nil {
+			return models.EvalSet{}, fmt.Errorf("scan eval question: %w", err)
+		}
+		s.Questions = append(s.Questions, q)
+	}
+	if err := rows.Err(); err != nil {
+		return models.EvalSet{}, fmt.Errorf("iterate eval questions: %w", err)
+	}
+	return s, nil
+}
+
+func (r *EvalRepo) CreateRun(ctx context.Context, evalRunID, corpusID, evalSetID string, config any) error {
+	cfgJSON, _ := json.Marshal(config)
+	_, err := r.db.Pool.Exec(ctx, `
+INSERT INTO retrieval_eval_runs (eval_run_id, corpus_id, eval_set_id, status, config)
+VALUES ($1, $2, $3, 'pending', $4::jsonb)`, evalRunID, corpusID, evalSetID, string(cfgJSON))
+	if err != nil {
+		return fmt.Errorf("create eval run: %w", err)
+	}
+	return nil
+}
+
+func (r *EvalRepo) UpdateRun(ctx context.Context, evalRunID, status string, metrics any, outPath, lastError string) error {
+	var metricsJSON *string
+	if metrics != nil {
+		b, _ := json.Marshal(metrics)
+		s := string(b)
+		metricsJSON = &s
+	}
+	_, err := r.db.Pool.Exec(ctx, `
+UPDATE retrieval_eval_runs
+SET status = $2,
+    metrics = COALESCE($3::jsonb, metrics),
+    out_path = COALESCE(NULLIF($4, ''), out_path),
+    last_error = NULLIF($5, ''),
+    updated_at = NOW()
+WHERE eval_run_id = $1`, evalRunID, status, metricsJSON, outPath, lastError)
+	if err != nil {
+		return fmt.Errorf("update eval run: %w", err)
+	}
+	return nil
+}
+
+func (r *EvalRepo) GetRun(ctx context.Context, corpusID, evalRunID string) (models.EvalRun, error) {
+	rows, err := r.db.Pool.Query(ctx, evalRunSelect+` WHERE corpus_id = $1 AND eval_run_id = $2`, corpusID, evalRunID)
+	if err != nil {
+		return models.EvalRun{}, fmt.Errorf("get eval run: %w", err)
+	}
+	runs, err := scanEvalRuns(rows)
+	if err != nil {
+		return models.EvalRun{}, err
+	}
+	if len(runs) == 0 {
+		return models.EvalRun{}, fmt.Errorf("eval run not found: %s", evalRunID)
+	}
+	return runs[0], nil
+}
+
+func (r *EvalRepo) ListRuns(ctx context.Context, corpusID string) ([]models.EvalRun, error) {
+	rows, err := r.db.Pool.Query(ctx, evalRunSelect+` WHERE corpus_id = $1 ORDER BY created_at DESC`, corpusID)
+	if err != nil {
+		return nil, fmt.Errorf("list eval runs: %w", err)
+	}
+	return scanEvalRuns(rows)
+}
+
+const evalRunSelect = `
+SELECT eval_run_id::text, corpus_id::text, eval_set_id::text, status, config, metrics,
+       COALESCE(out_path, ''), COALESCE(last_error, ''), created_at, updated_at
+FROM retrieval_eval_runs`
+
+func scanEvalRuns(rows pgx.Rows) ([]models.EvalRun, error) {
+	defer rows.Close()
+	out := make([]models.EvalRun, 0)
+	for rows.Next() {
+		var run models.EvalRun
+		if err := rows.Scan(&run.EvalRunID, &run.CorpusID, &run.EvalSetID, &run.Status, &run.Config, &run.Metrics, &run.OutPath, &run.LastError, &run.CreatedAt, &run.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan eval run: %w", err)
+		}
+		out = append(out, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate eval runs: %w", err)
+	}
+	return out, nil
+}
+
+func nonNilStrings(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return v
+}
@@ -10,6 +10,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type KGTripleInput struct {
@@ -46,6 +48,21 @@ type LineageEdge struct {
 	Depth      int    `json:"depth"`
 }
 
+// KGEdgeHit is one extracted relation matched by SearchKGEdges, with the provenance entries
+// (paper_id, chunk_id, evidence) accumulated from every extraction that produced it.
+type KGEdgeHit struct {
+	EdgeID       string           `json:"edge_id"`
+	CorpusID     string           `json:"corpus_id"`
+	EdgeType     string           `json:"edge_type"`
+	SourceType   string           `json:"source_type"`
+	SourceName   string           `json:"source_name"`
+	TargetType   string           `json:"target_type"`
+	TargetName   string           `json:"target_name"`
+	Weight       float64          `json:"weight"`
+	SupportCount int              `json:"support_count"`
+	Provenance   []map[string]any `json:"provenance"`
+}
+
 func (r *GraphRepo) UpsertKGRun(ctx context.Context, in KGRunRecord) error {
 	if err := r.ensureKGSchema(ctx); err != nil {
 		return err
@@ -194,6 +211,90 @@ ORDER BY l.depth ASC`, corpusID, root)
 	return outNodes, edges, rows.Err()
 }
 
+// SearchKGEdges finds extracted relations whose source or target label contains any of the
+// terms, best supported first. Topic retrieval edges are excluded.
+func (r *GraphRepo) SearchKGEdges(ctx context.Context, corpusIDs []string, terms []string, limit int) ([]KGEdgeHit, error) {
+	patterns := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if t = strings.TrimSpace(t); t != "" {
+			patterns = append(patterns, "%"+t+"%")
+		}
+	}
+	if len(patterns) == 0 {
+		return []KGEdgeHit{}, nil
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := r.db.Pool.Query(ctx, `
+SELECT e.edge_id, e.corpus_id::text, e.edge_type, ns.node_type, ns.label, nt.node_type, nt.label, e.weight,
+       COALESCE((e.payload->>'support_count')::int, 0), COALESCE(e.payload->'provenance', '[]'::jsonb)
+FROM graph_edges e
+JOIN graph_nodes ns ON ns.node_id = e.source_node_id
+JOIN graph_nodes nt ON nt.node_id = e.target_node_id
+WHERE e.corpus_id = ANY($1::uuid[])
+  AND e.edge_type <> 'retrieved_for_topic'
+  AND (ns.label ILIKE ANY($2) OR nt.label ILIKE ANY($2))
+ORDER BY COALESCE((e.payload->>'support_count')::int, 0) DESC, e.weight DESC, e.edge_id
+LIMIT $3`, corpusIDs, patterns, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search kg edges: %w", err)
+	}
+	return scanKGEdgeHits(rows)
+}
+
+// ListMethodFacts returns the comparison and lineage relations (OUTPERFORMS, EVALUATED_ON,
+// USES_DATASET, EXTENDS, BASED_ON) of every method that relations extracted from paperIDs mention, best
+// supported first. The facts themselves may come from any paper in the corpora.
+func (r *GraphRepo) ListMethodFacts(ctx context.Context, corpusIDs []string, paperIDs []string, limit int) ([]KGEdgeHit, error) {
+	if len(paperIDs) == 0 {
+		return []KGEdgeHit{}, nil
+	}
+	if limit <= 0 {
+		limit = 200
+	}
+	rows, err := r.db.Pool.Query(ctx, `
+WITH mentioned AS (
+  SELECT DISTINCT n.node_id
+  FROM graph_edges e
+  CROSS JOIN LATERAL jsonb_array_elements(COALESCE(e.payload->'provenance', '[]'::jsonb)) p
+  JOIN graph_nodes n ON n.node_id IN (e.source_node_id, e.target_node_id)
+  WHERE e.corpus_id = ANY($1::uuid[])
+    AND n.node_type = 'method'
+    AND p->>'paper_id' = ANY($2)
+)
+SELECT e.edge_id, e.corpus_id::text, e.edge_type, ns.node_type, ns.label, nt.node_type, nt.label, e.weight,
+       COALESCE((e.payload->>'support_count')::int, 0), COALESCE(e.payload->'provenance', '[]'::jsonb)
+FROM graph_edges e
+JOIN graph_nodes ns ON ns.node_id = e.source_node_id
+JOIN graph_nodes nt ON nt.node_id = e.target_node_id
+WHERE e.corpus_id = ANY($1::uuid[])
+  AND e.edge_type IN ('OUTPERFORMS', 'EVALUATED_ON', 'USES_DATASET', 'EXTENDS', 'BASED_ON')
+  AND (e.source_node_id IN (SELECT node_id FROM mentioned) OR e.target_node_id IN (SELECT node_id FROM mentioned))
+ORDER BY COALESCE((e.payload->>'support_count')::int, 0) DESC, e.weight DESC, e.edge_id
+LIMIT $3`, corpusIDs, paperIDs, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list method facts: %w", err)
+	}
+	return scanKGEdgeHits(rows)
+}
+
+func scanKGEdgeHits(rows pgx.Rows) ([]KGEdgeHit, error) {
+	defer rows.Close()
+	out := make([]KGEdgeHit, 0)
+	for rows.Next() {
+		var h KGEdgeHit
+		if err := rows.Scan(&h.EdgeID, &h.CorpusID, &h.EdgeType, &h.SourceType, &h.SourceName, &h.TargetType, &h.TargetName, &h.Weight, &h.SupportCount, &h.Provenance); err != nil {
+			return nil, fmt.Errorf("scan kg edge: %w", err)
+		}
+		out = append(out, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate kg edges: %w", err)
+	}
+	return out, nil
+}
+
 func (r *GraphRepo) QueryCypher(ctx context.Context, _ string, _ string) (map[string]any, error) {
 	return nil, fmt.Errorf("cypher query requires neo4j store; postgres graph store only supports lineage and graph APIs")
 }
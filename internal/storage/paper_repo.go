@@ -2,9 +2,12 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"litflow/internal/models"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type PaperRepo struct {
@@ -17,8 +20,8 @@ func NewPaperRepo(db *DB) *PaperRepo {
 
 func (r *PaperRepo) UpsertPaper(ctx context.Context, p models.Paper) error {
 	_, err := r.db.Pool.Exec(ctx, `
-INSERT INTO papers (paper_id, corpus_id, filename, title, authors, year, abstract, status, fail_reason)
-VALUES ($1, $2, $3, NULLIF($4,''), NULLIF($5,''), $6, NULLIF($7,''), $8, NULLIF($9,''))
+INSERT INTO papers (paper_id, corpus_id, filename, title, authors, year, abstract, status, fail_reason, venue, doi)
+VALUES ($1, $2, $3, NULLIF($4,''), NULLIF($5,''), $6, NULLIF($7,''), $8, NULLIF($9,''), NULLIF($10,''), NULLIF($11,''))
 ON CONFLICT (paper_id)
 DO UPDATE SET
   corpus_id = EXCLUDED.corpus_id,
@@ -27,10 +30,12 @@ DO UPDATE SET
   authors = COALESCE(EXCLUDED.authors, papers.authors),
   year = COALESCE(EXCLUDED.year, papers.year),
   abstract = COALESCE(EXCLUDED.abstract, papers.abstract),
+  venue = COALESCE(EXCLUDED.venue, papers.venue),
+  doi = COALESCE(EXCLUDED.doi, papers.doi),
   status = EXCLUDED.status,
   fail_reason = EXCLUDED.fail_reason,
   updated_at = NOW()`,
-		p.PaperID, p.CorpusID, p.Filename, p.Title, p.Authors, p.Year, p.Abstract, p.Status, p.FailReason,
+		p.PaperID, p.CorpusID, p.Filename, p.Title, p.Authors, p.Year, p.Abstract, p.Status, p.FailReason, p.Venue, p.DOI,
 	)
 	if err != nil {
 		return fmt.Errorf("upsert paper: %w", err)
@@ -49,7 +54,8 @@ func (r *PaperRepo) UpdatePaperStatus(ctx context.Context, paperID, status, fail
 func (r *PaperRepo) ListPapersByCorpus(ctx context.Context, corpusID string) ([]models.Paper, error) {
 	rows, err := r.db.Pool.Query(ctx, `
 SELECT paper_id, corpus_id::text, filename, COALESCE(title,''), COALESCE(authors,''), year,
-       COALESCE(abstract,''), status, COALESCE(fail_reason,''), created_at, updated_at
+       COALESCE(abstract,''), status, COALESCE(fail_reason,''), created_at, updated_at,
+       COALESCE(venue,''), COALESCE(doi,'')
 FROM papers
 WHERE corpus_id=$1
 ORDER BY created_at DESC`, corpusID)
@@ -61,7 +67,7 @@ ORDER BY created_at DESC`, corpusID)
 	out := make([]models.Paper, 0)
 	for rows.Next() {
 		var p models.Paper
-		if err := rows.Scan(&p.PaperID, &p.CorpusID, &p.Filename, &p.Title, &p.Authors, &p.Year, &p.Abstract, &p.Status, &p.FailReason, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.PaperID, &p.CorpusID, &p.Filename, &p.Title, &p.Authors, &p.Year, &p.Abstract, &p.Status, &p.FailReason, &p.CreatedAt, &p.UpdatedAt, &p.Venue, &p.DOI); err != nil {
 			return nil, fmt.Errorf("scan paper: %w", err)
 		}
 		out = append(out, p)
@@ -75,7 +81,8 @@ ORDER BY created_at DESC`, corpusID)
 func (r *PaperRepo) ListFailedPapers(ctx context.Context, corpusID string) ([]models.Paper, error) {
 	rows, err := r.db.Pool.Query(ctx, `
 SELECT paper_id, corpus_id::text, filename, COALESCE(title,''), COALESCE(authors,''), year,
-       COALESCE(abstract,''), status, COALESCE(fail_reason,''), created_at, updated_at
+       COALESCE(abstract,''), status, COALESCE(fail_reason,''), created_at, updated_at,
+       COALESCE(venue,''), COALESCE(doi,'')
 FROM papers
 WHERE corpus_id=$1 AND status='failed'
 ORDER BY updated_at DESC`, corpusID)
@@ -86,7 +93,7 @@ ORDER BY updated_at DESC`, corpusID)
 	out := make([]models.Paper, 0)
 	for rows.Next() {
 		var p models.Paper
-		if err := rows.Scan(&p.PaperID, &p.CorpusID, &p.Filename, &p.Title, &p.Authors, &p.Year, &p.Abstract, &p.Status, &p.FailReason, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.PaperID, &p.CorpusID, &p.Filename, &p.Title, &p.Authors, &p.Year, &p.Abstract, &p.Status, &p.FailReason, &p.CreatedAt, &p.UpdatedAt, &p.Venue, &p.DOI); err != nil {
 			return nil, fmt.Errorf("scan failed paper: %w", err)
 		}
 		out = append(out, p)
@@ -98,26 +105,28 @@ func (r *PaperRepo) GetPaperByID(ctx context.Context, corpusID, paperID string)
 	var p models.Paper
 	err := r.db.Pool.QueryRow(ctx, `
 SELECT paper_id, corpus_id::text, filename, COALESCE(title,''), COALESCE(authors,''), year,
-       COALESCE(abstract,''), status, COALESCE(fail_reason,''), created_at, updated_at
+       COALESCE(abstract,''), status, COALESCE(fail_reason,''), created_at, updated_at,
+       COALESCE(venue,''), COALESCE(doi,'')
 FROM papers
 WHERE corpus_id=$1 AND paper_id=$2`, corpusID, paperID).
-		Scan(&p.PaperID, &p.CorpusID, &p.Filename, &p.Title, &p.Authors, &p.Year, &p.Abstract, &p.Status, &p.FailReason, &p.CreatedAt, &p.UpdatedAt)
+		Scan(&p.PaperID, &p.CorpusID, &p.Filename, &p.Title, &p.Authors, &p.Year, &p.Abstract, &p.Status, &p.FailReason, &p.CreatedAt, &p.UpdatedAt, &p.Venue, &p.DOI)
 	if err != nil {
 		return models.Paper{}, fmt.Errorf("get paper by id: %w", err)
 	}
 	return p, nil
 }
 
-func (r *PaperRepo) ListPapersByIDs(ctx context.Context, corpusID string, paperIDs []string) ([]models.Paper, error) {
+func (r *PaperRepo) ListPapersByIDs(ctx context.Context, corpusIDs []string, paperIDs []string) ([]models.Paper, error) {
 	if len(paperIDs) == 0 {
 		return []models.Paper{}, nil
 	}
 	rows, err := r.db.Pool.Query(ctx, `
 SELECT paper_id, corpus_id::text, filename, COALESCE(title,''), COALESCE(authors,''), year,
-       COALESCE(abstract,''), status, COALESCE(fail_reason,''), created_at, updated_at
+       COALESCE(abstract,''), status, COALESCE(fail_reason,''), created_at, updated_at,
+       COALESCE(venue,''), COALESCE(doi,'')
 FROM papers
-WHERE corpus_id=$1 AND paper_id = ANY($2)
-ORDER BY created_at DESC`, corpusID, paperIDs)
+WHERE corpus_id = ANY($1::uuid[]) AND paper_id = ANY($2)
+ORDER BY created_at DESC`, corpusIDs, paperIDs)
 	if err != nil {
 		return nil, fmt.Errorf("list papers by ids: %w", err)
 	}
@@ -126,7 +135,7 @@ ORDER BY created_at DESC`, corpusID, paperIDs)
 	out := make([]models.Paper, 0, len(paperIDs))
 	for rows.Next() {
 		var p models.Paper
-		if err := rows.Scan(&p.PaperID, &p.CorpusID, &p.Filename, &p.Title, &p.Authors, &p.Year, &p.Abstract, &p.Status, &p.FailReason, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.PaperID, &p.CorpusID, &p.Filename, &p.Title, &p.Authors, &p.Year, &p.Abstract, &p.Status, &p.FailReason, &p.CreatedAt, &p.UpdatedAt, &p.Venue, &p.DOI); err != nil {
 			return nil, fmt.Errorf("scan paper by id: %w", err)
 		}
 		out = append(out, p)
@@ -136,3 +145,65 @@ ORDER BY created_at DESC`, corpusID, paperIDs)
 	}
 	return out, nil
 }
+
+// UpsertPaperSummary replaces the stored summary for a paper; regenerating with a new prompt
+// or model overwrites the previous version.
+func (r *PaperRepo) UpsertPaperSummary(ctx context.Context, s models.PaperSummary) error {
+	contributions, _ := json.Marshal(nonNilStrings(s.Contributions))
+	_, err := r.db.Pool.Exec(ctx, `
+INSERT INTO paper_summaries (paper_id, corpus_id, tldr, contributions, method, results, limitations, prompt_version, llm_provider, llm_model, chunk_count)
+VALUES ($1, $2, $3, $4::jsonb, $5, $6, $7, $8, NULLIF($9,''), NULLIF($10,''), $11)
+ON CONFLICT (paper_id)
+DO UPDATE SET
+  corpus_id = EXCLUDED.corpus_id,
+  tldr = EXCLUDED.tldr,
+  contributions = EXCLUDED.contributions,
+  method = EXCLUDED.method,
+  results = EXCLUDED.results,
+  limitations = EXCLUDED.limitations,
+  prompt_version = EXCLUDED.prompt_version,
+  llm_provider = EXCLUDED.llm_provider,
+  llm_model = EXCLUDED.llm_model,
+  chunk_count = EXCLUDED.chunk_count,
+  updated_at = NOW()`,
+		s.PaperID, s.CorpusID, s.TLDR, string(contributions), s.Method, s.Results, s.Limitations, s.PromptVersion, s.LLMProvider, s.LLMModel, s.ChunkCount,
+	)
+	if err != nil {
+		return fmt.Errorf("upsert paper summary: %w", err)
+	}
+	return nil
+}
+
+// ListPaperSummaries returns stored summaries in the given corpora. An empty paperIDs lists
+// every summary in scope.
+func (r *PaperRepo) ListPaperSummaries(ctx context.Context, corpusIDs, paperIDs []string) ([]models.PaperSummary, error) {
+	rows, err := r.db.Pool.Query(ctx, paperSummarySelect+`
+WHERE corpus_id = ANY($1::uuid[]) AND (cardinality($2::text[]) = 0 OR paper_id = ANY($2))
+ORDER BY updated_at DESC`, corpusIDs, nonNilStrings(paperIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list paper summaries: %w", err)
+	}
+	return scanPaperSummaries(rows)
+}
+
+const paperSummarySelect = `
+SELECT paper_id, corpus_id::text, tldr, contributions, method, results, limitations, prompt_version,
+       COALESCE(llm_provider, ''), COALESCE(llm_model, ''), chunk_count, created_at, updated_at
+FROM paper_summaries`
+
+func scanPaperSummaries(rows pgx.Rows) ([]models.PaperSummary, error) {
+	defer rows.Close()
+	out := make([]models.PaperSummary, 0)
+	for rows.Next() {
+		var s models.PaperSummary
+		if err := rows.Scan(&s.PaperID, &s.CorpusID, &s.TLDR, &s.Contributions, &s.Method, &s.Results, &s.Limitations, &s.PromptVersion, &s.LLMProvider, &s.LLMModel, &s.ChunkCount, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan paper summary: %w", err)
+		}
+		s.Contributions = nonNilStrings(s.Contributions)
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate paper summaries: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"litflow/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ResearchRepo struct {
+	db *DB
+}
+
+func NewResearchRepo(db *DB) *ResearchRepo {
+	return &ResearchRepo{db: db}
+}
+
+func (r *ResearchRepo) CreateRun(ctx context.Context, runID, corpusID, question string, config any) error {
+	cfgJSON, _ := json.Marshal(config)
+	_, err := r.db.Pool.Exec(ctx, `
+INSERT INTO research_runs (research_run_id, corpus_id, question, status, config)
+VALUES ($1, $2, $3, 'pending', $4::jsonb)`, runID, corpusID, question, string(cfgJSON))
+	if err != nil {
+		return fmt.Errorf("create research run: %w", err)
+	}
+	return nil
+}
+
+func (r *ResearchRepo) UpdateRun(ctx context.Context, runID, status, outPath, lastError string) error {
+	_, err := r.db.Pool.Exec(ctx, `
+UPDATE research_runs
+SET status = $2,
+    out_path = COALESCE(NULLIF($3, ''), out_path),
+    last_error = NULLIF($4, ''),
+    updated_at = NOW()
+WHERE research_run_id = $1`, runID, status, outPath, lastError)
+	if err != nil {
+		return fmt.Errorf("update research run: %w", err)
+	}
+	return nil
+}
+
+func (r *ResearchRepo) GetRun(ctx context.Context, corpusID, runID string) (models.ResearchRun, error) {
+	rows, err := r.db.Pool.Query(ctx, researchRunSelect+` WHERE corpus_id = $1 AND research_run_id = $2`, corpusID, runID)
+	if err != nil {
+		return models.ResearchRun{}, fmt.Errorf("get research run: %w", err)
+	}
+	runs, err := scanResearchRuns(rows)
+	if err != nil {
+		return models.ResearchRun{}, err
+	}
+	if len(runs) == 0 {
+		return models.ResearchRun{}, fmt.Errorf("research run not found: %s", runID)
+	}
+	return runs[0], nil
+}
+
+func (r *ResearchRepo) ListRuns(ctx context.Context, corpusID string) ([]models.ResearchRun, error) {
+	rows, err := r.db.Pool.Query(ctx, researchRunSelect+` WHERE corpus_id = $1 ORDER BY created_at DESC`, corpusID)
+	if err != nil {
+		return nil, fmt.Errorf("list research runs: %w", err)
+	}
+	return scanResearchRuns(rows)
+}
+
+const researchRunSelect = `
+SELECT research_run_id::text, corpus_id::text, question, status, config,
+       COALESCE(out_path, ''), COALESCE(last_error, ''), created_at, updated_at
+FROM research_runs`
+
+func scanResearchRuns(rows pgx.Rows) ([]models.ResearchRun, error) {
+	defer rows.Close()
+	out := make([]models.ResearchRun, 0)
+	for rows.Next() {
+		var run models.ResearchRun
+		if err := rows.Scan(&run.ResearchRunID, &run.CorpusID, &run.Question, &run.Status, &run.Config, &run.OutPath, &run.LastError, &run.CreatedAt, &run.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan research run: %w", err)
+		}
+		out = append(out, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate research runs: %w", err)
+	}
+	return out, nil
+}
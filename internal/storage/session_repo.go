@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"litflow/internal/models"
+)
+
+type SessionRepo struct {
+	db *DB
+}
+
+func NewSessionRepo(db *DB) *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+func (r *SessionRepo) ListSessions(ctx context.Context, corpusID string) ([]models.AskSession, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+SELECT s.session_id::text, s.corpus_id::text, s.title, COUNT(t.turn_id), s.created_at, s.updated_at
+FROM ask_sessions s
+LEFT JOIN ask_turns t ON t.session_id = s.session_id
+WHERE s.corpus_id = $1
+GROUP BY s.session_id
+ORDER BY s.updated_at DESC`, corpusID)
+	if err != nil {
+		return nil, fmt.Errorf("list ask sessions: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]models.AskSession, 0)
+	for rows.Next() {
+		var s models.AskSession
+		if err := rows.Scan(&s.SessionID, &s.CorpusID, &s.Title, &s.TurnCount, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan ask session: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate ask sessions: %w", err)
+	}
+	return out, nil
+}
+
+// GetSession loads a session with all of its turns in order.
+func (r *SessionRepo) GetSession(ctx context.Context, sessionID string) (models.AskSession, error) {
+	var s models.AskSession
+	if err := r.db.Pool.QueryRow(ctx, `
+SELECT session_id::text, corpus_id::text, title, created_at, updated_at
+FROM ask_sessions WHERE session_id = $1`, sessionID).Scan(&s.SessionID, &s.CorpusID, &s.Title, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return models.AskSession{}, fmt.Errorf("get ask session: %w", err)
+	}
+	rows, err := r.db.Pool.Query(ctx, `
+SELECT turn_id::text, position, question, standalone_query, answer, citations,
+       COALESCE(llm_provider, ''), COALESCE(llm_model, ''), created_at
+FROM ask_turns
+WHERE session_id = $1
+ORDER BY position`, sessionID)
+	if err != nil {
+		return models.AskSession{}, fmt.Errorf("list ask turns: %w", err)
+	}
+	defer rows.Close()
+	s.Turns = make([]models.AskTurn, 0)
+	for rows.Next() {
+		var t models.AskTurn
+		if err := rows.Scan(&t.TurnID, &t.Position, &t.Question, &t.StandaloneQuery, &t.Answer, &t.Citations, &t.LLMProvider, &t.LLMModel, &t.CreatedAt); err != nil {
+			return models.AskSession{}, fmt.Errorf("scan ask turn: %w", err)
+		}
+		s.Turns = append(s.Turns, t)
+	}
+	if err := rows.Err(); err != nil {
+		return models.AskSession{}, fmt.Errorf("iterate ask turns: %w", err)
+	}
+	s.TurnCount = len(s.Turns)
+	return s, nil
+}
+
+// StartSession creates a session together with its first turn, so a session only exists
+// once it has an answered question.
+func (r *SessionRepo) StartSession(ctx context.Context, sessionID, corpusID, title string, turn models.AskTurn, citations any) (int, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin ask session tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if _, err := tx.Exec(ctx, `INSERT INTO ask_sessions (session_id, corpus_id, title) VALUES ($1, $2, $3)`, sessionID, corpusID, title); err != nil {
+		return 0, fmt.Errorf("create ask session: %w", err)
+	}
+	position, err := insertTurn(ctx, tx, sessionID, turn, citations)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit ask session: %w", err)
+	}
+	return position, nil
+}
+
+// AppendTurn stores the next turn of a session and returns its position. The session row is
+// locked first so concurrent follow-ups are numbered one after another.
+func (r *SessionRepo) AppendTurn(ctx context.Context, sessionID string, turn models.AskTurn, citations any) (int, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin ask turn tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM ask_sessions WHERE session_id = $1 FOR UPDATE`, sessionID); err != nil {
+		return 0, fmt.Errorf("lock ask session: %w", err)
+	}
+	position, err := insertTurn(ctx, tx, sessionID, turn, citations)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE ask_sessions SET updated_at = NOW() WHERE session_id = $1`, sessionID); err != nil {
+		return 0, fmt.Errorf("touch ask session: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit ask turn: %w", err)
+	}
+	return position, nil
+}
+
+func insertTurn(ctx context.Context, tx pgx.Tx, sessionID string, turn models.AskTurn, citations any) (int, error) {
+	citationJSON, _ := json.Marshal(citations)
+	var position int
+	err := tx.QueryRow(ctx, `
+INSERT INTO ask_turns (turn_id, session_id, position, question, standalone_query, answer, citations, llm_provider, llm_model)
+SELECT $1, $2, COALESCE(MAX(position) + 1, 0), $3, $4, $5, $6::jsonb, NULLIF($7, ''), NULLIF($8, '')
+FROM ask_turns WHERE session_id = $2
+RETURNING position`, turn.TurnID, sessionID, turn.Question, turn.StandaloneQuery, turn.Answer, string(citationJSON), turn.LLMProvider, turn.LLMModel).Scan(&position)
+	if err != nil {
+		return 0, fmt.Errorf("append ask turn: %w", err)
+	}
+	return position, nil
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+
+	"litflow/internal/models"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type SurveyRepo struct {
@@ -14,20 +18,34 @@ func NewSurveyRepo(db *DB) *SurveyRepo {
 	return &SurveyRepo{db: db}
 }
 
-func (r *SurveyRepo) CreateRun(ctx context.Context, surveyRunID, corpusID string, topics, questions []string) error {
+func (r *SurveyRepo) CreateRun(ctx context.Context, surveyRunID, corpusID, parentRunID, outputFormat, templateID, citationStyle string, topics, questions []string) error {
 	topicJSON, _ := json.Marshal(topics)
 	questionJSON, _ := json.Marshal(questions)
 	_, err := r.db.Pool.Exec(ctx, `
-INSERT INTO survey_runs (survey_run_id, corpus_id, topics, questions, status)
-VALUES ($1, $2, $3::jsonb, $4::jsonb, 'pending')`, surveyRunID, corpusID, string(topicJSON), string(questionJSON))
+INSERT INTO survey_runs (survey_run_id, corpus_id, parent_run_id, output_format, template_id, citation_style, topics, questions, status)
+VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, $7::jsonb, $8::jsonb, 'pending')`, surveyRunID, corpusID, parentRunID, outputFormat, templateID, citationStyle, string(topicJSON), string(questionJSON))
 	if err != nil {
 		return fmt.Errorf("create survey run: %w", err)
 	}
 	return nil
 }
 
-func (r *SurveyRepo) UpdateRunStatus(ctx context.Context, surveyRunID, status, outPath string) error {
-	_, err := r.db.Pool.Exec(ctx, `UPDATE survey_runs SET status=$2, out_path=NULLIF($3,'' ) WHERE survey_run_id=$1`, surveyRunID, status, outPath)
+// UpdateRunStatus sets the status and output path of a run; non-nil models replace the
+// recorded "provider/model" pairs.
+func (r *SurveyRepo) UpdateRunStatus(ctx context.Context, surveyRunID, status, outPath string, models []string) error {
+	var modelJSON *string
+	if models != nil {
+		b, _ := json.Marshal(models)
+		v := string(b)
+		modelJSON = &v
+	}
+	_, err := r.db.Pool.Exec(ctx, `
+UPDATE survey_runs
+SET status = $2,
+    out_path = NULLIF($3, ''),
+    llm_models = COALESCE($4::jsonb, llm_models),
+    updated_at = NOW()
+WHERE survey_run_id = $1`, surveyRunID, status, outPath, modelJSON)
 	if err != nil {
 		return fmt.Errorf("update survey run: %w", err)
 	}
@@ -42,3 +60,72 @@ func (r *SurveyRepo) GetRunPath(ctx context.Context, surveyRunID string) (string
 	}
 	return outPath, status, nil
 }
+
+func (r *SurveyRepo) GetRun(ctx context.Context, surveyRunID string) (models.SurveyRun, error) {
+	rows, err := r.db.Pool.Query(ctx, surveyRunSelect+` WHERE survey_run_id = $1`, surveyRunID)
+	if err != nil {
+		return models.SurveyRun{}, fmt.Errorf("get survey run: %w", err)
+	}
+	runs, err := scanSurveyRuns(rows)
+	if err != nil {
+		return models.SurveyRun{}, err
+	}
+	if len(runs) == 0 {
+		return models.SurveyRun{}, fmt.Errorf("survey run not found: %s", surveyRunID)
+	}
+	return runs[0], nil
+}
+
+func (r *SurveyRepo) ListRuns(ctx context.Context, corpusID string) ([]models.SurveyRun, error) {
+	rows, err := r.db.Pool.Query(ctx, surveyRunSelect+` WHERE corpus_id = $1 ORDER BY created_at DESC`, corpusID)
+	if err != nil {
+		return nil, fmt.Errorf("list survey runs: %w", err)
+	}
+	return scanSurveyRuns(rows)
+}
+
+const surveyRunSelect = `
+SELECT survey_run_id::text, corpus_id::text, COALESCE(parent_run_id::text, ''), topics, questions, status,
+       output_format, template_id, citation_style, llm_models, COALESCE(out_path, ''), created_at, updated_at
+FROM survey_runs`
+
+func scanSurveyRuns(rows pgx.Rows) ([]models.SurveyRun, error) {
+	defer rows.Close()
+	out := make([]models.SurveyRun, 0)
+	for rows.Next() {
+		var run models.SurveyRun
+		var topics, questions, llmModels []byte
+		if err := rows.Scan(&run.SurveyRunID, &run.CorpusID, &run.ParentRunID, &topics, &questions, &run.Status, &run.OutputFormat, &run.TemplateID, &run.CitationStyle, &llmModels, &run.OutPath, &run.CreatedAt, &run.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan survey run: %w", err)
+		}
+		_ = json.Unmarshal(topics, &run.Topics)
+		_ = json.Unmarshal(questions, &run.Questions)
+		_ = json.Unmarshal(llmModels, &run.Models)
+		out = append(out, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate survey runs: %w", err)
+	}
+	return out, nil
+}
+
+// SaveValidation stores the citation checks of a run as JSON.
+func (r *SurveyRepo) SaveValidation(ctx context.Context, surveyRunID string, validation any) error {
+	payload, err := json.Marshal(validation)
+	if err != nil {
+		return fmt.Errorf("encode survey validation: %w", err)
+	}
+	if _, err := r.db.Pool.Exec(ctx, `UPDATE survey_runs SET validation=$2::jsonb WHERE survey_run_id=$1`, surveyRunID, string(payload)); err != nil {
+		return fmt.Errorf("save survey validation: %w", err)
+	}
+	return nil
+}
+
+// GetValidation returns the stored citation checks of a run, or nil before the run has any.
+func (r *SurveyRepo) GetValidation(ctx context.Context, surveyRunID string) (json.RawMessage, error) {
+	var raw []byte
+	if err := r.db.Pool.QueryRow(ctx, `SELECT validation FROM survey_runs WHERE survey_run_id=$1`, surveyRunID).Scan(&raw); err != nil {
+		return nil, fmt.Errorf("get survey validation: %w", err)
+	}
+	return raw, nil
+}
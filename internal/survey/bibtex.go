@@ -0,0 +1,179 @@
+package survey
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"litflow/internal/util"
+)
+
+// BibFilename is the bibliography the LaTeX renderer points \bibliography at.
+const BibFilename = "refs.bib"
+
+var (
+	authorSeparator = regexp.MustCompile(`\s*(?:;|\band\b|&)\s*`)
+	etAlSuffix      = regexp.MustCompile(`(?i)\s+et\.?\s+al\.?\s*$`)
+	nonKeyLetters   = regexp.MustCompile(`[^a-z]+`)
+	conferenceVenue = regexp.MustCompile(`(?i)\b(proceedings|conference|symposium|workshop)\b`)
+	journalVenue    = regexp.MustCompile(`(?i)\b(journal|transactions|letters|arxiv)\b`)
+	keyFolder       = strings.NewReplacer(
+		"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+		"é", "e", "è", "e", "ê", "e", "ë", "e",
+		"í", "i", "ì", "i", "î", "i", "ï", "i",
+		"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+		"ú", "u", "ù", "u", "û", "u", "ü", "u",
+		"ç", "c", "ñ", "n", "ß", "ss", "ł", "l", "ý", "y",
+	)
+	titleStopwords = map[string]bool{
+		"a": true, "an": true, "the": true, "on": true, "of": true, "in": true, "for": true, "and": true,
+		"to": true, "with": true, "towards": true, "toward": true, "via": true, "from": true, "is": true,
+		"are": true, "using": true, "do": true, "does": true, "what": true, "how": true, "why": true,
+	}
+)
+
+// SplitAuthors splits an author line into names, dropping a trailing "et al.". Names
+// separated only by commas are split on the commas; a single "Last, F." name is kept whole.
+func SplitAuthors(authors string) []string {
+	parts := authorSeparator.Split(etAlSuffix.ReplaceAllString(strings.TrimSpace(authors), ""), -1)
+	if len(parts) == 1 && !looksLikeLastFirst(parts[0]) {
+		parts = strings.Split(parts[0], ",")
+	}
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.Trim(strings.Join(strings.Fields(p), " "), " ,*†‡∗0123456789")
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// looksLikeLastFirst reports whether a single comma-separated name reads as "Last, F.".
+func looksLikeLastFirst(name string) bool {
+	last, first, ok := strings.Cut(name, ",")
+	if !ok {
+		return false
+	}
+	return len(strings.Fields(last)) == 1 && len(strings.Fields(first)) <= 2 && strings.Contains(first, ".")
+}
+
+// BibKeyBase is the citation key for a reference before collisions are resolved: the first
+// author's family name, the year and the first significant title word, e.g.
+// vaswani2017attention. Missing parts become "anon", "nd" and "paper".
+func BibKeyBase(ref Reference) string {
+	author := "anon"
+	if names := SplitAuthors(ref.Authors); len(names) > 0 {
+		first := names[0]
+		family := first
+		if last, _, ok := strings.Cut(first, ","); ok {
+			family = last
+		} else if fields := strings.Fields(first); len(fields) > 0 {
+			family = fields[len(fields)-1]
+		}
+		if k := keyPart(family); k != "" {
+			author = k
+		}
+	}
+	year := "nd"
+	if ref.Year > 0 {
+		year = strconv.Itoa(ref.Year)
+	}
+	word := "paper"
+	for _, w := range strings.Fields(ref.Title) {
+		if k := keyPart(w); k != "" && !titleStopwords[k] {
+			word = k
+			break
+		}
+	}
+	return author + year + word
+}
+
+func keyPart(s string) string {
+	return nonKeyLetters.ReplaceAllString(keyFolder.Replace(strings.ToLower(s)), "")
+}
+
+// AssignBibKeys maps each reference key to its BibTeX key. References that share a base key
+// get suffixes a, b, ... in paper ID order, so a paper keeps its key across surveys that cite
+// the same set of colliding papers.
+func AssignBibKeys(refs []Reference) map[string]string {
+	groups := map[string][]Reference{}
+	for _, ref := range refs {
+		base := BibKeyBase(ref)
+		groups[base] = append(groups[base], ref)
+	}
+	out := make(map[string]string, len(refs))
+	for base, group := range groups {
+		if len(group) == 1 {
+			out[group[0].Key] = base
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool { return group[i].PaperID < group[j].PaperID })
+		for i, ref := range group {
+			out[ref.Key] = base + suffixLetters(i)
+		}
+	}
+	return out
+}
+
+func suffixLetters(i int) string {
+	s := ""
+	for i >= 0 {
+		s = string(rune('a'+i%26)) + s
+		i = i/26 - 1
+	}
+	return s
+}
+
+// RenderBibTeX renders the reference list as BibTeX entries in reference order. The entry
+// type follows the venue: conference venues are @inproceedings, journals and preprints are
+// @article, and anything else is @misc.
+func RenderBibTeX(refs []Reference) string {
+	keys := AssignBibKeys(refs)
+	multiCorpus := spansCorpora(refs)
+	var b strings.Builder
+	for i, ref := range refs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		venue := strings.TrimSpace(ref.Venue)
+		kind, venueField := "misc", ""
+		switch {
+		case venue != "" && conferenceVenue.MatchString(venue):
+			kind, venueField = "inproceedings", "booktitle"
+		case venue != "" && journalVenue.MatchString(venue):
+			kind, venueField = "article", "journal"
+		case venue != "":
+			venueField = "howpublished"
+		}
+		fmt.Fprintf(&b, "@%s{%s,\n", kind, keys[ref.Key])
+		writeBibField(&b, "title", "{"+util.EscapeLaTeX(referenceTitle(ref))+"}")
+		if names := SplitAuthors(ref.Authors); len(names) > 0 {
+			escaped := make([]string, 0, len(names))
+			for _, n := range names {
+				escaped = append(escaped, util.EscapeLaTeX(n))
+			}
+			writeBibField(&b, "author", strings.Join(escaped, " and "))
+		}
+		if venueField != "" {
+			writeBibField(&b, venueField, util.EscapeLaTeX(venue))
+		}
+		if ref.Year > 0 {
+			writeBibField(&b, "year", strconv.Itoa(ref.Year))
+		}
+		if doi := strings.NewReplacer("{", "", "}", "").Replace(strings.TrimSpace(ref.DOI)); doi != "" {
+			writeBibField(&b, "doi", doi)
+		}
+		if multiCorpus && ref.CorpusID != "" {
+			writeBibField(&b, "note", "corpus \\texttt{"+util.EscapeLaTeX(ref.CorpusID)+"}")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func writeBibField(b *strings.Builder, name, value string) {
+	fmt.Fprintf(b, "  %s = {%s},\n", name, value)
+}
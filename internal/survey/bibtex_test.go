@@ -0,0 +1,33 @@
+package survey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBibKeys(t *testing.T) {
+	require.Equal(t, "vaswani2017attention", BibKeyBase(Reference{Title: "Attention Is All You Need", Authors: "Ashish Vaswani∗, Noam Shazeer, Niki Parmar", Year: 2017}))
+	require.Equal(t, "muller2019graphs", BibKeyBase(Reference{Title: "On Graphs", Authors: "Müller, R.", Year: 2019}))
+	require.Equal(t, "anonndpaper", BibKeyBase(Reference{}))
+	require.Equal(t, []string{"Jonathan Ho", "Ajay Jain"}, SplitAuthors("Jonathan Ho and Ajay Jain"))
+	require.Equal(t, []string{"Ho"}, SplitAuthors("Ho et al."))
+
+	keys := AssignBibKeys([]Reference{
+		{Key: "ref1", PaperID: "p9", Title: "Deep Residual Learning", Authors: "Kaiming He", Year: 2016},
+		{Key: "ref2", PaperID: "p1", Title: "Deep Residual Networks", Authors: "Kaiming He", Year: 2016},
+		{Key: "ref3", PaperID: "p5", Title: "BERT", Authors: "Jacob Devlin", Year: 2019},
+	})
+	require.Equal(t, map[string]string{"ref1": "he2016deepb", "ref2": "he2016deepa", "ref3": "devlin2019bert"}, keys)
+}
+
+func TestRenderBibTeX(t *testing.T) {
+	bib := RenderBibTeX([]Reference{
+		{Key: "ref1", Title: "Attention Is All You Need", Authors: "Ashish Vaswani, Noam Shazeer", Year: 2017, Venue: "31st Conference on Neural Information Processing Systems (NIPS 2017)", DOI: "10.5555/3295222.3295349"},
+		{Key: "ref2", Title: "LoRA: Low-Rank Adaptation", Authors: "Edward Hu", Venue: "arXiv preprint 2106.09685"},
+		{Key: "ref3", Title: "Notes_on RL"},
+	})
+	require.Contains(t, bib, "@inproceedings{vaswani2017attention,\n  title = {{Attention Is All You Need}},\n  author = {Ashish Vaswani and Noam Shazeer},\n  booktitle = {31st Conference on Neural Information Processing Systems (NIPS 2017)},\n  year = {2017},\n  doi = {10.5555/3295222.3295349},\n}\n")
+	require.Contains(t, bib, "@article{hundlora,\n  title = {{LoRA: Low-Rank Adaptation}},\n  author = {Edward Hu},\n  journal = {arXiv preprint 2106.09685},\n}\n")
+	require.Contains(t, bib, "@misc{anonndnoteson,\n  title = {{Notes\\_on RL}},\n}\n")
+}
@@ -0,0 +1,148 @@
+package survey
+
+import "strings"
+
+// Section change statuses reported by Compare.
+const (
+	SectionAdded   = "added"
+	SectionRemoved = "removed"
+	SectionChanged = "changed"
+)
+
+// SectionChange is a section that differs between two surveys. Paragraphs are compared with
+// citations rewritten to BibTeX key bases, so renumbered [refN] keys do not count as changes.
+type SectionChange struct {
+	Title             string   `json:"title"`
+	Status            string   `json:"status"`
+	AddedParagraphs   []string `json:"added_paragraphs,omitempty"`
+	RemovedParagraphs []string `json:"removed_paragraphs,omitempty"`
+}
+
+// Diff compares survey A (usually the older run) with survey B.
+type Diff struct {
+	AddedReferences   []Reference     `json:"added_references"`
+	RemovedReferences []Reference     `json:"removed_references"`
+	SharedReferences  int             `json:"shared_references"`
+	Sections          []SectionChange `json:"sections"`
+	UnchangedSections int             `json:"unchanged_sections"`
+}
+
+// Compare diffs two survey documents. References are matched by paper and sections by title;
+// sections of B come first in B's order, followed by sections only A has.
+func Compare(a, b Document) Diff {
+	out := Diff{AddedReferences: []Reference{}, RemovedReferences: []Reference{}, Sections: []SectionChange{}}
+	inA := map[string]bool{}
+	for _, ref := range a.References {
+		inA[referenceIdentity(ref)] = true
+	}
+	inB := map[string]bool{}
+	for _, ref := range b.References {
+		id := referenceIdentity(ref)
+		inB[id] = true
+		if inA[id] {
+			out.SharedReferences++
+		} else {
+			out.AddedReferences = append(out.AddedReferences, ref)
+		}
+	}
+	for _, ref := range a.References {
+		if !inB[referenceIdentity(ref)] {
+			out.RemovedReferences = append(out.RemovedReferences, ref)
+		}
+	}
+
+	sectionsA := map[string][]string{}
+	for _, sec := range a.Sections {
+		sectionsA[titleIdentity(sec.Title)] = sectionLines(sec, a.References)
+	}
+	seen := map[string]bool{}
+	for _, sec := range b.Sections {
+		id := titleIdentity(sec.Title)
+		seen[id] = true
+		linesB := sectionLines(sec, b.References)
+		linesA, ok := sectionsA[id]
+		if !ok {
+			out.Sections = append(out.Sections, SectionChange{Title: sec.Title, Status: SectionAdded, AddedParagraphs: linesB})
+			continue
+		}
+		added, removed := lineDifference(linesB, linesA), lineDifference(linesA, linesB)
+		if len(added) == 0 && len(removed) == 0 {
+			out.UnchangedSections++
+			continue
+		}
+		out.Sections = append(out.Sections, SectionChange{Title: sec.Title, Status: SectionChanged, AddedParagraphs: added, RemovedParagraphs: removed})
+	}
+	for _, sec := range a.Sections {
+		if !seen[titleIdentity(sec.Title)] {
+			out.Sections = append(out.Sections, SectionChange{Title: sec.Title, Status: SectionRemoved, RemovedParagraphs: sectionsA[titleIdentity(sec.Title)]})
+		}
+	}
+	return out
+}
+
+func referenceIdentity(ref Reference) string {
+	if ref.PaperID != "" {
+		return ref.CorpusID + "/" + ref.PaperID
+	}
+	return "title:" + strings.ToLower(strings.Join(strings.Fields(ref.Title), " "))
+}
+
+func titleIdentity(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// sectionLines flattens a section into comparable lines: one per paragraph and table row plus
+// a "### " line per subsection heading, with citation keys replaced by BibTeX key bases.
+func sectionLines(sec Section, refs []Reference) []string {
+	bases := make(map[string]string, len(refs))
+	for _, ref := range refs {
+		bases[ref.Key] = BibKeyBase(ref)
+	}
+	cite := func(keys []string) string {
+		mapped := make([]string, 0, len(keys))
+		for _, k := range keys {
+			if base, ok := bases[k]; ok {
+				k = base
+			}
+			mapped = append(mapped, k)
+		}
+		return "[" + strings.Join(mapped, ", ") + "]"
+	}
+	var lines []string
+	var walk func(s Section, heading bool)
+	walk = func(s Section, heading bool) {
+		if heading {
+			lines = append(lines, "### "+s.Title)
+		}
+		for _, p := range s.Paragraphs {
+			lines = append(lines, replaceCitations(p.Text, func(t string) string { return t }, cite))
+		}
+		for _, t := range s.Tables {
+			for _, row := range t.Rows {
+				lines = append(lines, replaceCitations("| "+strings.Join(row, " | ")+" |", func(t string) string { return t }, cite))
+			}
+		}
+		for _, sub := range s.Subsections {
+			walk(sub, true)
+		}
+	}
+	walk(sec, false)
+	return lines
+}
+
+// lineDifference returns the lines of a that b lacks, counting repeated lines.
+func lineDifference(a, b []string) []string {
+	remaining := map[string]int{}
+	for _, l := range b {
+		remaining[l]++
+	}
+	var out []string
+	for _, l := range a {
+		if remaining[l] > 0 {
+			remaining[l]--
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package survey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	ddpm := Reference{PaperID: "p1", Title: "Denoising Diffusion", Authors: "Ho", Year: 2020}
+	gan := Reference{PaperID: "p2", Title: "StyleGAN", Authors: "Karras", Year: 2019}
+	ldm := Reference{PaperID: "p3", Title: "Latent Diffusion", Authors: "Rombach", Year: 2022}
+
+	a := Document{
+		Sections: []Section{
+			{Title: "Introduction", Paragraphs: []Paragraph{NewParagraph("Two families [ref1, ref2].")}},
+			{Title: "GANs", Paragraphs: []Paragraph{NewParagraph("Adversarial [ref2].")}},
+			{Title: "Diffusion", Paragraphs: []Paragraph{NewParagraph("Denoising [ref1].")}},
+		},
+		References: []Reference{withKey(ddpm, "ref1"), withKey(gan, "ref2")},
+	}
+	b := Document{
+		Sections: []Section{
+			{Title: "Introduction", Paragraphs: []Paragraph{NewParagraph("Two families [ref2, ref1].")}},
+			{Title: "Diffusion", Paragraphs: []Paragraph{NewParagraph("Denoising [ref2]."), NewParagraph("Latents [ref1].")}},
+			{Title: "Efficiency", Paragraphs: []Paragraph{NewParagraph("Latents are cheap [ref1].")}},
+		},
+		References: []Reference{withKey(ldm, "ref1"), withKey(ddpm, "ref2")},
+	}
+
+	d := Compare(a, b)
+	require.Equal(t, 1, d.SharedReferences)
+	require.Equal(t, "Latent Diffusion", d.AddedReferences[0].Title)
+	require.Equal(t, "StyleGAN", d.RemovedReferences[0].Title)
+	require.Equal(t, 0, d.UnchangedSections)
+	require.Len(t, d.Sections, 4)
+	require.Equal(t, SectionChange{Title: "Introduction", Status: SectionChanged, AddedParagraphs: []string{"Two families [ho2020denoising, rombach2022latent]."}, RemovedParagraphs: []string{"Two families [ho2020denoising, karras2019stylegan]."}}, d.Sections[0])
+	require.Equal(t, SectionChange{Title: "Diffusion", Status: SectionChanged, AddedParagraphs: []string{"Latents [rombach2022latent]."}}, d.Sections[1])
+	require.Equal(t, SectionAdded, d.Sections[2].Status)
+	require.Equal(t, SectionChange{Title: "GANs", Status: SectionRemoved, RemovedParagraphs: []string{"Adversarial [karras2019stylegan]."}}, d.Sections[3])
+
+	require.Empty(t, Compare(b, b).Sections)
+}
+
+func withKey(ref Reference, key string) Reference {
+	ref.Key = key
+	return ref
+}
@@ -0,0 +1,311 @@
+// Package survey holds the format-neutral survey document that generation produces and the
+// renderers that turn it into LaTeX, Markdown or HTML.
+package survey
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Output formats a survey can be rendered to.
+const (
+	FormatLaTeX    = "latex"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// RevisionsDir holds the drafts and critiques of a run next to its report, as
+// revisions/<section_id>/draft-<round>.txt and revisions/<section_id>/critique-<round>.json.
+const RevisionsDir = "revisions"
+
+// Reference is one entry of the survey's shared reference list. Key is the inline citation
+// key ("ref1", "ref2", ...) that paragraphs cite.
+type Reference struct {
+	Key      string   `json:"key"`
+	CorpusID string   `json:"corpus_id,omitempty"`
+	PaperID  string   `json:"paper_id"`
+	Title    string   `json:"title"`
+	Authors  string   `json:"authors,omitempty"`
+	Year     int      `json:"year,omitempty"`
+	Venue    string   `json:"venue,omitempty"`
+	DOI      string   `json:"doi,omitempty"`
+	Filename string   `json:"filename,omitempty"`
+	ChunkIDs []string `json:"chunk_ids"`
+}
+
+// Paragraph is plain text with inline [refN] markers; Citations lists the cited keys in order
+// of first use.
+type Paragraph struct {
+	Text      string   `json:"text"`
+	Citations []string `json:"citations,omitempty"`
+}
+
+// Section is a titled run of paragraphs with optional subsections one level down. Tables
+// follow the paragraphs.
+type Section struct {
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title"`
+	// Query is the retrieval query the section was drafted from; updates search with it.
+	Query       string      `json:"query,omitempty"`
+	Paragraphs  []Paragraph `json:"paragraphs"`
+	Tables      []Table     `json:"tables,omitempty"`
+	Subsections []Section   `json:"subsections,omitempty"`
+}
+
+// Table is a captioned grid; cells may cite [refN] keys like paragraph text.
+type Table struct {
+	Caption string     `json:"caption"`
+	Header  []string   `json:"header"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Document is a complete survey. It is stored next to the rendered report so other formats
+// can be rendered later without another LLM call.
+type Document struct {
+	Title string `json:"title"`
+	// Author is the report's author line; empty uses DefaultAuthor.
+	Author     string      `json:"author,omitempty"`
+	Abstract   []Paragraph `json:"abstract,omitempty"`
+	Sections   []Section   `json:"sections"`
+	References []Reference `json:"references"`
+	// Notes are shown after the sections, e.g. when some sections need manual completion.
+	Notes []string `json:"notes,omitempty"`
+	// Validation holds the citation checks; its constraint verdicts close the report.
+	Validation *Validation `json:"validation,omitempty"`
+	// Changelog is set on versions produced by an incremental update.
+	Changelog *Changelog `json:"changelog,omitempty"`
+	// CorpusIDs lists the corpora a cross-corpus survey retrieved from.
+	CorpusIDs []string `json:"corpus_ids,omitempty"`
+	// TemplateID picks the report template and CitationStyle overrides its reference style;
+	// see Templates.
+	TemplateID    string `json:"template_id,omitempty"`
+	CitationStyle string `json:"citation_style,omitempty"`
+	// RepairRounds, CritiqueRounds and KGTables are the drafting settings of the run that built
+	// the document, kept so a regeneration drafts the same way.
+	RepairRounds   int  `json:"repair_rounds,omitempty"`
+	CritiqueRounds int  `json:"critique_rounds,omitempty"`
+	KGTables       bool `json:"kg_tables,omitempty"`
+}
+
+// Changelog lists the newly ingested sources an incremental update worked into the survey it
+// was based on.
+type Changelog struct {
+	BaseRunID string           `json:"base_run_id"`
+	Since     time.Time        `json:"since"`
+	NewPapers int              `json:"new_papers"`
+	Entries   []ChangelogEntry `json:"entries"`
+}
+
+// ChangelogEntry is one revised section and the reference keys it now cites for the first time.
+type ChangelogEntry struct {
+	SectionID string   `json:"section_id"`
+	Title     string   `json:"title"`
+	Sources   []string `json:"sources"`
+}
+
+// NormalizeFormat maps accepted format names to a Format constant; the empty string is LaTeX.
+func NormalizeFormat(format string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "latex", "tex":
+		return FormatLaTeX, true
+	case "markdown", "md":
+		return FormatMarkdown, true
+	case "html", "htm":
+		return FormatHTML, true
+	}
+	return "", false
+}
+
+// FileExtension is the report file extension for a normalized format.
+func FileExtension(format string) string {
+	switch format {
+	case FormatMarkdown:
+		return "md"
+	case FormatHTML:
+		return "html"
+	}
+	return "tex"
+}
+
+var (
+	citationPattern = regexp.MustCompile(`\[(ref\d+(?:\s*[,;]\s*ref\d+)*)\]`)
+	headingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*$`)
+	latexHeading    = regexp.MustCompile(`^\\(section|subsection|subsubsection)\*?\{(.+)\}\s*$`)
+	bulletPattern   = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s+`)
+	// citationGroupPattern also matches the whitespace before a group so dropped groups
+	// leave no gap before punctuation.
+	citationGroupPattern = regexp.MustCompile(`\s*` + citationPattern.String())
+)
+
+// NewParagraph builds a paragraph from text, collecting its citation keys.
+func NewParagraph(text string) Paragraph {
+	text = strings.Join(strings.Fields(text), " ")
+	return Paragraph{Text: text, Citations: CitationKeys(text)}
+}
+
+// CitationKeys lists the distinct [refN] keys cited in text, in order of first use.
+func CitationKeys(text string) []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, m := range citationPattern.FindAllStringSubmatch(text, -1) {
+		for _, k := range splitKeys(m[1]) {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+func splitKeys(group string) []string {
+	return strings.FieldsFunc(group, func(r rune) bool { return r == ',' || r == ';' || r == ' ' })
+}
+
+// ParseSection reads a drafted section body into a Section. Bodies are plain paragraphs
+// separated by blank lines; Markdown headings (or LaTeX \subsection lines from models that
+// ignore the instructions) start subsections, and a leading heading repeating the section
+// title is dropped. List items become paragraphs of their own.
+func ParseSection(title, body string) Section {
+	sec := Section{Title: strings.TrimSpace(title), Paragraphs: []Paragraph{}}
+	current := &sec
+	var buf []string
+	flush := func() {
+		if len(buf) == 0 {
+			return
+		}
+		if p := NewParagraph(strings.Join(buf, " ")); p.Text != "" {
+			current.Paragraphs = append(current.Paragraphs, p)
+		}
+		buf = nil
+	}
+	for _, line := range strings.Split(stripFences(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			flush()
+			continue
+		}
+		if heading, ok := parseHeading(line); ok {
+			flush()
+			if strings.EqualFold(heading, sec.Title) && len(sec.Paragraphs) == 0 && len(sec.Subsections) == 0 {
+				continue
+			}
+			sec.Subsections = append(sec.Subsections, Section{Title: heading, Paragraphs: []Paragraph{}})
+			current = &sec.Subsections[len(sec.Subsections)-1]
+			continue
+		}
+		if bulletPattern.MatchString(line) {
+			flush()
+			buf = append(buf, bulletPattern.ReplaceAllString(line, ""))
+			flush()
+			continue
+		}
+		buf = append(buf, line)
+	}
+	flush()
+	return sec
+}
+
+// ParseParagraphs splits text into paragraphs on blank lines.
+func ParseParagraphs(text string) []Paragraph {
+	out := []Paragraph{}
+	for _, block := range strings.Split(stripFences(text), "\n\n") {
+		if p := NewParagraph(block); p.Text != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func parseHeading(line string) (string, bool) {
+	if m := headingPattern.FindStringSubmatch(line); m != nil {
+		return strings.Trim(strings.TrimSpace(m[2]), "*"), true
+	}
+	if m := latexHeading.FindStringSubmatch(line); m != nil {
+		return strings.TrimSpace(m[2]), true
+	}
+	return "", false
+}
+
+func stripFences(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "```") {
+		if i := strings.Index(s, "\n"); i >= 0 {
+			s = s[i+1:]
+		} else {
+			s = ""
+		}
+	}
+	return strings.TrimSpace(strings.TrimSuffix(s, "```"))
+}
+
+// Render renders the document in a normalized format, through its template when the
+// template is written in that format.
+func Render(d Document, format string) (string, error) {
+	if t, ok := LookupTemplate(d.TemplateID); ok && t.Format == format {
+		return renderTemplate(d, t)
+	}
+	switch format {
+	case FormatMarkdown:
+		return RenderMarkdown(d), nil
+	case FormatHTML:
+		return RenderHTML(d), nil
+	}
+	return RenderLaTeX(d)
+}
+
+// PlainText writes a section back in the plain-text form drafts use: paragraphs separated by
+// blank lines, "### " subsection headings and inline [refN] keys.
+func PlainText(s Section) string {
+	parts := make([]string, 0, len(s.Paragraphs))
+	for _, p := range s.Paragraphs {
+		parts = append(parts, p.Text)
+	}
+	for _, sub := range s.Subsections {
+		parts = append(parts, strings.TrimSpace("### "+sub.Title+"\n"+PlainText(sub)))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// RemapCitations rewrites the citation keys of a section through mapping. Keys missing from
+// mapping are dropped, along with groups left empty.
+func RemapCitations(s Section, mapping map[string]string) Section {
+	out := Section{ID: s.ID, Title: s.Title, Query: s.Query, Paragraphs: make([]Paragraph, 0, len(s.Paragraphs))}
+	for _, p := range s.Paragraphs {
+		out.Paragraphs = append(out.Paragraphs, NewParagraph(remapText(p.Text, mapping)))
+	}
+	for _, t := range s.Tables {
+		rows := make([][]string, 0, len(t.Rows))
+		for _, row := range t.Rows {
+			cells := make([]string, 0, len(row))
+			for _, c := range row {
+				cells = append(cells, remapText(c, mapping))
+			}
+			rows = append(rows, cells)
+		}
+		out.Tables = append(out.Tables, Table{Caption: t.Caption, Header: t.Header, Rows: rows})
+	}
+	for _, sub := range s.Subsections {
+		out.Subsections = append(out.Subsections, RemapCitations(sub, mapping))
+	}
+	return out
+}
+
+func remapText(text string, mapping map[string]string) string {
+	return citationGroupPattern.ReplaceAllStringFunc(text, func(match string) string {
+		group := strings.TrimLeft(match, " \t\r\n")
+		seen := map[string]bool{}
+		mapped := make([]string, 0)
+		for _, k := range splitKeys(strings.Trim(group, "[]")) {
+			if shared, ok := mapping[k]; ok && !seen[shared] {
+				seen[shared] = true
+				mapped = append(mapped, shared)
+			}
+		}
+		if len(mapped) == 0 {
+			return ""
+		}
+		return match[:len(match)-len(group)] + "[" + strings.Join(mapped, ", ") + "]"
+	})
+}
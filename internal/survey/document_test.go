@@ -0,0 +1,113 @@
+package survey
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSection(t *testing.T) {
+	body := "```\n## Diffusion Models\nDenoising models [ref1]\nscale well [ref1; ref2].\n\n### Sampling\n- DDIM skips steps [ref2].\n- Distillation halves them.\n```"
+	sec := ParseSection("Diffusion Models", body)
+	require.Equal(t, "Diffusion Models", sec.Title)
+	require.Len(t, sec.Paragraphs, 1)
+	require.Equal(t, "Denoising models [ref1] scale well [ref1; ref2].", sec.Paragraphs[0].Text)
+	require.Equal(t, []string{"ref1", "ref2"}, sec.Paragraphs[0].Citations)
+	require.Len(t, sec.Subsections, 1)
+	require.Equal(t, "Sampling", sec.Subsections[0].Title)
+	require.Len(t, sec.Subsections[0].Paragraphs, 2)
+	require.Equal(t, "DDIM skips steps [ref2].", sec.Subsections[0].Paragraphs[0].Text)
+
+	latex := ParseSection("GANs", "\\section{GANs}\n\\subsection{Training}\nUnstable [ref3].")
+	require.Empty(t, latex.Paragraphs)
+	require.Equal(t, "Training", latex.Subsections[0].Title)
+}
+
+func TestRemapCitations(t *testing.T) {
+	sec := Section{Title: "GANs", Paragraphs: []Paragraph{NewParagraph("Adversarial [ref1, ref2] and unknown [ref7].")}}
+	out := RemapCitations(sec, map[string]string{"ref1": "ref3", "ref2": "ref3"})
+	require.Equal(t, "Adversarial [ref3] and unknown.", out.Paragraphs[0].Text)
+	require.Equal(t, []string{"ref3"}, out.Paragraphs[0].Citations)
+}
+
+func TestRenderFormats(t *testing.T) {
+	doc := Document{
+		Title:    "Diffusion & GANs",
+		Abstract: []Paragraph{NewParagraph("We compare 2 families.")},
+		Sections: []Section{{ID: "s1", Title: "Models", Paragraphs: []Paragraph{NewParagraph("Scores <90% [ref1, ref2].")}}},
+		References: []Reference{
+			{Key: "ref1", CorpusID: "a", Title: "DDPM", Authors: "Ho et al.", Year: 2020},
+			{Key: "ref2", CorpusID: "b", Title: "StyleGAN"},
+		},
+		Notes: []string{"Section \"GANs\" needs review."},
+	}
+
+	latex := mustRender(t, doc, FormatLaTeX)
+	require.Contains(t, latex, "\\title{Literature Survey: Diffusion \\& GANs}")
+	require.Contains(t, latex, "\\begin{abstract}\nWe compare 2 families.\n\\end{abstract}")
+	require.Contains(t, latex, "\\section{Models}\nScores <90\\% \\cite{ho2020ddpm,anonndstylegan}.")
+	require.Contains(t, latex, "\\section*{Generation Note}")
+	require.Contains(t, latex, "\\bibliography{refs}")
+
+	md := mustRender(t, doc, FormatMarkdown)
+	require.True(t, strings.HasPrefix(md, "# Literature Survey: Diffusion & GANs\n"))
+	require.Contains(t, md, "## Models\n\nScores <90% [[ref1](#ref1), [ref2](#ref2)].")
+	require.Contains(t, md, "- <a id=\"ref1\"></a>**[ref1]** DDPM. Ho et al., 2020 (corpus `a`)")
+
+	page := mustRender(t, doc, FormatHTML)
+	require.Contains(t, page, "<h1>Literature Survey: Diffusion &amp; GANs</h1>")
+	require.Contains(t, page, "<section id=\"s1\">\n<h2>Models</h2>\n<p>Scores &lt;90% [<a href=\"#ref1\">ref1</a>, <a href=\"#ref2\">ref2</a>].</p>")
+	require.Contains(t, page, "<li id=\"ref2\">[ref2] <cite>StyleGAN</cite> (corpus <code>b</code>)</li>")
+
+	format, ok := NormalizeFormat(" MD ")
+	require.True(t, ok)
+	require.Equal(t, "md", FileExtension(format))
+	_, ok = NormalizeFormat("docx")
+	require.False(t, ok)
+}
+
+func TestRenderChangelog(t *testing.T) {
+	doc := Document{
+		Title:      "Diffusion",
+		Sections:   []Section{{ID: "s1", Title: "Samplers", Paragraphs: []Paragraph{NewParagraph("Fewer steps [ref1].")}}},
+		References: []Reference{{Key: "ref1", Title: "DPM-Solver", Authors: "Lu et al.", Year: 2022}},
+		Changelog: &Changelog{
+			BaseRunID: "run-0",
+			Since:     time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+			NewPapers: 2,
+			Entries:   []ChangelogEntry{{SectionID: "s1", Title: "Samplers", Sources: []string{"ref1"}}},
+		},
+	}
+
+	md := mustRender(t, doc, FormatMarkdown)
+	require.Contains(t, md, "## Changelog\n\nUpdated from survey run run-0 with 2 paper(s) ingested since 2026-03-01 09:30 UTC.\n\n- Samplers: incorporates [[ref1](#ref1)]\n")
+	require.Contains(t, mustRender(t, doc, FormatLaTeX), "\\item Samplers: incorporates \\cite{lu2022dpmsolver}")
+	require.Contains(t, mustRender(t, doc, FormatHTML), "<li>Samplers: incorporates [<a href=\"#ref1\">ref1</a>]</li>")
+
+	doc.Changelog.Entries = nil
+	require.Contains(t, mustRender(t, doc, FormatMarkdown), "None of them was relevant to the existing sections.")
+}
+
+func TestRenderTables(t *testing.T) {
+	doc := Document{
+		Title: "Diffusion",
+		Sections: []Section{{ID: "kg", Title: "Method Comparison", Tables: []Table{{
+			Caption: "Datasets & wins",
+			Header:  []string{"Method", "CIFAR-10", "Outperforms"},
+			Rows:    [][]string{{"DDIM", "[ref1]", "DDPM [ref2]"}, {"A|B", "", ""}},
+		}}}},
+		References: []Reference{{Key: "ref1", Title: "DDIM", Authors: "Jiaming Song", Year: 2021}, {Key: "ref2", Title: "DDPM", Authors: "Jonathan Ho", Year: 2020}},
+	}
+
+	require.Contains(t, mustRender(t, doc, FormatLaTeX), "\\caption{Datasets \\& wins}\n\\begin{tabular}{lcc}\n\\hline\nMethod & CIFAR-10 & Outperforms \\\\\n\\hline\nDDIM & \\cite{song2021ddim} & DDPM \\cite{ho2020ddpm} \\\\\n")
+	md := mustRender(t, doc, FormatMarkdown)
+	require.Contains(t, md, "| Method | CIFAR-10 | Outperforms |\n| --- | --- | --- |\n| DDIM | [[ref1](#ref1)] | DDPM [[ref2](#ref2)] |\n| A\\|B |  |  |\n\n*Datasets & wins*\n")
+	require.Contains(t, mustRender(t, doc, FormatHTML), "<tr><td>DDIM</td><td>[<a href=\"#ref1\">ref1</a>]</td><td>DDPM [<a href=\"#ref2\">ref2</a>]</td></tr>")
+	doc.TemplateID = "memo"
+	require.Contains(t, mustRender(t, doc, FormatMarkdown), "| DDIM | [[ref1](#ref1)] | DDPM [[ref2](#ref2)] |\n")
+
+	remapped := RemapCitations(doc.Sections[0], map[string]string{"ref1": "ref5"})
+	require.Equal(t, []string{"DDIM", "[ref5]", "DDPM"}, remapped.Tables[0].Rows[0])
+}
@@ -0,0 +1,332 @@
+package survey
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"litflow/internal/util"
+)
+
+// RenderLaTeX renders the document's LaTeX template, or the IEEE one when it names none.
+// Inline [refN] keys become \cite commands with the BibTeX keys of RenderBibTeX, and the
+// bibliography is read from BibFilename. Every retrieved source is listed, cited or not.
+func RenderLaTeX(d Document) (string, error) {
+	t, ok := LookupTemplate(d.TemplateID)
+	if !ok || t.Format != FormatLaTeX {
+		t, _ = LookupTemplate(DefaultTemplateID)
+	}
+	return renderTemplate(d, t)
+}
+
+// RenderMarkdown renders GitHub-flavoured Markdown; inline keys link to anchored entries of
+// the reference list, written in the document's citation style when it sets one.
+func RenderMarkdown(d Document) string {
+	var b strings.Builder
+	b.WriteString("# Literature Survey: " + d.Title + "\n\n")
+	if len(d.Abstract) > 0 {
+		b.WriteString("## Abstract\n\n")
+		for _, p := range d.Abstract {
+			b.WriteString(markdownCitations(p.Text) + "\n\n")
+		}
+	}
+	var section func(s Section, level int)
+	section = func(s Section, level int) {
+		b.WriteString(strings.Repeat("#", level+2) + " " + s.Title + "\n\n")
+		for _, p := range s.Paragraphs {
+			b.WriteString(markdownCitations(p.Text) + "\n\n")
+		}
+		for _, t := range s.Tables {
+			b.WriteString(markdownTable(t, markdownCell))
+		}
+		for _, sub := range s.Subsections {
+			section(sub, level+1)
+		}
+	}
+	for _, s := range d.Sections {
+		section(s, 0)
+	}
+	if len(d.Notes) > 0 {
+		b.WriteString("## Generation Note\n\n")
+		for _, n := range d.Notes {
+			b.WriteString("> " + n + "\n")
+		}
+		b.WriteString("\n")
+	}
+	if d.Validation != nil && len(d.Validation.Constraints) > 0 {
+		b.WriteString("## Citation Checks\n\n")
+		for _, c := range d.Validation.Constraints {
+			mark := " "
+			if c.Satisfied {
+				mark = "x"
+			}
+			b.WriteString("- [" + mark + "] " + constraintLine(c) + "\n")
+		}
+		b.WriteString("\n")
+	}
+	if d.Changelog != nil {
+		summary, entries := changelogText(d.Changelog)
+		b.WriteString("## Changelog\n\n" + summary + "\n\n")
+		for _, e := range entries {
+			b.WriteString("- " + markdownCitations(e) + "\n")
+		}
+		if len(entries) > 0 {
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("## References\n\n")
+	multiCorpus := spansCorpora(d.References)
+	for _, ref := range d.References {
+		entry := referenceTitle(ref)
+		if d.CitationStyle != "" {
+			entry = FormatReference(ref, d.CitationStyle)
+		} else {
+			if details := referenceDetails(ref); details != "" {
+				entry += ". " + details
+			}
+			if doi := strings.TrimSpace(ref.DOI); doi != "" {
+				entry += ". [doi:" + doi + "](https://doi.org/" + doi + ")"
+			}
+		}
+		if multiCorpus && ref.CorpusID != "" {
+			entry += " (corpus `" + ref.CorpusID + "`)"
+		}
+		fmt.Fprintf(&b, "- <a id=\"%s\"></a>**[%s]** %s\n", ref.Key, ref.Key, entry)
+	}
+	return b.String()
+}
+
+// RenderHTML renders a standalone HTML page; inline keys link to the reference list.
+func RenderHTML(d Document) string {
+	esc := html.EscapeString
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<title>Literature Survey: " + esc(d.Title) + "</title>\n</head>\n<body>\n")
+	b.WriteString("<h1>Literature Survey: " + esc(d.Title) + "</h1>\n")
+	if len(d.Abstract) > 0 {
+		b.WriteString("<section class=\"abstract\">\n<h2>Abstract</h2>\n")
+		for _, p := range d.Abstract {
+			b.WriteString("<p>" + htmlCitations(p.Text) + "</p>\n")
+		}
+		b.WriteString("</section>\n")
+	}
+	var section func(s Section, level int)
+	section = func(s Section, level int) {
+		tag := fmt.Sprintf("h%d", min(level+2, 6))
+		open := "<section>\n"
+		if s.ID != "" {
+			open = "<section id=\"" + esc(s.ID) + "\">\n"
+		}
+		b.WriteString(open + "<" + tag + ">" + esc(s.Title) + "</" + tag + ">\n")
+		for _, p := range s.Paragraphs {
+			b.WriteString("<p>" + htmlCitations(p.Text) + "</p>\n")
+		}
+		for _, t := range s.Tables {
+			b.WriteString("<table>\n<caption>" + esc(t.Caption) + "</caption>\n<thead><tr>")
+			for _, h := range t.Header {
+				b.WriteString("<th>" + esc(h) + "</th>")
+			}
+			b.WriteString("</tr></thead>\n<tbody>\n")
+			for _, row := range t.Rows {
+				b.WriteString("<tr>")
+				for _, c := range row {
+					b.WriteString("<td>" + htmlCitations(c) + "</td>")
+				}
+				b.WriteString("</tr>\n")
+			}
+			b.WriteString("</tbody>\n</table>\n")
+		}
+		for _, sub := range s.Subsections {
+			section(sub, level+1)
+		}
+		b.WriteString("</section>\n")
+	}
+	for _, s := range d.Sections {
+		section(s, 0)
+	}
+	if len(d.Notes) > 0 {
+		b.WriteString("<aside class=\"generation-note\">\n")
+		for _, n := range d.Notes {
+			b.WriteString("<p>" + esc(n) + "</p>\n")
+		}
+		b.WriteString("</aside>\n")
+	}
+	if d.Validation != nil && len(d.Validation.Constraints) > 0 {
+		b.WriteString("<h2>Citation Checks</h2>\n<ul class=\"citation-checks\">\n")
+		for _, c := range d.Validation.Constraints {
+			class := "unsatisfied"
+			if c.Satisfied {
+				class = "satisfied"
+			}
+			b.WriteString("<li class=\"" + class + "\">" + esc(constraintLine(c)) + "</li>\n")
+		}
+		b.WriteString("</ul>\n")
+	}
+	if d.Changelog != nil {
+		summary, entries := changelogText(d.Changelog)
+		b.WriteString("<h2>Changelog</h2>\n<p>" + esc(summary) + "</p>\n")
+		if len(entries) > 0 {
+			b.WriteString("<ul class=\"changelog\">\n")
+			for _, e := range entries {
+				b.WriteString("<li>" + htmlCitations(e) + "</li>\n")
+			}
+			b.WriteString("</ul>\n")
+		}
+	}
+	b.WriteString("<h2>References</h2>\n<ul class=\"references\">\n")
+	multiCorpus := spansCorpora(d.References)
+	for _, ref := range d.References {
+		entry := "<cite>" + esc(referenceTitle(ref)) + "</cite>"
+		if d.CitationStyle != "" {
+			entry = esc(FormatReference(ref, d.CitationStyle))
+		} else {
+			if details := referenceDetails(ref); details != "" {
+				entry += ". " + esc(details)
+			}
+			if doi := strings.TrimSpace(ref.DOI); doi != "" {
+				entry += ". <a href=\"https://doi.org/" + esc(doi) + "\">doi:" + esc(doi) + "</a>"
+			}
+		}
+		if multiCorpus && ref.CorpusID != "" {
+			entry += " (corpus <code>" + esc(ref.CorpusID) + "</code>)"
+		}
+		fmt.Fprintf(&b, "<li id=\"%s\">[%s] %s</li>\n", esc(ref.Key), esc(ref.Key), entry)
+	}
+	b.WriteString("</ul>\n</body>\n</html>\n")
+	return b.String()
+}
+
+// latexCitations escapes text and turns citation groups into \cite commands; keys without a
+// reference are dropped.
+func latexCitations(text string, bibKeys map[string]string) string {
+	return replaceCitations(text, util.EscapeLaTeX, func(keys []string) string {
+		cited := make([]string, 0, len(keys))
+		for _, k := range keys {
+			if bib, ok := bibKeys[k]; ok {
+				cited = append(cited, bib)
+			}
+		}
+		if len(cited) == 0 {
+			return ""
+		}
+		return "\\cite{" + strings.Join(cited, ",") + "}"
+	})
+}
+
+// markdownTable writes a pipe table followed by its caption in italics.
+func markdownTable(t Table, cell func(string) string) string {
+	var b strings.Builder
+	header := make([]string, 0, len(t.Header))
+	for _, h := range t.Header {
+		header = append(header, strings.ReplaceAll(h, "|", "\\|"))
+	}
+	b.WriteString("| " + strings.Join(header, " | ") + " |\n|" + strings.Repeat(" --- |", len(t.Header)) + "\n")
+	for _, row := range t.Rows {
+		cells := make([]string, 0, len(row))
+		for _, c := range row {
+			cells = append(cells, cell(c))
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	if t.Caption != "" {
+		b.WriteString("\n*" + t.Caption + "*\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// markdownCell renders a table cell; pipes are escaped so they do not split the cell.
+func markdownCell(text string) string {
+	return markdownCitations(strings.ReplaceAll(text, "|", "\\|"))
+}
+
+func markdownCitations(text string) string {
+	return replaceCitations(text, func(s string) string { return s }, linkedGroup(func(key string) string {
+		return "[" + key + "](#" + key + ")"
+	}))
+}
+
+func htmlCitations(text string) string {
+	return replaceCitations(text, html.EscapeString, linkedGroup(func(key string) string {
+		return "<a href=\"#" + key + "\">" + key + "</a>"
+	}))
+}
+
+// linkedGroup renders a citation group as bracketed, individually linked keys.
+func linkedGroup(link func(string) string) func([]string) string {
+	return func(keys []string) string {
+		linked := make([]string, 0, len(keys))
+		for _, k := range keys {
+			linked = append(linked, link(k))
+		}
+		return "[" + strings.Join(linked, ", ") + "]"
+	}
+}
+
+// replaceCitations escapes the prose between citation groups and renders each group's keys
+// with group.
+func replaceCitations(text string, escape func(string) string, group func([]string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range citationPattern.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(escape(text[last:m[0]]))
+		b.WriteString(group(splitKeys(text[m[2]:m[3]])))
+		last = m[1]
+	}
+	b.WriteString(escape(text[last:]))
+	return b.String()
+}
+
+// constraintLine reads "Label: satisfied" or "Label: not satisfied (detail)".
+func constraintLine(c Constraint) string {
+	if c.Satisfied {
+		return c.Label + ": satisfied"
+	}
+	if c.Detail == "" {
+		return c.Label + ": not satisfied"
+	}
+	return c.Label + ": not satisfied (" + c.Detail + ")"
+}
+
+// changelogText is the summary sentence of a changelog and one "Section: incorporates [keys]"
+// line per revised section, with citation groups left for the renderer.
+func changelogText(c *Changelog) (string, []string) {
+	summary := fmt.Sprintf("Updated from survey run %s with %d paper(s) ingested since %s.", c.BaseRunID, c.NewPapers, c.Since.UTC().Format("2006-01-02 15:04 UTC"))
+	if len(c.Entries) == 0 {
+		summary += " None of them was relevant to the existing sections."
+	}
+	entries := make([]string, 0, len(c.Entries))
+	for _, e := range c.Entries {
+		entries = append(entries, e.Title+": incorporates ["+strings.Join(e.Sources, ", ")+"]")
+	}
+	return summary, entries
+}
+
+func referenceTitle(ref Reference) string {
+	if title := strings.TrimSpace(ref.Title); title != "" {
+		return title
+	}
+	return "Untitled paper"
+}
+
+// referenceDetails is the "authors, venue, year" part of a reference entry.
+func referenceDetails(ref Reference) string {
+	parts := make([]string, 0, 3)
+	for _, p := range []string{ref.Authors, ref.Venue} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if ref.Year > 0 {
+		parts = append(parts, fmt.Sprintf("%d", ref.Year))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func spansCorpora(refs []Reference) bool {
+	corpora := map[string]bool{}
+	for _, ref := range refs {
+		corpora[ref.CorpusID] = true
+	}
+	return len(corpora) > 1
+}
@@ -0,0 +1,192 @@
+package survey
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Citation styles for reference lists.
+const (
+	StyleIEEE = "ieee"
+	StyleAPA  = "apa"
+	StyleACM  = "acm"
+)
+
+// NormalizeCitationStyle maps accepted style names to a Style constant; the empty string
+// stays empty so the template's own style applies.
+func NormalizeCitationStyle(style string) (string, bool) {
+	switch s := strings.ToLower(strings.TrimSpace(style)); s {
+	case "", StyleIEEE, StyleAPA, StyleACM:
+		return s, true
+	}
+	return "", false
+}
+
+// BibStyle is the BibTeX style LaTeX reports use for a citation style.
+func BibStyle(style string) string {
+	switch style {
+	case StyleAPA:
+		return "apalike"
+	case StyleACM:
+		return "ACM-Reference-Format"
+	}
+	return "IEEEtran"
+}
+
+// FormatReference writes one reference list entry from paper metadata in the given style,
+// e.g. IEEE: A. Vaswani and N. Shazeer, "Attention is all you need," NeurIPS, 2017.
+// Missing fields are left out; APA marks a missing year as n.d.
+func FormatReference(ref Reference, style string) string {
+	title := referenceTitle(ref)
+	names := SplitAuthors(ref.Authors)
+	etAl := etAlSuffix.MatchString(strings.TrimSpace(ref.Authors))
+	venue := strings.TrimSpace(ref.Venue)
+	year := ""
+	if ref.Year > 0 {
+		year = strconv.Itoa(ref.Year)
+	}
+	doi := strings.TrimSpace(ref.DOI)
+
+	var parts []string
+	switch style {
+	case StyleAPA:
+		if year == "" {
+			year = "n.d."
+		}
+		if len(names) > 0 {
+			formatted := make([]string, 0, len(names))
+			for _, n := range names {
+				given, family := nameParts(n)
+				formatted = append(formatted, strings.TrimSpace(family+", "+initials(given)))
+			}
+			parts = append(parts, sentence(joinAPA(formatted, etAl)), "("+year+").", sentence(title))
+		} else {
+			parts = append(parts, sentence(title), "("+year+").")
+		}
+		if venue != "" {
+			parts = append(parts, sentence(venue))
+		}
+		if doi != "" {
+			parts = append(parts, "https://doi.org/"+doi)
+		}
+	case StyleACM:
+		if len(names) > 0 {
+			formatted := make([]string, 0, len(names))
+			for _, n := range names {
+				given, family := nameParts(n)
+				formatted = append(formatted, strings.TrimSpace(strings.Join(given, " ")+" "+family))
+			}
+			parts = append(parts, sentence(joinList(formatted, etAl)))
+		}
+		if year != "" {
+			parts = append(parts, year+".")
+		}
+		parts = append(parts, sentence(title))
+		if venue != "" {
+			parts = append(parts, sentence(venue))
+		}
+		if doi != "" {
+			parts = append(parts, "https://doi.org/"+doi)
+		}
+	default:
+		lead := ""
+		if len(names) > 0 {
+			formatted := make([]string, 0, len(names))
+			for _, n := range names {
+				given, family := nameParts(n)
+				formatted = append(formatted, strings.TrimSpace(initials(given)+" "+family))
+			}
+			if len(formatted) > 6 {
+				formatted, etAl = formatted[:1], true
+			}
+			lead = joinList(formatted, etAl) + ", "
+		}
+		// The comma or period after the title goes inside the quotes unless the title already
+		// ends in a question or exclamation mark.
+		title = strings.TrimRight(title, ".")
+		tail := strings.Join(nonEmpty(venue, year), ", ")
+		closing := ","
+		if tail == "" {
+			closing = "."
+		}
+		if strings.HasSuffix(title, "?") || strings.HasSuffix(title, "!") {
+			closing = ""
+		}
+		entry := lead + "\"" + title + closing + "\""
+		if tail != "" {
+			entry += " " + tail + "."
+		}
+		parts = append(parts, entry)
+		if doi != "" {
+			parts = append(parts, "doi: "+doi+".")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// nameParts splits a name into given names and family name; "Last, F." names are read
+// family first.
+func nameParts(name string) ([]string, string) {
+	if last, first, ok := strings.Cut(name, ","); ok {
+		return strings.Fields(first), strings.TrimSpace(last)
+	}
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return nil, ""
+	}
+	return fields[:len(fields)-1], fields[len(fields)-1]
+}
+
+// initials abbreviates given names, e.g. ["Ashish", "K."] to "A. K.".
+func initials(given []string) string {
+	out := make([]string, 0, len(given))
+	for _, g := range given {
+		if r := []rune(strings.Trim(g, ".")); len(r) > 0 {
+			out = append(out, string(r[0])+".")
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// joinList joins names as "a", "a and b" or "a, b, and c".
+func joinList(names []string, etAl bool) string {
+	if etAl {
+		return strings.Join(names, ", ") + " et al."
+	}
+	switch len(names) {
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + " and " + names[1]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + ", and " + names[len(names)-1]
+}
+
+// joinAPA joins names as "a", "a, & b" or "a, b, & c".
+func joinAPA(names []string, etAl bool) string {
+	if etAl {
+		return strings.Join(names, ", ") + ", et al."
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + ", & " + names[len(names)-1]
+}
+
+// sentence ends s with a period unless it already ends in punctuation.
+func sentence(s string) string {
+	if strings.HasSuffix(s, ".") || strings.HasSuffix(s, "?") || strings.HasSuffix(s, "!") {
+		return s
+	}
+	return s + "."
+}
+
+func nonEmpty(values ...string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
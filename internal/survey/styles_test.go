@@ -0,0 +1,31 @@
+package survey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatReference(t *testing.T) {
+	attention := Reference{Title: "Attention Is All You Need", Authors: "Ashish Vaswani, Noam Shazeer, Niki Parmar", Year: 2017, Venue: "NeurIPS", DOI: "10.5555/3295222"}
+	question := Reference{Title: "What is a sampler?", Authors: "Ho, J."}
+	etAl := Reference{Title: "Score Models", Authors: "Yang Song et al.", Year: 2021, Venue: "ICLR"}
+
+	require.Equal(t, `A. Vaswani, N. Shazeer, and N. Parmar, "Attention Is All You Need," NeurIPS, 2017. doi: 10.5555/3295222.`, FormatReference(attention, StyleIEEE))
+	require.Equal(t, `J. Ho, "What is a sampler?"`, FormatReference(question, StyleIEEE))
+	require.Equal(t, `Y. Song et al., "Score Models," ICLR, 2021.`, FormatReference(etAl, StyleIEEE))
+
+	require.Equal(t, "Vaswani, A., Shazeer, N., & Parmar, N. (2017). Attention Is All You Need. NeurIPS. https://doi.org/10.5555/3295222", FormatReference(attention, StyleAPA))
+	require.Equal(t, "Ho, J. (n.d.). What is a sampler?", FormatReference(question, StyleAPA))
+	require.Equal(t, "Untitled paper. (n.d.).", FormatReference(Reference{}, StyleAPA))
+
+	require.Equal(t, "Ashish Vaswani, Noam Shazeer, and Niki Parmar. 2017. Attention Is All You Need. NeurIPS. https://doi.org/10.5555/3295222", FormatReference(attention, StyleACM))
+	require.Equal(t, "Yang Song et al. 2021. Score Models. ICLR.", FormatReference(etAl, StyleACM))
+
+	style, ok := NormalizeCitationStyle(" APA ")
+	require.True(t, ok)
+	require.Equal(t, StyleAPA, style)
+	_, ok = NormalizeCitationStyle("chicago")
+	require.False(t, ok)
+	require.Equal(t, "apalike", BibStyle(StyleAPA))
+}
@@ -0,0 +1,207 @@
+package survey
+
+import (
+	"embed"
+	"fmt"
+	"html"
+	"strings"
+	"text/template"
+
+	"litflow/internal/util"
+)
+
+// DefaultTemplateID is the layout of LaTeX reports that name no template.
+const DefaultTemplateID = "ieee"
+
+// DefaultAuthor is the author line of reports whose document names none.
+const DefaultAuthor = "LitFlow Automated Draft"
+
+// Template describes one report layout under templates/. Its format fixes the output format
+// of a report that uses it; CitationStyle applies unless the request picks another.
+type Template struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Format        string `json:"format"`
+	CitationStyle string `json:"citation_style"`
+	Description   string `json:"description"`
+	file          string
+}
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+var (
+	reportTemplates = template.Must(template.New("").Funcs(template.FuncMap{"join": strings.Join}).ParseFS(templateFiles, "templates/*.tmpl"))
+	templateList    = []Template{
+		{ID: "ieee", Name: "IEEE conference", Format: FormatLaTeX, CitationStyle: StyleIEEE, Description: "Two-column IEEEtran conference paper with numbered IEEE references.", file: "ieee.tex.tmpl"},
+		{ID: "acm", Name: "ACM article", Format: FormatLaTeX, CitationStyle: StyleACM, Description: "acmart sigconf article with the ACM reference format.", file: "acm.tex.tmpl"},
+		{ID: "article", Name: "Plain article", Format: FormatLaTeX, CitationStyle: StyleAPA, Description: "Single-column LaTeX article with author-year references.", file: "article.tex.tmpl"},
+		{ID: "memo", Name: "Internal memo", Format: FormatMarkdown, CitationStyle: StyleAPA, Description: "Markdown memo: summary, findings per section and a formatted source list.", file: "memo.md.tmpl"},
+	}
+)
+
+// Templates lists the available report templates.
+func Templates() []Template {
+	return append([]Template(nil), templateList...)
+}
+
+// LookupTemplate finds a template by ID, ignoring case and surrounding space.
+func LookupTemplate(id string) (Template, bool) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	for _, t := range templateList {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// templateData is what report templates execute against. Every string is already escaped
+// for the template's format and citation groups are already rendered.
+type templateData struct {
+	Title      string
+	Author     string
+	Abstract   []string
+	Sections   []templateSection
+	Notes      []string
+	Checks     []templateCheck
+	Changelog  *templateChangelog
+	References []templateReference
+	BibStyle   string
+	BibFile    string
+}
+
+type templateSection struct {
+	ID          string
+	Title       string
+	Paragraphs  []string
+	Tables      []templateTable
+	Subsections []templateSection
+}
+
+// templateTable carries the LaTeX column spec along with the escaped cells.
+type templateTable struct {
+	Caption string
+	Spec    string
+	Header  []string
+	Rows    [][]string
+}
+
+type templateCheck struct {
+	Text      string
+	Satisfied bool
+}
+
+type templateChangelog struct {
+	Summary string
+	Entries []string
+}
+
+// templateReference is one reference list entry; Corpus is set only when the references
+// span several corpora.
+type templateReference struct {
+	Key    string
+	BibKey string
+	Text   string
+	Corpus string
+}
+
+// renderTemplate executes t for d.
+func renderTemplate(d Document, t Template) (string, error) {
+	style := d.CitationStyle
+	if style == "" {
+		style = t.CitationStyle
+	}
+	var b strings.Builder
+	if err := reportTemplates.ExecuteTemplate(&b, t.file, newTemplateData(d, t.Format, style)); err != nil {
+		return "", fmt.Errorf("render survey template %s: %w", t.ID, err)
+	}
+	return b.String(), nil
+}
+
+func newTemplateData(d Document, format, style string) templateData {
+	bibKeys := AssignBibKeys(d.References)
+	// esc escapes plain text, text also renders citation groups and cell is text for table
+	// cells and headers.
+	var esc, text, cell func(string) string
+	switch format {
+	case FormatMarkdown:
+		esc = func(s string) string { return s }
+		text, cell = markdownCitations, markdownCell
+	case FormatHTML:
+		esc = html.EscapeString
+		text, cell = htmlCitations, htmlCitations
+	default:
+		esc = util.EscapeLaTeX
+		text = func(s string) string { return latexCitations(s, bibKeys) }
+		cell = text
+	}
+	paragraphs := func(ps []Paragraph) []string {
+		out := make([]string, 0, len(ps))
+		for _, p := range ps {
+			out = append(out, text(p.Text))
+		}
+		return out
+	}
+	var section func(s Section) templateSection
+	section = func(s Section) templateSection {
+		ts := templateSection{ID: esc(s.ID), Title: esc(s.Title), Paragraphs: paragraphs(s.Paragraphs)}
+		for _, t := range s.Tables {
+			tt := templateTable{Caption: esc(t.Caption), Spec: "l" + strings.Repeat("c", max(len(t.Header)-1, 0))}
+			for _, h := range t.Header {
+				tt.Header = append(tt.Header, cell(h))
+			}
+			for _, row := range t.Rows {
+				cells := make([]string, 0, len(row))
+				for _, c := range row {
+					cells = append(cells, cell(c))
+				}
+				tt.Rows = append(tt.Rows, cells)
+			}
+			ts.Tables = append(ts.Tables, tt)
+		}
+		for _, sub := range s.Subsections {
+			ts.Subsections = append(ts.Subsections, section(sub))
+		}
+		return ts
+	}
+
+	author := strings.TrimSpace(d.Author)
+	if author == "" {
+		author = DefaultAuthor
+	}
+	data := templateData{
+		Title:    esc(d.Title),
+		Author:   esc(author),
+		Abstract: paragraphs(d.Abstract),
+		BibStyle: BibStyle(style),
+		BibFile:  strings.TrimSuffix(BibFilename, ".bib"),
+	}
+	for _, s := range d.Sections {
+		data.Sections = append(data.Sections, section(s))
+	}
+	for _, n := range d.Notes {
+		data.Notes = append(data.Notes, esc(n))
+	}
+	if d.Validation != nil {
+		for _, c := range d.Validation.Constraints {
+			data.Checks = append(data.Checks, templateCheck{Text: esc(constraintLine(c)), Satisfied: c.Satisfied})
+		}
+	}
+	if d.Changelog != nil {
+		summary, entries := changelogText(d.Changelog)
+		data.Changelog = &templateChangelog{Summary: esc(summary)}
+		for _, e := range entries {
+			data.Changelog.Entries = append(data.Changelog.Entries, text(e))
+		}
+	}
+	multiCorpus := spansCorpora(d.References)
+	for _, ref := range d.References {
+		entry := templateReference{Key: esc(ref.Key), BibKey: bibKeys[ref.Key], Text: esc(FormatReference(ref, style))}
+		if multiCorpus {
+			entry.Corpus = esc(ref.CorpusID)
+		}
+		data.References = append(data.References, entry)
+	}
+	return data
+}
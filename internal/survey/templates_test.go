@@ -0,0 +1,64 @@
+package survey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates(t *testing.T) {
+	doc := Document{
+		Title:      "Diffusion & Scores",
+		Abstract:   []Paragraph{NewParagraph("Two views [ref1].")},
+		Sections:   []Section{{ID: "s1", Title: "Samplers", Paragraphs: []Paragraph{NewParagraph("Fast [ref1, ref2].")}, Subsections: []Section{{Title: "ODE", Paragraphs: []Paragraph{NewParagraph("Ten steps.")}}}}},
+		References: []Reference{{Key: "ref1", Title: "DDPM", Authors: "Jonathan Ho", Year: 2020}, {Key: "ref2", Title: "DPM-Solver", Authors: "Cheng Lu", Year: 2022}},
+		Notes:      []string{"Review GANs."},
+		Validation: &Validation{Constraints: []Constraint{{Label: "Every claim cites a source", Satisfied: true}}},
+	}
+	for _, tmpl := range Templates() {
+		doc.TemplateID = tmpl.ID
+		out := mustRender(t, doc, tmpl.Format)
+		require.NotEmpty(t, out, tmpl.ID)
+		require.NotContains(t, out, "<no value>", tmpl.ID)
+	}
+
+	doc.TemplateID = "acm"
+	acm := mustRender(t, doc, FormatLaTeX)
+	require.True(t, strings.HasPrefix(acm, "\\documentclass[sigconf,nonacm]{acmart}\n"))
+	require.Contains(t, acm, "\\begin{abstract}\nTwo views \\cite{ho2020ddpm}.\n\\end{abstract}\n\n\\maketitle")
+	require.Contains(t, acm, "\\subsection{ODE}\nTen steps.")
+	require.Contains(t, acm, "\\bibliographystyle{ACM-Reference-Format}")
+	require.Contains(t, acm, "\\author{"+DefaultAuthor+"}")
+
+	doc.CitationStyle = StyleIEEE
+	require.Contains(t, mustRender(t, doc, FormatLaTeX), "\\bibliographystyle{IEEEtran}")
+	// A LaTeX template does not apply to other formats; the citation style still does.
+	require.Contains(t, mustRender(t, doc, FormatMarkdown), `**[ref1]** J. Ho, "DDPM," 2020.`)
+
+	doc.TemplateID, doc.CitationStyle = "memo", ""
+	memo := mustRender(t, doc, FormatMarkdown)
+	require.True(t, strings.HasPrefix(memo, "# Memo: Diffusion & Scores\n"))
+	require.Contains(t, memo, "## Samplers\n\nFast [[ref1](#ref1), [ref2](#ref2)].\n\n### ODE\n\nTen steps.\n\n")
+	require.Contains(t, memo, "- [x] Every claim cites a source: satisfied\n")
+	require.Contains(t, memo, "- <a id=\"ref2\"></a>**[ref2]** Lu, C. (2022). DPM-Solver.\n")
+	require.True(t, strings.HasPrefix(mustRender(t, doc, FormatLaTeX), "\\documentclass[conference]{IEEEtran}"))
+
+	doc.Author = "Lab & Co"
+	require.Contains(t, mustRender(t, doc, FormatLaTeX), "\\author{Lab \\& Co}")
+
+	_, err := renderTemplate(doc, Template{ID: "missing", Format: FormatLaTeX, file: "missing.tex.tmpl"})
+	require.Error(t, err)
+
+	_, ok := LookupTemplate(" IEEE ")
+	require.True(t, ok)
+	_, ok = LookupTemplate("springer")
+	require.False(t, ok)
+}
+
+func mustRender(t *testing.T, d Document, format string) string {
+	t.Helper()
+	out, err := Render(d, format)
+	require.NoError(t, err)
+	return out
+}
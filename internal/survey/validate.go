@@ -0,0 +1,163 @@
+package survey
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Citation constraints checked on every drafted section.
+const (
+	IssueUnknownKey            = "unknown_key"
+	IssueUncitedSource         = "uncited_source"
+	IssueUncitedParagraph      = "uncited_paragraph"
+	IssueUnbalancedEnvironment = "unbalanced_environment"
+)
+
+// constraintLabels describes each constraint in reports, in report order.
+var constraintLabels = []struct{ kind, label string }{
+	{IssueUnknownKey, "Only retrieved sources are cited"},
+	{IssueUncitedSource, "Every retrieved source is cited"},
+	{IssueUncitedParagraph, "Every paragraph cites a source"},
+	{IssueUnbalancedEnvironment, "LaTeX environments are balanced"},
+}
+
+// minCitedParagraphWords exempts short connective paragraphs from the citation requirement.
+const minCitedParagraphWords = 8
+
+var environmentPattern = regexp.MustCompile(`\\(begin|end)\{([^}]+)\}`)
+
+// Issue is one violated constraint in a drafted section.
+type Issue struct {
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+// SectionValidation records the checks of one section: how many issues the first draft had,
+// how many repair rounds ran, and the issues left in the kept draft.
+type SectionValidation struct {
+	SectionID     string  `json:"section_id"`
+	Title         string  `json:"title"`
+	InitialIssues int     `json:"initial_issues"`
+	RepairRounds  int     `json:"repair_rounds"`
+	Issues        []Issue `json:"issues"`
+}
+
+// Constraint is a survey-wide verdict for one constraint.
+type Constraint struct {
+	Kind      string `json:"kind"`
+	Label     string `json:"label"`
+	Satisfied bool   `json:"satisfied"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// Validation is stored with the survey run and summarized at the end of the report.
+type Validation struct {
+	Constraints []Constraint        `json:"constraints"`
+	Sections    []SectionValidation `json:"sections"`
+}
+
+// ValidateSection checks a drafted section against the keys it was allowed to cite. raw is
+// the model output the section was parsed from; it is scanned for LaTeX environments, which
+// the parsed paragraphs no longer show as structure.
+func ValidateSection(raw string, sec Section, allowed []string) []Issue {
+	issues := make([]Issue, 0)
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, k := range allowed {
+		allowedSet[k] = true
+	}
+	cited := map[string]bool{}
+	var unknown []string
+	var uncited []string
+	var walk func(s Section)
+	walk = func(s Section) {
+		for _, p := range s.Paragraphs {
+			if len(p.Citations) == 0 && len(strings.Fields(p.Text)) >= minCitedParagraphWords {
+				uncited = append(uncited, fmt.Sprintf("%q", truncateWords(p.Text, 12)))
+			}
+			for _, k := range p.Citations {
+				if !allowedSet[k] && !cited[k] {
+					unknown = append(unknown, k)
+				}
+				cited[k] = true
+			}
+		}
+		for _, sub := range s.Subsections {
+			walk(sub)
+		}
+	}
+	walk(sec)
+	if len(unknown) > 0 {
+		issues = append(issues, Issue{Kind: IssueUnknownKey, Detail: "cites unknown keys " + strings.Join(unknown, ", ")})
+	}
+	var missing []string
+	for _, k := range allowed {
+		if !cited[k] {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		issues = append(issues, Issue{Kind: IssueUncitedSource, Detail: "never cites " + strings.Join(missing, ", ")})
+	}
+	for _, p := range uncited {
+		issues = append(issues, Issue{Kind: IssueUncitedParagraph, Detail: "paragraph without citation: " + p})
+	}
+	for _, d := range unbalancedEnvironments(raw) {
+		issues = append(issues, Issue{Kind: IssueUnbalancedEnvironment, Detail: d})
+	}
+	return issues
+}
+
+func unbalancedEnvironments(raw string) []string {
+	var out []string
+	var stack []string
+	for _, m := range environmentPattern.FindAllStringSubmatch(raw, -1) {
+		name := strings.TrimSpace(m[2])
+		if m[1] == "begin" {
+			stack = append(stack, name)
+			continue
+		}
+		if len(stack) == 0 || stack[len(stack)-1] != name {
+			out = append(out, "\\end{"+name+"} without matching \\begin")
+			continue
+		}
+		stack = stack[:len(stack)-1]
+	}
+	for _, name := range stack {
+		out = append(out, "\\begin{"+name+"} is never closed")
+	}
+	return out
+}
+
+func truncateWords(s string, n int) string {
+	words := strings.Fields(s)
+	if len(words) <= n {
+		return s
+	}
+	return strings.Join(words[:n], " ") + " ..."
+}
+
+// Summarize turns per-section results into survey-wide constraint verdicts.
+func Summarize(sections []SectionValidation) Validation {
+	counts := map[string]int{}
+	failing := map[string][]string{}
+	for _, s := range sections {
+		seen := map[string]bool{}
+		for _, is := range s.Issues {
+			counts[is.Kind]++
+			if !seen[is.Kind] {
+				seen[is.Kind] = true
+				failing[is.Kind] = append(failing[is.Kind], s.Title)
+			}
+		}
+	}
+	out := Validation{Constraints: make([]Constraint, 0, len(constraintLabels)), Sections: sections}
+	for _, c := range constraintLabels {
+		con := Constraint{Kind: c.kind, Label: c.label, Satisfied: counts[c.kind] == 0}
+		if !con.Satisfied {
+			con.Detail = fmt.Sprintf("%d issue(s) in %s", counts[c.kind], strings.Join(failing[c.kind], "; "))
+		}
+		out.Constraints = append(out.Constraints, con)
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+package survey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSection(t *testing.T) {
+	raw := "Diffusion models denoise images step by step without any cited source.\n\nThey scale [ref1, ref4].\n\\begin{itemize}"
+	sec := ParseSection("Diffusion", raw)
+	issues := ValidateSection(raw, sec, []string{"ref1", "ref2"})
+	kinds := make([]string, 0, len(issues))
+	for _, is := range issues {
+		kinds = append(kinds, is.Kind)
+	}
+	require.Equal(t, []string{IssueUnknownKey, IssueUncitedSource, IssueUncitedParagraph, IssueUnbalancedEnvironment}, kinds)
+	require.Equal(t, "cites unknown keys ref4", issues[0].Detail)
+	require.Equal(t, "never cites ref2", issues[1].Detail)
+
+	clean := "Diffusion models denoise images step by step [ref1].\n\nShort aside.\n\nLatents cut cost [ref2]."
+	require.Empty(t, ValidateSection(clean, ParseSection("Diffusion", clean), []string{"ref1", "ref2"}))
+}
+
+func TestSummarize(t *testing.T) {
+	v := Summarize([]SectionValidation{
+		{SectionID: "s1", Title: "Diffusion", Issues: []Issue{{Kind: IssueUncitedSource}}},
+		{SectionID: "s2", Title: "GANs"},
+	})
+	require.Len(t, v.Constraints, len(constraintLabels))
+	require.True(t, v.Constraints[0].Satisfied)
+	require.False(t, v.Constraints[1].Satisfied)
+	require.Equal(t, "1 issue(s) in Diffusion", v.Constraints[1].Detail)
+
+	md := RenderMarkdown(Document{Title: "T", Validation: &v})
+	require.Contains(t, md, "- [x] Only retrieved sources are cited: satisfied\n")
+	require.Contains(t, md, "- [ ] Every retrieved source is cited: not satisfied (1 issue(s) in Diffusion)\n")
+}
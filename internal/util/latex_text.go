@@ -0,0 +1,132 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	latexTitlePattern   = regexp.MustCompile(`\\title\s*(?:\[[^\]]*\]\s*)?\{`)
+	latexSkippedEnvs    = regexp.MustCompile(`(?s)\\begin\{(?:equation|align|gather|multline|eqnarray|displaymath|tabular|verbatim|lstlisting|tikzpicture|algorithmic)\*?\}.*?\\end\{(?:equation|align|gather|multline|eqnarray|displaymath|tabular|verbatim|lstlisting|tikzpicture|algorithmic)\*?\}`)
+	latexDisplayMath    = regexp.MustCompile(`(?s)\\\[.*?\\\]|\$\$.*?\$\$`)
+	latexInlineMath     = regexp.MustCompile(`\$[^$]*\$`)
+	latexHeading        = regexp.MustCompile(`\\(?:part|chapter|section|subsection|subsubsection|paragraph)\*?\s*(?:\[[^\]]*\]\s*)?\{([^{}]*)\}`)
+	latexDroppedCommand = regexp.MustCompile(`\\(?:cite[a-zA-Z]*|ref|eqref|autoref|cref|Cref|label|includegraphics|bibliographystyle|bibliography|usepackage|input|include|url|vspace|hspace)\*?\s*(?:\[[^\]]*\]\s*)*\{[^{}]*\}`)
+	latexLineBreak      = regexp.MustCompile(`\\\\(?:\[[^\]]*\])?`)
+	latexEscapedChar    = regexp.MustCompile(`\\([%&_#{}])`)
+	latexEnvMarker      = regexp.MustCompile(`\\(?:begin|end)\{[^}]*\}`)
+	latexCommand        = regexp.MustCompile(`\\[a-zA-Z]+\*?`)
+	latexParagraphBreak = regexp.MustCompile(`\n\s*\n`)
+	latexSpacedPunct    = regexp.MustCompile(` ([.,;:])`)
+)
+
+// latexDollar stands in for escaped dollar signs while math is removed.
+const latexDollar = "\uE000"
+
+// LaTeXTitle returns the plain text of the first \title{...} in a LaTeX source, or "" when
+// there is none.
+func LaTeXTitle(src string) string {
+	src = stripLaTeXComments(src)
+	loc := latexTitlePattern.FindStringIndex(src)
+	if loc == nil {
+		return ""
+	}
+	arg, ok := latexBracedArg(src[loc[1]-1:])
+	if !ok {
+		return ""
+	}
+	return strings.Join(strings.Fields(LaTeXPlainText(arg)), " ")
+}
+
+// LaTeXPlainText reduces a LaTeX source to its prose: the document body without comments,
+// math, tables, citations or labels, with formatting commands unwrapped to their text.
+// Paragraphs and headings are separated by blank lines.
+func LaTeXPlainText(src string) string {
+	src = stripLaTeXComments(src)
+	if i := strings.Index(src, `\begin{document}`); i >= 0 {
+		src = src[i+len(`\begin{document}`):]
+	}
+	if i := strings.Index(src, `\end{document}`); i >= 0 {
+		src = src[:i]
+	}
+	src = strings.ReplaceAll(src, `\$`, latexDollar)
+	src = latexSkippedEnvs.ReplaceAllString(src, "\n\n")
+	src = latexDisplayMath.ReplaceAllString(src, " ")
+	src = latexInlineMath.ReplaceAllString(src, "")
+	src = latexHeading.ReplaceAllString(src, "\n\n$1\n\n")
+	src = latexDroppedCommand.ReplaceAllString(src, "")
+	src = latexLineBreak.ReplaceAllString(src, " ")
+	src = latexEscapedChar.ReplaceAllString(src, "$1")
+	src = latexEnvMarker.ReplaceAllString(src, "\n\n")
+	src = latexCommand.ReplaceAllString(src, "")
+	src = strings.NewReplacer("{", "", "}", "", "~", " ", latexDollar, "$").Replace(src)
+
+	paragraphs := latexParagraphBreak.Split(src, -1)
+	out := make([]string, 0, len(paragraphs))
+	for _, p := range paragraphs {
+		// Removed citations and references leave a space before the punctuation after them.
+		if p = latexSpacedPunct.ReplaceAllString(strings.Join(strings.Fields(p), " "), "$1"); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, "\n\n")
+}
+
+// stripLaTeXComments drops everything after an unescaped % on each line.
+func stripLaTeXComments(src string) string {
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		for j := 0; j < len(line); j++ {
+			if line[j] == '\\' {
+				j++
+				continue
+			}
+			if line[j] == '%' {
+				lines[i] = line[:j]
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// latexBracedArg returns the contents of the brace group s starts with.
+func latexBracedArg(s string) (string, bool) {
+	if !strings.HasPrefix(s, "{") {
+		return "", false
+	}
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return s[1:i], true
+			}
+		}
+	}
+	return "", false
+}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"&", `\&`,
+	"%", `\%`,
+	"$", `\$`,
+	"#", `\#`,
+	"_", `\_`,
+	"{", `\{`,
+	"}", `\}`,
+	"~", `\textasciitilde{}`,
+	"^", `\textasciicircum{}`,
+	"\n", " ",
+)
+
+// EscapeLaTeX escapes text for use in LaTeX body content.
+func EscapeLaTeX(s string) string {
+	return latexEscaper.Replace(s)
+}
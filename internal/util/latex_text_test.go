@@ -0,0 +1,48 @@
+package util
+
+import "testing"
+
+const latexDraft = `\documentclass{article}
+\usepackage{amsmath}
+\title{Sparse Routing for \textbf{Efficient} Transformers}
+\begin{document}
+\maketitle
+\begin{abstract}
+We propose SparseRoute, which cuts attention cost by 40\% % drop this remark
+on long inputs~\cite{vaswani2017attention}.
+\end{abstract}
+\section{Introduction}
+Prior work~\citep[see][]{child2019sparse} uses fixed patterns with cost $O(n\sqrt{n})$.
+\begin{equation}
+  y = \mathrm{softmax}(QK^T)V
+\end{equation}
+Our router costs \$0 to train.\\
+See Section~\ref{sec:method}.
+\end{document}`
+
+func TestLaTeXTitle(t *testing.T) {
+	if got := LaTeXTitle(latexDraft); got != "Sparse Routing for Efficient Transformers" {
+		t.Fatalf("unexpected title: %q", got)
+	}
+	if got := LaTeXTitle(`\section{No title}`); got != "" {
+		t.Fatalf("expected no title, got %q", got)
+	}
+}
+
+func TestLaTeXPlainText(t *testing.T) {
+	want := "We propose SparseRoute, which cuts attention cost by 40% on long inputs.\n\n" +
+		"Introduction\n\n" +
+		"Prior work uses fixed patterns with cost.\n\n" +
+		"Our router costs $0 to train. See Section."
+	if got := LaTeXPlainText(latexDraft); got != want {
+		t.Fatalf("unexpected plain text:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestEscapeLaTeX(t *testing.T) {
+	got := EscapeLaTeX("50% of $x_1$ & {a}\\b ~^\nnext")
+	want := `50\% of \$x\_1\$ \& \{a\}\textbackslash{}b \textasciitilde{}\textasciicircum{} next`
+	if got != want {
+		t.Fatalf("unexpected escape:\n%q\nwant:\n%q", got, want)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"litflow/internal/models"
 
@@ -13,6 +15,7 @@ import (
 type SearchFilters struct {
 	PaperIDs         []string
 	EmbeddingVersion string
+	ChunkVersion     string
 }
 
 type Searcher struct {
@@ -27,41 +30,72 @@ func NewSearcher(q Queryer) *Searcher {
 	return &Searcher{q: q}
 }
 
-func (s *Searcher) SearchChunks(ctx context.Context, corpusID string, queryVec []float32, topK int, filters SearchFilters) ([]models.ChunkResult, error) {
+// SearchChunks ranks chunks from one or more corpora in a single cosine-distance ordering.
+// Scores are only comparable across corpora that share an embedding space, so callers
+// searching several corpora should pin filters.EmbeddingVersion.
+func (s *Searcher) SearchChunks(ctx context.Context, corpusIDs []string, queryVec []float32, topK int, filters SearchFilters) ([]models.ChunkResult, error) {
+	return s.search(ctx, corpusIDs, queryVec, nil, topK, filters)
+}
+
+// SearchChunksText ranks chunks by full-text match against any of the query's words, for
+// hybrid retrieval next to SearchChunks. Score is still the cosine similarity to queryVec so
+// hits from both rankings can be compared.
+func (s *Searcher) SearchChunksText(ctx context.Context, corpusIDs []string, query string, queryVec []float32, topK int, filters SearchFilters) ([]models.ChunkResult, error) {
+	terms := textQueryTerms(query)
+	if len(terms) == 0 {
+		return []models.ChunkResult{}, nil
+	}
+	return s.search(ctx, corpusIDs, queryVec, terms, topK, filters)
+}
+
+func (s *Searcher) search(ctx context.Context, corpusIDs []string, queryVec []float32, textTerms []string, topK int, filters SearchFilters) ([]models.ChunkResult, error) {
 	if topK <= 0 {
 		topK = 8
 	}
-	vecLiteral := ToLiteral(queryVec)
-	args := []any{corpusID, vecLiteral, topK}
+	args := []any{corpusIDs, ToLiteral(queryVec), topK}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
 
 	filterSQL := ""
 	if len(filters.PaperIDs) > 0 {
-		filterSQL = " AND c.paper_id = ANY($4)"
-		args = append(args, filters.PaperIDs)
+		filterSQL = " AND c.paper_id = ANY(" + arg(filters.PaperIDs) + ")"
 	}
 	if strings.TrimSpace(filters.EmbeddingVersion) != "" {
-		if len(args) == 3 {
-			filterSQL += " AND c.embedding_version = $4"
-			args = append(args, filters.EmbeddingVersion)
-		} else {
-			filterSQL += " AND c.embedding_version = $5"
-			args = append(args, filters.EmbeddingVersion)
+		filterSQL += " AND c.embedding_version = " + arg(filters.EmbeddingVersion)
+	}
+	if strings.TrimSpace(filters.ChunkVersion) != "" {
+		filterSQL += " AND c.chunk_version = " + arg(filters.ChunkVersion)
+	}
+	orderSQL := "c.embedding <=> $2::vector"
+	if len(textTerms) > 0 {
+		// plainto_tsquery parses each term as plain text, so no query syntax needs escaping.
+		parts := make([]string, 0, len(textTerms))
+		for _, term := range textTerms {
+			parts = append(parts, "plainto_tsquery('english', "+arg(term)+")")
 		}
+		q := "(" + strings.Join(parts, " || ") + ")"
+		filterSQL += " AND to_tsvector('english', c.text) @@ " + q
+		orderSQL = "ts_rank(to_tsvector('english', c.text), " + q + ") DESC, " + orderSQL
 	}
 
 	query := `
-SELECT c.paper_id,
+SELECT c.corpus_id::text,
+       c.paper_id,
        COALESCE(p.title, p.filename) AS title,
        p.filename,
        c.chunk_id,
+       c.chunk_index,
+       COALESCE(c.section, '') AS section,
        LEFT(c.text, 420) AS snippet,
        1 - (c.embedding <=> $2::vector) AS score,
        c.text
 FROM chunks c
 JOIN papers p ON p.paper_id = c.paper_id
-WHERE c.corpus_id = $1
+WHERE c.corpus_id = ANY($1::uuid[])
   AND c.embedding IS NOT NULL` + filterSQL + `
-ORDER BY c.embedding <=> $2::vector
+ORDER BY ` + orderSQL + `
 LIMIT $3`
 
 	rows, err := s.q.Query(ctx, query, args...)
@@ -73,7 +107,7 @@ LIMIT $3`
 	results := make([]models.ChunkResult, 0, topK)
 	for rows.Next() {
 		var r models.ChunkResult
-		if err := rows.Scan(&r.PaperID, &r.Title, &r.Filename, &r.ChunkID, &r.Snippet, &r.Score, &r.ChunkText); err != nil {
+		if err := rows.Scan(&r.CorpusID, &r.PaperID, &r.Title, &r.Filename, &r.ChunkID, &r.ChunkIndex, &r.Section, &r.Snippet, &r.Score, &r.ChunkText); err != nil {
 			return nil, fmt.Errorf("scan chunk result: %w", err)
 		}
 		results = append(results, r)
@@ -84,6 +118,27 @@ LIMIT $3`
 	return results, nil
 }
 
+// textQueryTerms keeps the lowercase letter and digit runs of a query in any script, at most
+// 16, for SearchChunksText to OR together.
+func textQueryTerms(query string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := map[string]bool{}
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if utf8.RuneCountInString(f) < 2 || seen[f] {
+			continue
+		}
+		seen[f] = true
+		out = append(out, f)
+		if len(out) == 16 {
+			break
+		}
+	}
+	return out
+}
+
 func ToLiteral(v []float32) string {
 	parts := make([]string, 0, len(v))
 	for _, x := range v {
@@ -0,0 +1,120 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"litflow/internal/activities"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const positioningDraft = `Sparse Routing for Efficient Transformers
+
+Abstract. We propose SparseRoute, a learned router that sends each token to a few attention heads. Our results show a 40% cost reduction on long-document summarization.
+
+Introduction. Attention cost grows quadratically [1]. Prior work such as Attention Is All You Need established the architecture.`
+
+func TestDraftPositioningWorkflowReportsPriorWork(t *testing.T) {
+	env := newStubbedTestEnv(t, DraftPositioningWorkflow,
+		"UpdatePositioningRunActivity", "ExtractDraftTextActivity", "LLMGenerateActivity", "LogLLMCallActivity", "EmbedQueryActivity", "SearchChunksActivity", "GetSurveyPaperMetaActivity", "WritePositioningReportActivity")
+	env.OnActivity("ExtractDraftTextActivity", mock.Anything, activities.ExtractDraftTextInput{Path: "/tmp/draft.tex", Format: "latex"}).Return(activities.ExtractDraftTextOutput{Title: "Sparse Routing for Efficient Transformers", Text: positioningDraft}, nil)
+	env.OnActivity("LLMGenerateActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.LLMGenerateInput) (activities.LLMGenerateOutput, error) {
+		text := "Unlike fixed sparse patterns [ref2], SparseRoute learns the routing [ref1]."
+		switch {
+		case in.Operation == "draft_claims":
+			text = `{"title": "SparseRoute", "claims": [{"kind": "contribution", "text": "SparseRoute learns which attention heads each token uses."}, {"kind": "claim", "text": "SparseRoute cuts summarization cost by 40%."}]}`
+		case strings.Contains(in.Prompt, "40%"):
+			// Cites a key that was not retrieved for this claim.
+			text = "This improves on prior summarizers [ref9]."
+		}
+		return activities.LLMGenerateOutput{Text: text, ProviderName: "mock", Model: "mock-llm-v1"}, nil
+	})
+	env.OnActivity("EmbedQueryActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.EmbedQueryInput) (activities.EmbedQueryOutput, error) {
+		if strings.Contains(in.Text, "40%") {
+			return activities.EmbedQueryOutput{Vector: []float32{0.2}}, nil
+		}
+		return activities.EmbedQueryOutput{Vector: []float32{0.1}}, nil
+	})
+	env.OnActivity("SearchChunksActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.SearchChunksInput) (activities.SearchChunksOutput, error) {
+		if in.QueryVec[0] > 0.15 {
+			return activities.SearchChunksOutput{Results: []activities.SearchChunk{
+				{CorpusID: "c", PaperID: "p2", Title: "Generating Long Sequences with Sparse Transformers", ChunkID: "p2-c3", Text: "Factorized attention on long sequences.", Score: 0.61},
+			}}, nil
+		}
+		return activities.SearchChunksOutput{Results: []activities.SearchChunk{
+			{CorpusID: "c", PaperID: "p1", Title: "Attention Is All You Need", ChunkID: "p1-c1", Text: "Multi-head attention.", Score: 0.72},
+			{CorpusID: "c", PaperID: "p2", Title: "Generating Long Sequences with Sparse Transformers", ChunkID: "p2-c1", Text: "Fixed sparse attention patterns.", Score: 0.83},
+		}}, nil
+	})
+	env.OnActivity("GetSurveyPaperMetaActivity", mock.Anything, mock.Anything).Return(activities.GetSurveyPaperMetaOutput{}, nil)
+	var written activities.WritePositioningReportInput
+	env.OnActivity("WritePositioningReportActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.WritePositioningReportInput) (activities.WritePositioningReportOutput, error) {
+		written = in
+		return activities.WritePositioningReportOutput{ReportPath: "/tmp/drafts/d1/positioning/r1/report.md"}, nil
+	})
+
+	env.ExecuteWorkflow(DraftPositioningWorkflow, DraftPositioningInput{
+		PositioningRunID: "r1",
+		DraftID:          "d1",
+		CorpusID:         "c",
+		DraftPath:        "/tmp/draft.tex",
+		DraftFormat:      "latex",
+		DraftFilename:    "draft.tex",
+		EmbedProviders:   1,
+		LLMProviders:     1,
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var trace DraftPositioningTrace
+	b, _ := json.Marshal(written.Trace)
+	require.NoError(t, json.Unmarshal(b, &trace))
+	require.Equal(t, "Sparse Routing for Efficient Transformers", trace.Title)
+	require.Len(t, trace.Claims, 2)
+	require.Equal(t, []string{"ref1", "ref2"}, trace.Claims[0].RefKeys)
+	require.Equal(t, "llm", trace.Claims[0].Source)
+	require.Equal(t, []string{"ref2"}, trace.Claims[1].RefKeys)
+	require.Equal(t, "extractive", trace.Claims[1].Source)
+
+	// The sparse transformer paper is close to both claims and never mentioned in the draft.
+	require.Len(t, trace.PriorWork, 2)
+	require.Equal(t, "ref2", trace.PriorWork[0].Key)
+	require.Equal(t, []string{"C1", "C2"}, trace.PriorWork[0].ClaimIDs)
+	require.Equal(t, 0.83, trace.PriorWork[0].BestScore)
+	require.False(t, trace.PriorWork[0].CitedInDraft)
+	require.True(t, trace.PriorWork[1].CitedInDraft)
+
+	report := written.Markdown
+	require.Contains(t, report, "# Positioning report: Sparse Routing for Efficient Transformers")
+	require.Contains(t, report, "| 1 | Generating Long Sequences with Sparse Transformers [ref2] | C1, C2 | 0.830 | no |")
+	require.Contains(t, report, "**How the draft differs.** Unlike fixed sparse patterns [ref2], SparseRoute learns the routing [ref1].")
+	missing := report[strings.Index(report, "## Possibly Missing Citations"):strings.Index(report, "## References")]
+	require.Contains(t, missing, "Generating Long Sequences with Sparse Transformers [ref2], close to C1, C2")
+	require.NotContains(t, missing, "Attention Is All You Need")
+}
+
+func TestHeuristicDraftClaims(t *testing.T) {
+	claims := heuristicDraftClaims(positioningDraft, 5)
+	require.Equal(t, []DraftClaim{
+		{Kind: "contribution", Text: "We propose SparseRoute, a learned router that sends each token to a few attention heads."},
+		{Kind: "claim", Text: "Our results show a 40% cost reduction on long-document summarization."},
+	}, claims)
+
+	fallback := heuristicDraftClaims("Attention cost grows quadratically with the sequence length of the input.", 5)
+	require.Len(t, fallback, 1)
+	require.Equal(t, "claim", fallback[0].Kind)
+}
+
+func TestParseDraftClaims(t *testing.T) {
+	claims, title, ok := parseDraftClaims("```json\n{\"title\": \" SparseRoute \", \"claims\": [{\"kind\": \"Contribution\", \"text\": \"A router.\"}, {\"kind\": \"result\", \"text\": \"It is  fast.\"}, {\"kind\": \"claim\", \"text\": \"a router.\"}]}\n```", 5)
+	require.True(t, ok)
+	require.Equal(t, "SparseRoute", title)
+	require.Equal(t, []DraftClaim{{Kind: "contribution", Text: "A router."}, {Kind: "claim", Text: "It is fast."}}, claims)
+
+	_, _, ok = parseDraftClaims("Mock response.", 5)
+	require.False(t, ok)
+}
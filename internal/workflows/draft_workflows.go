@@ -0,0 +1,403 @@
+package workflows
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"litflow/internal/activities"
+	"litflow/internal/retrieval"
+	"litflow/internal/survey"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	defaultDraftClaims   = 6
+	maxDraftClaims       = 12
+	defaultDraftTopK     = 5
+	maxDraftPromptRunes  = 12000
+	maxPositioningPapers = 10
+)
+
+var (
+	draftClaimCue        = regexp.MustCompile(`(?i)\b(we (propose|present|introduce|develop|design|show|demonstrate|find|achieve|outperform|improve|release)|our (method|approach|model|framework|system|results?|contributions?)|this (paper|work) (proposes|presents|introduces|shows))\b`)
+	draftContributionCue = regexp.MustCompile(`(?i)\b(propose|present|introduce|develop|design|release)`)
+)
+
+// DraftPositioningWorkflow positions an uploaded draft against the corpus for its related-work
+// section. It extracts the draft's contributions and claims, retrieves the closest corpus
+// chunks for each, asks how the draft differs from them, and ranks the retrieved papers by
+// how many claims they are close to. Ranked papers whose title never appears in the draft
+// are reported as possibly missing citations.
+func DraftPositioningWorkflow(ctx workflow.Context, input DraftPositioningInput) (string, error) {
+	maxClaims := input.MaxClaims
+	if maxClaims <= 0 {
+		maxClaims = defaultDraftClaims
+	}
+	if maxClaims > maxDraftClaims {
+		maxClaims = maxDraftClaims
+	}
+	topK := input.TopK
+	if topK <= 0 {
+		topK = defaultDraftTopK
+	}
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    2 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    30 * time.Second,
+			MaximumAttempts:    2,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+	startedAt := workflow.Now(ctx)
+	fail := func(err error) (string, error) {
+		_ = workflow.ExecuteActivity(ctx, "UpdatePositioningRunActivity", activities.UpdatePositioningRunInput{PositioningRunID: input.PositioningRunID, Status: "failed", Error: err.Error()}).Get(ctx, nil)
+		return "", err
+	}
+	_ = workflow.ExecuteActivity(ctx, "UpdatePositioningRunActivity", activities.UpdatePositioningRunInput{PositioningRunID: input.PositioningRunID, Status: "running"}).Get(ctx, nil)
+
+	var draft activities.ExtractDraftTextOutput
+	if err := workflow.ExecuteActivity(ctx, "ExtractDraftTextActivity", activities.ExtractDraftTextInput{Path: input.DraftPath, Format: input.DraftFormat}).Get(ctx, &draft); err != nil {
+		return fail(err)
+	}
+
+	embedProviders := defaultCount(input.EmbedProviders)
+	llmProviders := defaultCount(input.LLMProviders)
+	cooldown := durationOrDefault(input.CooldownSeconds, 900)
+	embedState := newProviderState()
+	llmState := newProviderState()
+
+	out, _, err := callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, activities.LLMGenerateInput{
+		Operation: "draft_claims",
+		CorpusID:  input.CorpusID,
+		Prompt:    buildDraftClaimsPrompt(maxClaims),
+		Context:   []string{truncateRunes(draft.Text, maxDraftPromptRunes)},
+	}, nil)
+	claims, llmTitle, ok := parseDraftClaims(out.Text, maxClaims)
+	if err != nil || !ok {
+		claims = heuristicDraftClaims(draft.Text, maxClaims)
+	}
+	title := strings.TrimSpace(draft.Title)
+	if title == "" {
+		title = llmTitle
+	}
+	if title == "" {
+		title = input.DraftFilename
+	}
+	if len(claims) == 0 {
+		return fail(fmt.Errorf("no contributions or claims found in the draft"))
+	}
+
+	// Hits for all claims go into one list so the claims share one reference numbering;
+	// spans[i] is claim i's slice of it.
+	var hits []activities.SearchChunk
+	spans := make([][2]int, len(claims))
+	for i, c := range claims {
+		start := len(hits)
+		eq, err := callEmbedQueryWithFailover(ctx, &embedState, embedProviders, cooldown, activities.EmbedQueryInput{
+			Operation: "draft_claim_embed",
+			Text:      c.Text,
+		}, nil)
+		if err == nil {
+			var res activities.SearchChunksOutput
+			if err := workflow.ExecuteActivity(ctx, "SearchChunksActivity", activities.SearchChunksInput{
+				CorpusID:         input.CorpusID,
+				CorpusIDs:        input.CorpusIDs,
+				QueryVec:         eq.Vector,
+				TopK:             topK,
+				EmbeddingVersion: defaultEmbedVersion(input.EmbedVersion),
+			}).Get(ctx, &res); err == nil {
+				hits = append(hits, res.Results...)
+			}
+		}
+		spans[i] = [2]int{start, len(hits)}
+	}
+	refs, contextLines := buildSurveyReferences(hits, 0)
+	enrichSurveyReferences(ctx, input.CorpusID, input.CorpusIDs, refs, false)
+	refByKey := make(map[string]SurveyReference, len(refs))
+	keyByPaper := make(map[string]string, len(refs))
+	for _, ref := range refs {
+		refByKey[ref.Key] = ref
+		keyByPaper[ref.PaperID] = ref.Key
+	}
+
+	prior := map[string]*PriorWork{}
+	for i := range claims {
+		claims[i].ClaimID = fmt.Sprintf("C%d", i+1)
+		span := spans[i]
+		keys := make([]string, 0, span[1]-span[0])
+		for _, h := range hits[span[0]:span[1]] {
+			paperID := strings.TrimSpace(h.PaperID)
+			if paperID == "" {
+				paperID = h.ChunkID
+			}
+			key := keyByPaper[paperID]
+			keys = append(keys, key)
+			pw, ok := prior[key]
+			if !ok {
+				ref := refByKey[key]
+				pw = &PriorWork{Key: key, CorpusID: ref.CorpusID, PaperID: ref.PaperID, Title: ref.Title, BestScore: h.Score}
+				prior[key] = pw
+			}
+			pw.ClaimIDs = dedupeStrings(append(pw.ClaimIDs, claims[i].ClaimID))
+			pw.BestScore = max(pw.BestScore, h.Score)
+		}
+		claims[i].RefKeys = dedupeStrings(keys)
+		if len(claims[i].RefKeys) == 0 {
+			claims[i].Positioning = "No corpus paper was retrieved for this claim."
+			claims[i].Source = "none"
+			continue
+		}
+		claimRefs := make([]SurveyReference, 0, len(claims[i].RefKeys))
+		for _, k := range claims[i].RefKeys {
+			claimRefs = append(claimRefs, refByKey[k])
+		}
+		out, _, err := callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, activities.LLMGenerateInput{
+			Operation: "draft_positioning",
+			CorpusID:  input.CorpusID,
+			Prompt:    buildDraftPositioningPrompt(title, claims[i], claimRefs),
+			Context:   contextLines[span[0]:span[1]],
+		}, nil)
+		text := strings.Join(strings.Fields(out.Text), " ")
+		if err == nil && citesOnly(text, claims[i].RefKeys) {
+			claims[i].Positioning = text
+			claims[i].Source = "llm"
+		} else {
+			claims[i].Positioning = extractivePositioning(claimRefs)
+			claims[i].Source = "extractive"
+		}
+	}
+
+	ranked := make([]PriorWork, 0, len(prior))
+	for _, ref := range refs {
+		if pw, ok := prior[ref.Key]; ok {
+			pw.CitedInDraft = titleMentioned(draft.Text, pw.Title)
+			ranked = append(ranked, *pw)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if len(ranked[i].ClaimIDs) != len(ranked[j].ClaimIDs) {
+			return len(ranked[i].ClaimIDs) > len(ranked[j].ClaimIDs)
+		}
+		return ranked[i].BestScore > ranked[j].BestScore
+	})
+	ranked = ranked[:min(len(ranked), maxPositioningPapers)]
+
+	markdown := renderPositioningReport(title, input.DraftFilename, claims, ranked, refs)
+	trace := DraftPositioningTrace{
+		DraftID:    input.DraftID,
+		Title:      title,
+		Claims:     claims,
+		PriorWork:  ranked,
+		References: refs,
+		StartedAt:  startedAt,
+		FinishedAt: workflow.Now(ctx),
+	}
+	var written activities.WritePositioningReportOutput
+	if err := workflow.ExecuteActivity(ctx, "WritePositioningReportActivity", activities.WritePositioningReportInput{
+		CorpusID:         input.CorpusID,
+		DraftID:          input.DraftID,
+		PositioningRunID: input.PositioningRunID,
+		Markdown:         markdown,
+		Trace:            trace,
+	}).Get(ctx, &written); err != nil {
+		return fail(err)
+	}
+	_ = workflow.ExecuteActivity(ctx, "UpdatePositioningRunActivity", activities.UpdatePositioningRunInput{PositioningRunID: input.PositioningRunID, Status: "completed", OutPath: written.ReportPath}).Get(ctx, nil)
+	return written.ReportPath, nil
+}
+
+func buildDraftClaimsPrompt(maxClaims int) string {
+	return strings.Join([]string{
+		"You read the draft of a scientific paper provided as context to prepare its related-work section.",
+		fmt.Sprintf("List up to %d of its contributions and central claims, each as one self-contained sentence that names the method, task or finding.", maxClaims),
+		`Use kind "contribution" for what the paper proposes or builds and "claim" for results and assertions it makes.`,
+		"",
+		`Output STRICT JSON: {"title": "...", "claims": [{"kind": "contribution", "text": "..."}]}`,
+	}, "\n")
+}
+
+// parseDraftClaims reads a draft_claims completion. ok is false when the output is not the
+// requested JSON or lists no claims, so the caller can fall back to heuristicDraftClaims.
+func parseDraftClaims(raw string, maxClaims int) ([]DraftClaim, string, bool) {
+	raw = strings.TrimSpace(raw)
+	if start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}"); start >= 0 && end > start {
+		raw = raw[start : end+1]
+	}
+	var payload struct {
+		Title  string `json:"title"`
+		Claims []struct {
+			Kind string `json:"kind"`
+			Text string `json:"text"`
+		} `json:"claims"`
+	}
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return nil, "", false
+	}
+	out := make([]DraftClaim, 0, len(payload.Claims))
+	seen := map[string]bool{}
+	for _, c := range payload.Claims {
+		text := strings.Join(strings.Fields(c.Text), " ")
+		if text == "" || seen[strings.ToLower(text)] {
+			continue
+		}
+		seen[strings.ToLower(text)] = true
+		kind := strings.ToLower(strings.TrimSpace(c.Kind))
+		if kind != "contribution" {
+			kind = "claim"
+		}
+		out = append(out, DraftClaim{Kind: kind, Text: text})
+		if len(out) == maxClaims {
+			break
+		}
+	}
+	return out, strings.Join(strings.Fields(payload.Title), " "), len(out) > 0
+}
+
+// heuristicDraftClaims picks sentences with contribution cues such as "we propose" or "our
+// method", in draft order; a draft without any falls back to its first full sentences.
+func heuristicDraftClaims(text string, maxClaims int) []DraftClaim {
+	var sentences []string
+	for _, s := range retrieval.PassageSentences(text) {
+		if n := len([]rune(s)); n >= 40 && n <= 400 {
+			sentences = append(sentences, s)
+		}
+	}
+	out := make([]DraftClaim, 0, maxClaims)
+	seen := map[string]bool{}
+	for _, s := range sentences {
+		if len(out) == maxClaims {
+			break
+		}
+		if !draftClaimCue.MatchString(s) || seen[strings.ToLower(s)] {
+			continue
+		}
+		seen[strings.ToLower(s)] = true
+		kind := "claim"
+		if draftContributionCue.MatchString(s) {
+			kind = "contribution"
+		}
+		out = append(out, DraftClaim{Kind: kind, Text: s})
+	}
+	if len(out) == 0 {
+		for _, s := range sentences[:min(len(sentences), maxClaims)] {
+			out = append(out, DraftClaim{Kind: "claim", Text: s})
+		}
+	}
+	return out
+}
+
+func buildDraftPositioningPrompt(title string, claim DraftClaim, refs []SurveyReference) string {
+	return strings.Join([]string{
+		"Draft paper: " + title,
+		"Draft " + claim.Kind + ": " + claim.Text,
+		"",
+		"The context holds the corpus passages closest to this " + claim.Kind + ".",
+		"In 2-4 sentences, name the most similar prior work and state precisely how the draft differs from it (problem, method, evidence or scope).",
+		"If the prior work already covers the " + claim.Kind + ", say so plainly.",
+		"Cite sources as [refN] right after each statement about them and use only these keys:",
+		referenceKeyLines(refs),
+	}, "\n")
+}
+
+// citesOnly reports whether text cites at least one key and only keys from allowed.
+func citesOnly(text string, allowed []string) bool {
+	keys := survey.CitationKeys(text)
+	if len(keys) == 0 {
+		return false
+	}
+	ok := make(map[string]bool, len(allowed))
+	for _, k := range allowed {
+		ok[k] = true
+	}
+	for _, k := range keys {
+		if !ok[k] {
+			return false
+		}
+	}
+	return true
+}
+
+func extractivePositioning(refs []SurveyReference) string {
+	parts := make([]string, 0, 3)
+	for _, ref := range refs[:min(len(refs), 3)] {
+		parts = append(parts, fmt.Sprintf("%q [%s]", ref.Title, ref.Key))
+	}
+	return "Closest corpus work: " + strings.Join(parts, ", ") + ". State how the draft goes beyond these papers."
+}
+
+// titleMentioned reports whether the draft text contains the paper title, comparing
+// lowercase words so that line breaks and punctuation do not matter.
+func titleMentioned(text, title string) bool {
+	t := normalizedWords(title)
+	if t == "" {
+		return false
+	}
+	return strings.Contains(" "+normalizedWords(text)+" ", " "+t+" ")
+}
+
+func normalizedWords(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'))
+	}), " ")
+}
+
+// renderPositioningReport writes the report: the ranked prior work, one subsection per
+// claim with its closest papers and positioning, the possibly missing citations and the
+// reference list every [refN] points into.
+func renderPositioningReport(title, filename string, claims []DraftClaim, prior []PriorWork, refs []SurveyReference) string {
+	var b strings.Builder
+	b.WriteString("# Positioning report: " + title + "\n\n")
+	if filename != "" {
+		b.WriteString(fmt.Sprintf("Draft `%s`: ", filename))
+	}
+	b.WriteString(fmt.Sprintf("%d contributions and claims compared against %d corpus papers.\n", len(claims), len(refs)))
+
+	b.WriteString("\n## Most Similar Prior Work\n")
+	if len(prior) == 0 {
+		b.WriteString("No corpus paper was retrieved for the draft.\n")
+	} else {
+		b.WriteString("| Rank | Paper | Claims | Best score | Cited in draft |\n|---|---|---|---|---|\n")
+		for i, pw := range prior {
+			cited := "no"
+			if pw.CitedInDraft {
+				cited = "yes"
+			}
+			b.WriteString(fmt.Sprintf("| %d | %s [%s] | %s | %.3f | %s |\n", i+1, strings.ReplaceAll(pw.Title, "|", `\|`), pw.Key, strings.Join(pw.ClaimIDs, ", "), pw.BestScore, cited))
+		}
+	}
+
+	b.WriteString("\n## Contributions and Claims\n")
+	for _, c := range claims {
+		b.WriteString(fmt.Sprintf("\n### %s (%s)\n%s\n\n", c.ClaimID, c.Kind, c.Text))
+		b.WriteString("**How the draft differs.** " + c.Positioning + "\n")
+	}
+
+	b.WriteString("\n## Possibly Missing Citations\n")
+	missing := 0
+	for _, pw := range prior {
+		if pw.CitedInDraft {
+			continue
+		}
+		missing++
+		b.WriteString(fmt.Sprintf("- %s [%s], close to %s\n", pw.Title, pw.Key, strings.Join(pw.ClaimIDs, ", ")))
+	}
+	if missing == 0 {
+		b.WriteString("Every closely related corpus paper is already mentioned in the draft.\n")
+	}
+
+	if len(refs) > 0 {
+		b.WriteString("\n## References\n")
+		for _, ref := range refs {
+			b.WriteString(fmt.Sprintf("- [%s] %s — paper `%s`\n", ref.Key, survey.FormatReference(ref, survey.StyleIEEE), ref.PaperID))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"litflow/internal/activities"
+	"litflow/internal/retrieval"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestRetrievalEvalWorkflowScoresQuestions(t *testing.T) {
+	env := newStubbedTestEnv(t, RetrievalEvalWorkflow,
+		"UpdateEvalRunActivity", "LoadEvalSetActivity", "EmbedQueryActivity", "SearchChunksActivity", "WriteEvalReportActivity")
+	env.OnActivity("LoadEvalSetActivity", mock.Anything, mock.Anything).Return(activities.LoadEvalSetOutput{
+		Name: "golden",
+		Questions: []activities.EvalQuestionItem{
+			{QuestionID: "q1", Question: "what is attention", Judgment: retrieval.EvalJudgment{ChunkIDs: []string{"c2"}}},
+		},
+	}, nil)
+	env.OnActivity("EmbedQueryActivity", mock.Anything, mock.Anything).Return(activities.EmbedQueryOutput{Vector: []float32{0.1}}, nil)
+	env.OnActivity("SearchChunksActivity", mock.Anything, mock.Anything).Return(activities.SearchChunksOutput{Results: []activities.SearchChunk{
+		{ChunkID: "c1", PaperID: "p1"},
+		{ChunkID: "c2", PaperID: "p1"},
+	}}, nil)
+	var written RetrievalEvalReport
+	env.OnActivity("WriteEvalReportActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.WriteEvalReportInput) (activities.WriteEvalReportOutput, error) {
+		b, _ := json.Marshal(in.Report)
+		_ = json.Unmarshal(b, &written)
+		return activities.WriteEvalReportOutput{Path: "/tmp/runs/r1/retrieval_eval.json"}, nil
+	})
+
+	env.ExecuteWorkflow(RetrievalEvalWorkflow, RetrievalEvalInput{EvalRunID: "r1", CorpusID: "c", EvalSetID: "s1", EmbedProviders: 1, LLMProviders: 1})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Equal(t, []int{1, 5, 10}, written.Config.Ks)
+	require.Equal(t, 10, written.Config.TopK)
+	require.Len(t, written.Questions, 1)
+	require.Equal(t, 0.5, written.Aggregate.MRR)
+	require.Equal(t, 1.0, written.Aggregate.RecallAtK[5])
+}
+
+func TestRetrievalEvalWorkflowHybridPinnedProvider(t *testing.T) {
+	env := newStubbedTestEnv(t, RetrievalEvalWorkflow,
+		"UpdateEvalRunActivity", "LoadEvalSetActivity", "EmbedQueryActivity", "SearchChunksActivity", "WriteEvalReportActivity")
+	env.OnActivity("LoadEvalSetActivity", mock.Anything, mock.Anything).Return(activities.LoadEvalSetOutput{
+		Questions: []activities.EvalQuestionItem{
+			{QuestionID: "q1", Question: "sparse attention", Judgment: retrieval.EvalJudgment{ChunkIDs: []string{"c3"}}},
+		},
+	}, nil)
+	var embedIndexes []int
+	env.OnActivity("EmbedQueryActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.EmbedQueryInput) (activities.EmbedQueryOutput, error) {
+		embedIndexes = append(embedIndexes, in.ProviderIndex)
+		return activities.EmbedQueryOutput{Vector: []float32{0.1}}, nil
+	})
+	var searches []activities.SearchChunksInput
+	env.OnActivity("SearchChunksActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.SearchChunksInput) (activities.SearchChunksOutput, error) {
+		searches = append(searches, in)
+		if in.TextQuery != "" {
+			return activities.SearchChunksOutput{Results: []activities.SearchChunk{{ChunkID: "c3", PaperID: "p2"}}}, nil
+		}
+		return activities.SearchChunksOutput{Results: []activities.SearchChunk{{ChunkID: "c1", PaperID: "p1"}, {ChunkID: "c3", PaperID: "p2"}}}, nil
+	})
+	var written RetrievalEvalReport
+	env.OnActivity("WriteEvalReportActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.WriteEvalReportInput) (activities.WriteEvalReportOutput, error) {
+		b, _ := json.Marshal(in.Report)
+		_ = json.Unmarshal(b, &written)
+		return activities.WriteEvalReportOutput{Path: "/tmp/runs/r2/retrieval_eval.json"}, nil
+	})
+
+	env.ExecuteWorkflow(RetrievalEvalWorkflow, RetrievalEvalInput{
+		EvalRunID:          "r2",
+		CorpusID:           "c",
+		EvalSetID:          "s1",
+		Config:             RetrievalEvalConfig{ChunkVersion: "v2", EmbedProvider: "ollama:bge", Hybrid: true},
+		EmbedProviderIndex: 2,
+		EmbedProviders:     3,
+		LLMProviders:       1,
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Equal(t, []int{2}, embedIndexes)
+	require.Len(t, searches, 2)
+	for _, s := range searches {
+		require.Equal(t, "v2", s.ChunkVersion)
+	}
+	require.Equal(t, "", searches[0].TextQuery)
+	require.Equal(t, "sparse attention", searches[1].TextQuery)
+	// c3 ranks first in the full-text list and second by vector, so fusion lifts it to rank 1.
+	require.Equal(t, 1.0, written.Aggregate.MRR)
+	require.True(t, written.Config.Hybrid)
+}
+
+func TestRetrievalEvalWorkflowSkipsFailedHybridVariant(t *testing.T) {
+	env := newStubbedTestEnv(t, RetrievalEvalWorkflow,
+		"UpdateEvalRunActivity", "LoadEvalSetActivity", "LLMGenerateActivity", "LogLLMCallActivity", "EmbedQueryActivity", "SearchChunksActivity", "WriteEvalReportActivity")
+	env.OnActivity("LoadEvalSetActivity", mock.Anything, mock.Anything).Return(activities.LoadEvalSetOutput{
+		Questions: []activities.EvalQuestionItem{
+			{QuestionID: "q1", Question: "sparse attention", Judgment: retrieval.EvalJudgment{ChunkIDs: []string{"c1"}}},
+		},
+	}, nil)
+	env.OnActivity("LLMGenerateActivity", mock.Anything, mock.Anything).Return(activities.LLMGenerateOutput{Text: "Sparse attention restricts each token to a few keys.", ProviderName: "mock", Model: "mock-llm-v1"}, nil)
+	env.OnActivity("EmbedQueryActivity", mock.Anything, mock.Anything).Return(activities.EmbedQueryOutput{Vector: []float32{0.1}}, nil)
+	env.OnActivity("SearchChunksActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.SearchChunksInput) (activities.SearchChunksOutput, error) {
+		if in.TextQuery != "" && in.TextQuery != "sparse attention" {
+			return activities.SearchChunksOutput{}, temporal.NewNonRetryableApplicationError("text search failed", "test", nil)
+		}
+		return activities.SearchChunksOutput{Results: []activities.SearchChunk{{ChunkID: "c1", PaperID: "p1"}}}, nil
+	})
+	var written RetrievalEvalReport
+	env.OnActivity("WriteEvalReportActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.WriteEvalReportInput) (activities.WriteEvalReportOutput, error) {
+		b, _ := json.Marshal(in.Report)
+		_ = json.Unmarshal(b, &written)
+		return activities.WriteEvalReportOutput{Path: "/tmp/runs/r3/retrieval_eval.json"}, nil
+	})
+
+	env.ExecuteWorkflow(RetrievalEvalWorkflow, RetrievalEvalInput{
+		EvalRunID:      "r3",
+		CorpusID:       "c",
+		EvalSetID:      "s1",
+		Config:         RetrievalEvalConfig{QueryRewrite: retrieval.RewriteHyDE, Hybrid: true},
+		EmbedProviders: 1,
+		LLMProviders:   1,
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	// The HyDE variant's full-text search fails; the original query still scores the question.
+	require.Equal(t, 1.0, written.Aggregate.MRR)
+}
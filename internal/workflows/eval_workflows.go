@@ -0,0 +1,185 @@
+package workflows
+
+import (
+	"sort"
+	"time"
+
+	"litflow/internal/activities"
+	"litflow/internal/retrieval"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	QueryGetRetrievalEvalProgress = "GetRetrievalEvalProgress"
+)
+
+// RetrievalEvalWorkflow replays every golden question of an eval set through one retrieval
+// configuration and scores the ranked hits against the stored judgments.
+func RetrievalEvalWorkflow(ctx workflow.Context, input RetrievalEvalInput) (string, error) {
+	progress := RetrievalEvalProgress{EvalRunID: input.EvalRunID, CorpusID: input.CorpusID, Status: "running"}
+	if err := workflow.SetQueryHandler(ctx, QueryGetRetrievalEvalProgress, func() (RetrievalEvalProgress, error) { return progress, nil }); err != nil {
+		return "", err
+	}
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    2 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    30 * time.Second,
+			MaximumAttempts:    2,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+	startedAt := workflow.Now(ctx)
+	cfg := normalizeEvalConfig(input.Config)
+	fail := func(err error) (string, error) {
+		progress.Status = "failed"
+		_ = workflow.ExecuteActivity(ctx, "UpdateEvalRunActivity", activities.UpdateEvalRunInput{EvalRunID: input.EvalRunID, Status: "failed", Error: err.Error()}).Get(ctx, nil)
+		return "", err
+	}
+	_ = workflow.ExecuteActivity(ctx, "UpdateEvalRunActivity", activities.UpdateEvalRunInput{EvalRunID: input.EvalRunID, Status: "running"}).Get(ctx, nil)
+
+	rewriteMode, err := retrieval.NormalizeRewriteMode(cfg.QueryRewrite)
+	if err != nil {
+		return fail(err)
+	}
+	cfg.QueryRewrite = rewriteMode
+	var set activities.LoadEvalSetOutput
+	if err := workflow.ExecuteActivity(ctx, "LoadEvalSetActivity", activities.LoadEvalSetInput{CorpusID: input.CorpusID, EvalSetID: input.EvalSetID}).Get(ctx, &set); err != nil {
+		return fail(err)
+	}
+	progress.Total = len(set.Questions)
+
+	embedProviders := defaultCount(input.EmbedProviders)
+	llmProviders := defaultCount(input.LLMProviders)
+	cooldown := durationOrDefault(input.CooldownSeconds, 900)
+	embedState := newProviderState()
+	llmState := newProviderState()
+
+	results := make([]retrieval.EvalQuestionResult, 0, len(set.Questions))
+	for _, q := range set.Questions {
+		res := retrieval.EvalQuestionResult{QuestionID: q.QuestionID, Question: q.Question}
+		hits, err := runEvalQuery(ctx, &embedState, &llmState, embedProviders, llmProviders, input.LLMProviderRefs, cooldown, input.CorpusID, q.Question, cfg, input.EmbedProviderIndex)
+		if err != nil {
+			res.Error = err.Error()
+			progress.Failed++
+		} else {
+			res.Metrics, res.Level, res.Relevant, res.Retrieved = retrieval.ScoreHits(hits, q.Judgment, cfg.Ks)
+		}
+		results = append(results, res)
+		progress.Done++
+	}
+
+	report := RetrievalEvalReport{
+		EvalRunID:   input.EvalRunID,
+		CorpusID:    input.CorpusID,
+		EvalSetID:   input.EvalSetID,
+		EvalSetName: set.Name,
+		Config:      cfg,
+		Aggregate:   retrieval.AggregateMetrics(results, cfg.Ks),
+		Questions:   results,
+		StartedAt:   startedAt,
+		FinishedAt:  workflow.Now(ctx),
+	}
+	var out activities.WriteEvalReportOutput
+	if err := workflow.ExecuteActivity(ctx, "WriteEvalReportActivity", activities.WriteEvalReportInput{
+		CorpusID:  input.CorpusID,
+		EvalRunID: input.EvalRunID,
+		Report:    report,
+	}).Get(ctx, &out); err != nil {
+		return fail(err)
+	}
+	progress.Status = "completed"
+	_ = workflow.ExecuteActivity(ctx, "UpdateEvalRunActivity", activities.UpdateEvalRunInput{
+		EvalRunID: input.EvalRunID,
+		Status:    "completed",
+		Metrics:   &report.Aggregate,
+		OutPath:   out.Path,
+	}).Get(ctx, nil)
+	return out.Path, nil
+}
+
+// runEvalQuery mirrors the survey retrieval path: optional rewriting, one search per query
+// variant (plus a full-text search in hybrid mode) and reciprocal rank fusion when there is
+// more than one list.
+func runEvalQuery(ctx workflow.Context, embedState, llmState *providerState, embedProviders, llmProviders int, llmRefs []string, cooldown time.Duration, corpusID, question string, cfg RetrievalEvalConfig, embedIndex int) ([]retrieval.EvalHit, error) {
+	queries := rewriteSurveyQueries(ctx, llmState, llmProviders, llmRefs, cooldown, corpusID, question, cfg.QueryRewrite, cfg.RewriteCount)
+	lists := make([][]activities.SearchChunk, 0, 2*len(queries))
+	for i, q := range queries {
+		embedIn := activities.EmbedQueryInput{Operation: "eval_query_embed", Text: q.Text}
+		var eq activities.EmbedQueryOutput
+		var err error
+		if cfg.EmbedProvider != "" {
+			embedIn.ProviderIndex = embedIndex
+			err = workflow.ExecuteActivity(ctx, "EmbedQueryActivity", embedIn).Get(ctx, &eq)
+		} else {
+			eq, err = callEmbedQueryWithFailover(ctx, embedState, embedProviders, cooldown, embedIn, nil)
+		}
+		if err != nil {
+			if i > 0 {
+				continue
+			}
+			return nil, err
+		}
+		search := activities.SearchChunksInput{
+			CorpusID:         corpusID,
+			QueryVec:         eq.Vector,
+			TopK:             cfg.TopK,
+			EmbeddingVersion: cfg.EmbedVersion,
+			ChunkVersion:     cfg.ChunkVersion,
+		}
+		var hits activities.SearchChunksOutput
+		if err := workflow.ExecuteActivity(ctx, "SearchChunksActivity", search).Get(ctx, &hits); err != nil {
+			if i > 0 {
+				continue
+			}
+			return nil, err
+		}
+		if cfg.Hybrid {
+			search.TextQuery = q.Text
+			var textHits activities.SearchChunksOutput
+			if err := workflow.ExecuteActivity(ctx, "SearchChunksActivity", search).Get(ctx, &textHits); err != nil {
+				if i > 0 {
+					continue
+				}
+				return nil, err
+			}
+			lists = append(lists, hits.Results, textHits.Results)
+			continue
+		}
+		lists = append(lists, hits.Results)
+	}
+	ranked := lists[0]
+	if len(lists) > 1 {
+		ranked = retrieval.FuseRanked(lists, func(c activities.SearchChunk) string { return c.ChunkID }, cfg.TopK)
+	}
+	out := make([]retrieval.EvalHit, 0, len(ranked))
+	for _, c := range ranked {
+		out = append(out, retrieval.EvalHit{ChunkID: c.ChunkID, PaperID: c.PaperID})
+	}
+	return out, nil
+}
+
+func normalizeEvalConfig(cfg RetrievalEvalConfig) RetrievalEvalConfig {
+	ks := make([]int, 0, len(cfg.Ks))
+	seen := map[int]bool{}
+	for _, k := range cfg.Ks {
+		if k > 0 && !seen[k] {
+			seen[k] = true
+			ks = append(ks, k)
+		}
+	}
+	if len(ks) == 0 {
+		ks = append(ks, retrieval.DefaultEvalKs...)
+	}
+	sort.Ints(ks)
+	cfg.Ks = ks
+	if cfg.TopK < ks[len(ks)-1] {
+		cfg.TopK = ks[len(ks)-1]
+	}
+	cfg.EmbedVersion = defaultEmbedVersion(cfg.EmbedVersion)
+	cfg.ChunkVersion = defaultChunkVersion(cfg.ChunkVersion)
+	return cfg
+}
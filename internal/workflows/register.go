@@ -6,7 +6,13 @@ func Register(w worker.Worker) {
 	w.RegisterWorkflow(CorpusIngestWorkflow)
 	w.RegisterWorkflow(PaperProcessWorkflow)
 	w.RegisterWorkflow(SurveyBuildWorkflow)
+	w.RegisterWorkflow(SurveySectionWorkflow)
+	w.RegisterWorkflow(SurveyUpdateWorkflow)
 	w.RegisterWorkflow(BackfillWorkflow)
+	w.RegisterWorkflow(RetrievalEvalWorkflow)
+	w.RegisterWorkflow(DeepResearchWorkflow)
+	w.RegisterWorkflow(DraftPositioningWorkflow)
+	w.RegisterWorkflow(PaperSummarizeWorkflow)
 	w.RegisterWorkflow(KGBackfillWorkflow)
 	w.RegisterWorkflow(KGExtractPaperWorkflow)
 }
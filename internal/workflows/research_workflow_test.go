@@ -0,0 +1,88 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"litflow/internal/activities"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func newResearchTestEnv(t *testing.T, llm func(activities.LLMGenerateInput) string) (*testsuite.TestWorkflowEnvironment, *activities.WriteResearchReportInput) {
+	t.Helper()
+	env := newStubbedTestEnv(t, DeepResearchWorkflow,
+		"UpdateResearchRunActivity", "LLMGenerateActivity", "LogLLMCallActivity", "EmbedQueryActivity", "SearchChunksActivity", "SearchGraphEdgesActivity", "WriteResearchReportActivity")
+	env.OnActivity("LLMGenerateActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.LLMGenerateInput) (activities.LLMGenerateOutput, error) {
+		return activities.LLMGenerateOutput{Text: llm(in), ProviderName: "mock", Model: "mock-llm-v1"}, nil
+	})
+	env.OnActivity("EmbedQueryActivity", mock.Anything, mock.Anything).Return(activities.EmbedQueryOutput{Vector: []float32{0.1}}, nil)
+	env.OnActivity("SearchChunksActivity", mock.Anything, mock.Anything).Return(activities.SearchChunksOutput{Results: []activities.SearchChunk{
+		{ChunkID: "c1", PaperID: "p1", Title: "LoRA", Text: "LoRA extends adapters and is evaluated on GLUE."},
+	}}, nil)
+	env.OnActivity("SearchGraphEdgesActivity", mock.Anything, mock.Anything).Return(activities.SearchGraphEdgesOutput{Edges: []activities.GraphEdgeHit{
+		{EdgeID: "e1", EdgeType: "EVALUATED_ON", SourceName: "LoRA", TargetName: "GLUE", PaperID: "p1", ChunkID: "c1", Evidence: "evaluated on GLUE"},
+	}}, nil)
+	written := &activities.WriteResearchReportInput{}
+	env.OnActivity("WriteResearchReportActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.WriteResearchReportInput) (activities.WriteResearchReportOutput, error) {
+		*written = in
+		return activities.WriteResearchReportOutput{ReportPath: "/tmp/research/r1/report.md"}, nil
+	})
+	return env, written
+}
+
+func TestDeepResearchWorkflowPlansAndCites(t *testing.T) {
+	plans := 0
+	env, written := newResearchTestEnv(t, func(in activities.LLMGenerateInput) string {
+		switch in.Operation {
+		case "research_plan":
+			plans++
+			if plans > 1 {
+				return `{"done": true, "sub_questions": []}`
+			}
+			return "```json\n{\"done\": false, \"sub_questions\": [{\"question\": \"Which methods extend adapters?\", \"source\": \"graph\", \"terms\": [\"adapters\"]}, {\"question\": \"What are LoRA weaknesses?\", \"source\": \"chunks\"}]}\n```"
+		case "research_note":
+			return "LoRA is evaluated on GLUE [E1]."
+		default:
+			return "## Answer\nLoRA extends adapters [E1][E2]."
+		}
+	})
+	env.ExecuteWorkflow(DeepResearchWorkflow, DeepResearchInput{ResearchRunID: "r1", CorpusID: "c", Question: "Which methods that extend adapters were evaluated on GLUE?", EmbedProviders: 1, LLMProviders: 1})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var trace DeepResearchTrace
+	b, _ := json.Marshal(written.Trace)
+	require.NoError(t, json.Unmarshal(b, &trace))
+	require.Equal(t, 2, trace.Progress.Step)
+	require.Equal(t, "planner_done", trace.Progress.StopReason)
+	require.Len(t, trace.Evidence, 2)
+	require.Equal(t, "edge", trace.Evidence[0].Kind)
+	require.Equal(t, "chunk", trace.Evidence[1].Kind)
+	require.Greater(t, trace.Progress.TokensUsed, 0)
+	require.True(t, strings.Contains(written.Markdown, "## Evidence"))
+	require.True(t, strings.Contains(written.Markdown, "[E2] LoRA — paper `p1`, chunk `c1`"))
+}
+
+func TestDeepResearchWorkflowStopsAtTokenBudget(t *testing.T) {
+	env, written := newResearchTestEnv(t, func(in activities.LLMGenerateInput) string {
+		if in.Operation == "research_plan" {
+			return `{"done": false, "sub_questions": [{"question": "first hop", "source": "chunks"}, {"question": "second hop", "source": "chunks"}]}`
+		}
+		return strings.Repeat("long note ", 200)
+	})
+	env.ExecuteWorkflow(DeepResearchWorkflow, DeepResearchInput{ResearchRunID: "r2", CorpusID: "c", Question: "budgeted question", TokenBudget: 600, EmbedProviders: 1, LLMProviders: 1})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var trace DeepResearchTrace
+	b, _ := json.Marshal(written.Trace)
+	require.NoError(t, json.Unmarshal(b, &trace))
+	require.Equal(t, "token_budget", trace.Progress.StopReason)
+	require.Equal(t, 1, trace.Progress.Step)
+	require.Equal(t, "skipped", trace.Progress.Plan[1].Status)
+}
@@ -0,0 +1,517 @@
+package workflows
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"litflow/internal/activities"
+	"litflow/internal/retrieval"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	QueryGetDeepResearchProgress = "GetDeepResearchProgress"
+)
+
+const (
+	researchSourceChunks = "chunks"
+	researchSourceGraph  = "graph"
+
+	defaultResearchSteps  = 6
+	maxResearchSteps      = 20
+	defaultResearchBudget = 24000
+	maxResearchPlanRounds = 4
+	maxSubQuestionsPerRun = 3
+)
+
+// DeepResearchWorkflow answers questions that need several retrieval hops. It alternates
+// between planning sub-questions, retrieving evidence for each one from chunks or from
+// KG relations, and writing a cited note, until the planner is satisfied or the step or
+// token budget runs out. A quarter of the token budget is reserved for the final report.
+func DeepResearchWorkflow(ctx workflow.Context, input DeepResearchInput) (string, error) {
+	question := strings.TrimSpace(input.Question)
+	maxSteps := input.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultResearchSteps
+	}
+	if maxSteps > maxResearchSteps {
+		maxSteps = maxResearchSteps
+	}
+	budget := input.TokenBudget
+	if budget <= 0 {
+		budget = defaultResearchBudget
+	}
+	topK := input.TopK
+	if topK <= 0 {
+		topK = 6
+	}
+	progress := DeepResearchProgress{
+		ResearchRunID: input.ResearchRunID,
+		CorpusID:      input.CorpusID,
+		Question:      question,
+		Status:        "running",
+		Phase:         "planning",
+		MaxSteps:      maxSteps,
+		TokenBudget:   budget,
+		Plan:          []ResearchSubQuestion{},
+		Findings:      []ResearchFinding{},
+	}
+	if err := workflow.SetQueryHandler(ctx, QueryGetDeepResearchProgress, func() (DeepResearchProgress, error) { return progress, nil }); err != nil {
+		return "", err
+	}
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    2 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    30 * time.Second,
+			MaximumAttempts:    2,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+	startedAt := workflow.Now(ctx)
+	fail := func(err error) (string, error) {
+		progress.Status = "failed"
+		_ = workflow.ExecuteActivity(ctx, "UpdateResearchRunActivity", activities.UpdateResearchRunInput{ResearchRunID: input.ResearchRunID, Status: "failed", Error: err.Error()}).Get(ctx, nil)
+		return "", err
+	}
+	if question == "" {
+		return fail(fmt.Errorf("research question is required"))
+	}
+	_ = workflow.ExecuteActivity(ctx, "UpdateResearchRunActivity", activities.UpdateResearchRunInput{ResearchRunID: input.ResearchRunID, Status: "running"}).Get(ctx, nil)
+
+	embedProviders := defaultCount(input.EmbedProviders)
+	llmProviders := defaultCount(input.LLMProviders)
+	cooldown := durationOrDefault(input.CooldownSeconds, 900)
+	embedState := newProviderState()
+	llmState := newProviderState()
+	researchBudget := budget - budget/4
+
+	generate := func(op, prompt string, contextItems []string) (string, error) {
+		out, _, err := callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, activities.LLMGenerateInput{
+			Operation: op,
+			CorpusID:  input.CorpusID,
+			Prompt:    prompt,
+			Context:   contextItems,
+		}, nil)
+		progress.TokensUsed += retrieval.EstimateTokens(prompt) + retrieval.EstimateTokens(strings.Join(contextItems, "\n\n"))
+		if err != nil {
+			return "", err
+		}
+		progress.TokensUsed += retrieval.EstimateTokens(out.Text)
+		return out.Text, nil
+	}
+
+	evidence := make([]ResearchEvidence, 0)
+	evidenceByKey := map[string]string{}
+	addEvidence := func(key string, ev ResearchEvidence) string {
+		if id, ok := evidenceByKey[key]; ok {
+			return id
+		}
+		ev.EvidenceID = fmt.Sprintf("E%d", len(evidence)+1)
+		evidence = append(evidence, ev)
+		evidenceByKey[key] = ev.EvidenceID
+		return ev.EvidenceID
+	}
+	evidenceText := func(ids []string) []string {
+		out := make([]string, 0, len(ids))
+		for _, id := range ids {
+			for _, ev := range evidence {
+				if ev.EvidenceID == id {
+					out = append(out, fmt.Sprintf("[%s] %s: %s", ev.EvidenceID, ev.Title, truncateRunes(ev.Text, 1200)))
+					break
+				}
+			}
+		}
+		return out
+	}
+
+	pending := make([]int, 0)
+	planRounds := 0
+	for progress.Step < maxSteps {
+		if progress.TokensUsed >= researchBudget {
+			progress.StopReason = "token_budget"
+			break
+		}
+		if len(pending) == 0 {
+			if planRounds >= maxResearchPlanRounds {
+				progress.StopReason = "plan_rounds"
+				break
+			}
+			progress.Phase = "planning"
+			raw, err := generate("research_plan", buildResearchPlanPrompt(question, progress.Plan, progress.Findings), nil)
+			subs, done, ok := parseResearchPlan(raw)
+			if err != nil || !ok {
+				subs, done = fallbackResearchPlan(question, planRounds)
+			}
+			planRounds++
+			if done {
+				subs = nil
+			}
+			for _, sq := range subs {
+				if len(pending) >= maxSubQuestionsPerRun || hasSubQuestion(progress.Plan, sq.Question) {
+					continue
+				}
+				sq.Status = "pending"
+				progress.Plan = append(progress.Plan, sq)
+				pending = append(pending, len(progress.Plan)-1)
+			}
+			if len(pending) == 0 {
+				progress.StopReason = "planner_done"
+				break
+			}
+			continue
+		}
+
+		idx := pending[0]
+		pending = pending[1:]
+		sq := progress.Plan[idx]
+		progress.Plan[idx].Status = "retrieving"
+		progress.Phase = "retrieving"
+		stepIDs := make([]string, 0, topK)
+		source := sq.Source
+		if source == researchSourceGraph {
+			terms := sq.Terms
+			if len(terms) == 0 {
+				terms = researchTerms(sq.Question)
+			}
+			var edges activities.SearchGraphEdgesOutput
+			if err := workflow.ExecuteActivity(ctx, "SearchGraphEdgesActivity", activities.SearchGraphEdgesInput{
+				CorpusID:  input.CorpusID,
+				CorpusIDs: input.CorpusIDs,
+				Terms:     terms,
+				Limit:     topK * 2,
+			}).Get(ctx, &edges); err == nil {
+				for _, e := range edges.Edges {
+					text := fmt.Sprintf("%s %s %s", e.SourceName, e.EdgeType, e.TargetName)
+					if strings.TrimSpace(e.Evidence) != "" {
+						text += " (evidence: " + strings.TrimSpace(e.Evidence) + ")"
+					}
+					stepIDs = append(stepIDs, addEvidence("edge:"+e.EdgeID, ResearchEvidence{
+						Kind:    "edge",
+						EdgeID:  e.EdgeID,
+						PaperID: e.PaperID,
+						ChunkID: e.ChunkID,
+						Title:   "KG relation",
+						Text:    text,
+						Score:   e.Weight,
+					}))
+				}
+			}
+			if len(stepIDs) == 0 {
+				// No extracted relations matched; answer this hop from chunks instead.
+				source = researchSourceChunks
+			}
+		}
+		if source == researchSourceChunks {
+			eq, err := callEmbedQueryWithFailover(ctx, &embedState, embedProviders, cooldown, activities.EmbedQueryInput{
+				Operation: "research_query_embed",
+				Text:      sq.Question,
+			}, nil)
+			if err == nil {
+				var hits activities.SearchChunksOutput
+				if err := workflow.ExecuteActivity(ctx, "SearchChunksActivity", activities.SearchChunksInput{
+					CorpusID:         input.CorpusID,
+					CorpusIDs:        input.CorpusIDs,
+					QueryVec:         eq.Vector,
+					TopK:             topK,
+					EmbeddingVersion: defaultEmbedVersion(input.EmbedVersion),
+				}).Get(ctx, &hits); err == nil {
+					for _, c := range hits.Results {
+						text := c.Text
+						if strings.TrimSpace(text) == "" {
+							text = c.Snippet
+						}
+						stepIDs = append(stepIDs, addEvidence("chunk:"+c.ChunkID, ResearchEvidence{
+							Kind:    "chunk",
+							PaperID: c.PaperID,
+							ChunkID: c.ChunkID,
+							Title:   c.Title,
+							Text:    text,
+							Score:   c.Score,
+						}))
+					}
+				}
+			}
+		}
+		stepIDs = dedupeStrings(stepIDs)
+
+		progress.Phase = "noting"
+		finding := ResearchFinding{Step: progress.Step + 1, SubQuestion: sq.Question, Source: source, EvidenceIDs: stepIDs}
+		if len(stepIDs) == 0 {
+			finding.Note = "No evidence was found for this sub-question."
+			progress.Plan[idx].Status = "no_evidence"
+		} else {
+			note, err := generate("research_note", buildResearchNotePrompt(question, sq.Question), evidenceText(stepIDs))
+			if err != nil || strings.TrimSpace(note) == "" {
+				note = extractiveResearchNote(evidence, stepIDs)
+			}
+			finding.Note = strings.TrimSpace(note)
+			progress.Plan[idx].Status = "done"
+		}
+		progress.Findings = append(progress.Findings, finding)
+		progress.Step++
+	}
+	if progress.StopReason == "" && progress.Step >= maxSteps {
+		progress.StopReason = "max_steps"
+	}
+	for i := range progress.Plan {
+		if progress.Plan[i].Status == "pending" {
+			progress.Plan[i].Status = "skipped"
+		}
+	}
+
+	progress.Phase = "reporting"
+	notes := make([]string, 0, len(progress.Findings))
+	for _, f := range progress.Findings {
+		notes = append(notes, fmt.Sprintf("Step %d (%s) - %s\n%s", f.Step, f.Source, f.SubQuestion, f.Note))
+	}
+	body := ""
+	if len(progress.Findings) > 0 && progress.TokensUsed < budget {
+		if text, err := generate("research_report", buildResearchReportPrompt(question), notes); err == nil {
+			body = cleanLLMDocument(text)
+		}
+	}
+	if strings.TrimSpace(body) == "" {
+		body = fallbackResearchReport(progress.Findings)
+	}
+	markdown := renderResearchReport(question, body, evidence)
+
+	progress.Status = "completed"
+	progress.Phase = "done"
+	trace := DeepResearchTrace{Progress: progress, Evidence: evidence, StartedAt: startedAt, FinishedAt: workflow.Now(ctx)}
+	var out activities.WriteResearchReportOutput
+	if err := workflow.ExecuteActivity(ctx, "WriteResearchReportActivity", activities.WriteResearchReportInput{
+		CorpusID:      input.CorpusID,
+		ResearchRunID: input.ResearchRunID,
+		Markdown:      markdown,
+		Trace:         trace,
+	}).Get(ctx, &out); err != nil {
+		return fail(err)
+	}
+	_ = workflow.ExecuteActivity(ctx, "UpdateResearchRunActivity", activities.UpdateResearchRunInput{ResearchRunID: input.ResearchRunID, Status: "completed", OutPath: out.ReportPath}).Get(ctx, nil)
+	return out.ReportPath, nil
+}
+
+func buildResearchPlanPrompt(question string, plan []ResearchSubQuestion, findings []ResearchFinding) string {
+	lines := []string{
+		"You plan multi-hop research over a corpus of scientific papers and its knowledge graph.",
+		"Question: " + question,
+		"",
+	}
+	if len(plan) > 0 {
+		lines = append(lines, "Sub-questions already investigated:")
+		for _, sq := range plan {
+			lines = append(lines, "- "+sq.Question)
+		}
+		lines = append(lines, "")
+	}
+	if len(findings) > 0 {
+		lines = append(lines, "Findings so far:")
+		for _, f := range findings {
+			lines = append(lines, fmt.Sprintf("- [step %d] %s", f.Step, truncateRunes(strings.Join(strings.Fields(f.Note), " "), 400)))
+		}
+		lines = append(lines, "")
+	}
+	lines = append(lines,
+		fmt.Sprintf("Propose up to %d NEW sub-questions still needed to answer the question, or set \"done\": true when the findings are sufficient.", maxSubQuestionsPerRun),
+		`Use source "graph" with entity "terms" (method, dataset or task names) for relations such as extends, based on or evaluated on; use source "chunks" for everything else.`,
+		"",
+		`Output STRICT JSON: {"done": false, "sub_questions": [{"question": "...", "source": "chunks", "terms": []}]}`,
+	)
+	return strings.Join(lines, "\n")
+}
+
+// parseResearchPlan reads a planner completion. ok is false when the output is not the
+// requested JSON so the caller can fall back to a fixed plan.
+func parseResearchPlan(raw string) ([]ResearchSubQuestion, bool, bool) {
+	raw = strings.TrimSpace(raw)
+	if start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}"); start >= 0 && end > start {
+		raw = raw[start : end+1]
+	}
+	var payload struct {
+		Done         bool `json:"done"`
+		SubQuestions []struct {
+			Question string   `json:"question"`
+			Source   string   `json:"source"`
+			Terms    []string `json:"terms"`
+		} `json:"sub_questions"`
+	}
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return nil, false, false
+	}
+	out := make([]ResearchSubQuestion, 0, len(payload.SubQuestions))
+	for _, sq := range payload.SubQuestions {
+		q := strings.Join(strings.Fields(sq.Question), " ")
+		if q == "" {
+			continue
+		}
+		source := strings.ToLower(strings.TrimSpace(sq.Source))
+		if source != researchSourceGraph {
+			source = researchSourceChunks
+		}
+		terms := make([]string, 0, len(sq.Terms))
+		for _, t := range sq.Terms {
+			if t = strings.TrimSpace(t); t != "" {
+				terms = append(terms, t)
+			}
+		}
+		out = append(out, ResearchSubQuestion{Question: q, Source: source, Terms: terms})
+	}
+	return out, payload.Done, payload.Done || len(out) > 0
+}
+
+// fallbackResearchPlan is used when the planner output is unusable: the first round looks
+// the question up in both chunks and the KG, later rounds stop.
+func fallbackResearchPlan(question string, round int) ([]ResearchSubQuestion, bool) {
+	if round > 0 {
+		return nil, true
+	}
+	subs := []ResearchSubQuestion{{Question: question, Source: researchSourceChunks}}
+	if terms := researchTerms(question); len(terms) > 0 {
+		subs = append(subs, ResearchSubQuestion{Question: "Knowledge graph relations for " + strings.Join(terms, ", "), Source: researchSourceGraph, Terms: terms})
+	}
+	return subs, false
+}
+
+func buildResearchNotePrompt(question, subQuestion string) string {
+	return strings.Join([]string{
+		"Overall question: " + question,
+		"Sub-question: " + subQuestion,
+		"",
+		"Using ONLY the evidence items provided, write a concise research note (3-6 sentences) answering the sub-question.",
+		"Cite items as [E#] immediately after each claim they support.",
+		"If the evidence is insufficient, say exactly what is missing.",
+	}, "\n")
+}
+
+func buildResearchReportPrompt(question string) string {
+	return strings.Join([]string{
+		"Question: " + question,
+		"",
+		"Write a cited research report in Markdown that answers the question from the research notes provided.",
+		"Keep every [E#] citation attached to the claims it supports and do not invent new evidence IDs.",
+		"Use these sections: ## Answer, ## Supporting Findings, ## Gaps and Open Questions.",
+	}, "\n")
+}
+
+func extractiveResearchNote(evidence []ResearchEvidence, ids []string) string {
+	parts := make([]string, 0, 2)
+	for _, id := range ids {
+		for _, ev := range evidence {
+			if ev.EvidenceID == id {
+				parts = append(parts, truncateRunes(strings.Join(strings.Fields(ev.Text), " "), 240)+" ["+id+"]")
+			}
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func fallbackResearchReport(findings []ResearchFinding) string {
+	if len(findings) == 0 {
+		return "## Answer\nNo evidence was gathered for this question."
+	}
+	lines := []string{"## Supporting Findings"}
+	for _, f := range findings {
+		lines = append(lines, "", "### "+f.SubQuestion, f.Note)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderResearchReport wraps the report body with a title and an evidence list that maps
+// every [E#] to its paper, chunk or KG relation.
+func renderResearchReport(question, body string, evidence []ResearchEvidence) string {
+	var b strings.Builder
+	b.WriteString("# Deep research: " + question + "\n\n")
+	b.WriteString(strings.TrimSpace(body) + "\n")
+	if len(evidence) == 0 {
+		return b.String()
+	}
+	b.WriteString("\n## Evidence\n")
+	for _, ev := range evidence {
+		line := fmt.Sprintf("- [%s] %s", ev.EvidenceID, ev.Title)
+		if ev.Kind == "edge" {
+			line = fmt.Sprintf("- [%s] KG: %s", ev.EvidenceID, truncateRunes(ev.Text, 200))
+		}
+		if ev.PaperID != "" {
+			line += fmt.Sprintf(" — paper `%s`", ev.PaperID)
+		}
+		if ev.ChunkID != "" {
+			line += fmt.Sprintf(", chunk `%s`", ev.ChunkID)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+var researchStopwords = map[string]bool{
+	"which": true, "what": true, "were": true, "their": true, "there": true, "that": true, "this": true,
+	"these": true, "those": true, "with": true, "from": true, "have": true, "does": true, "into": true,
+	"extend": true, "extends": true, "evaluated": true, "weaknesses": true, "strengths": true, "methods": true,
+	"method": true, "about": true, "compare": true, "compared": true, "other": true, "when": true, "where": true,
+}
+
+// researchTerms picks entity-like words from a question for KG lookups: capitalized or
+// mixed-case tokens first, then longer content words, at most four.
+func researchTerms(question string) []string {
+	fields := strings.FieldsFunc(question, func(r rune) bool {
+		return !(r == '-' || r == '_' || r == '.' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'))
+	})
+	named := make([]string, 0, 4)
+	other := make([]string, 0, 4)
+	for _, f := range fields {
+		f = strings.Trim(f, ".-_")
+		lower := strings.ToLower(f)
+		if len(f) < 3 || researchStopwords[lower] {
+			continue
+		}
+		if f != lower {
+			named = append(named, f)
+		} else if len(f) >= 6 {
+			other = append(other, f)
+		}
+	}
+	terms := dedupeStrings(append(named, other...))
+	if len(terms) > 4 {
+		terms = terms[:4]
+	}
+	return terms
+}
+
+func hasSubQuestion(plan []ResearchSubQuestion, q string) bool {
+	key := strings.ToLower(strings.Join(strings.Fields(q), " "))
+	for _, sq := range plan {
+		if strings.ToLower(strings.Join(strings.Fields(sq.Question), " ")) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupeStrings(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(r[:n])) + "..."
+}
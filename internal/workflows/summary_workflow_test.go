@@ -0,0 +1,68 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"litflow/internal/activities"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaperSummarizeWorkflowMapReduce(t *testing.T) {
+	env := newStubbedTestEnv(t, PaperSummarizeWorkflow,
+		"ListPaperChunksActivity", "LLMGenerateActivity", "LogLLMCallActivity", "UpsertPaperSummaryActivity")
+	chunks := make([]activities.KGPaperChunk, 0, 8)
+	for i := 0; i < 8; i++ {
+		chunks = append(chunks, activities.KGPaperChunk{ChunkID: fmt.Sprintf("c%d", i), Text: strings.Repeat("word ", 30)})
+	}
+	env.OnActivity("ListPaperChunksActivity", mock.Anything, mock.Anything).Return(activities.ListPaperChunksOutput{Title: "LoRA", Chunks: chunks}, nil)
+	calls := map[string]int{}
+	reduceContext := 0
+	env.OnActivity("LLMGenerateActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.LLMGenerateInput) (activities.LLMGenerateOutput, error) {
+		calls[in.Operation]++
+		text := "- Contributions: low-rank adapters"
+		if in.Operation == "paper_summary_reduce" {
+			reduceContext = len(in.Context)
+			text = "```json\n{\"tldr\": \"LoRA freezes weights and trains low-rank updates.\", \"contributions\": [\"Low-rank adapters\", \" \"], \"method\": \"Rank decomposition.\", \"results\": \"Matches fine-tuning on GLUE.\", \"limitations\": \"Not reported.\"}\n```"
+		}
+		return activities.LLMGenerateOutput{Text: text, ProviderName: "mock", Model: "mock-llm-v1"}, nil
+	})
+	var stored activities.UpsertPaperSummaryInput
+	env.OnActivity("UpsertPaperSummaryActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.UpsertPaperSummaryInput) error {
+		stored = in
+		return nil
+	})
+
+	env.ExecuteWorkflow(PaperSummarizeWorkflow, PaperSummarizeInput{CorpusID: "c", PaperID: "p1", BatchChars: 150, LLMProviders: 1})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var status string
+	require.NoError(t, env.GetWorkflowResult(&status))
+	require.Equal(t, "completed", status)
+
+	require.Equal(t, 8, calls["paper_summary_map"])
+	require.Equal(t, 2, calls["paper_summary_merge"])
+	require.Equal(t, 1, calls["paper_summary_reduce"])
+	require.Equal(t, 2, reduceContext)
+	require.Equal(t, "p1", stored.Summary.PaperID)
+	require.Equal(t, PaperSummaryPromptVersion, stored.PromptVersion)
+	require.Equal(t, "mock-llm-v1", stored.LLMModel)
+	require.Equal(t, []string{"Low-rank adapters"}, stored.Summary.Contributions)
+	require.Equal(t, 8, stored.ChunkCount)
+}
+
+func TestPaperSummarizeWorkflowFailsOnUnusableSummary(t *testing.T) {
+	env := newStubbedTestEnv(t, PaperSummarizeWorkflow,
+		"ListPaperChunksActivity", "LLMGenerateActivity", "LogLLMCallActivity", "UpsertPaperSummaryActivity")
+	env.OnActivity("ListPaperChunksActivity", mock.Anything, mock.Anything).Return(activities.ListPaperChunksOutput{Title: "LoRA", Chunks: []activities.KGPaperChunk{{ChunkID: "c0", Text: "Low-rank adapters."}}}, nil)
+	env.OnActivity("LLMGenerateActivity", mock.Anything, mock.Anything).Return(activities.LLMGenerateOutput{Text: "Mock response.", ProviderName: "mock", Model: "mock-llm-v1"}, nil)
+
+	env.ExecuteWorkflow(PaperSummarizeWorkflow, PaperSummarizeInput{CorpusID: "c", PaperID: "p1", LLMProviders: 1})
+	require.True(t, env.IsWorkflowCompleted())
+	require.ErrorContains(t, env.GetWorkflowError(), "unusable reduce output")
+	env.AssertNotCalled(t, "UpsertPaperSummaryActivity", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,213 @@
+package workflows
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"litflow/internal/activities"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// PaperSummaryPromptVersion identifies the map, merge and reduce prompts. Summaries stored
+// under another version are regenerated by the SUMMARIZE_PAPERS backfill.
+const PaperSummaryPromptVersion = "paper-summary-v1"
+
+const (
+	defaultSummaryBatchChars = 6000
+	// summaryBackfillConcurrency bounds how many papers SUMMARIZE_PAPERS summarizes at once.
+	summaryBackfillConcurrency = 4
+	// summaryReduceFanIn bounds how many partial notes go into one merge or reduce call.
+	summaryReduceFanIn = 6
+)
+
+// PaperSummarizeWorkflow summarizes one paper with map-reduce over its chunks: chunks are
+// batched into map calls that take notes, notes are merged in groups until they fit one
+// reduce call, and the reduce call returns the structured summary that is stored.
+func PaperSummarizeWorkflow(ctx workflow.Context, input PaperSummarizeInput) (string, error) {
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    1 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    20 * time.Second,
+			MaximumAttempts:    2,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	var chunkOut activities.ListPaperChunksOutput
+	if err := workflow.ExecuteActivity(ctx, "ListPaperChunksActivity", activities.KGPaperInput{CorpusID: input.CorpusID, PaperID: input.PaperID}).Get(ctx, &chunkOut); err != nil {
+		return "", err
+	}
+	if len(chunkOut.Chunks) == 0 {
+		return "skipped", nil
+	}
+
+	state := newProviderState()
+	llmProviders := defaultCount(input.LLMProviders)
+	cooldown := durationOrDefault(input.CooldownSeconds, 900)
+	generate := func(op, prompt string, context []string) (activities.LLMGenerateOutput, error) {
+		out, _, err := callLLMWithFailover(ctx, &state, llmProviders, input.LLMProviderRefs, cooldown, activities.LLMGenerateInput{
+			Operation: op,
+			CorpusID:  input.CorpusID,
+			PaperID:   input.PaperID,
+			Prompt:    prompt,
+			Context:   context,
+		}, nil)
+		return out, err
+	}
+
+	batches := batchChunkTexts(chunkOut.Chunks, input.BatchChars)
+	notes := make([]string, 0, len(batches))
+	for i, batch := range batches {
+		out, err := generate("paper_summary_map", buildSummaryMapPrompt(chunkOut.Title, i+1, len(batches)), batch)
+		if err != nil {
+			continue
+		}
+		if text := strings.TrimSpace(out.Text); text != "" {
+			notes = append(notes, text)
+		}
+	}
+	if len(notes) == 0 {
+		return "", fmt.Errorf("summarize paper %s: every map call failed", input.PaperID)
+	}
+	for len(notes) > summaryReduceFanIn {
+		merged := make([]string, 0, len(notes)/summaryReduceFanIn+1)
+		for i := 0; i < len(notes); i += summaryReduceFanIn {
+			group := notes[i:min(i+summaryReduceFanIn, len(notes))]
+			out, err := generate("paper_summary_merge", buildSummaryMergePrompt(chunkOut.Title), group)
+			if err != nil || strings.TrimSpace(out.Text) == "" {
+				merged = append(merged, strings.Join(group, "\n\n"))
+				continue
+			}
+			merged = append(merged, strings.TrimSpace(out.Text))
+		}
+		notes = merged
+	}
+
+	out, err := generate("paper_summary_reduce", buildSummaryReducePrompt(chunkOut.Title), notes)
+	if err != nil {
+		return "", err
+	}
+	summary, ok := parsePaperSummary(out.Text)
+	if !ok {
+		return "", fmt.Errorf("summarize paper %s: unusable reduce output", input.PaperID)
+	}
+	summary.PaperID = input.PaperID
+	if err := workflow.ExecuteActivity(ctx, "UpsertPaperSummaryActivity", activities.UpsertPaperSummaryInput{
+		CorpusID:      input.CorpusID,
+		Summary:       summary,
+		PromptVersion: PaperSummaryPromptVersion,
+		LLMProvider:   out.ProviderName,
+		LLMModel:      out.Model,
+		ChunkCount:    len(chunkOut.Chunks),
+	}).Get(ctx, nil); err != nil {
+		return "", err
+	}
+	return "completed", nil
+}
+
+// batchChunkTexts groups consecutive chunks into map batches of roughly maxChars characters.
+// A chunk longer than the budget forms its own batch.
+func batchChunkTexts(chunks []activities.KGPaperChunk, maxChars int) [][]string {
+	if maxChars <= 0 {
+		maxChars = defaultSummaryBatchChars
+	}
+	batches := make([][]string, 0)
+	var current []string
+	size := 0
+	for _, c := range chunks {
+		text := strings.Join(strings.Fields(c.Text), " ")
+		if text == "" {
+			continue
+		}
+		if len(current) > 0 && size+len(text) > maxChars {
+			batches = append(batches, current)
+			current, size = nil, 0
+		}
+		current = append(current, text)
+		size += len(text)
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+func buildSummaryMapPrompt(title string, part, total int) string {
+	return strings.Join([]string{
+		fmt.Sprintf("The context is part %d of %d of the paper %q.", part, total, strings.TrimSpace(title)),
+		"Take terse bullet notes under these headings: Contributions, Method, Results, Limitations.",
+		"Record only what this part states, keep concrete numbers, datasets and names, and write \"none\" under headings this part does not cover.",
+	}, "\n")
+}
+
+func buildSummaryMergePrompt(title string) string {
+	return strings.Join([]string{
+		fmt.Sprintf("The context holds partial notes on consecutive parts of the paper %q.", strings.TrimSpace(title)),
+		"Merge them into one set of terse bullet notes under the headings Contributions, Method, Results, Limitations.",
+		"Remove duplicates but keep every distinct finding, number and dataset.",
+	}, "\n")
+}
+
+func buildSummaryReducePrompt(title string) string {
+	return strings.Join([]string{
+		fmt.Sprintf("The context holds notes covering the whole paper %q.", strings.TrimSpace(title)),
+		"Write the paper summary from these notes only:",
+		"- tldr: one or two sentences",
+		"- contributions: three to five short items",
+		"- method, results, limitations: two to four sentences each; say \"Not reported.\" when the notes are silent",
+		"",
+		`Output STRICT JSON: {"tldr": "...", "contributions": ["..."], "method": "...", "results": "...", "limitations": "..."}`,
+	}, "\n")
+}
+
+// parsePaperSummary reads the reduce output. A summary without a TL;DR is unusable.
+func parsePaperSummary(raw string) (activities.PaperSummaryRecord, bool) {
+	raw = strings.TrimSpace(raw)
+	if start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}"); start >= 0 && end > start {
+		raw = raw[start : end+1]
+	}
+	var payload struct {
+		TLDR          string   `json:"tldr"`
+		Contributions []string `json:"contributions"`
+		Method        string   `json:"method"`
+		Results       string   `json:"results"`
+		Limitations   string   `json:"limitations"`
+	}
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return activities.PaperSummaryRecord{}, false
+	}
+	clean := func(s string) string { return strings.Join(strings.Fields(s), " ") }
+	out := activities.PaperSummaryRecord{
+		TLDR:          clean(payload.TLDR),
+		Contributions: make([]string, 0, len(payload.Contributions)),
+		Method:        clean(payload.Method),
+		Results:       clean(payload.Results),
+		Limitations:   clean(payload.Limitations),
+	}
+	for _, c := range payload.Contributions {
+		if c = clean(c); c != "" {
+			out.Contributions = append(out.Contributions, c)
+		}
+	}
+	return out, out.TLDR != ""
+}
+
+// paperSummaryContext renders a stored summary as survey context for the reference key.
+func paperSummaryContext(key, title string, s activities.PaperSummaryRecord) string {
+	parts := []string{"TL;DR: " + s.TLDR}
+	if len(s.Contributions) > 0 {
+		parts = append(parts, "Contributions: "+strings.Join(s.Contributions, "; "))
+	}
+	for _, f := range []struct{ label, text string }{{"Method", s.Method}, {"Results", s.Results}, {"Limitations", s.Limitations}} {
+		if strings.TrimSpace(f.text) != "" {
+			parts = append(parts, f.label+": "+f.text)
+		}
+	}
+	return fmt.Sprintf("Source %s | Title: %s | Paper summary: %s", key, title, latexSanitizeContext(strings.Join(parts, " "), 2000))
+}
@@ -0,0 +1,595 @@
+package workflows
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"litflow/internal/activities"
+	"litflow/internal/models"
+	"litflow/internal/survey"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestBuildSurveyReferencesAcrossCorpora(t *testing.T) {
+	refs, ctxWindow := buildSurveyReferences([]activities.SearchChunk{
+		{CorpusID: "corpus-a", PaperID: "p1", Title: "Attention Is All You Need", ChunkID: "c1", Text: "self attention"},
+		{CorpusID: "corpus-b", PaperID: "p2", Title: "BERT", ChunkID: "c2", Text: "masked language modeling"},
+		{CorpusID: "corpus-a", PaperID: "p1", Title: "Attention Is All You Need", ChunkID: "c3", Text: "multi-head"},
+	}, 0)
+	require.Len(t, refs, 2)
+	require.Len(t, ctxWindow, 3)
+	require.Equal(t, "corpus-b", refs[1].CorpusID)
+	require.Equal(t, []string{"c1", "c3"}, refs[0].ChunkIDs)
+
+	bib := survey.RenderBibTeX(refs)
+	require.True(t, strings.Contains(bib, "note = {corpus \\texttt{corpus-b}}"))
+
+	single := survey.RenderBibTeX(refs[:1])
+	require.False(t, strings.Contains(single, "\\texttt{corpus-a}"))
+}
+
+func TestLatexSanitizeContextTruncatesOnRunes(t *testing.T) {
+	got := latexSanitizeContext("Résumé\n  über   naïve", 8)
+	require.True(t, utf8.ValidString(got))
+	require.Equal(t, "Résumé ü...", got)
+}
+
+func TestKGComparisonSectionCitesProvenance(t *testing.T) {
+	refs := []SurveyReference{
+		{Key: "ref1", CorpusID: "corpus-a", PaperID: "p1"},
+		{Key: "ref2", CorpusID: "corpus-a", PaperID: "p2"},
+	}
+	prov := func(paperID string) []activities.SurveyKGProvenance {
+		return []activities.SurveyKGProvenance{{PaperID: paperID, ChunkID: paperID + "-c1"}}
+	}
+	sec, added, ok := kgComparisonSection([]activities.SurveyKGFact{
+		{CorpusID: "corpus-a", EdgeType: "EVALUATED_ON", SourceType: "method", SourceName: "Transformer", TargetType: "dataset", TargetName: "WMT14", Provenance: prov("p1")},
+		{CorpusID: "corpus-a", EdgeType: "EVALUATED_ON", SourceType: "method", SourceName: "BERT", TargetType: "dataset", TargetName: "GLUE", Provenance: prov("p2")},
+		{CorpusID: "corpus-a", EdgeType: "OUTPERFORMS", SourceType: "method", SourceName: "Transformer", TargetType: "method", TargetName: "ConvS2S", Provenance: prov("p9")},
+		{CorpusID: "corpus-a", EdgeType: "EXTENDS", SourceType: "method", SourceName: "BERT", TargetType: "method", TargetName: "Transformer", Provenance: prov("p2")},
+		{CorpusID: "corpus-a", EdgeType: "EVALUATED_ON", SourceType: "method", SourceName: "ELMo", TargetType: "dataset", TargetName: "SQuAD"},
+		{CorpusID: "corpus-a", EdgeType: "EVALUATED_ON", SourceType: "dataset", SourceName: "GLUE", TargetType: "dataset", TargetName: "SQuAD", Provenance: prov("p1")},
+	}, refs)
+	require.True(t, ok)
+	require.Equal(t, kgComparisonSectionID, sec.ID)
+
+	require.Len(t, added, 1)
+	require.Equal(t, "ref3", added[0].Key)
+	require.Equal(t, "p9", added[0].PaperID)
+	require.Equal(t, []string{"p9-c1"}, added[0].ChunkIDs)
+
+	require.Len(t, sec.Tables, 1)
+	table := sec.Tables[0]
+	require.Equal(t, []string{"Method", "WMT14", "GLUE", "Outperforms"}, table.Header)
+	require.Equal(t, [][]string{
+		{"Transformer", "[ref1]", "", "ConvS2S [ref3]"},
+		{"BERT", "", "[ref2]", ""},
+	}, table.Rows)
+
+	require.Len(t, sec.Subsections, 1)
+	require.Equal(t, "BERT extends Transformer [ref2].", sec.Subsections[0].Paragraphs[0].Text)
+	require.Equal(t, []string{"ref2"}, sec.Subsections[0].Paragraphs[0].Citations)
+
+	_, _, ok = kgComparisonSection([]activities.SurveyKGFact{
+		{EdgeType: "EVALUATED_ON", SourceType: "method", SourceName: "ELMo", TargetType: "dataset", TargetName: "SQuAD"},
+	}, refs)
+	require.False(t, ok)
+}
+
+// TestKGComparisonSectionPromptExampleFacts uses the fact shapes of the extraction prompt's
+// few-shot example, where GLUE is typed as a task.
+func TestKGComparisonSectionPromptExampleFacts(t *testing.T) {
+	refs := []SurveyReference{{Key: "ref1", CorpusID: "corpus-a", PaperID: "bert"}}
+	prov := []activities.SurveyKGProvenance{{PaperID: "bert", ChunkID: "bert-c1"}}
+	sec, added, ok := kgComparisonSection([]activities.SurveyKGFact{
+		{CorpusID: "corpus-a", EdgeType: "BASED_ON", SourceType: "method", SourceName: "BERT", TargetType: "method", TargetName: "Transformer", Provenance: prov},
+		{CorpusID: "corpus-a", EdgeType: "EVALUATED_ON", SourceType: "method", SourceName: "BERT", TargetType: "task", TargetName: "GLUE", Provenance: prov},
+		{CorpusID: "corpus-a", EdgeType: "USES_DATASET", SourceType: "method", SourceName: "BERT", TargetType: "dataset", TargetName: "BooksCorpus", Provenance: prov},
+		{CorpusID: "corpus-a", EdgeType: "EVALUATED_ON", SourceType: "method", SourceName: "BERT", TargetType: "metric", TargetName: "F1", Provenance: prov},
+	}, refs)
+	require.True(t, ok)
+	require.Empty(t, added)
+	require.Len(t, sec.Tables, 1)
+	require.Equal(t, []string{"Method", "GLUE", "BooksCorpus"}, sec.Tables[0].Header)
+	require.Equal(t, [][]string{{"BERT", "[ref1]", "[ref1]"}}, sec.Tables[0].Rows)
+	require.Len(t, sec.Subsections, 1)
+}
+
+func TestSurveyBuildWorkflowSectionsShareReferences(t *testing.T) {
+	var ts testsuite.WorkflowTestSuite
+	env := ts.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SurveyBuildWorkflow)
+	env.RegisterWorkflow(SurveySectionWorkflow)
+	registerSurveyActivities(env)
+
+	vectors := map[string]float32{"diffusion models": 1, "gans": 2, "Which models are fastest?": 3}
+	hitsByVector := map[float32][]activities.SearchChunk{
+		1: {{PaperID: "p1", Title: "DDPM", ChunkID: "c1"}, {PaperID: "p2", Title: "Latent Diffusion", ChunkID: "c2"}},
+		2: {{PaperID: "p3", Title: "StyleGAN", ChunkID: "c3"}, {PaperID: "p1", Title: "DDPM", ChunkID: "c4"}},
+		3: {{PaperID: "p2", Title: "Latent Diffusion", ChunkID: "c5"}},
+	}
+	env.OnActivity("UpdateSurveyRunActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("LogLLMCallActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("UpsertTopicGraphActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("GetSurveyPaperMetaActivity", mock.Anything, mock.Anything).Return(activities.GetSurveyPaperMetaOutput{}, nil)
+	env.OnActivity("EmbedQueryActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.EmbedQueryInput) (activities.EmbedQueryOutput, error) {
+		return activities.EmbedQueryOutput{Vector: []float32{vectors[in.Text]}}, nil
+	})
+	env.OnActivity("SearchChunksActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.SearchChunksInput) (activities.SearchChunksOutput, error) {
+		return activities.SearchChunksOutput{Results: hitsByVector[in.QueryVec[0]]}, nil
+	})
+	env.OnActivity("LLMGenerateActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.LLMGenerateInput) (activities.LLMGenerateOutput, error) {
+		switch in.Operation {
+		case "survey_outline":
+			return activities.LLMGenerateOutput{Text: `{"sections": [{"title": "Diffusion Models", "query": "diffusion models"}, {"title": "GANs", "query": "gans"}]}`}, nil
+		case "survey_intro":
+			return activities.LLMGenerateOutput{Text: `{"abstract": "A short survey.", "introduction": "Two families."}`}, nil
+		case "survey_section_repair":
+			return activities.LLMGenerateOutput{Text: "Findings [ref1, ref2]."}, nil
+		}
+		return activities.LLMGenerateOutput{Text: "Findings [ref1, ref2] and [ref9]."}, nil
+	})
+	var saved activities.SaveSurveyValidationInput
+	env.OnActivity("SaveSurveyValidationActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.SaveSurveyValidationInput) error {
+		saved = in
+		return nil
+	})
+	var written activities.WriteSurveyReportInput
+	env.OnActivity("WriteSurveyReportActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.WriteSurveyReportInput) (activities.WriteSurveyReportOutput, error) {
+		written = in
+		return activities.WriteSurveyReportOutput{OutPath: "/tmp/survey.tex"}, nil
+	})
+
+	env.ExecuteWorkflow(SurveyBuildWorkflow, SurveyBuildInput{
+		SurveyRunID:  "run-1",
+		CorpusID:     "c",
+		Topics:       []string{"diffusion models", "gans"},
+		Questions:    []string{"Which models are fastest?"},
+		OutputFormat: "md",
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	doc := written.Document
+	require.Equal(t, survey.FormatMarkdown, written.OutputFormat)
+	require.Equal(t, "A short survey.", doc.Abstract[0].Text)
+	require.Len(t, doc.Sections, 4)
+	require.Equal(t, "Two families.", doc.Sections[0].Paragraphs[0].Text)
+	require.Equal(t, "Diffusion Models", doc.Sections[1].Title)
+	require.Equal(t, "Findings [ref1, ref2].", doc.Sections[1].Paragraphs[0].Text)
+	require.Equal(t, []string{"ref3", "ref1"}, doc.Sections[2].Paragraphs[0].Citations)
+	require.Equal(t, "Research Questions", doc.Sections[3].Title)
+	require.Equal(t, []string{"ref2"}, doc.Sections[3].Paragraphs[0].Citations)
+	require.Len(t, doc.References, 3)
+	require.Equal(t, "StyleGAN", doc.References[2].Title)
+	require.Empty(t, doc.Notes)
+
+	// Topic sections are repaired in one round; the questions section only has one source, so
+	// the repair still cites an unknown key and the original draft is kept.
+	require.Equal(t, "run-1", saved.SurveyRunID)
+	require.Equal(t, doc.Validation, &saved.Validation)
+	require.Len(t, saved.Validation.Sections, 3)
+	require.Equal(t, 1, saved.Validation.Sections[0].InitialIssues)
+	require.Equal(t, 1, saved.Validation.Sections[0].RepairRounds)
+	require.Empty(t, saved.Validation.Sections[0].Issues)
+	require.Equal(t, 2, saved.Validation.Sections[2].RepairRounds)
+	require.Len(t, saved.Validation.Sections[2].Issues, 1)
+	require.False(t, saved.Validation.Constraints[0].Satisfied)
+	require.Contains(t, saved.Validation.Constraints[0].Detail, "Research Questions")
+	require.True(t, saved.Validation.Constraints[1].Satisfied)
+
+	val, err := env.QueryWorkflow(QueryGetSurveyProgress)
+	require.NoError(t, err)
+	var progress SurveyProgress
+	require.NoError(t, val.Get(&progress))
+	require.Equal(t, "done", progress.Phase)
+	require.Equal(t, 3, progress.TotalTopics)
+	require.Equal(t, 3, progress.DoneTopics)
+	require.Equal(t, "done", progress.TopicStatus["Research Questions"])
+	require.Equal(t, "survey-run-1-s2", progress.SectionWorkflows["s2"])
+}
+
+func TestSurveyBuildWorkflowOutlineReview(t *testing.T) {
+	var ts testsuite.WorkflowTestSuite
+	env := ts.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SurveyBuildWorkflow)
+	env.RegisterWorkflow(SurveySectionWorkflow)
+	registerSurveyActivities(env)
+
+	var statuses []string
+	env.OnActivity("UpdateSurveyRunActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.UpdateSurveyRunInput) error {
+		statuses = append(statuses, in.Status)
+		return nil
+	})
+	env.OnActivity("LogLLMCallActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("UpsertTopicGraphActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("GetSurveyPaperMetaActivity", mock.Anything, mock.Anything).Return(activities.GetSurveyPaperMetaOutput{}, nil)
+	env.OnActivity("SaveSurveyValidationActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("EmbedQueryActivity", mock.Anything, mock.Anything).Return(activities.EmbedQueryOutput{Vector: []float32{1}}, nil)
+	env.OnActivity("SearchChunksActivity", mock.Anything, mock.Anything).Return(activities.SearchChunksOutput{Results: []activities.SearchChunk{{PaperID: "p1", Title: "DDPM", ChunkID: "c1"}}}, nil)
+	var queries []string
+	env.OnActivity("LLMGenerateActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.LLMGenerateInput) (activities.LLMGenerateOutput, error) {
+		if in.Operation == "survey_section" {
+			queries = append(queries, in.Prompt)
+		}
+		return activities.LLMGenerateOutput{Text: "Findings [ref1]."}, nil
+	})
+	var written activities.WriteSurveyReportInput
+	env.OnActivity("WriteSurveyReportActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.WriteSurveyReportInput) (activities.WriteSurveyReportOutput, error) {
+		written = in
+		return activities.WriteSurveyReportOutput{OutPath: "/tmp/survey.md"}, nil
+	})
+
+	var pending, rejected SurveyOutlineState
+	env.RegisterDelayedCallback(func() {
+		val, err := env.QueryWorkflow(QueryGetSurveyOutline)
+		require.NoError(t, err)
+		require.NoError(t, val.Get(&pending))
+		env.SignalWorkflow(SignalSurveyOutlineReview, SurveyOutlineReview{Action: SurveyOutlineEdit, Sections: []SurveyOutlineSection{{Title: " "}}})
+	}, time.Minute)
+	env.RegisterDelayedCallback(func() {
+		val, err := env.QueryWorkflow(QueryGetSurveyOutline)
+		require.NoError(t, err)
+		require.NoError(t, val.Get(&rejected))
+		env.SignalWorkflow(SignalSurveyOutlineReview, SurveyOutlineReview{Action: SurveyOutlineEdit, Sections: []SurveyOutlineSection{
+			{Title: "Score Matching", Query: "score based generative models"},
+			{Title: "Samplers"},
+		}})
+	}, 2*time.Minute)
+
+	env.ExecuteWorkflow(SurveyBuildWorkflow, SurveyBuildInput{
+		SurveyRunID:   "run-2",
+		CorpusID:      "c",
+		Topics:        []string{"diffusion models"},
+		OutputFormat:  "md",
+		ReviewOutline: true,
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Equal(t, "pending_review", pending.Status)
+	require.Equal(t, "diffusion models", pending.Outline[0].Title)
+	require.NotNil(t, pending.ReviewDeadline)
+	require.Equal(t, "pending_review", rejected.Status)
+	require.Equal(t, "section 1 has no title", rejected.LastError)
+	require.Equal(t, []string{"running", "awaiting_review", "running", "completed"}, statuses)
+
+	require.Len(t, queries, 2)
+	require.Contains(t, strings.Join(queries, "\n"), "Section scope: score based generative models")
+	require.Len(t, written.Document.Sections, 3)
+	require.Equal(t, "Score Matching", written.Document.Sections[1].Title)
+	require.Equal(t, "Samplers", written.Document.Sections[2].Title)
+
+	val, err := env.QueryWorkflow(QueryGetSurveyOutline)
+	require.NoError(t, err)
+	var final SurveyOutlineState
+	require.NoError(t, val.Get(&final))
+	require.Equal(t, "edited", final.Status)
+	require.Equal(t, "s2", final.Outline[1].SectionID)
+}
+
+func TestSurveyBuildWorkflowOutlineReviewTimeout(t *testing.T) {
+	var ts testsuite.WorkflowTestSuite
+	env := ts.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SurveyBuildWorkflow)
+	env.RegisterWorkflow(SurveySectionWorkflow)
+	registerSurveyActivities(env)
+	env.OnActivity("UpdateSurveyRunActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("LogLLMCallActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("UpsertTopicGraphActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("GetSurveyPaperMetaActivity", mock.Anything, mock.Anything).Return(activities.GetSurveyPaperMetaOutput{}, nil)
+	env.OnActivity("SaveSurveyValidationActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("EmbedQueryActivity", mock.Anything, mock.Anything).Return(activities.EmbedQueryOutput{Vector: []float32{1}}, nil)
+	env.OnActivity("SearchChunksActivity", mock.Anything, mock.Anything).Return(activities.SearchChunksOutput{Results: []activities.SearchChunk{{PaperID: "p1", Title: "DDPM", ChunkID: "c1"}}}, nil)
+	env.OnActivity("LLMGenerateActivity", mock.Anything, mock.Anything).Return(activities.LLMGenerateOutput{Text: "Findings [ref1]."}, nil)
+	env.OnActivity("WriteSurveyReportActivity", mock.Anything, mock.Anything).Return(activities.WriteSurveyReportOutput{OutPath: "/tmp/survey.md"}, nil)
+
+	env.ExecuteWorkflow(SurveyBuildWorkflow, SurveyBuildInput{
+		SurveyRunID:                 "run-3",
+		CorpusID:                    "c",
+		Topics:                      []string{"diffusion models"},
+		ReviewOutline:               true,
+		OutlineReviewTimeoutSeconds: 600,
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	val, err := env.QueryWorkflow(QueryGetSurveyOutline)
+	require.NoError(t, err)
+	var final SurveyOutlineState
+	require.NoError(t, val.Get(&final))
+	require.Equal(t, "timed_out", final.Status)
+	require.Len(t, final.Outline, 1)
+}
+
+// registerSurveyActivities registers every activity the survey workflows call so tests only
+// need to mock them.
+func registerSurveyActivities(env *testsuite.TestWorkflowEnvironment) {
+	registerActivityName(env, "UpdateSurveyRunActivity", func(context.Context, activities.UpdateSurveyRunInput) error { return nil })
+	registerActivityName(env, "LLMGenerateActivity", func(context.Context, activities.LLMGenerateInput) (activities.LLMGenerateOutput, error) {
+		return activities.LLMGenerateOutput{}, nil
+	})
+	registerActivityName(env, "LogLLMCallActivity", func(context.Context, activities.LogLLMCallInput) error { return nil })
+	registerActivityName(env, "EmbedQueryActivity", func(context.Context, activities.EmbedQueryInput) (activities.EmbedQueryOutput, error) {
+		return activities.EmbedQueryOutput{}, nil
+	})
+	registerActivityName(env, "SearchChunksActivity", func(context.Context, activities.SearchChunksInput) (activities.SearchChunksOutput, error) {
+		return activities.SearchChunksOutput{}, nil
+	})
+	registerActivityName(env, "UpsertTopicGraphActivity", func(context.Context, activities.UpsertTopicGraphInput) error { return nil })
+	registerActivityName(env, "GetSurveyPaperMetaActivity", func(context.Context, activities.GetSurveyPaperMetaInput) (activities.GetSurveyPaperMetaOutput, error) {
+		return activities.GetSurveyPaperMetaOutput{}, nil
+	})
+	registerActivityName(env, "WriteSurveyArtifactActivity", func(context.Context, activities.WriteSurveyArtifactInput) error { return nil })
+	registerActivityName(env, "SaveSurveyValidationActivity", func(context.Context, activities.SaveSurveyValidationInput) error { return nil })
+	registerActivityName(env, "WriteSurveyReportActivity", func(context.Context, activities.WriteSurveyReportInput) (activities.WriteSurveyReportOutput, error) {
+		return activities.WriteSurveyReportOutput{}, nil
+	})
+}
+
+func TestSurveySectionWorkflowCritiqueRounds(t *testing.T) {
+	var ts testsuite.WorkflowTestSuite
+	env := ts.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SurveySectionWorkflow)
+	registerSurveyActivities(env)
+	env.OnActivity("LogLLMCallActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("UpsertTopicGraphActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("GetSurveyPaperMetaActivity", mock.Anything, mock.Anything).Return(activities.GetSurveyPaperMetaOutput{}, nil)
+	env.OnActivity("EmbedQueryActivity", mock.Anything, mock.Anything).Return(activities.EmbedQueryOutput{Vector: []float32{1}}, nil)
+	env.OnActivity("SearchChunksActivity", mock.Anything, mock.Anything).Return(activities.SearchChunksOutput{Results: []activities.SearchChunk{
+		{PaperID: "p1", Title: "DDPM", ChunkID: "c1"},
+		{PaperID: "p2", Title: "Latent Diffusion", ChunkID: "c2"},
+	}}, nil)
+	critiques := 0
+	var critiquePrompt string
+	env.OnActivity("LLMGenerateActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.LLMGenerateInput) (activities.LLMGenerateOutput, error) {
+		switch in.Operation {
+		case "survey_critique":
+			critiques++
+			critiquePrompt = in.Prompt
+			if critiques == 1 {
+				return activities.LLMGenerateOutput{Text: `{"coverage": 2, "grounding": 4, "redundancy": 3, "structure": 4, "comments": ["Latent Diffusion is never discussed."]}`}, nil
+			}
+			return activities.LLMGenerateOutput{Text: "```json\n{\"coverage\": 5, \"grounding\": 4, \"redundancy\": 4, \"structure\": 4, \"comments\": []}\n```"}, nil
+		case "survey_revision":
+			require.Contains(t, in.Prompt, "- Latent Diffusion is never discussed.")
+			return activities.LLMGenerateOutput{Text: "Denoising [ref1] and latents [ref2]."}, nil
+		}
+		return activities.LLMGenerateOutput{Text: "Denoising [ref1]."}, nil
+	})
+	var artifacts []string
+	env.OnActivity("WriteSurveyArtifactActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.WriteSurveyArtifactInput) error {
+		artifacts = append(artifacts, in.Name)
+		return nil
+	})
+
+	env.ExecuteWorkflow(SurveySectionWorkflow, SurveySectionInput{
+		SurveyRunID:    "run-4",
+		CorpusID:       "c",
+		SurveyTitle:    "Diffusion",
+		Section:        SurveyOutlineSection{SectionID: "s1", Kind: SurveySectionTopic, Title: "Samplers", Query: "samplers"},
+		CritiqueRounds: 3,
+		OtherSections:  []string{"Training"},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var res SurveySectionResult
+	require.NoError(t, env.GetWorkflowResult(&res))
+
+	require.Equal(t, 2, critiques)
+	require.Contains(t, critiquePrompt, "Other sections of the survey: Training")
+	require.Len(t, res.Critiques, 2)
+	require.True(t, res.Critiques[0].Revised)
+	require.Equal(t, 2, res.Critiques[0].Coverage)
+	require.False(t, res.Critiques[1].Revised)
+	require.Equal(t, "Denoising [ref1] and latents [ref2].", res.Section.Paragraphs[0].Text)
+	require.Equal(t, []string{
+		"revisions/s1/draft-0.txt",
+		"revisions/s1/critique-1.json",
+		"revisions/s1/draft-1.txt",
+		"revisions/s1/critique-2.json",
+	}, artifacts)
+}
+
+func TestBackfillRegenerateSurveyLinksParent(t *testing.T) {
+	var ts testsuite.WorkflowTestSuite
+	env := ts.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(BackfillWorkflow)
+	env.RegisterWorkflow(SurveyBuildWorkflow)
+	env.RegisterWorkflow(SurveySectionWorkflow)
+	registerSurveyActivities(env)
+	registerActivityName(env, "GetSurveyRunActivity", func(context.Context, activities.GetSurveyRunInput) (activities.GetSurveyRunOutput, error) {
+		return activities.GetSurveyRunOutput{}, nil
+	})
+	registerActivityName(env, "CreateSurveyRunActivity", func(context.Context, activities.CreateSurveyRunInput) error { return nil })
+	registerActivityName(env, "WriteRunManifestActivity", func(context.Context, activities.WriteRunManifestInput) error { return nil })
+	registerActivityName(env, "ReadSurveyDocumentActivity", func(context.Context, activities.ReadSurveyDocumentInput) (activities.ReadSurveyDocumentOutput, error) {
+		return activities.ReadSurveyDocumentOutput{}, nil
+	})
+
+	env.OnActivity("GetSurveyRunActivity", mock.Anything, mock.Anything).Return(activities.GetSurveyRunOutput{Run: models.SurveyRun{
+		SurveyRunID:  "parent-run",
+		CorpusID:     "c",
+		Topics:       []string{"diffusion models"},
+		Questions:    []string{"Which sampler is fastest?"},
+		OutputFormat: survey.FormatHTML,
+		OutPath:      "/tmp/parent/report.html",
+	}}, nil)
+	env.OnActivity("ReadSurveyDocumentActivity", mock.Anything, activities.ReadSurveyDocumentInput{OutPath: "/tmp/parent/report.html"}).Return(activities.ReadSurveyDocumentOutput{Document: survey.Document{
+		Author:         "Survey Team",
+		RepairRounds:   1,
+		CritiqueRounds: 1,
+	}}, nil)
+	var created activities.CreateSurveyRunInput
+	env.OnActivity("CreateSurveyRunActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.CreateSurveyRunInput) error {
+		created = in
+		return nil
+	})
+	var completed activities.UpdateSurveyRunInput
+	env.OnActivity("UpdateSurveyRunActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.UpdateSurveyRunInput) error {
+		if in.Status == "completed" {
+			completed = in
+		}
+		return nil
+	})
+	env.OnActivity("WriteRunManifestActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("LogLLMCallActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("UpsertTopicGraphActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("GetSurveyPaperMetaActivity", mock.Anything, mock.Anything).Return(activities.GetSurveyPaperMetaOutput{}, nil)
+	env.OnActivity("SaveSurveyValidationActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("EmbedQueryActivity", mock.Anything, mock.Anything).Return(activities.EmbedQueryOutput{Vector: []float32{1}}, nil)
+	env.OnActivity("SearchChunksActivity", mock.Anything, mock.Anything).Return(activities.SearchChunksOutput{Results: []activities.SearchChunk{{PaperID: "p1", Title: "DDPM", ChunkID: "c1"}}}, nil)
+	env.OnActivity("LLMGenerateActivity", mock.Anything, mock.Anything).Return(activities.LLMGenerateOutput{Text: "Findings [ref1].", ProviderName: "mock", Model: "m1"}, nil)
+	var written activities.WriteSurveyReportInput
+	env.OnActivity("WriteSurveyReportActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.WriteSurveyReportInput) (activities.WriteSurveyReportOutput, error) {
+		written = in
+		return activities.WriteSurveyReportOutput{OutPath: "/tmp/report.html"}, nil
+	})
+
+	env.ExecuteWorkflow(BackfillWorkflow, BackfillInput{CorpusID: "c", Mode: "REGENERATE_SURVEY", SurveyRunID: "new-run", ParentRunID: "parent-run"})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Equal(t, "parent-run", created.ParentRunID)
+	require.Equal(t, "new-run", created.SurveyRunID)
+	require.Equal(t, []string{"diffusion models"}, created.Topics)
+	require.Equal(t, survey.FormatHTML, created.OutputFormat)
+	require.Equal(t, survey.FormatHTML, written.OutputFormat)
+	require.Equal(t, created.SurveyRunID, written.SurveyRunID)
+	require.Equal(t, "Research Questions", written.Document.Sections[len(written.Document.Sections)-1].Title)
+	require.Equal(t, []string{"mock/m1"}, completed.Models)
+	require.Equal(t, "Survey Team", written.Document.Author)
+	require.Equal(t, 1, written.Document.RepairRounds)
+	require.Equal(t, 1, written.Document.CritiqueRounds)
+}
+
+func TestBackfillUpdateSurveyIncorporatesNewPapers(t *testing.T) {
+	var ts testsuite.WorkflowTestSuite
+	env := ts.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(BackfillWorkflow)
+	env.RegisterWorkflow(SurveyUpdateWorkflow)
+	registerSurveyActivities(env)
+	registerActivityName(env, "GetSurveyRunActivity", func(context.Context, activities.GetSurveyRunInput) (activities.GetSurveyRunOutput, error) {
+		return activities.GetSurveyRunOutput{}, nil
+	})
+	registerActivityName(env, "CreateSurveyRunActivity", func(context.Context, activities.CreateSurveyRunInput) error { return nil })
+	registerActivityName(env, "WriteRunManifestActivity", func(context.Context, activities.WriteRunManifestInput) error { return nil })
+	registerActivityName(env, "ReadSurveyDocumentActivity", func(context.Context, activities.ReadSurveyDocumentInput) (activities.ReadSurveyDocumentOutput, error) {
+		return activities.ReadSurveyDocumentOutput{}, nil
+	})
+	registerActivityName(env, "ListCorpusPapersActivity", func(context.Context, activities.ListCorpusPapersInput) (activities.ListCorpusPapersOutput, error) {
+		return activities.ListCorpusPapersOutput{}, nil
+	})
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	env.OnActivity("GetSurveyRunActivity", mock.Anything, mock.Anything).Return(activities.GetSurveyRunOutput{Run: models.SurveyRun{
+		SurveyRunID:  "base-run",
+		CorpusID:     "c",
+		Status:       "completed",
+		Topics:       []string{"samplers"},
+		OutputFormat: survey.FormatMarkdown,
+		OutPath:      "/tmp/base/report.md",
+		CreatedAt:    since,
+	}}, nil)
+	env.OnActivity("ReadSurveyDocumentActivity", mock.Anything, mock.Anything).Return(activities.ReadSurveyDocumentOutput{Document: survey.Document{
+		Title: "Survey",
+		Sections: []survey.Section{
+			{ID: "intro", Title: "Introduction", Paragraphs: []survey.Paragraph{survey.NewParagraph("Overview [ref1].")}},
+			{ID: "samplers", Title: "Samplers", Query: "fast diffusion samplers", Paragraphs: []survey.Paragraph{survey.NewParagraph("DDPM samples slowly [ref1].")}},
+			{ID: "metrics", Title: "Metrics", Paragraphs: []survey.Paragraph{survey.NewParagraph("FID dominates [ref1].")}},
+		},
+		References: []survey.Reference{{Key: "ref1", PaperID: "p0", Title: "DDPM"}},
+		Validation: &survey.Validation{},
+		CorpusIDs:  []string{"c", "c2"},
+	}}, nil)
+	env.OnActivity("ListCorpusPapersActivity", mock.Anything, mock.Anything).Return(activities.ListCorpusPapersOutput{Papers: []activities.CorpusPaper{
+		{PaperID: "p0", Status: "processed", CreatedAt: since.Add(-time.Hour)},
+		{PaperID: "p1", Status: "processed", CreatedAt: since.Add(time.Hour)},
+		{PaperID: "p2", Status: "failed", CreatedAt: since.Add(time.Hour)},
+	}}, nil)
+	var created activities.CreateSurveyRunInput
+	env.OnActivity("CreateSurveyRunActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.CreateSurveyRunInput) error {
+		created = in
+		return nil
+	})
+	env.OnActivity("UpdateSurveyRunActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("WriteRunManifestActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("LogLLMCallActivity", mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("GetSurveyPaperMetaActivity", mock.Anything, mock.Anything).Return(activities.GetSurveyPaperMetaOutput{}, nil)
+	var embedded []string
+	env.OnActivity("EmbedQueryActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.EmbedQueryInput) (activities.EmbedQueryOutput, error) {
+		embedded = append(embedded, in.Text)
+		return activities.EmbedQueryOutput{Vector: []float32{1}}, nil
+	})
+	var searched, searchedCorpora []string
+	env.OnActivity("SearchChunksActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.SearchChunksInput) (activities.SearchChunksOutput, error) {
+		searched, searchedCorpora = in.PaperIDs, in.CorpusIDs
+		return activities.SearchChunksOutput{Results: []activities.SearchChunk{{PaperID: "p1", Title: "DPM-Solver", ChunkID: "c9", Text: "ten steps"}}}, nil
+	})
+	env.OnActivity("LLMGenerateActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.LLMGenerateInput) (activities.LLMGenerateOutput, error) {
+		text := "FID dominates [ref1]."
+		if strings.Contains(in.Prompt, "Section title: Samplers") {
+			text = "DDPM samples slowly [ref1]. Solvers need ten steps [ref2]. Unknown [ref7]."
+		}
+		return activities.LLMGenerateOutput{Text: text, ProviderName: "mock", Model: "m1"}, nil
+	})
+	var written activities.WriteSurveyReportInput
+	env.OnActivity("WriteSurveyReportActivity", mock.Anything, mock.Anything).Return(func(_ context.Context, in activities.WriteSurveyReportInput) (activities.WriteSurveyReportOutput, error) {
+		written = in
+		return activities.WriteSurveyReportOutput{OutPath: "/tmp/update/report.md"}, nil
+	})
+
+	env.ExecuteWorkflow(BackfillWorkflow, BackfillInput{CorpusID: "c", Mode: "UPDATE_SURVEY", SurveyRunID: "base-run"})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Equal(t, "base-run", created.ParentRunID)
+	require.Equal(t, []string{"samplers"}, created.Topics)
+	require.Equal(t, []string{"p1"}, searched)
+	require.Equal(t, []string{"c", "c2"}, searchedCorpora)
+	require.Equal(t, []string{"fast diffusion samplers", "Metrics"}, embedded)
+	doc := written.Document
+	require.Equal(t, "fast diffusion samplers", doc.Sections[1].Query)
+	require.Equal(t, created.SurveyRunID, written.SurveyRunID)
+	require.Equal(t, survey.FormatMarkdown, written.OutputFormat)
+	require.Nil(t, doc.Validation)
+	require.Len(t, doc.References, 2)
+	require.Equal(t, "ref2", doc.References[1].Key)
+	require.Equal(t, "p1", doc.References[1].PaperID)
+	require.Equal(t, []string{"ref1", "ref2"}, doc.Sections[1].Paragraphs[0].Citations)
+	require.Equal(t, "FID dominates [ref1].", doc.Sections[2].Paragraphs[0].Text)
+	require.NotNil(t, doc.Changelog)
+	require.Equal(t, "base-run", doc.Changelog.BaseRunID)
+	require.Equal(t, 1, doc.Changelog.NewPapers)
+	require.Equal(t, []survey.ChangelogEntry{{SectionID: "samplers", Title: "Samplers", Sources: []string{"ref2"}}}, doc.Changelog.Entries)
+}
+
+func TestBackfillSurveyModesRejectOtherCorpus(t *testing.T) {
+	for _, in := range []BackfillInput{
+		{CorpusID: "c", Mode: "REGENERATE_SURVEY", ParentRunID: "base-run"},
+		{CorpusID: "c", Mode: "UPDATE_SURVEY", SurveyRunID: "base-run"},
+	} {
+		var ts testsuite.WorkflowTestSuite
+		env := ts.NewTestWorkflowEnvironment()
+		env.RegisterWorkflow(BackfillWorkflow)
+		registerActivityName(env, "GetSurveyRunActivity", func(context.Context, activities.GetSurveyRunInput) (activities.GetSurveyRunOutput, error) {
+			return activities.GetSurveyRunOutput{}, nil
+		})
+		env.OnActivity("GetSurveyRunActivity", mock.Anything, mock.Anything).Return(activities.GetSurveyRunOutput{Run: models.SurveyRun{
+			SurveyRunID: "base-run",
+			CorpusID:    "other",
+			Status:      "completed",
+			OutPath:     "/tmp/base/report.md",
+		}}, nil)
+
+		env.ExecuteWorkflow(BackfillWorkflow, in)
+		require.True(t, env.IsWorkflowCompleted())
+		require.ErrorContains(t, env.GetWorkflowError(), "belongs to corpus other", in.Mode)
+	}
+}
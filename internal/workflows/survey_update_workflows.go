@@ -0,0 +1,285 @@
+package workflows
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"litflow/internal/activities"
+	"litflow/internal/survey"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const defaultSurveyUpdateTopK = 8
+
+// SurveyUpdateWorkflow refreshes a completed survey with papers ingested after it was run.
+// Each section retrieves from the new papers only and, when any is relevant, is redrafted to
+// work them in; untouched sections are copied. The result is written as a new run whose
+// changelog lists the sources each revised section incorporated.
+func SurveyUpdateWorkflow(ctx workflow.Context, input SurveyUpdateInput) (string, error) {
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    2 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    30 * time.Second,
+			MaximumAttempts:    2,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+	fail := func(err error) (string, error) {
+		_ = workflow.ExecuteActivity(ctx, "UpdateSurveyRunActivity", activities.UpdateSurveyRunInput{SurveyRunID: input.SurveyRunID, Status: "failed"}).Get(ctx, nil)
+		return "", err
+	}
+	_ = workflow.ExecuteActivity(ctx, "UpdateSurveyRunActivity", activities.UpdateSurveyRunInput{SurveyRunID: input.SurveyRunID, Status: "running"}).Get(ctx, nil)
+
+	var base activities.ReadSurveyDocumentOutput
+	if err := workflow.ExecuteActivity(ctx, "ReadSurveyDocumentActivity", activities.ReadSurveyDocumentInput{OutPath: input.BaseOutPath}).Get(ctx, &base); err != nil {
+		return fail(err)
+	}
+	doc := base.Document
+	corpusIDs := input.CorpusIDs
+	if len(corpusIDs) == 0 {
+		corpusIDs = doc.CorpusIDs
+	}
+	scope := corpusIDs
+	if len(scope) == 0 {
+		scope = []string{input.CorpusID}
+	}
+	var newPaperIDs []string
+	for _, corpusID := range scope {
+		var papers activities.ListCorpusPapersOutput
+		if err := workflow.ExecuteActivity(ctx, "ListCorpusPapersActivity", activities.ListCorpusPapersInput{CorpusID: corpusID}).Get(ctx, &papers); err != nil {
+			return fail(err)
+		}
+		for _, p := range papers.Papers {
+			if p.Status == "processed" && p.CreatedAt.After(input.Since) {
+				newPaperIDs = append(newPaperIDs, p.PaperID)
+			}
+		}
+	}
+	newPaperIDs = dedupeStrings(newPaperIDs)
+
+	changelog := survey.Changelog{BaseRunID: input.BaseRunID, Since: input.Since, NewPapers: len(newPaperIDs), Entries: []survey.ChangelogEntry{}}
+	embedProviders := defaultCount(input.EmbedProviders)
+	llmProviders := defaultCount(input.LLMProviders)
+	cooldown := durationOrDefault(input.CooldownSeconds, 900)
+	embedState := newProviderState()
+	llmState := newProviderState()
+	topK := input.RetrievalTopK
+	if topK <= 0 {
+		topK = defaultSurveyUpdateTopK
+	}
+
+	// New sources get provisional keys after the existing reference list; only the ones a
+	// revised section actually cites are kept and renumbered at the end.
+	var pending []SurveyReference
+	provisional := map[string]string{}
+	var usedModels []string
+	for i, sec := range doc.Sections {
+		if len(newPaperIDs) == 0 {
+			break
+		}
+		// The introduction frames the whole survey and the KG comparison is not drafted text.
+		if sec.ID == "intro" || sec.ID == kgComparisonSectionID {
+			continue
+		}
+		// Surveys written before section queries were stored fall back to the title.
+		query := sec.Query
+		if strings.TrimSpace(query) == "" {
+			query = sec.Title
+		}
+		eq, err := callEmbedQueryWithFailover(ctx, &embedState, embedProviders, cooldown, activities.EmbedQueryInput{
+			Operation: "survey_update_embed",
+			Text:      query,
+		}, nil)
+		if err != nil {
+			continue
+		}
+		var hits activities.SearchChunksOutput
+		if err := workflow.ExecuteActivity(ctx, "SearchChunksActivity", activities.SearchChunksInput{
+			CorpusID:         input.CorpusID,
+			CorpusIDs:        corpusIDs,
+			QueryVec:         eq.Vector,
+			TopK:             topK,
+			EmbeddingVersion: defaultEmbedVersion(input.EmbedVersion),
+			PaperIDs:         newPaperIDs,
+		}).Get(ctx, &hits); err != nil || len(hits.Results) == 0 {
+			continue
+		}
+		localRefs, contextWindow := buildSurveyReferences(hits.Results, 0)
+		enrichSurveyReferences(ctx, input.CorpusID, corpusIDs, localRefs, false)
+		mapping := make(map[string]string, len(localRefs))
+		newRefs := make([]SurveyReference, 0, len(localRefs))
+		for _, ref := range localRefs {
+			key, ok := provisional[ref.PaperID]
+			if !ok {
+				key = fmt.Sprintf("ref%d", len(doc.References)+len(pending)+1)
+				provisional[ref.PaperID] = key
+				global := ref
+				global.Key = key
+				pending = append(pending, global)
+			}
+			mapping[ref.Key] = key
+			ref.Key = key
+			newRefs = append(newRefs, ref)
+		}
+		for j, line := range contextWindow {
+			if rest, ok := strings.CutPrefix(line, "Source "); ok {
+				if local, tail, ok := strings.Cut(rest, " | "); ok {
+					contextWindow[j] = "Source " + mapping[local] + " | " + tail
+				}
+			}
+		}
+
+		allowed := map[string]string{}
+		var cited []SurveyReference
+		for _, k := range sectionCitations(sec) {
+			for _, ref := range doc.References {
+				if ref.Key == k {
+					allowed[k] = k
+					cited = append(cited, ref)
+				}
+			}
+		}
+		isNew := map[string]bool{}
+		for _, ref := range newRefs {
+			allowed[ref.Key] = ref.Key
+			isNew[ref.Key] = true
+		}
+		out, _, err := callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, activities.LLMGenerateInput{
+			Operation: "survey_section_update",
+			CorpusID:  input.CorpusID,
+			Prompt:    buildSectionUpdatePrompt(doc.Title, sec, cited, newRefs),
+			Context:   contextWindow,
+		}, nil)
+		if err != nil {
+			continue
+		}
+		usedModels = append(usedModels, modelLabel(out))
+		candidate := survey.RemapCitations(survey.ParseSection(sec.Title, out.Text), allowed)
+		if len(candidate.Paragraphs) == 0 && len(candidate.Subsections) == 0 {
+			continue
+		}
+		var incorporated []string
+		for _, k := range sectionCitations(candidate) {
+			if isNew[k] {
+				incorporated = append(incorporated, k)
+			}
+		}
+		// A section that cites none of the new sources was judged unaffected.
+		if len(incorporated) == 0 {
+			continue
+		}
+		candidate.ID = sec.ID
+		candidate.Query = sec.Query
+		doc.Sections[i] = candidate
+		changelog.Entries = append(changelog.Entries, survey.ChangelogEntry{SectionID: sec.ID, Title: sec.Title, Sources: incorporated})
+	}
+
+	if len(changelog.Entries) > 0 {
+		final := make(map[string]string, len(doc.References)+len(pending))
+		for _, ref := range doc.References {
+			final[ref.Key] = ref.Key
+		}
+		used := map[string]bool{}
+		for _, e := range changelog.Entries {
+			for _, k := range e.Sources {
+				used[k] = true
+			}
+		}
+		for _, ref := range pending {
+			if !used[ref.Key] {
+				continue
+			}
+			key := fmt.Sprintf("ref%d", len(doc.References)+1)
+			final[ref.Key] = key
+			ref.Key = key
+			doc.References = append(doc.References, ref)
+		}
+		for i, e := range changelog.Entries {
+			for j, k := range e.Sources {
+				changelog.Entries[i].Sources[j] = final[k]
+			}
+			for k, sec := range doc.Sections {
+				if sec.ID == e.SectionID {
+					doc.Sections[k] = survey.RemapCitations(sec, final)
+				}
+			}
+		}
+	}
+	doc.Changelog = &changelog
+	// The base run's citation checks describe the old text, so they are not carried over.
+	doc.Validation = nil
+
+	format, ok := survey.NormalizeFormat(input.OutputFormat)
+	if !ok {
+		format = survey.FormatLaTeX
+	}
+	var reportOut activities.WriteSurveyReportOutput
+	if err := workflow.ExecuteActivity(ctx, "WriteSurveyReportActivity", activities.WriteSurveyReportInput{
+		CorpusID:     input.CorpusID,
+		SurveyRunID:  input.SurveyRunID,
+		Document:     doc,
+		OutputFormat: format,
+	}).Get(ctx, &reportOut); err != nil {
+		return fail(err)
+	}
+	_ = workflow.ExecuteActivity(ctx, "UpdateSurveyRunActivity", activities.UpdateSurveyRunInput{SurveyRunID: input.SurveyRunID, Status: "completed", OutPath: reportOut.OutPath, Models: dedupeStrings(usedModels)}).Get(ctx, nil)
+	return reportOut.OutPath, nil
+}
+
+// buildSectionUpdatePrompt asks for a section revised to work in newly ingested sources while
+// keeping its existing claims and citations.
+func buildSectionUpdatePrompt(surveyTitle string, sec survey.Section, cited, newRefs []SurveyReference) string {
+	lines := []string{
+		"Update one section of a citation-grounded literature survey titled: " + surveyTitle,
+		"Section title: " + sec.Title,
+		"",
+		"Current section text:",
+		survey.PlainText(sec),
+		"",
+		"Newly ingested sources (their evidence is given as context):",
+		referenceKeyLines(newRefs),
+		"",
+		"Output requirements:",
+		"1. Return the full revised section as plain text paragraphs separated by blank lines; keep \"### Subsection title\" lines and add one only if a new theme needs it.",
+		"2. Keep every existing claim and its citation keys unless a new source contradicts it; then say so and cite both.",
+		"3. Add claims from the new sources where they extend, confirm or contradict the section, citing their keys inline like [ref1].",
+		"4. If none of the new sources is relevant, return the current text unchanged.",
+		"5. Do not cite any key outside the existing and new sources listed here.",
+	}
+	if len(cited) > 0 {
+		lines = append(lines, "", "Existing sources cited by the section:", referenceKeyLines(cited))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sectionCitations lists the keys a section cites, subsections included, in order of first use.
+func sectionCitations(sec survey.Section) []string {
+	var keys []string
+	for _, p := range sec.Paragraphs {
+		keys = append(keys, p.Citations...)
+	}
+	for _, sub := range sec.Subsections {
+		keys = append(keys, sectionCitations(sub)...)
+	}
+	return dedupeStrings(keys)
+}
+
+// createSurveyRun registers a new survey run for a backfill and returns its ID; unless the
+// caller names the run, the ID is drawn once and replayed from history.
+func createSurveyRun(ctx workflow.Context, in activities.CreateSurveyRunInput) (string, error) {
+	if in.SurveyRunID == "" {
+		if err := workflow.SideEffect(ctx, func(workflow.Context) any { return uuid.NewString() }).Get(&in.SurveyRunID); err != nil {
+			return "", err
+		}
+	}
+	if err := workflow.ExecuteActivity(ctx, "CreateSurveyRunActivity", in).Get(ctx, nil); err != nil {
+		return "", err
+	}
+	return in.SurveyRunID, nil
+}
@@ -0,0 +1,1173 @@
+package workflows
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"litflow/internal/activities"
+	"litflow/internal/providers"
+	"litflow/internal/retrieval"
+	"litflow/internal/survey"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	maxSurveySections               = 8
+	defaultSurveySectionConcurrency = 3
+	defaultSurveyRepairRounds       = 2
+	defaultOutlineReviewSeconds     = 24 * 60 * 60
+	maxSurveyKGFacts                = 200
+	maxKGTableMethods               = 12
+	maxKGTableDatasets              = 6
+	maxKGLineageMethods             = 10
+	// kgComparisonSectionID marks the section assembled from KG facts rather than drafted.
+	kgComparisonSectionID = "kg-comparison"
+)
+
+const (
+	QueryGetSurveyOutline     = "GetSurveyOutline"
+	SignalSurveyOutlineReview = "SurveyOutlineReview"
+)
+
+// SurveyBuildWorkflow plans an outline for the requested topics, drafts every section (and a
+// questions section when questions were asked) in its own SurveySectionWorkflow child with
+// bounded concurrency, and assembles one document with a shared reference list.
+func SurveyBuildWorkflow(ctx workflow.Context, input SurveyBuildInput) (string, error) {
+	title, topics := surveyTitleAndTopics(input)
+	if title == "" {
+		return "", fmt.Errorf("survey prompt/topic is required")
+	}
+	progress := SurveyProgress{
+		SurveyRunID:      input.SurveyRunID,
+		CorpusID:         input.CorpusID,
+		CorpusIDs:        input.CorpusIDs,
+		TopicStatus:      map[string]string{},
+		SectionWorkflows: map[string]string{},
+		Phase:            "outline",
+	}
+	if err := workflow.SetQueryHandler(ctx, QueryGetSurveyProgress, func() (SurveyProgress, error) { return progress, nil }); err != nil {
+		return "", err
+	}
+	outlineState := SurveyOutlineState{SurveyRunID: input.SurveyRunID, Status: "planning"}
+	if err := workflow.SetQueryHandler(ctx, QueryGetSurveyOutline, func() (SurveyOutlineState, error) { return outlineState, nil }); err != nil {
+		return "", err
+	}
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    2 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    30 * time.Second,
+			MaximumAttempts:    2,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+	rewriteMode, err := retrieval.NormalizeRewriteMode(input.QueryRewrite)
+	if err != nil {
+		return "", err
+	}
+	progress.QueryRewrite = rewriteMode
+	_ = workflow.ExecuteActivity(ctx, "UpdateSurveyRunActivity", activities.UpdateSurveyRunInput{SurveyRunID: input.SurveyRunID, Status: "running"}).Get(ctx, nil)
+
+	llmProviders := defaultCount(input.LLMProviders)
+	cooldown := durationOrDefault(input.CooldownSeconds, 900)
+	llmState := newProviderState()
+
+	var usedModels []string
+	outline := fallbackSurveyOutline(topics)
+	if out, _, err := callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, activities.LLMGenerateInput{
+		Operation: "survey_outline",
+		CorpusID:  input.CorpusID,
+		Prompt:    buildSurveyOutlinePrompt(title, topics, input.Questions),
+	}, nil); err == nil {
+		usedModels = append(usedModels, modelLabel(out))
+		if sections, ok := parseSurveyOutline(out.Text, len(topics)); ok {
+			outline = sections
+		}
+	}
+	if questions := dedupeStrings(trimAll(input.Questions)); len(questions) > 0 {
+		outline = append(outline, SurveyOutlineSection{Kind: SurveySectionQuestions, Title: "Research Questions", Query: title, Questions: questions})
+	}
+	for i := range outline {
+		outline[i].SectionID = fmt.Sprintf("s%d", i+1)
+	}
+	progress.Outline = outline
+	outlineState.Outline = outline
+	outlineState.Status = "not_required"
+	if input.ReviewOutline {
+		progress.Phase = "review"
+		_ = workflow.ExecuteActivity(ctx, "UpdateSurveyRunActivity", activities.UpdateSurveyRunInput{SurveyRunID: input.SurveyRunID, Status: "awaiting_review"}).Get(ctx, nil)
+		outline = awaitOutlineReview(ctx, &outlineState, durationOrDefault(input.OutlineReviewTimeoutSeconds, defaultOutlineReviewSeconds))
+		progress.Outline = outline
+		_ = workflow.ExecuteActivity(ctx, "UpdateSurveyRunActivity", activities.UpdateSurveyRunInput{SurveyRunID: input.SurveyRunID, Status: "running"}).Get(ctx, nil)
+	}
+	for _, section := range outline {
+		progress.TopicStatus[sectionLabel(section)] = "pending"
+	}
+	progress.TotalTopics = len(outline)
+
+	progress.Phase = "sections"
+	results := make([]SurveySectionResult, len(outline))
+	maxChildren := input.MaxConcurrentSections
+	if maxChildren <= 0 {
+		maxChildren = defaultSurveySectionConcurrency
+	}
+	for i := 0; i < len(outline); i += maxChildren {
+		end := min(i+maxChildren, len(outline))
+		futures := make([]workflow.ChildWorkflowFuture, 0, end-i)
+		for _, section := range outline[i:end] {
+			workflowID := "survey-" + sanitizeID(input.SurveyRunID) + "-" + section.SectionID
+			childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{WorkflowID: workflowID})
+			futures = append(futures, workflow.ExecuteChildWorkflow(childCtx, SurveySectionWorkflow, SurveySectionInput{
+				SurveyRunID:       input.SurveyRunID,
+				CorpusID:          input.CorpusID,
+				CorpusIDs:         input.CorpusIDs,
+				SurveyTitle:       title,
+				Section:           section,
+				RetrievalTopK:     input.RetrievalTopK,
+				EmbedProviders:    defaultCount(input.EmbedProviders),
+				LLMProviders:      llmProviders,
+				LLMProviderRefs:   input.LLMProviderRefs,
+				CooldownSeconds:   input.CooldownSeconds,
+				EmbedVersion:      input.EmbedVersion,
+				QueryRewrite:      rewriteMode,
+				RewriteCount:      input.RewriteCount,
+				ContextWindow:     input.ContextWindow,
+				ContextBudget:     input.ContextBudget,
+				UsePaperSummaries: input.UsePaperSummaries,
+				RepairRounds:      input.RepairRounds,
+				CritiqueRounds:    input.CritiqueRounds,
+				OtherSections:     otherSectionTitles(outline, section.SectionID),
+			}))
+			progress.SectionWorkflows[section.SectionID] = workflowID
+			progress.TopicStatus[sectionLabel(section)] = "drafting"
+		}
+		for k, f := range futures {
+			section := outline[i+k]
+			var res SurveySectionResult
+			if err := f.Get(ctx, &res); err != nil {
+				res = SurveySectionResult{SectionID: section.SectionID, Kind: section.Kind, Title: section.Title, Status: "failed", Error: err.Error()}
+			}
+			results[i+k] = res
+			progress.TopicStatus[sectionLabel(section)] = res.Status
+			progress.DoneTopics++
+			progress.RewrittenQueries = append(progress.RewrittenQueries, res.RewrittenQueries...)
+		}
+	}
+
+	progress.Phase = "assembling"
+	refs, sections := mergeSurveySections(results)
+	if len(sections) == 0 {
+		_ = workflow.ExecuteActivity(ctx, "UpdateSurveyRunActivity", activities.UpdateSurveyRunInput{SurveyRunID: input.SurveyRunID, Status: "failed"}).Get(ctx, nil)
+		return "", fmt.Errorf("all survey sections failed")
+	}
+	queries := make(map[string]string, len(outline))
+	for _, section := range outline {
+		queries[section.SectionID] = section.Query
+	}
+	for i := range sections {
+		sections[i].Query = queries[sections[i].ID]
+	}
+	doc := survey.Document{
+		Title:          title,
+		Author:         input.Author,
+		References:     refs,
+		TemplateID:     input.TemplateID,
+		CitationStyle:  input.CitationStyle,
+		CorpusIDs:      input.CorpusIDs,
+		RepairRounds:   input.RepairRounds,
+		CritiqueRounds: input.CritiqueRounds,
+		KGTables:       input.KGTables,
+	}
+	intro := survey.Section{ID: "intro", Title: "Introduction", Paragraphs: fallbackSurveyIntro(title, outline)}
+	if out, _, err := callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, activities.LLMGenerateInput{
+		Operation: "survey_intro",
+		CorpusID:  input.CorpusID,
+		Prompt:    buildSurveyIntroPrompt(title, outline),
+		Context:   sectionDigests(sections),
+	}, nil); err == nil {
+		usedModels = append(usedModels, modelLabel(out))
+		if abstract, paragraphs, ok := parseSurveyIntro(out.Text); ok {
+			doc.Abstract, intro.Paragraphs = abstract, paragraphs
+		}
+	}
+	doc.Sections = append([]survey.Section{intro}, sections...)
+	if input.KGTables {
+		appendKGComparison(ctx, input, &doc)
+	}
+	for _, res := range results {
+		usedModels = append(usedModels, res.Models...)
+		if res.Status == "failed" || res.GenerationFailed {
+			doc.Notes = append(doc.Notes, fmt.Sprintf("Model generation for the section %q encountered an issue; review and expand it manually.", res.Title))
+		}
+	}
+	validations := make([]survey.SectionValidation, 0, len(results))
+	for _, res := range results {
+		if res.Validation.SectionID != "" {
+			validations = append(validations, res.Validation)
+		}
+	}
+	validation := survey.Summarize(validations)
+	doc.Validation = &validation
+	_ = workflow.ExecuteActivity(ctx, "SaveSurveyValidationActivity", activities.SaveSurveyValidationInput{SurveyRunID: input.SurveyRunID, Validation: validation}).Get(ctx, nil)
+	format, ok := survey.NormalizeFormat(input.OutputFormat)
+	if !ok {
+		format = survey.FormatLaTeX
+	}
+
+	var reportOut activities.WriteSurveyReportOutput
+	if err := workflow.ExecuteActivity(ctx, "WriteSurveyReportActivity", activities.WriteSurveyReportInput{
+		CorpusID:     input.CorpusID,
+		SurveyRunID:  input.SurveyRunID,
+		Document:     doc,
+		OutputFormat: format,
+	}).Get(ctx, &reportOut); err != nil {
+		return "", err
+	}
+	progress.Phase = "done"
+	_ = workflow.ExecuteActivity(ctx, "UpdateSurveyRunActivity", activities.UpdateSurveyRunInput{SurveyRunID: input.SurveyRunID, Status: "completed", OutPath: reportOut.OutPath, Models: dedupeStrings(usedModels)}).Get(ctx, nil)
+	return reportOut.OutPath, nil
+}
+
+// SurveySectionWorkflow retrieves evidence for one outline section and drafts it. Topic
+// sections retrieve with the section query (plus rewrites); the questions section retrieves
+// for every question and answers each in a subsection. Failures are reported in the result
+// so the parent can still assemble the other sections.
+func SurveySectionWorkflow(ctx workflow.Context, input SurveySectionInput) (SurveySectionResult, error) {
+	section := input.Section
+	res := SurveySectionResult{SectionID: section.SectionID, Kind: section.Kind, Title: section.Title, References: []SurveyReference{}}
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    2 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    30 * time.Second,
+			MaximumAttempts:    2,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+	embedProviders := defaultCount(input.EmbedProviders)
+	llmProviders := defaultCount(input.LLMProviders)
+	cooldown := durationOrDefault(input.CooldownSeconds, 900)
+	embedState := newProviderState()
+	llmState := newProviderState()
+	topK := input.RetrievalTopK
+	if topK <= 0 {
+		topK = 14
+	}
+
+	var queries []retrieval.RewrittenQuery
+	if section.Kind == SurveySectionQuestions {
+		for _, q := range section.Questions {
+			queries = append(queries, retrieval.RewrittenQuery{Kind: retrieval.QueryKindOriginal, Text: q})
+		}
+	} else {
+		queries = rewriteSurveyQueries(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, input.CorpusID, section.Query, input.QueryRewrite, input.RewriteCount)
+		res.RewrittenQueries = queries[1:]
+	}
+	if len(queries) == 0 {
+		res.Status, res.Error = "failed", "section has no retrieval query"
+		return res, nil
+	}
+	resultLists := make([][]activities.SearchChunk, 0, len(queries))
+	for i, q := range queries {
+		eq, err := callEmbedQueryWithFailover(ctx, &embedState, embedProviders, cooldown, activities.EmbedQueryInput{
+			Operation: "survey_topic_embed",
+			Text:      q.Text,
+		}, nil)
+		if err != nil {
+			if i > 0 {
+				continue
+			}
+			res.Status, res.Error = "failed", err.Error()
+			return res, nil
+		}
+		var hits activities.SearchChunksOutput
+		if err := workflow.ExecuteActivity(ctx, "SearchChunksActivity", activities.SearchChunksInput{
+			CorpusID:         input.CorpusID,
+			CorpusIDs:        input.CorpusIDs,
+			QueryVec:         eq.Vector,
+			TopK:             topK,
+			EmbeddingVersion: defaultEmbedVersion(input.EmbedVersion),
+		}).Get(ctx, &hits); err != nil {
+			if i > 0 {
+				continue
+			}
+			res.Status, res.Error = "failed", err.Error()
+			return res, nil
+		}
+		resultLists = append(resultLists, hits.Results)
+	}
+	retrieved := resultLists[0]
+	if len(resultLists) > 1 {
+		retrieved = retrieval.FuseRanked(resultLists, func(c activities.SearchChunk) string { return c.ChunkID }, topK)
+	}
+	if len(retrieved) == 0 {
+		res.Status = "empty"
+		res.Section = survey.Section{ID: section.SectionID, Title: section.Title, Paragraphs: []survey.Paragraph{survey.NewParagraph("No evidence for this section was retrieved from the corpus.")}}
+		return res, nil
+	}
+	contextChars := 1400
+	if input.ContextBudget > 0 {
+		var expanded activities.ExpandChunkContextOutput
+		if err := workflow.ExecuteActivity(ctx, "ExpandChunkContextActivity", activities.ExpandChunkContextInput{
+			CorpusID:    input.CorpusID,
+			CorpusIDs:   input.CorpusIDs,
+			Hits:        retrieved,
+			Window:      input.ContextWindow,
+			TokenBudget: input.ContextBudget,
+		}).Get(ctx, &expanded); err == nil && len(expanded.Results) > 0 {
+			retrieved = expanded.Results
+			contextChars = 4 * input.ContextBudget
+		}
+	}
+	for _, c := range retrieved {
+		_ = workflow.ExecuteActivity(ctx, "UpsertTopicGraphActivity", activities.UpsertTopicGraphInput{
+			CorpusID: input.CorpusID,
+			Topic:    section.Query,
+			PaperID:  c.PaperID,
+			Title:    c.Title,
+			ChunkID:  c.ChunkID,
+			Score:    c.Score,
+		}).Get(ctx, nil)
+	}
+
+	refs, contextWindow := buildSurveyReferences(retrieved, contextChars)
+	contextWindow = append(contextWindow, enrichSurveyReferences(ctx, input.CorpusID, input.CorpusIDs, refs, input.UsePaperSummaries)...)
+	res.References = refs
+
+	sectionInput := activities.LLMGenerateInput{
+		Operation: "survey_section",
+		CorpusID:  input.CorpusID,
+		Prompt:    buildSectionPrompt(input.SurveyTitle, section, refs),
+		Context:   contextWindow,
+	}
+	if section.Kind == SurveySectionQuestions {
+		sectionInput.Operation = "survey_questions"
+		sectionInput.Prompt = buildQuestionsPrompt(input.SurveyTitle, section, refs)
+	}
+	out, errType, err := callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, sectionInput, nil)
+	if err != nil && errType == string(providers.ErrorContext) {
+		if len(sectionInput.Context) > 5 {
+			sectionInput.Context = sectionInput.Context[:5]
+		}
+		out, _, err = callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, sectionInput, nil)
+	}
+	if err == nil {
+		res.Models = append(res.Models, modelLabel(out))
+	}
+	res.Section = survey.ParseSection(section.Title, out.Text)
+	res.Section.ID = section.SectionID
+	if err != nil || (len(res.Section.Paragraphs) == 0 && len(res.Section.Subsections) == 0) {
+		res.GenerationFailed = true
+		res.Section.Paragraphs = []survey.Paragraph{survey.NewParagraph("This section requires manual completion; the retrieved evidence includes " + inlineRefMentions(refs) + ".")}
+		res.Section.Subsections = nil
+	}
+	res.Status = "done"
+	if res.GenerationFailed {
+		return res, nil
+	}
+
+	if input.CritiqueRounds > 0 {
+		writeArtifact := func(name, content string) {
+			_ = workflow.ExecuteActivity(ctx, "WriteSurveyArtifactActivity", activities.WriteSurveyArtifactInput{
+				CorpusID:    input.CorpusID,
+				SurveyRunID: input.SurveyRunID,
+				Name:        survey.RevisionsDir + "/" + section.SectionID + "/" + name,
+				Content:     content,
+			}).Get(ctx, nil)
+		}
+		writeArtifact("draft-0.txt", out.Text)
+		for round := 1; round <= input.CritiqueRounds; round++ {
+			critiqueInput := sectionInput
+			critiqueInput.Operation = "survey_critique"
+			critiqueInput.Prompt = buildSectionCritiquePrompt(input.SurveyTitle, section, refs, input.OtherSections, out.Text)
+			reviewed, _, err := callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, critiqueInput, nil)
+			if err != nil {
+				break
+			}
+			res.Models = append(res.Models, modelLabel(reviewed))
+			critique, ok := parseSurveyCritique(reviewed.Text)
+			if !ok {
+				break
+			}
+			critique.Round = round
+			// A draft that scores well on every dimension is not revised further.
+			if !critique.passes() {
+				reviseInput := sectionInput
+				reviseInput.Operation = "survey_revision"
+				reviseInput.Prompt = buildSectionRevisionPrompt(sectionInput.Prompt, out.Text, critique)
+				if revised, _, err := callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, reviseInput, nil); err == nil {
+					res.Models = append(res.Models, modelLabel(revised))
+					if candidate := survey.ParseSection(section.Title, revised.Text); len(candidate.Paragraphs) > 0 || len(candidate.Subsections) > 0 {
+						out = revised
+						res.Section = candidate
+						res.Section.ID = section.SectionID
+						critique.Revised = true
+					}
+				}
+			}
+			res.Critiques = append(res.Critiques, critique)
+			if payload, err := json.MarshalIndent(critique, "", "  "); err == nil {
+				writeArtifact(fmt.Sprintf("critique-%d.json", round), string(payload))
+			}
+			if !critique.Revised {
+				break
+			}
+			writeArtifact(fmt.Sprintf("draft-%d.txt", round), out.Text)
+		}
+	}
+
+	allowed := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		allowed = append(allowed, ref.Key)
+	}
+	issues := survey.ValidateSection(out.Text, res.Section, allowed)
+	res.Validation = survey.SectionValidation{SectionID: section.SectionID, Title: section.Title, InitialIssues: len(issues)}
+	rounds := input.RepairRounds
+	if rounds == 0 {
+		rounds = defaultSurveyRepairRounds
+	}
+	for round := 0; round < rounds && len(issues) > 0; round++ {
+		repairInput := sectionInput
+		repairInput.Operation = "survey_section_repair"
+		repairInput.Prompt = buildSectionRepairPrompt(sectionInput.Prompt, out.Text, issues)
+		res.Validation.RepairRounds++
+		repaired, _, err := callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, repairInput, nil)
+		if err != nil {
+			break
+		}
+		res.Models = append(res.Models, modelLabel(repaired))
+		candidate := survey.ParseSection(section.Title, repaired.Text)
+		if len(candidate.Paragraphs) == 0 && len(candidate.Subsections) == 0 {
+			continue
+		}
+		// Keep a repair only when it leaves fewer violations than the draft it replaces.
+		if candidateIssues := survey.ValidateSection(repaired.Text, candidate, allowed); len(candidateIssues) < len(issues) {
+			out, issues = repaired, candidateIssues
+			res.Section = candidate
+			res.Section.ID = section.SectionID
+		}
+	}
+	res.Validation.Issues = issues
+	res.Models = dedupeStrings(res.Models)
+	return res, nil
+}
+
+// buildSectionRepairPrompt asks for a corrected section: the original instructions, the
+// previous draft and the violations the validator found in it.
+func buildSectionRepairPrompt(basePrompt, draft string, issues []survey.Issue) string {
+	lines := []string{
+		basePrompt,
+		"",
+		"Your previous draft of this section was:",
+		strings.TrimSpace(draft),
+		"",
+		"It violated these requirements:",
+	}
+	for _, is := range issues {
+		lines = append(lines, "- "+is.Detail)
+	}
+	lines = append(lines,
+		"",
+		"Return the full corrected section. Keep every supported claim, cite only the allowed keys, and remove claims no listed source supports.",
+	)
+	return strings.Join(lines, "\n")
+}
+
+// buildSectionCritiquePrompt asks a reviewer to score a section draft as JSON.
+func buildSectionCritiquePrompt(surveyTitle string, section SurveyOutlineSection, refs []SurveyReference, otherSections []string, draft string) string {
+	lines := []string{
+		"You are reviewing one section of a citation-grounded literature survey titled: " + surveyTitle,
+		"Section title: " + section.Title,
+		"Section scope: " + section.Query,
+		"",
+		"Retrieved sources the section may cite:",
+	}
+	for _, ref := range refs {
+		lines = append(lines, fmt.Sprintf("[%s] %s", ref.Key, ref.Title))
+	}
+	if len(otherSections) > 0 {
+		lines = append(lines, "", "Other sections of the survey: "+strings.Join(otherSections, "; "))
+	}
+	lines = append(lines,
+		"",
+		"Draft:",
+		strings.TrimSpace(draft),
+		"",
+		"Score the draft from 1 (poor) to 5 (excellent) on:",
+		"- coverage: how well it uses the retrieved sources",
+		"- grounding: whether every claim is supported by the cited context",
+		"- redundancy: 5 means no repetition within the section or overlap with other sections",
+		"- structure: logical order, paragraphing and subsections",
+		`Return JSON only: {"coverage": 1-5, "grounding": 1-5, "redundancy": 1-5, "structure": 1-5, "comments": ["specific, actionable problem", ...]}`,
+	)
+	return strings.Join(lines, "\n")
+}
+
+// buildSectionRevisionPrompt asks for a revised section that addresses a critique.
+func buildSectionRevisionPrompt(basePrompt, draft string, critique SurveyCritique) string {
+	lines := []string{
+		basePrompt,
+		"",
+		"Your previous draft of this section was:",
+		strings.TrimSpace(draft),
+		"",
+		fmt.Sprintf("A reviewer scored it coverage %d, grounding %d, redundancy %d, structure %d (out of 5) and noted:", critique.Coverage, critique.Grounding, critique.Redundancy, critique.Structure),
+	}
+	for _, c := range critique.Comments {
+		lines = append(lines, "- "+c)
+	}
+	lines = append(lines,
+		"",
+		"Return the full revised section addressing the review. Cite only the allowed keys and keep every supported claim.",
+	)
+	return strings.Join(lines, "\n")
+}
+
+// parseSurveyCritique reads the reviewer's JSON; every score must be between 1 and 5.
+func parseSurveyCritique(raw string) (SurveyCritique, bool) {
+	raw = strings.TrimSpace(raw)
+	if start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}"); start >= 0 && end > start {
+		raw = raw[start : end+1]
+	}
+	var c SurveyCritique
+	if err := json.Unmarshal([]byte(raw), &c); err != nil {
+		return SurveyCritique{}, false
+	}
+	for _, score := range []int{c.Coverage, c.Grounding, c.Redundancy, c.Structure} {
+		if score < 1 || score > 5 {
+			return SurveyCritique{}, false
+		}
+	}
+	c.Comments = dedupeStrings(trimAll(c.Comments))
+	c.Round, c.Revised = 0, false
+	return c, true
+}
+
+// passes reports whether every score reached 4, the point where revising is not worth a call.
+func (c SurveyCritique) passes() bool {
+	return min(c.Coverage, c.Grounding, c.Redundancy, c.Structure) >= 4
+}
+
+// enrichSurveyReferences fills in paper metadata for the references and, when requested,
+// returns the stored paper summaries of the referenced papers as extra context.
+func enrichSurveyReferences(ctx workflow.Context, corpusID string, corpusIDs []string, refs []SurveyReference, withSummaries bool) []string {
+	paperIDs := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if strings.TrimSpace(ref.PaperID) != "" {
+			paperIDs = append(paperIDs, ref.PaperID)
+		}
+	}
+	if len(paperIDs) == 0 {
+		return nil
+	}
+	var metaOut activities.GetSurveyPaperMetaOutput
+	if err := workflow.ExecuteActivity(ctx, "GetSurveyPaperMetaActivity", activities.GetSurveyPaperMetaInput{
+		CorpusID:  corpusID,
+		CorpusIDs: corpusIDs,
+		PaperIDs:  paperIDs,
+	}).Get(ctx, &metaOut); err == nil {
+		metaByID := make(map[string]activities.SurveyPaperMeta, len(metaOut.Papers))
+		for _, m := range metaOut.Papers {
+			metaByID[m.PaperID] = m
+		}
+		for i := range refs {
+			m, ok := metaByID[refs[i].PaperID]
+			if !ok {
+				continue
+			}
+			if strings.TrimSpace(m.Title) != "" {
+				refs[i].Title = strings.TrimSpace(m.Title)
+			}
+			refs[i].Authors = strings.TrimSpace(m.Authors)
+			refs[i].Year = m.Year
+			refs[i].Venue = strings.TrimSpace(m.Venue)
+			refs[i].DOI = strings.TrimSpace(m.DOI)
+			refs[i].Filename = strings.TrimSpace(m.Filename)
+		}
+	}
+	if !withSummaries {
+		return nil
+	}
+	var summaries activities.ListPaperSummariesOutput
+	if err := workflow.ExecuteActivity(ctx, "ListPaperSummariesActivity", activities.ListPaperSummariesInput{
+		CorpusID:  corpusID,
+		CorpusIDs: corpusIDs,
+		PaperIDs:  paperIDs,
+	}).Get(ctx, &summaries); err != nil {
+		return nil
+	}
+	byPaper := make(map[string]activities.PaperSummaryRecord, len(summaries.Summaries))
+	for _, sum := range summaries.Summaries {
+		byPaper[sum.PaperID] = sum
+	}
+	var extra []string
+	for _, ref := range refs {
+		if sum, ok := byPaper[ref.PaperID]; ok {
+			extra = append(extra, paperSummaryContext(ref.Key, ref.Title, sum))
+		}
+	}
+	return extra
+}
+
+// surveyTitleAndTopics reads the request: the prompt (or the topics) titles the survey and
+// every topic becomes an outline entry; a prompt alone is the only topic.
+func surveyTitleAndTopics(input SurveyBuildInput) (string, []string) {
+	topics := dedupeStrings(trimAll(input.Topics))
+	title := strings.TrimSpace(input.Prompt)
+	if title == "" {
+		title = strings.Join(topics, "; ")
+	}
+	if len(topics) == 0 && title != "" {
+		topics = []string{title}
+	}
+	return title, topics
+}
+
+func fallbackSurveyOutline(topics []string) []SurveyOutlineSection {
+	out := make([]SurveyOutlineSection, 0, len(topics))
+	for _, t := range topics {
+		out = append(out, SurveyOutlineSection{Kind: SurveySectionTopic, Title: t, Query: t})
+	}
+	return out
+}
+
+func buildSurveyOutlinePrompt(title string, topics, questions []string) string {
+	lines := []string{
+		"Plan the sections of a literature survey titled: " + title,
+		"",
+		"Requested topics:",
+	}
+	for _, t := range topics {
+		lines = append(lines, "- "+t)
+	}
+	if len(questions) > 0 {
+		lines = append(lines, "", "Research questions (answered in a separate final section; do not plan sections for them):")
+		for _, q := range questions {
+			lines = append(lines, "- "+q)
+		}
+	}
+	lines = append(lines,
+		"",
+		fmt.Sprintf("Return between %d and %d sections in reading order. Every requested topic must be covered by at least one section.", max(1, len(topics)), maxSurveySections),
+		"For each section give a short title, a retrieval query that finds the relevant papers, and one sentence on what the section should compare.",
+		"Do not plan an introduction, conclusion or references section.",
+		"",
+		`Output STRICT JSON: {"sections": [{"title": "...", "query": "...", "focus": "..."}]}`,
+	)
+	return strings.Join(lines, "\n")
+}
+
+// awaitOutlineReview blocks until the outline is approved, replaced by a valid edit, or the
+// timeout passes, and returns the outline to draft. Invalid edits are recorded in
+// state.LastError and the review stays open.
+func awaitOutlineReview(ctx workflow.Context, state *SurveyOutlineState, timeout time.Duration) []SurveyOutlineSection {
+	deadline := workflow.Now(ctx).Add(timeout)
+	state.Status = "pending_review"
+	state.ReviewDeadline = &deadline
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	defer cancelTimer()
+	timer := workflow.NewTimer(timerCtx, timeout)
+	reviews := workflow.GetSignalChannel(ctx, SignalSurveyOutlineReview)
+	for state.Status == "pending_review" {
+		selector := workflow.NewSelector(ctx)
+		selector.AddReceive(reviews, func(c workflow.ReceiveChannel, _ bool) {
+			var review SurveyOutlineReview
+			c.Receive(ctx, &review)
+			switch review.Action {
+			case SurveyOutlineApprove:
+				state.Status = "approved"
+				state.LastError = ""
+			case SurveyOutlineEdit:
+				edited, err := NormalizeSurveyOutline(review.Sections)
+				if err != nil {
+					state.LastError = err.Error()
+					return
+				}
+				state.Outline = edited
+				state.Status = "edited"
+				state.LastError = ""
+			default:
+				state.LastError = fmt.Sprintf("unknown outline review action %q", review.Action)
+			}
+		})
+		selector.AddFuture(timer, func(workflow.Future) {
+			state.Status = "timed_out"
+		})
+		selector.Select(ctx)
+	}
+	return state.Outline
+}
+
+// NormalizeSurveyOutline checks an edited outline and assigns section IDs in order. Titles
+// are required and unique, queries default to the title, and at most one questions section
+// with at least one question is allowed next to 1 to maxSurveySections topic sections.
+func NormalizeSurveyOutline(sections []SurveyOutlineSection) ([]SurveyOutlineSection, error) {
+	out := make([]SurveyOutlineSection, 0, len(sections))
+	seen := map[string]bool{}
+	topics, questionSections := 0, 0
+	for i, s := range sections {
+		title := strings.Join(strings.Fields(s.Title), " ")
+		if title == "" {
+			return nil, fmt.Errorf("section %d has no title", i+1)
+		}
+		if seen[strings.ToLower(title)] {
+			return nil, fmt.Errorf("duplicate section title %q", title)
+		}
+		seen[strings.ToLower(title)] = true
+		query := strings.Join(strings.Fields(s.Query), " ")
+		if query == "" {
+			query = title
+		}
+		section := SurveyOutlineSection{Kind: s.Kind, Title: title, Query: query, Focus: strings.Join(strings.Fields(s.Focus), " ")}
+		switch s.Kind {
+		case "", SurveySectionTopic:
+			section.Kind = SurveySectionTopic
+			topics++
+		case SurveySectionQuestions:
+			section.Questions = dedupeStrings(trimAll(s.Questions))
+			if len(section.Questions) == 0 {
+				return nil, fmt.Errorf("questions section %q has no questions", title)
+			}
+			questionSections++
+		default:
+			return nil, fmt.Errorf("section %q has unknown kind %q", title, s.Kind)
+		}
+		out = append(out, section)
+	}
+	if topics == 0 || topics > maxSurveySections {
+		return nil, fmt.Errorf("outline needs between 1 and %d topic sections", maxSurveySections)
+	}
+	if questionSections > 1 {
+		return nil, fmt.Errorf("outline allows at most one questions section")
+	}
+	for i := range out {
+		out[i].SectionID = fmt.Sprintf("s%d", i+1)
+	}
+	return out, nil
+}
+
+// parseSurveyOutline reads the planner output. It is unusable when it is not JSON, has fewer
+// sections than requested topics, or more than maxSurveySections.
+func parseSurveyOutline(raw string, topicCount int) ([]SurveyOutlineSection, bool) {
+	raw = strings.TrimSpace(raw)
+	if start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}"); start >= 0 && end > start {
+		raw = raw[start : end+1]
+	}
+	var payload struct {
+		Sections []struct {
+			Title string `json:"title"`
+			Query string `json:"query"`
+			Focus string `json:"focus"`
+		} `json:"sections"`
+	}
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return nil, false
+	}
+	out := make([]SurveyOutlineSection, 0, len(payload.Sections))
+	seen := map[string]bool{}
+	for _, s := range payload.Sections {
+		title := strings.Join(strings.Fields(s.Title), " ")
+		if title == "" || seen[strings.ToLower(title)] {
+			continue
+		}
+		seen[strings.ToLower(title)] = true
+		query := strings.Join(strings.Fields(s.Query), " ")
+		if query == "" {
+			query = title
+		}
+		out = append(out, SurveyOutlineSection{Kind: SurveySectionTopic, Title: title, Query: query, Focus: strings.Join(strings.Fields(s.Focus), " ")})
+	}
+	if len(out) == 0 || len(out) < topicCount || len(out) > maxSurveySections {
+		return nil, false
+	}
+	return out, true
+}
+
+func buildSectionPrompt(surveyTitle string, section SurveyOutlineSection, refs []SurveyReference) string {
+	lines := []string{
+		"Write one section of a citation-grounded literature survey titled: " + surveyTitle,
+		"Section title: " + section.Title,
+		"Section scope: " + section.Query,
+	}
+	if section.Focus != "" {
+		lines = append(lines, "Section focus: "+section.Focus)
+	}
+	lines = append(lines,
+		"",
+		"Output requirements:",
+		"1. Output plain text paragraphs separated by blank lines; no LaTeX, no HTML, no code fences, and no heading for the section itself.",
+		"2. Use \"### Subsection title\" lines for structure if helpful; do not create one subsection per individual paper.",
+		"3. Synthesize the papers thematically and compare methods/findings.",
+		"4. Use inline citation keys like [ref1], [ref2] directly in text.",
+		"5. Every factual claim must cite one or more listed keys; do not cite any key outside this list.",
+		"6. Do not include an introduction, conclusion, bibliography or references section.",
+		"7. If evidence is weak, explicitly state limitations.",
+		"",
+		"Allowed citation keys:",
+		referenceKeyLines(refs),
+	)
+	return strings.Join(lines, "\n")
+}
+
+func buildQuestionsPrompt(surveyTitle string, section SurveyOutlineSection, refs []SurveyReference) string {
+	lines := []string{
+		"Write the research questions section of a citation-grounded literature survey titled: " + surveyTitle,
+		"",
+		"Questions:",
+	}
+	for i, q := range section.Questions {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, q))
+	}
+	lines = append(lines,
+		"",
+		"Output requirements:",
+		"1. Output plain text paragraphs separated by blank lines; no LaTeX, no HTML, no code fences.",
+		"2. Answer each question under its own \"### <question>\" line.",
+		"3. Use inline citation keys like [ref1], [ref2] directly in text.",
+		"4. Every factual claim must cite one or more listed keys; do not cite any key outside this list.",
+		"5. When the evidence does not answer a question, say so explicitly.",
+		"",
+		"Allowed citation keys:",
+		referenceKeyLines(refs),
+	)
+	return strings.Join(lines, "\n")
+}
+
+func referenceKeyLines(refs []SurveyReference) string {
+	lines := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		lines = append(lines, fmt.Sprintf("- %s: %s", ref.Key, ref.Title))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func buildSurveyIntroPrompt(title string, outline []SurveyOutlineSection) string {
+	titles := make([]string, 0, len(outline))
+	for _, s := range outline {
+		titles = append(titles, "- "+s.Title)
+	}
+	return strings.Join([]string{
+		"Write the abstract and introduction of a literature survey titled: " + title,
+		"The survey has these sections, whose opening text is given as context:",
+		strings.Join(titles, "\n"),
+		"",
+		"The abstract is one plain text paragraph. The introduction motivates the survey and walks through the sections in order, in plain text paragraphs separated by blank lines.",
+		"Do not cite papers and do not use LaTeX or Markdown.",
+		"",
+		`Output STRICT JSON: {"abstract": "...", "introduction": "..."}`,
+	}, "\n")
+}
+
+// parseSurveyIntro reads the intro output. It is unusable without an introduction.
+func parseSurveyIntro(raw string) ([]survey.Paragraph, []survey.Paragraph, bool) {
+	raw = strings.TrimSpace(raw)
+	if start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}"); start >= 0 && end > start {
+		raw = raw[start : end+1]
+	}
+	var payload struct {
+		Abstract     string `json:"abstract"`
+		Introduction string `json:"introduction"`
+	}
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return nil, nil, false
+	}
+	intro := survey.ParseParagraphs(payload.Introduction)
+	if len(intro) == 0 {
+		return nil, nil, false
+	}
+	return survey.ParseParagraphs(payload.Abstract), intro, true
+}
+
+func fallbackSurveyIntro(title string, outline []SurveyOutlineSection) []survey.Paragraph {
+	topics := make([]string, 0, len(outline))
+	hasQuestions := false
+	for _, s := range outline {
+		if s.Kind == SurveySectionQuestions {
+			hasQuestions = true
+			continue
+		}
+		topics = append(topics, s.Title)
+	}
+	text := "This survey reviews the literature on " + title + "."
+	if len(topics) > 0 {
+		text += " It is organized into sections on " + strings.Join(topics, "; ") + "."
+	}
+	if hasQuestions {
+		text += " It closes by answering the research questions posed for this review."
+	}
+	return []survey.Paragraph{survey.NewParagraph(text)}
+}
+
+// sectionDigests gives the intro call the opening of every drafted section.
+func sectionDigests(sections []survey.Section) []string {
+	out := make([]string, 0, len(sections))
+	for _, s := range sections {
+		texts := make([]string, 0, len(s.Paragraphs))
+		for _, p := range s.Paragraphs {
+			texts = append(texts, p.Text)
+		}
+		for _, sub := range s.Subsections {
+			texts = append(texts, sub.Title+":")
+			for _, p := range sub.Paragraphs {
+				texts = append(texts, p.Text)
+			}
+		}
+		out = append(out, s.Title+": "+truncateRunes(strings.Join(texts, " "), 600))
+	}
+	return out
+}
+
+// mergeSurveySections builds the shared reference list, keyed by paper in order of first
+// citation across sections, and rewrites each section from its local keys to the shared
+// ones. Keys a section cites but never retrieved are dropped. Failed sections are left out.
+func mergeSurveySections(results []SurveySectionResult) ([]SurveyReference, []survey.Section) {
+	refs := make([]SurveyReference, 0)
+	byPaper := map[string]int{}
+	sections := make([]survey.Section, 0, len(results))
+	for _, res := range results {
+		if res.Status == "failed" {
+			continue
+		}
+		local := make(map[string]string, len(res.References))
+		for _, r := range res.References {
+			idx, ok := byPaper[r.PaperID]
+			if !ok {
+				idx = len(refs)
+				byPaper[r.PaperID] = idx
+				shared := r
+				shared.Key = fmt.Sprintf("ref%d", idx+1)
+				shared.ChunkIDs = append([]string(nil), r.ChunkIDs...)
+				refs = append(refs, shared)
+			} else {
+				refs[idx].ChunkIDs = dedupeStrings(append(refs[idx].ChunkIDs, r.ChunkIDs...))
+			}
+			local[r.Key] = refs[idx].Key
+		}
+		sections = append(sections, survey.RemapCitations(res.Section, local))
+	}
+	return refs, sections
+}
+
+// modelLabel is the "provider/model" pair recorded for a survey run, or "" when the provider
+// did not report one.
+func modelLabel(out activities.LLMGenerateOutput) string {
+	if out.ProviderName == "" {
+		return ""
+	}
+	if out.Model == "" {
+		return out.ProviderName
+	}
+	return out.ProviderName + "/" + out.Model
+}
+
+func otherSectionTitles(outline []SurveyOutlineSection, sectionID string) []string {
+	out := make([]string, 0, len(outline))
+	for _, s := range outline {
+		if s.SectionID != sectionID {
+			out = append(out, s.Title)
+		}
+	}
+	return out
+}
+
+func sectionLabel(s SurveyOutlineSection) string {
+	if len([]rune(s.Title)) > 64 {
+		return truncateRunes(s.Title, 61)
+	}
+	return s.Title
+}
+
+func trimAll(in []string) []string {
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		out = append(out, strings.TrimSpace(s))
+	}
+	return out
+}
+
+// appendKGComparison adds a Method Comparison section built from KG facts about the methods
+// the referenced papers mention. Provenance papers that were not retrieved join the reference
+// list so every cell can cite the paper its fact came from.
+func appendKGComparison(ctx workflow.Context, input SurveyBuildInput, doc *survey.Document) {
+	paperIDs := make([]string, 0, len(doc.References))
+	for _, ref := range doc.References {
+		paperIDs = append(paperIDs, ref.PaperID)
+	}
+	var facts activities.GetSurveyKGFactsOutput
+	if err := workflow.ExecuteActivity(ctx, "GetSurveyKGFactsActivity", activities.GetSurveyKGFactsInput{
+		CorpusID:  input.CorpusID,
+		CorpusIDs: input.CorpusIDs,
+		PaperIDs:  dedupeStrings(paperIDs),
+		Limit:     maxSurveyKGFacts,
+	}).Get(ctx, &facts); err != nil || len(facts.Facts) == 0 {
+		return
+	}
+	sec, added, ok := kgComparisonSection(facts.Facts, doc.References)
+	if !ok {
+		return
+	}
+	if len(added) > 0 {
+		enrichSurveyReferences(ctx, input.CorpusID, input.CorpusIDs, added, false)
+		doc.References = append(doc.References, added...)
+	}
+	doc.Sections = append(doc.Sections, sec)
+}
+
+// kgComparisonSection lays out KG facts as a method × dataset table from EVALUATED_ON and
+// USES_DATASET facts on datasets or tasks, with the methods each row is reported to
+// outperform, and a lineage subsection with one sentence per method from EXTENDS and
+// BASED_ON facts. Methods keep the facts' support order.
+// Each cell and clause cites the referenced papers the fact was extracted from, or else its
+// first provenance paper, which is returned as a new reference.
+func kgComparisonSection(facts []activities.SurveyKGFact, refs []SurveyReference) (survey.Section, []SurveyReference, bool) {
+	keyByPaper := make(map[string]string, len(refs))
+	for _, ref := range refs {
+		if _, ok := keyByPaper[ref.PaperID]; !ok {
+			keyByPaper[ref.PaperID] = ref.Key
+		}
+	}
+	var added []SurveyReference
+	cite := func(f activities.SurveyKGFact) string {
+		var keys []string
+		for _, p := range f.Provenance {
+			if k, ok := keyByPaper[p.PaperID]; ok {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) == 0 {
+			p := f.Provenance[0]
+			key := fmt.Sprintf("ref%d", len(refs)+len(added)+1)
+			keyByPaper[p.PaperID] = key
+			added = append(added, SurveyReference{Key: key, CorpusID: f.CorpusID, PaperID: p.PaperID, ChunkIDs: dedupeStrings([]string{p.ChunkID})})
+			keys = []string{key}
+		}
+		keys = dedupeStrings(keys)
+		return "[" + strings.Join(keys[:min(len(keys), 3)], ", ") + "]"
+	}
+
+	type methodRow struct {
+		datasets map[string]activities.SurveyKGFact
+		beats    []activities.SurveyKGFact
+	}
+	rows := map[string]*methodRow{}
+	var methods, datasets []string
+	datasetCount := map[string]int{}
+	lineage := map[string][]activities.SurveyKGFact{}
+	var lineageMethods []string
+	row := func(name string) *methodRow {
+		r, ok := rows[name]
+		if !ok {
+			r = &methodRow{datasets: map[string]activities.SurveyKGFact{}}
+			rows[name] = r
+			methods = append(methods, name)
+		}
+		return r
+	}
+	for _, f := range facts {
+		src, dst := strings.TrimSpace(f.SourceName), strings.TrimSpace(f.TargetName)
+		if len(f.Provenance) == 0 || src == "" || dst == "" || f.SourceType != "method" {
+			continue
+		}
+		switch f.EdgeType {
+		case "EVALUATED_ON", "USES_DATASET":
+			// The extraction prompt types benchmarks such as GLUE as tasks as well as datasets.
+			if f.TargetType != "dataset" && f.TargetType != "task" {
+				continue
+			}
+			r := row(src)
+			if _, seen := r.datasets[dst]; seen {
+				continue
+			}
+			r.datasets[dst] = f
+			if datasetCount[dst] == 0 {
+				datasets = append(datasets, dst)
+			}
+			datasetCount[dst]++
+		case "OUTPERFORMS":
+			if f.TargetType == "method" {
+				r := row(src)
+				r.beats = append(r.beats, f)
+			}
+		case "EXTENDS", "BASED_ON":
+			if _, ok := lineage[src]; !ok {
+				lineageMethods = append(lineageMethods, src)
+			}
+			lineage[src] = append(lineage[src], f)
+		}
+	}
+
+	sec := survey.Section{
+		ID:    kgComparisonSectionID,
+		Title: "Method Comparison",
+		Paragraphs: []survey.Paragraph{survey.NewParagraph(
+			"This section is assembled from relations extracted into the knowledge graph from the surveyed papers; every entry cites the paper it was extracted from.",
+		)},
+	}
+	if len(methods) > 0 {
+		sort.SliceStable(datasets, func(i, j int) bool { return datasetCount[datasets[i]] > datasetCount[datasets[j]] })
+		datasets = datasets[:min(len(datasets), maxKGTableDatasets)]
+		methods = methods[:min(len(methods), maxKGTableMethods)]
+		withBeats := false
+		for _, m := range methods {
+			withBeats = withBeats || len(rows[m].beats) > 0
+		}
+		table := survey.Table{
+			Caption: "Datasets each method is evaluated on and methods it is reported to outperform.",
+			Header:  append([]string{"Method"}, datasets...),
+		}
+		if withBeats {
+			table.Header = append(table.Header, "Outperforms")
+		}
+		for _, m := range methods {
+			r := rows[m]
+			cells := []string{m}
+			for _, d := range datasets {
+				cell := ""
+				if f, ok := r.datasets[d]; ok {
+					cell = cite(f)
+				}
+				cells = append(cells, cell)
+			}
+			if withBeats {
+				beats := make([]string, 0, 3)
+				for _, f := range r.beats[:min(len(r.beats), 3)] {
+					beats = append(beats, strings.TrimSpace(f.TargetName)+" "+cite(f))
+				}
+				cells = append(cells, strings.Join(beats, "; "))
+			}
+			table.Rows = append(table.Rows, cells)
+		}
+		sec.Tables = append(sec.Tables, table)
+	}
+	if len(lineageMethods) > 0 {
+		sub := survey.Section{Title: "Method Lineage"}
+		for _, m := range lineageMethods[:min(len(lineageMethods), maxKGLineageMethods)] {
+			clauses := make([]string, 0, 4)
+			for _, f := range lineage[m][:min(len(lineage[m]), 4)] {
+				verb := "extends"
+				if f.EdgeType == "BASED_ON" {
+					verb = "builds on"
+				}
+				clauses = append(clauses, verb+" "+strings.TrimSpace(f.TargetName)+" "+cite(f))
+			}
+			sub.Paragraphs = append(sub.Paragraphs, survey.NewParagraph(m+" "+joinClauses(clauses)+"."))
+		}
+		sec.Subsections = append(sec.Subsections, sub)
+	}
+	return sec, added, len(sec.Tables) > 0 || len(sec.Subsections) > 0
+}
+
+// joinClauses joins clauses as "a", "a and b" or "a, b and c".
+func joinClauses(clauses []string) string {
+	if len(clauses) <= 1 {
+		return strings.Join(clauses, "")
+	}
+	return strings.Join(clauses[:len(clauses)-1], ", ") + " and " + clauses[len(clauses)-1]
+}
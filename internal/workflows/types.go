@@ -1,5 +1,12 @@
 package workflows
 
+import (
+	"time"
+
+	"litflow/internal/retrieval"
+	"litflow/internal/survey"
+)
+
 type CorpusIngestInput struct {
 	CorpusID              string `json:"corpus_id"`
 	InputDir              string `json:"input_dir"`
@@ -24,11 +31,104 @@ type PaperProcessInput struct {
 }
 
 type SurveyBuildInput struct {
-	SurveyRunID     string   `json:"survey_run_id"`
-	CorpusID        string   `json:"corpus_id"`
-	Prompt          string   `json:"prompt,omitempty"`
-	Topics          []string `json:"topics"`
-	Questions       []string `json:"questions"`
+	SurveyRunID  string   `json:"survey_run_id"`
+	CorpusID     string   `json:"corpus_id"`
+	Prompt       string   `json:"prompt,omitempty"`
+	Topics       []string `json:"topics"`
+	Questions    []string `json:"questions"`
+	OutputFormat string   `json:"output_format,omitempty"`
+	// TemplateID and CitationStyle are stored on the document and pick its report layout.
+	TemplateID    string `json:"template_id,omitempty"`
+	CitationStyle string `json:"citation_style,omitempty"`
+	// Author is the report's author line; empty uses survey.DefaultAuthor.
+	Author          string   `json:"author,omitempty"`
+	RetrievalTopK   int      `json:"retrieval_top_k,omitempty"`
+	EmbedProviders  int      `json:"embed_providers"`
+	LLMProviders    int      `json:"llm_providers"`
+	LLMProviderRefs []string `json:"llm_provider_refs,omitempty"`
+	CooldownSeconds int      `json:"cooldown_seconds"`
+	EmbedVersion    string   `json:"embed_version"`
+	QueryRewrite    string   `json:"query_rewrite,omitempty"`
+	RewriteCount    int      `json:"rewrite_count,omitempty"`
+	ContextWindow   int      `json:"context_window,omitempty"`
+	ContextBudget   int      `json:"context_token_budget,omitempty"`
+	// CorpusIDs widens retrieval to several corpora; CorpusID stays the run's owner.
+	CorpusIDs []string `json:"corpus_ids,omitempty"`
+	// UsePaperSummaries adds stored paper summaries of the retrieved papers to the context.
+	UsePaperSummaries bool `json:"use_paper_summaries,omitempty"`
+	// MaxConcurrentSections bounds how many section child workflows draft at once.
+	MaxConcurrentSections int `json:"max_concurrent_sections,omitempty"`
+	// RepairRounds bounds the citation repair calls per section: 0 means the default of 2 and
+	// a negative value only validates.
+	RepairRounds int `json:"repair_rounds,omitempty"`
+	// ReviewOutline pauses after planning until the outline is approved or edited through
+	// SignalSurveyOutlineReview, or OutlineReviewTimeoutSeconds (default 24h) pass; on timeout
+	// the generated outline is drafted.
+	ReviewOutline               bool `json:"review_outline,omitempty"`
+	OutlineReviewTimeoutSeconds int  `json:"outline_review_timeout_seconds,omitempty"`
+	// CritiqueRounds enables a reviewer/reviser loop per section (0 disables it).
+	CritiqueRounds int `json:"critique_rounds,omitempty"`
+	// KGTables appends a Method Comparison section built from knowledge graph facts about the
+	// methods the retrieved papers mention.
+	KGTables bool `json:"kg_tables,omitempty"`
+}
+
+// Survey section kinds: topic sections synthesize one outline entry, the questions section
+// answers the request's questions.
+const (
+	SurveySectionTopic     = "topic"
+	SurveySectionQuestions = "questions"
+)
+
+// SurveyOutlineSection is one planned section of a survey. Query drives retrieval; Focus is
+// guidance for drafting.
+type SurveyOutlineSection struct {
+	SectionID string   `json:"section_id"`
+	Kind      string   `json:"kind"`
+	Title     string   `json:"title"`
+	Query     string   `json:"query"`
+	Focus     string   `json:"focus,omitempty"`
+	Questions []string `json:"questions,omitempty"`
+}
+
+// Outline review actions carried by SignalSurveyOutlineReview.
+const (
+	SurveyOutlineApprove = "approve"
+	SurveyOutlineEdit    = "edit"
+)
+
+// SurveyOutlineReview approves the planned outline or replaces it with Sections. Section IDs
+// are reassigned in order; questions sections keep their Questions.
+type SurveyOutlineReview struct {
+	Action   string                 `json:"action"`
+	Sections []SurveyOutlineSection `json:"sections,omitempty"`
+}
+
+// SurveyOutlineState answers QueryGetSurveyOutline. Status is planning, pending_review,
+// approved, edited, timed_out or not_required; LastError explains the last rejected edit.
+type SurveyOutlineState struct {
+	SurveyRunID    string                 `json:"survey_run_id"`
+	Status         string                 `json:"status"`
+	Outline        []SurveyOutlineSection `json:"outline"`
+	ReviewDeadline *time.Time             `json:"review_deadline,omitempty"`
+	LastError      string                 `json:"last_error,omitempty"`
+}
+
+// SurveyReference is a cited paper. Section drafts number their own keys (ref1, ref2, ...);
+// the assembled survey renumbers them into one shared reference list.
+type SurveyReference = survey.Reference
+
+// SurveyUpdateInput refreshes the survey stored at BaseOutPath with papers ingested after
+// Since, writing the result as run SurveyRunID.
+type SurveyUpdateInput struct {
+	SurveyRunID string    `json:"survey_run_id"`
+	BaseRunID   string    `json:"base_run_id"`
+	BaseOutPath string    `json:"base_out_path"`
+	Since       time.Time `json:"since"`
+	CorpusID    string    `json:"corpus_id"`
+	// CorpusIDs is the base run's cross-corpus scope; when empty it is read from the base
+	// document.
+	CorpusIDs       []string `json:"corpus_ids,omitempty"`
 	OutputFormat    string   `json:"output_format,omitempty"`
 	RetrievalTopK   int      `json:"retrieval_top_k,omitempty"`
 	EmbedProviders  int      `json:"embed_providers"`
@@ -38,10 +138,67 @@ type SurveyBuildInput struct {
 	EmbedVersion    string   `json:"embed_version"`
 }
 
+type SurveySectionInput struct {
+	SurveyRunID       string               `json:"survey_run_id"`
+	CorpusID          string               `json:"corpus_id"`
+	CorpusIDs         []string             `json:"corpus_ids,omitempty"`
+	SurveyTitle       string               `json:"survey_title"`
+	Section           SurveyOutlineSection `json:"section"`
+	RetrievalTopK     int                  `json:"retrieval_top_k,omitempty"`
+	EmbedProviders    int                  `json:"embed_providers"`
+	LLMProviders      int                  `json:"llm_providers"`
+	LLMProviderRefs   []string             `json:"llm_provider_refs,omitempty"`
+	CooldownSeconds   int                  `json:"cooldown_seconds"`
+	EmbedVersion      string               `json:"embed_version"`
+	QueryRewrite      string               `json:"query_rewrite,omitempty"`
+	RewriteCount      int                  `json:"rewrite_count,omitempty"`
+	ContextWindow     int                  `json:"context_window,omitempty"`
+	ContextBudget     int                  `json:"context_token_budget,omitempty"`
+	UsePaperSummaries bool                 `json:"use_paper_summaries,omitempty"`
+	RepairRounds      int                  `json:"repair_rounds,omitempty"`
+	CritiqueRounds    int                  `json:"critique_rounds,omitempty"`
+	// OtherSections lists the titles of the sibling sections so the reviewer can flag overlap.
+	OtherSections []string `json:"other_sections,omitempty"`
+}
+
+// SurveyCritique is the reviewer's verdict on one draft of a section. Scores run from 1 (poor)
+// to 5 (excellent); a higher Redundancy score means less repetition.
+type SurveyCritique struct {
+	Round      int      `json:"round"`
+	Coverage   int      `json:"coverage"`
+	Grounding  int      `json:"grounding"`
+	Redundancy int      `json:"redundancy"`
+	Structure  int      `json:"structure"`
+	Comments   []string `json:"comments"`
+	// Revised reports whether a revision addressing this critique replaced the draft.
+	Revised bool `json:"revised"`
+}
+
+// SurveySectionResult is a drafted section citing References by their section-local keys.
+// Status is done, empty (nothing retrieved) or failed.
+type SurveySectionResult struct {
+	SectionID        string                     `json:"section_id"`
+	Kind             string                     `json:"kind"`
+	Title            string                     `json:"title"`
+	Section          survey.Section             `json:"section"`
+	References       []SurveyReference          `json:"references"`
+	RewrittenQueries []retrieval.RewrittenQuery `json:"rewritten_queries,omitempty"`
+	Status           string                     `json:"status"`
+	Error            string                     `json:"error,omitempty"`
+	GenerationFailed bool                       `json:"generation_failed,omitempty"`
+	Validation       survey.SectionValidation   `json:"validation"`
+	Critiques        []SurveyCritique           `json:"critiques,omitempty"`
+	// Models lists the "provider/model" pairs that wrote the section.
+	Models []string `json:"models,omitempty"`
+}
+
 type BackfillInput struct {
-	CorpusID                    string   `json:"corpus_id"`
-	Mode                        string   `json:"mode"`
-	SurveyRunID                 string   `json:"survey_run_id,omitempty"`
+	CorpusID    string `json:"corpus_id"`
+	Mode        string `json:"mode"`
+	SurveyRunID string `json:"survey_run_id,omitempty"`
+	// ParentRunID names the run REGENERATE_SURVEY regenerates; SurveyRunID stays the ID of
+	// the new run there.
+	ParentRunID                 string   `json:"parent_run_id,omitempty"`
 	Topics                      []string `json:"topics,omitempty"`
 	Questions                   []string `json:"questions,omitempty"`
 	DataInRoot                  string   `json:"data_in_root,omitempty"`
@@ -53,6 +210,18 @@ type BackfillInput struct {
 	LLMProviders                int      `json:"llm_providers,omitempty"`
 	LLMProviderRefs             []string `json:"llm_provider_refs,omitempty"`
 	CooldownSeconds             int      `json:"cooldown_seconds,omitempty"`
+	// Force applies to SUMMARIZE_PAPERS: papers whose summary already has the current
+	// PaperSummaryPromptVersion are skipped unless it is set.
+	Force bool `json:"force,omitempty"`
+}
+
+type PaperSummarizeInput struct {
+	CorpusID        string   `json:"corpus_id"`
+	PaperID         string   `json:"paper_id"`
+	BatchChars      int      `json:"batch_chars,omitempty"`
+	LLMProviders    int      `json:"llm_providers"`
+	LLMProviderRefs []string `json:"llm_provider_refs,omitempty"`
+	CooldownSeconds int      `json:"cooldown_seconds"`
 }
 
 type PaperStatus struct {
@@ -76,11 +245,19 @@ type CorpusIngestProgress struct {
 }
 
 type SurveyProgress struct {
-	SurveyRunID string            `json:"survey_run_id"`
-	CorpusID    string            `json:"corpus_id"`
-	TotalTopics int               `json:"total_topics"`
-	DoneTopics  int               `json:"done_topics"`
-	TopicStatus map[string]string `json:"topic_status"`
+	SurveyRunID      string                     `json:"survey_run_id"`
+	CorpusID         string                     `json:"corpus_id"`
+	CorpusIDs        []string                   `json:"corpus_ids,omitempty"`
+	TotalTopics      int                        `json:"total_topics"`
+	DoneTopics       int                        `json:"done_topics"`
+	TopicStatus      map[string]string          `json:"topic_status"`
+	QueryRewrite     string                     `json:"query_rewrite,omitempty"`
+	RewrittenQueries []retrieval.RewrittenQuery `json:"rewritten_queries,omitempty"`
+	// Phase is outline, review, sections, assembling or done; TopicStatus is keyed by section
+	// title.
+	Phase            string                 `json:"phase,omitempty"`
+	Outline          []SurveyOutlineSection `json:"outline,omitempty"`
+	SectionWorkflows map[string]string      `json:"section_workflows,omitempty"`
 }
 
 type KGBackfillInput struct {
@@ -110,3 +287,169 @@ type KGBackfillProgress struct {
 	Failed   int               `json:"failed"`
 	PerPaper map[string]string `json:"per_paper_status"`
 }
+
+type RetrievalEvalConfig struct {
+	TopK         int    `json:"top_k"`
+	Ks           []int  `json:"ks"`
+	QueryRewrite string `json:"query_rewrite,omitempty"`
+	RewriteCount int    `json:"rewrite_count,omitempty"`
+	EmbedVersion string `json:"embed_version"`
+	ChunkVersion string `json:"chunk_version"`
+	// EmbedProvider pins query embedding to one provider (no failover) so the run measures
+	// a single embedding model; empty uses the normal failover order.
+	EmbedProvider string `json:"embed_provider,omitempty"`
+	// Hybrid adds a full-text ranking per query, fused with the vector ranking.
+	Hybrid bool `json:"hybrid,omitempty"`
+}
+
+type RetrievalEvalInput struct {
+	EvalRunID string              `json:"eval_run_id"`
+	CorpusID  string              `json:"corpus_id"`
+	EvalSetID string              `json:"eval_set_id"`
+	Config    RetrievalEvalConfig `json:"config"`
+	// EmbedProviderIndex is the index of Config.EmbedProvider when one is pinned.
+	EmbedProviderIndex int      `json:"embed_provider_index,omitempty"`
+	EmbedProviders     int      `json:"embed_providers"`
+	LLMProviders       int      `json:"llm_providers"`
+	LLMProviderRefs    []string `json:"llm_provider_refs,omitempty"`
+	CooldownSeconds    int      `json:"cooldown_seconds"`
+}
+
+type RetrievalEvalProgress struct {
+	EvalRunID string `json:"eval_run_id"`
+	CorpusID  string `json:"corpus_id"`
+	Status    string `json:"status"`
+	Total     int    `json:"total"`
+	Done      int    `json:"done"`
+	Failed    int    `json:"failed"`
+}
+
+// RetrievalEvalReport is the versioned artifact written to runs/<eval_run_id>/retrieval_eval.json.
+type RetrievalEvalReport struct {
+	EvalRunID   string                         `json:"eval_run_id"`
+	CorpusID    string                         `json:"corpus_id"`
+	EvalSetID   string                         `json:"eval_set_id"`
+	EvalSetName string                         `json:"eval_set_name"`
+	Config      RetrievalEvalConfig            `json:"config"`
+	Aggregate   retrieval.EvalMetrics          `json:"aggregate"`
+	Questions   []retrieval.EvalQuestionResult `json:"questions"`
+	StartedAt   time.Time                      `json:"started_at"`
+	FinishedAt  time.Time                      `json:"finished_at"`
+}
+
+type DeepResearchInput struct {
+	ResearchRunID   string   `json:"research_run_id"`
+	CorpusID        string   `json:"corpus_id"`
+	CorpusIDs       []string `json:"corpus_ids,omitempty"`
+	Question        string   `json:"question"`
+	MaxSteps        int      `json:"max_steps"`
+	TokenBudget     int      `json:"token_budget"`
+	TopK            int      `json:"top_k"`
+	EmbedVersion    string   `json:"embed_version"`
+	EmbedProviders  int      `json:"embed_providers"`
+	LLMProviders    int      `json:"llm_providers"`
+	LLMProviderRefs []string `json:"llm_provider_refs,omitempty"`
+	CooldownSeconds int      `json:"cooldown_seconds"`
+}
+
+// ResearchSubQuestion is one planned hop. Source is "chunks" for vector retrieval or
+// "graph" for a KG lookup over Terms.
+type ResearchSubQuestion struct {
+	Question string   `json:"question"`
+	Source   string   `json:"source"`
+	Terms    []string `json:"terms,omitempty"`
+	Status   string   `json:"status"`
+}
+
+// ResearchEvidence is a citable item gathered during research, numbered [E#] across the run.
+type ResearchEvidence struct {
+	EvidenceID string  `json:"evidence_id"`
+	Kind       string  `json:"kind"`
+	PaperID    string  `json:"paper_id,omitempty"`
+	ChunkID    string  `json:"chunk_id,omitempty"`
+	EdgeID     string  `json:"edge_id,omitempty"`
+	Title      string  `json:"title,omitempty"`
+	Text       string  `json:"text"`
+	Score      float64 `json:"score,omitempty"`
+}
+
+type ResearchFinding struct {
+	Step        int      `json:"step"`
+	SubQuestion string   `json:"sub_question"`
+	Source      string   `json:"source"`
+	Note        string   `json:"note"`
+	EvidenceIDs []string `json:"evidence_ids"`
+}
+
+type DeepResearchProgress struct {
+	ResearchRunID string                `json:"research_run_id"`
+	CorpusID      string                `json:"corpus_id"`
+	Question      string                `json:"question"`
+	Status        string                `json:"status"`
+	Phase         string                `json:"phase"`
+	Step          int                   `json:"step"`
+	MaxSteps      int                   `json:"max_steps"`
+	TokensUsed    int                   `json:"tokens_used"`
+	TokenBudget   int                   `json:"token_budget"`
+	Plan          []ResearchSubQuestion `json:"plan"`
+	Findings      []ResearchFinding     `json:"findings"`
+	StopReason    string                `json:"stop_reason,omitempty"`
+}
+
+// DeepResearchTrace is written next to the report as research.json.
+type DeepResearchTrace struct {
+	Progress   DeepResearchProgress `json:"progress"`
+	Evidence   []ResearchEvidence   `json:"evidence"`
+	StartedAt  time.Time            `json:"started_at"`
+	FinishedAt time.Time            `json:"finished_at"`
+}
+
+type DraftPositioningInput struct {
+	PositioningRunID string   `json:"positioning_run_id"`
+	DraftID          string   `json:"draft_id"`
+	CorpusID         string   `json:"corpus_id"`
+	CorpusIDs        []string `json:"corpus_ids,omitempty"`
+	DraftPath        string   `json:"draft_path"`
+	DraftFormat      string   `json:"draft_format"`
+	DraftFilename    string   `json:"draft_filename"`
+	MaxClaims        int      `json:"max_claims"`
+	TopK             int      `json:"top_k"`
+	EmbedVersion     string   `json:"embed_version"`
+	EmbedProviders   int      `json:"embed_providers"`
+	LLMProviders     int      `json:"llm_providers"`
+	LLMProviderRefs  []string `json:"llm_provider_refs,omitempty"`
+	CooldownSeconds  int      `json:"cooldown_seconds"`
+}
+
+// DraftClaim is a contribution or claim of the draft with the corpus references retrieved
+// for it. Source says whether Positioning was written by the LLM or listed extractively.
+type DraftClaim struct {
+	ClaimID     string   `json:"claim_id"`
+	Kind        string   `json:"kind"`
+	Text        string   `json:"text"`
+	RefKeys     []string `json:"ref_keys"`
+	Positioning string   `json:"positioning"`
+	Source      string   `json:"source"`
+}
+
+// PriorWork is a corpus paper close to the draft, ranked by how many claims retrieved it.
+type PriorWork struct {
+	Key          string   `json:"key"`
+	CorpusID     string   `json:"corpus_id,omitempty"`
+	PaperID      string   `json:"paper_id"`
+	Title        string   `json:"title"`
+	ClaimIDs     []string `json:"claim_ids"`
+	BestScore    float64  `json:"best_score"`
+	CitedInDraft bool     `json:"cited_in_draft"`
+}
+
+// DraftPositioningTrace is written next to the positioning report as positioning.json.
+type DraftPositioningTrace struct {
+	DraftID    string            `json:"draft_id"`
+	Title      string            `json:"title"`
+	Claims     []DraftClaim      `json:"claims"`
+	PriorWork  []PriorWork       `json:"prior_work"`
+	References []SurveyReference `json:"references"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+}
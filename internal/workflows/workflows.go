@@ -8,6 +8,8 @@ import (
 
 	"litflow/internal/activities"
 	"litflow/internal/providers"
+	"litflow/internal/retrieval"
+	"litflow/internal/survey"
 
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
@@ -245,7 +247,7 @@ func PaperProcessWorkflow(ctx workflow.Context, input PaperProcessInput) (string
 
 	status.CurrentStep = "mark_processed"
 	status.Steps[status.CurrentStep] = "processing"
-	if err := workflow.ExecuteActivity(ctx, "UpdatePaperStatusActivity", activities.UpdatePaperStatusInput{PaperID: computeOut.PaperID, CorpusID: input.CorpusID, Filename: filename, Title: metaOut.Title, Authors: metaOut.Authors, Status: "processed"}).Get(ctx, nil); err != nil {
+	if err := workflow.ExecuteActivity(ctx, "UpdatePaperStatusActivity", activities.UpdatePaperStatusInput{PaperID: computeOut.PaperID, CorpusID: input.CorpusID, Filename: filename, Title: metaOut.Title, Authors: metaOut.Authors, Year: metaOut.Year, Venue: metaOut.Venue, DOI: metaOut.DOI, Status: "processed"}).Get(ctx, nil); err != nil {
 		return "", err
 	}
 	status.Steps[status.CurrentStep] = "done"
@@ -254,148 +256,6 @@ func PaperProcessWorkflow(ctx workflow.Context, input PaperProcessInput) (string
 	return status.Status, nil
 }
 
-func SurveyBuildWorkflow(ctx workflow.Context, input SurveyBuildInput) (string, error) {
-	topic := strings.TrimSpace(input.Prompt)
-	if topic == "" && len(input.Topics) > 0 {
-		topic = strings.TrimSpace(input.Topics[0])
-	}
-	if topic == "" {
-		return "", fmt.Errorf("survey prompt/topic is required")
-	}
-	topicLabel := topic
-	if len(topicLabel) > 64 {
-		topicLabel = topicLabel[:61] + "..."
-	}
-	progress := SurveyProgress{SurveyRunID: input.SurveyRunID, CorpusID: input.CorpusID, TotalTopics: 1, TopicStatus: map[string]string{}}
-	if err := workflow.SetQueryHandler(ctx, QueryGetSurveyProgress, func() (SurveyProgress, error) { return progress, nil }); err != nil {
-		return "", err
-	}
-	ao := workflow.ActivityOptions{
-		StartToCloseTimeout: 5 * time.Minute,
-		RetryPolicy: &temporal.RetryPolicy{
-			InitialInterval:    2 * time.Second,
-			BackoffCoefficient: 2,
-			MaximumInterval:    30 * time.Second,
-			MaximumAttempts:    2,
-		},
-	}
-	ctx = workflow.WithActivityOptions(ctx, ao)
-	_ = workflow.ExecuteActivity(ctx, "UpdateSurveyRunActivity", activities.UpdateSurveyRunInput{SurveyRunID: input.SurveyRunID, Status: "running"}).Get(ctx, nil)
-
-	embedProviders := input.EmbedProviders
-	if embedProviders <= 0 {
-		embedProviders = 1
-	}
-	llmProviders := input.LLMProviders
-	if llmProviders <= 0 {
-		llmProviders = 1
-	}
-	cooldown := durationOrDefault(input.CooldownSeconds, 900)
-	embedState := newProviderState()
-	llmState := newProviderState()
-	topK := input.RetrievalTopK
-	if topK <= 0 {
-		topK = 14
-	}
-
-	progress.TopicStatus[topicLabel] = "retrieving"
-	eq, err := callEmbedQueryWithFailover(ctx, &embedState, embedProviders, cooldown, activities.EmbedQueryInput{
-		Operation: "survey_topic_embed",
-		Text:      topic,
-	}, nil)
-	if err != nil {
-		progress.TopicStatus[topicLabel] = "failed"
-		return "", err
-	}
-	var retrieved activities.SearchChunksOutput
-	if err := workflow.ExecuteActivity(ctx, "SearchChunksActivity", activities.SearchChunksInput{
-		CorpusID:         input.CorpusID,
-		QueryVec:         eq.Vector,
-		TopK:             topK,
-		EmbeddingVersion: defaultEmbedVersion(input.EmbedVersion),
-	}).Get(ctx, &retrieved); err != nil {
-		progress.TopicStatus[topicLabel] = "failed"
-		return "", err
-	}
-	for _, c := range retrieved.Results {
-		_ = workflow.ExecuteActivity(ctx, "UpsertTopicGraphActivity", activities.UpsertTopicGraphInput{
-			CorpusID: input.CorpusID,
-			Topic:    topic,
-			PaperID:  c.PaperID,
-			Title:    c.Title,
-			ChunkID:  c.ChunkID,
-			Score:    c.Score,
-		}).Get(ctx, nil)
-	}
-	progress.TopicStatus[topicLabel] = "drafting"
-
-	refs, contextWindow := buildSurveyReferences(retrieved.Results)
-	paperIDs := make([]string, 0, len(refs))
-	for _, ref := range refs {
-		if strings.TrimSpace(ref.PaperID) != "" {
-			paperIDs = append(paperIDs, ref.PaperID)
-		}
-	}
-	if len(paperIDs) > 0 {
-		var metaOut activities.GetSurveyPaperMetaOutput
-		if err := workflow.ExecuteActivity(ctx, "GetSurveyPaperMetaActivity", activities.GetSurveyPaperMetaInput{
-			CorpusID: input.CorpusID,
-			PaperIDs: paperIDs,
-		}).Get(ctx, &metaOut); err == nil {
-			metaByID := make(map[string]activities.SurveyPaperMeta, len(metaOut.Papers))
-			for _, m := range metaOut.Papers {
-				metaByID[m.PaperID] = m
-			}
-			for i := range refs {
-				m, ok := metaByID[refs[i].PaperID]
-				if !ok {
-					continue
-				}
-				if strings.TrimSpace(m.Title) != "" {
-					refs[i].Title = strings.TrimSpace(m.Title)
-				}
-				refs[i].Authors = strings.TrimSpace(m.Authors)
-				refs[i].Year = m.Year
-				refs[i].Filename = strings.TrimSpace(m.Filename)
-			}
-		}
-	}
-	sectionInput := activities.LLMGenerateInput{
-		Operation: "survey_ieee_latex",
-		CorpusID:  input.CorpusID,
-		Prompt:    buildLatexPrompt(topic, refs),
-		Context:   contextWindow,
-	}
-	section, sectionErrType, sectionErr := callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, sectionInput, nil)
-	if sectionErr != nil && sectionErrType == string(providers.ErrorContext) {
-		reduced := contextWindow
-		if len(reduced) > 5 {
-			reduced = reduced[:5]
-		}
-		sectionInput.Context = reduced
-		section, _, sectionErr = callLLMWithFailover(ctx, &llmState, llmProviders, input.LLMProviderRefs, cooldown, sectionInput, nil)
-	}
-
-	report := buildLatexDocument(topic, refs, cleanLLMDocument(section.Text), sectionErr != nil)
-	if strings.TrimSpace(input.OutputFormat) == "" {
-		input.OutputFormat = "latex"
-	}
-
-	var reportOut activities.WriteSurveyReportOutput
-	if err := workflow.ExecuteActivity(ctx, "WriteSurveyReportActivity", activities.WriteSurveyReportInput{
-		CorpusID:     input.CorpusID,
-		SurveyRunID:  input.SurveyRunID,
-		Report:       report,
-		OutputFormat: input.OutputFormat,
-	}).Get(ctx, &reportOut); err != nil {
-		return "", err
-	}
-	progress.TopicStatus[topicLabel] = "done"
-	progress.DoneTopics = 1
-	_ = workflow.ExecuteActivity(ctx, "UpdateSurveyRunActivity", activities.UpdateSurveyRunInput{SurveyRunID: input.SurveyRunID, Status: "completed", OutPath: reportOut.OutPath}).Get(ctx, nil)
-	return reportOut.OutPath, nil
-}
-
 func BackfillWorkflow(ctx workflow.Context, input BackfillInput) (string, error) {
 	ao := workflow.ActivityOptions{
 		StartToCloseTimeout: 5 * time.Minute,
@@ -463,16 +323,73 @@ func BackfillWorkflow(ctx workflow.Context, input BackfillInput) (string, error)
 		manifest["reembedded_papers"] = processed
 		manifest["total_papers_seen"] = len(all.Papers)
 	case "REGENERATE_SURVEY":
-		run := input.SurveyRunID
-		if strings.TrimSpace(run) == "" {
-			run = sanitizeID(fmt.Sprintf("%s-%d", input.CorpusID, workflow.Now(ctx).Unix()))
+		// SurveyRunID, when set, is the ID of the new run. ParentRunID names the run to
+		// regenerate: the new run inherits its topics and questions unless overridden, and its
+		// format, template, citation style, corpus scope, author and repair, critique and KG
+		// table settings, and links back to it as its parent.
+		parentRunID := strings.TrimSpace(input.ParentRunID)
+		topics, questions, format := input.Topics, input.Questions, ""
+		var templateID, citationStyle string
+		var corpusIDs []string
+		var author string
+		var repairRounds, critiqueRounds int
+		var kgTables bool
+		if parentRunID != "" {
+			var parent activities.GetSurveyRunOutput
+			if err := workflow.ExecuteActivity(ctx, "GetSurveyRunActivity", activities.GetSurveyRunInput{SurveyRunID: parentRunID}).Get(ctx, &parent); err != nil {
+				return "", err
+			}
+			if parent.Run.CorpusID != input.CorpusID {
+				return "", fmt.Errorf("survey run %s belongs to corpus %s, not %s", parentRunID, parent.Run.CorpusID, input.CorpusID)
+			}
+			if parent.Run.OutPath != "" {
+				var parentDoc activities.ReadSurveyDocumentOutput
+				if err := workflow.ExecuteActivity(ctx, "ReadSurveyDocumentActivity", activities.ReadSurveyDocumentInput{OutPath: parent.Run.OutPath}).Get(ctx, &parentDoc); err == nil {
+					d := parentDoc.Document
+					corpusIDs, author = d.CorpusIDs, d.Author
+					repairRounds, critiqueRounds, kgTables = d.RepairRounds, d.CritiqueRounds, d.KGTables
+				}
+			}
+			if len(topics) == 0 {
+				topics = parent.Run.Topics
+			}
+			if len(questions) == 0 {
+				questions = parent.Run.Questions
+			}
+			format = parent.Run.OutputFormat
+			templateID, citationStyle = parent.Run.TemplateID, parent.Run.CitationStyle
+		}
+		if format == "" {
+			format = survey.FormatLaTeX
+		}
+		run, err := createSurveyRun(ctx, activities.CreateSurveyRunInput{
+			SurveyRunID:   strings.TrimSpace(input.SurveyRunID),
+			CorpusID:      input.CorpusID,
+			ParentRunID:   parentRunID,
+			OutputFormat:  format,
+			TemplateID:    templateID,
+			CitationStyle: citationStyle,
+			Topics:        topics,
+			Questions:     questions,
+		})
+		if err != nil {
+			return "", err
 		}
 		var outPath string
-		if err := workflow.ExecuteChildWorkflow(ctx, SurveyBuildWorkflow, SurveyBuildInput{
+		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{WorkflowID: "survey-" + run})
+		if err := workflow.ExecuteChildWorkflow(childCtx, SurveyBuildWorkflow, SurveyBuildInput{
 			SurveyRunID:     run,
 			CorpusID:        input.CorpusID,
-			Topics:          input.Topics,
-			Questions:       input.Questions,
+			CorpusIDs:       corpusIDs,
+			Topics:          topics,
+			Author:          author,
+			Questions:       questions,
+			OutputFormat:    format,
+			TemplateID:      templateID,
+			CitationStyle:   citationStyle,
+			RepairRounds:    repairRounds,
+			CritiqueRounds:  critiqueRounds,
+			KGTables:        kgTables,
 			EmbedProviders:  defaultCount(input.EmbedProviders),
 			LLMProviders:    defaultCount(input.LLMProviders),
 			LLMProviderRefs: input.LLMProviderRefs,
@@ -482,7 +399,117 @@ func BackfillWorkflow(ctx workflow.Context, input BackfillInput) (string, error)
 			return "", err
 		}
 		manifest["regenerated_survey_run_id"] = run
+		if parentRunID != "" {
+			manifest["parent_survey_run_id"] = parentRunID
+		}
 		manifest["report_path"] = outPath
+	case "UPDATE_SURVEY":
+		// SurveyRunID names the completed run to bring up to date with papers ingested since it
+		// was created; the update is written as a new run linked to it.
+		baseRunID := strings.TrimSpace(input.SurveyRunID)
+		if baseRunID == "" {
+			return "", fmt.Errorf("UPDATE_SURVEY requires survey_run_id")
+		}
+		var base activities.GetSurveyRunOutput
+		if err := workflow.ExecuteActivity(ctx, "GetSurveyRunActivity", activities.GetSurveyRunInput{SurveyRunID: baseRunID}).Get(ctx, &base); err != nil {
+			return "", err
+		}
+		if base.Run.CorpusID != input.CorpusID {
+			return "", fmt.Errorf("survey run %s belongs to corpus %s, not %s", baseRunID, base.Run.CorpusID, input.CorpusID)
+		}
+		if base.Run.Status != "completed" || base.Run.OutPath == "" {
+			return "", fmt.Errorf("survey run %s is %s; only completed runs can be updated", baseRunID, base.Run.Status)
+		}
+		format := base.Run.OutputFormat
+		if format == "" {
+			format = survey.FormatLaTeX
+		}
+		run, err := createSurveyRun(ctx, activities.CreateSurveyRunInput{
+			CorpusID:      input.CorpusID,
+			ParentRunID:   baseRunID,
+			OutputFormat:  format,
+			TemplateID:    base.Run.TemplateID,
+			CitationStyle: base.Run.CitationStyle,
+			Topics:        base.Run.Topics,
+			Questions:     base.Run.Questions,
+		})
+		if err != nil {
+			return "", err
+		}
+		var outPath string
+		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{WorkflowID: "survey-" + run})
+		if err := workflow.ExecuteChildWorkflow(childCtx, SurveyUpdateWorkflow, SurveyUpdateInput{
+			SurveyRunID:     run,
+			BaseRunID:       baseRunID,
+			BaseOutPath:     base.Run.OutPath,
+			Since:           base.Run.CreatedAt,
+			CorpusID:        input.CorpusID,
+			OutputFormat:    format,
+			EmbedProviders:  defaultCount(input.EmbedProviders),
+			LLMProviders:    defaultCount(input.LLMProviders),
+			LLMProviderRefs: input.LLMProviderRefs,
+			CooldownSeconds: defaultSeconds(input.CooldownSeconds, 900),
+			EmbedVersion:    defaultEmbedVersion(input.EmbedVersion),
+		}).Get(ctx, &outPath); err != nil {
+			return "", err
+		}
+		manifest["updated_survey_run_id"] = run
+		manifest["parent_survey_run_id"] = baseRunID
+		manifest["report_path"] = outPath
+	case "SUMMARIZE_PAPERS":
+		var all activities.ListCorpusPapersOutput
+		if err := workflow.ExecuteActivity(ctx, "ListCorpusPapersActivity", activities.ListCorpusPapersInput{CorpusID: input.CorpusID}).Get(ctx, &all); err != nil {
+			return "", err
+		}
+		var existing activities.ListPaperSummariesOutput
+		if err := workflow.ExecuteActivity(ctx, "ListPaperSummariesActivity", activities.ListPaperSummariesInput{CorpusID: input.CorpusID}).Get(ctx, &existing); err != nil {
+			return "", err
+		}
+		current := make(map[string]bool, len(existing.Summaries))
+		for _, sum := range existing.Summaries {
+			current[sum.PaperID] = sum.PromptVersion == PaperSummaryPromptVersion
+		}
+		summarized, skipped, failed := 0, 0, 0
+		pending := make([]string, 0, len(all.Papers))
+		for _, p := range all.Papers {
+			if p.Status != "processed" {
+				continue
+			}
+			if current[p.PaperID] && !input.Force {
+				skipped++
+				continue
+			}
+			pending = append(pending, p.PaperID)
+		}
+		for i := 0; i < len(pending); i += summaryBackfillConcurrency {
+			end := min(i+summaryBackfillConcurrency, len(pending))
+			futures := make([]workflow.ChildWorkflowFuture, 0, end-i)
+			for _, paperID := range pending[i:end] {
+				futures = append(futures, workflow.ExecuteChildWorkflow(ctx, PaperSummarizeWorkflow, PaperSummarizeInput{
+					CorpusID:        input.CorpusID,
+					PaperID:         paperID,
+					LLMProviders:    defaultCount(input.LLMProviders),
+					LLMProviderRefs: input.LLMProviderRefs,
+					CooldownSeconds: defaultSeconds(input.CooldownSeconds, 900),
+				}))
+			}
+			for _, f := range futures {
+				var out string
+				err := f.Get(ctx, &out)
+				switch {
+				case err == nil && out == "completed":
+					summarized++
+				case err == nil && out == "skipped":
+					skipped++
+				default:
+					failed++
+				}
+			}
+		}
+		manifest["summary_prompt_version"] = PaperSummaryPromptVersion
+		manifest["summarized_papers"] = summarized
+		manifest["skipped_current_summaries"] = skipped
+		manifest["failed_summaries"] = failed
 	default:
 		return "", fmt.Errorf("unsupported backfill mode: %s", input.Mode)
 	}
@@ -677,6 +704,35 @@ func callLLMWithFailover(ctx workflow.Context, state *providerState, providerCou
 	return activities.LLMGenerateOutput{}, string(providers.ClassifyError(lastErr)), lastErr
 }
 
+// rewriteSurveyQueries expands a survey topic into retrieval queries. The topic itself is
+// always first; rewriting failures degrade to topic-only retrieval.
+func rewriteSurveyQueries(ctx workflow.Context, state *providerState, providerCount int, providerRefs []string, cooldown time.Duration, corpusID, topic, mode string, count int) []retrieval.RewrittenQuery {
+	queries := []retrieval.RewrittenQuery{{Kind: retrieval.QueryKindOriginal, Text: topic}}
+	if retrieval.UsesMultiQuery(mode) {
+		out, _, err := callLLMWithFailover(ctx, state, providerCount, providerRefs, cooldown, activities.LLMGenerateInput{
+			Operation: "query_rewrite",
+			CorpusID:  corpusID,
+			Prompt:    retrieval.BuildMultiQueryPrompt(topic, count),
+		}, nil)
+		if err == nil {
+			for _, q := range retrieval.ParseRewrittenQueries(out.Text, topic, count) {
+				queries = append(queries, retrieval.RewrittenQuery{Kind: retrieval.QueryKindParaphrase, Text: q})
+			}
+		}
+	}
+	if retrieval.UsesHyDE(mode) {
+		out, _, err := callLLMWithFailover(ctx, state, providerCount, providerRefs, cooldown, activities.LLMGenerateInput{
+			Operation: "query_hyde",
+			CorpusID:  corpusID,
+			Prompt:    retrieval.BuildHyDEPrompt(topic),
+		}, nil)
+		if passage := retrieval.CleanHyDEPassage(out.Text); err == nil && passage != "" {
+			queries = append(queries, retrieval.RewrittenQuery{Kind: retrieval.QueryKindHyDE, Text: passage})
+		}
+	}
+	return queries
+}
+
 func isProviderDisabled(ctx workflow.Context, state *providerState, idx int) bool {
 	until, ok := state.disabledUntil[idx]
 	if !ok {
@@ -728,18 +784,8 @@ func sanitizeID(s string) string {
 	return s
 }
 
-type surveyReference struct {
-	Key      string
-	PaperID  string
-	Title    string
-	Authors  string
-	Year     int
-	Filename string
-	ChunkIDs []string
-}
-
-func buildSurveyReferences(results []activities.SearchChunk) ([]surveyReference, []string) {
-	refs := make([]surveyReference, 0)
+func buildSurveyReferences(results []activities.SearchChunk, maxContextChars int) ([]SurveyReference, []string) {
+	refs := make([]SurveyReference, 0)
 	paperToIdx := map[string]int{}
 	context := make([]string, 0, len(results))
 	for _, c := range results {
@@ -755,10 +801,11 @@ func buildSurveyReferences(results []activities.SearchChunk) ([]surveyReference,
 			if title == "" {
 				title = "Untitled Source"
 			}
-			refs = append(refs, surveyReference{
-				Key:     fmt.Sprintf("ref%d", idx+1),
-				PaperID: paperID,
-				Title:   title,
+			refs = append(refs, SurveyReference{
+				Key:      fmt.Sprintf("ref%d", idx+1),
+				CorpusID: c.CorpusID,
+				PaperID:  paperID,
+				Title:    title,
 			})
 		}
 		if c.ChunkID != "" {
@@ -769,48 +816,23 @@ func buildSurveyReferences(results []activities.SearchChunk) ([]surveyReference,
 			refs[idx].Key,
 			refs[idx].Title,
 			c.ChunkID,
-			latexSanitizeContext(c.Text),
+			latexSanitizeContext(c.Text, maxContextChars),
 		))
 	}
 	return refs, context
 }
 
-func latexSanitizeContext(s string) string {
+func latexSanitizeContext(s string, maxChars int) string {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		return ""
 	}
+	if maxChars <= 0 {
+		maxChars = 1400
+	}
 	s = strings.ReplaceAll(s, "\n", " ")
 	s = strings.ReplaceAll(s, "\r", " ")
-	s = strings.Join(strings.Fields(s), " ")
-	if len(s) > 1400 {
-		s = s[:1400] + "..."
-	}
-	return s
-}
-
-func buildLatexPrompt(topic string, refs []surveyReference) string {
-	refLines := make([]string, 0, len(refs))
-	for _, ref := range refs {
-		refLines = append(refLines, fmt.Sprintf("- %s: %s", ref.Key, ref.Title))
-	}
-	return strings.Join([]string{
-		"Write a citation-grounded literature survey in LaTeX body format for this topic:",
-		topic,
-		"",
-		"Output requirements:",
-		"1. Output ONLY LaTeX content for the body (no \\documentclass, no bibliography environment, no code fences).",
-		"2. Include exactly one \\section{Related Work}; do not create one section per individual paper.",
-		"3. The Related Work section must synthesize papers thematically and compare methods/findings.",
-		"4. Use inline citation keys like [ref1], [ref2] directly in text (do not use \\cite).",
-		"5. Every source key listed below must appear at least once in the Related Work section.",
-		"6. Every factual claim must cite one or more listed keys; do not cite any key outside this list.",
-		"7. Do not include a bibliography or references section.",
-		"8. If evidence is weak, explicitly state limitations.",
-		"",
-		"Allowed citation keys:",
-		strings.Join(refLines, "\n"),
-	}, "\n")
+	return truncateRunes(strings.Join(strings.Fields(s), " "), maxChars)
 }
 
 func cleanLLMDocument(s string) string {
@@ -833,53 +855,7 @@ func cleanLLMDocument(s string) string {
 	return strings.TrimSpace(s)
 }
 
-func buildLatexDocument(topic string, refs []surveyReference, body string, generationFailed bool) string {
-	var b strings.Builder
-	b.WriteString("\\documentclass[conference]{IEEEtran}\n")
-	b.WriteString("\\usepackage[hidelinks]{hyperref}\n\n")
-	b.WriteString("\\title{Literature Survey: " + latexEscape(topic) + "}\n")
-	b.WriteString("\\author{LitFlow Automated Draft}\n\n")
-	b.WriteString("\\begin{document}\n")
-	b.WriteString("\\maketitle\n\n")
-	if strings.TrimSpace(body) == "" {
-		b.WriteString("\\begin{abstract}\n")
-		b.WriteString("This draft was generated from retrieved evidence but requires manual completion due to limited model output.\n")
-		b.WriteString("\\end{abstract}\n\n")
-		b.WriteString("\\section{Related Work}\n")
-		b.WriteString("This section summarizes the retrieved conference literature for the topic and requires manual expansion.\n")
-		b.WriteString("The current evidence pool includes " + inlineRefMentions(refs) + ".\n\n")
-	} else {
-		if !hasRelatedWorkSection(body) {
-			b.WriteString("\\section{Related Work}\n")
-			b.WriteString("This section synthesizes the retrieved conference literature for the topic. ")
-			b.WriteString("Core references considered in this synthesis include " + inlineRefMentions(refs) + ".\n\n")
-		}
-		b.WriteString(body + "\n\n")
-	}
-	if generationFailed {
-		b.WriteString("\\section*{Generation Note}\n")
-		b.WriteString("Model generation encountered an issue; review and expand this draft manually.\n\n")
-	}
-	b.WriteString("\\section*{Source Papers}\n")
-	b.WriteString("\\begin{itemize}\n")
-	for _, ref := range refs {
-		title := latexEscape(strings.TrimSpace(ref.Title))
-		if title == "" {
-			title = "Untitled paper"
-		}
-		b.WriteString("\\item [" + latexEscape(ref.Key) + "] " + title + "\n")
-	}
-	b.WriteString("\\end{itemize}\n\n")
-	b.WriteString("\\end{document}\n")
-	return b.String()
-}
-
-func hasRelatedWorkSection(body string) bool {
-	lower := strings.ToLower(body)
-	return strings.Contains(lower, "\\section{related work}")
-}
-
-func inlineRefMentions(refs []surveyReference) string {
+func inlineRefMentions(refs []SurveyReference) string {
 	if len(refs) == 0 {
 		return "the retrieved sources"
 	}
@@ -890,22 +866,6 @@ func inlineRefMentions(refs []surveyReference) string {
 	return strings.Join(keys, ", ")
 }
 
-func latexEscape(s string) string {
-	r := strings.NewReplacer(
-		`\`, `\textbackslash{}`,
-		`{`, `\{`,
-		`}`, `\}`,
-		`$`, `\$`,
-		`&`, `\&`,
-		`#`, `\#`,
-		`_`, `\_`,
-		`%`, `\%`,
-		`~`, `\textasciitilde{}`,
-		`^`, `\textasciicircum{}`,
-	)
-	return r.Replace(strings.TrimSpace(s))
-}
-
 func durationOrDefault(seconds int, fallback int) time.Duration {
 	if seconds <= 0 {
 		seconds = fallback
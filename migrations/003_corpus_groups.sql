@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS corpus_groups (
+  group_id UUID PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS corpus_group_members (
+  group_id UUID NOT NULL REFERENCES corpus_groups(group_id) ON DELETE CASCADE,
+  corpus_id UUID NOT NULL REFERENCES corpora(corpus_id) ON DELETE CASCADE,
+  PRIMARY KEY (group_id, corpus_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chunks_corpus_version ON chunks(corpus_id, embedding_version);
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS retrieval_eval_sets (
+  eval_set_id UUID PRIMARY KEY,
+  corpus_id UUID NOT NULL REFERENCES corpora(corpus_id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (corpus_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS retrieval_eval_questions (
+  question_id UUID PRIMARY KEY,
+  eval_set_id UUID NOT NULL REFERENCES retrieval_eval_sets(eval_set_id) ON DELETE CASCADE,
+  position INT NOT NULL,
+  question TEXT NOT NULL,
+  relevant_paper_ids TEXT[] NOT NULL DEFAULT '{}',
+  relevant_chunk_ids TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_eval_questions_set ON retrieval_eval_questions(eval_set_id, position);
+
+CREATE TABLE IF NOT EXISTS retrieval_eval_runs (
+  eval_run_id UUID PRIMARY KEY,
+  corpus_id UUID NOT NULL REFERENCES corpora(corpus_id) ON DELETE CASCADE,
+  eval_set_id UUID NOT NULL REFERENCES retrieval_eval_sets(eval_set_id) ON DELETE CASCADE,
+  status TEXT NOT NULL DEFAULT 'pending',
+  config JSONB NOT NULL DEFAULT '{}'::jsonb,
+  metrics JSONB,
+  out_path TEXT,
+  last_error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_eval_runs_corpus ON retrieval_eval_runs(corpus_id, created_at DESC);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS ask_sessions (
+  session_id UUID PRIMARY KEY,
+  corpus_id UUID NOT NULL REFERENCES corpora(corpus_id) ON DELETE CASCADE,
+  title TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ask_sessions_corpus ON ask_sessions(corpus_id, updated_at DESC);
+
+CREATE TABLE IF NOT EXISTS ask_turns (
+  turn_id UUID PRIMARY KEY,
+  session_id UUID NOT NULL REFERENCES ask_sessions(session_id) ON DELETE CASCADE,
+  position INT NOT NULL,
+  question TEXT NOT NULL,
+  standalone_query TEXT NOT NULL DEFAULT '',
+  answer TEXT NOT NULL DEFAULT '',
+  citations JSONB NOT NULL DEFAULT '[]'::jsonb,
+  llm_provider TEXT,
+  llm_model TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (session_id, position)
+);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS ask_log (
+  ask_id UUID PRIMARY KEY,
+  corpus_id UUID NOT NULL REFERENCES corpora(corpus_id) ON DELETE CASCADE,
+  corpus_ids UUID[] NOT NULL DEFAULT '{}',
+  session_id UUID REFERENCES ask_sessions(session_id) ON DELETE SET NULL,
+  replay_of UUID REFERENCES ask_log(ask_id) ON DELETE SET NULL,
+  question TEXT NOT NULL,
+  standalone_query TEXT NOT NULL DEFAULT '',
+  filters JSONB NOT NULL DEFAULT '{}'::jsonb,
+  rewritten_queries JSONB NOT NULL DEFAULT '[]'::jsonb,
+  embed_provider TEXT,
+  embed_model TEXT,
+  embed_version TEXT NOT NULL,
+  citations JSONB NOT NULL DEFAULT '[]'::jsonb,
+  prompt_version TEXT NOT NULL,
+  llm_provider TEXT,
+  llm_model TEXT,
+  answer TEXT NOT NULL DEFAULT '',
+  grounding_score DOUBLE PRECISION,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ask_log_corpus ON ask_log(corpus_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_ask_log_replay_of ON ask_log(replay_of);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS research_runs (
+  research_run_id UUID PRIMARY KEY,
+  corpus_id UUID NOT NULL REFERENCES corpora(corpus_id) ON DELETE CASCADE,
+  question TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('pending','running','completed','failed')),
+  config JSONB NOT NULL DEFAULT '{}'::jsonb,
+  out_path TEXT,
+  last_error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_research_runs_corpus ON research_runs(corpus_id, created_at DESC);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS paper_summaries (
+  paper_id TEXT PRIMARY KEY REFERENCES papers(paper_id) ON DELETE CASCADE,
+  corpus_id UUID NOT NULL REFERENCES corpora(corpus_id) ON DELETE CASCADE,
+  tldr TEXT NOT NULL,
+  contributions JSONB NOT NULL DEFAULT '[]'::jsonb,
+  method TEXT NOT NULL DEFAULT '',
+  results TEXT NOT NULL DEFAULT '',
+  limitations TEXT NOT NULL DEFAULT '',
+  prompt_version TEXT NOT NULL,
+  llm_provider TEXT,
+  llm_model TEXT,
+  chunk_count INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_paper_summaries_corpus ON paper_summaries(corpus_id);
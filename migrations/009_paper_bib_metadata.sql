@@ -0,0 +1,2 @@
+ALTER TABLE papers ADD COLUMN IF NOT EXISTS venue TEXT;
+ALTER TABLE papers ADD COLUMN IF NOT EXISTS doi TEXT;
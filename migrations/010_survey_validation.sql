@@ -0,0 +1 @@
+ALTER TABLE survey_runs ADD COLUMN IF NOT EXISTS validation JSONB;
@@ -0,0 +1,7 @@
+ALTER TABLE survey_runs ADD COLUMN IF NOT EXISTS parent_run_id UUID REFERENCES survey_runs(survey_run_id) ON DELETE SET NULL;
+ALTER TABLE survey_runs ADD COLUMN IF NOT EXISTS output_format TEXT NOT NULL DEFAULT 'latex';
+ALTER TABLE survey_runs ADD COLUMN IF NOT EXISTS llm_models JSONB NOT NULL DEFAULT '[]'::jsonb;
+ALTER TABLE survey_runs ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_survey_runs_corpus_created ON survey_runs(corpus_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_survey_runs_parent ON survey_runs(parent_run_id);
@@ -0,0 +1,2 @@
+ALTER TABLE survey_runs ADD COLUMN IF NOT EXISTS template_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE survey_runs ADD COLUMN IF NOT EXISTS citation_style TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS drafts (
+  draft_id UUID PRIMARY KEY,
+  corpus_id UUID NOT NULL REFERENCES corpora(corpus_id) ON DELETE CASCADE,
+  filename TEXT NOT NULL,
+  format TEXT NOT NULL CHECK (format IN ('pdf','latex')),
+  file_path TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_drafts_corpus ON drafts(corpus_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS positioning_runs (
+  positioning_run_id UUID PRIMARY KEY,
+  draft_id UUID NOT NULL REFERENCES drafts(draft_id) ON DELETE CASCADE,
+  corpus_id UUID NOT NULL REFERENCES corpora(corpus_id) ON DELETE CASCADE,
+  status TEXT NOT NULL CHECK (status IN ('pending','running','completed','failed')),
+  config JSONB NOT NULL DEFAULT '{}'::jsonb,
+  out_path TEXT,
+  last_error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_positioning_runs_draft ON positioning_runs(draft_id, created_at DESC);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_chunks_text_search ON chunks USING GIN (to_tsvector('english', text));
@@ -0,0 +1,3 @@
+ALTER TABLE chunks ADD COLUMN IF NOT EXISTS chunk_version TEXT NOT NULL DEFAULT 'v1';
+
+CREATE INDEX IF NOT EXISTS idx_chunks_corpus_chunk_version ON chunks(corpus_id, chunk_version);